@@ -0,0 +1,227 @@
+package auth
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+
+	"github.com/mitchellh/go-homedir"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// AccountsDirName is the directory the accounts file is kept in
+	AccountsDirName = ".githubby"
+
+	// AccountsFileName is the name of the file listing the logged in accounts
+	AccountsFileName = "accounts.yaml"
+
+	// DefaultAccountName is how the unnamed account of a host is displayed
+	DefaultAccountName = "default"
+)
+
+// accountNamePattern matches valid account names
+var accountNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,63}$`)
+
+// Account is a login saved with "githubby login". Every host has a default
+// account with an empty name, and any number of named accounts
+// ("githubby login --account work").
+type Account struct {
+	// Name is the account name, empty for the default account of the host
+	Name string `yaml:"name,omitempty"`
+
+	// Hostname is the normalized GitHub hostname
+	Hostname string `yaml:"hostname"`
+
+	// Login is the GitHub username the token belongs to
+	Login string `yaml:"login,omitempty"`
+}
+
+// DisplayName returns the account name, or "default" for the default account
+func (a Account) DisplayName() string {
+	if a.Name == "" {
+		return DefaultAccountName
+	}
+	return a.Name
+}
+
+// ValidateAccountName checks an account name. An empty name is the default
+// account.
+func ValidateAccountName(name string) error {
+	if name == "" {
+		return nil
+	}
+	if name == DefaultAccountName {
+		return fmt.Errorf("account name %q is reserved for the default account", name)
+	}
+	if !accountNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid account name %q (use letters, digits, '.', '-' and '_')", name)
+	}
+	return nil
+}
+
+// accountKey returns the keychain entry of an account: the hostname for the
+// default account, so tokens stored before named accounts keep working
+func accountKey(name, hostname string) string {
+	if name == "" {
+		return hostname
+	}
+	return name + "@" + hostname
+}
+
+// accountEntry is an account in the accounts file
+type accountEntry struct {
+	Account `yaml:",inline"`
+
+	// Token is only set if there is neither keychain nor encrypted store, or
+	// by older versions; see MigratePlaintextTokens
+	Token string `yaml:"token,omitempty"`
+}
+
+// accountsFileData represents the structure of the accounts file
+type accountsFileData struct {
+	Accounts []accountEntry `yaml:"accounts"`
+}
+
+// getAccountsPath returns the path to the accounts file, next to the config
+// file when a custom config path is set
+func (s *Storage) getAccountsPath() (string, error) {
+	if s.configPath != "" {
+		return filepath.Join(filepath.Dir(s.configPath), AccountsFileName), nil
+	}
+
+	home, err := homedir.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, AccountsDirName, AccountsFileName), nil
+}
+
+// readAccounts reads the accounts file, which may not exist yet
+func (s *Storage) readAccounts() (*accountsFileData, error) {
+	path, err := s.getAccountsPath()
+	if err != nil {
+		return nil, err
+	}
+
+	var data accountsFileData
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &data, nil
+		}
+		return nil, err
+	}
+	if err := yaml.Unmarshal(content, &data); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return &data, nil
+}
+
+// updateAccounts applies fn to the accounts file and writes it back
+func (s *Storage) updateAccounts(fn func(data *accountsFileData)) error {
+	data, err := s.readAccounts()
+	if err != nil {
+		return err
+	}
+	fn(data)
+
+	path, err := s.getAccountsPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	content, err := yaml.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, content, 0600)
+}
+
+// find returns the index of an account, or -1
+func (d *accountsFileData) find(name, hostname string) int {
+	for i, entry := range d.Accounts {
+		if entry.Name == name && entry.Hostname == hostname {
+			return i
+		}
+	}
+	return -1
+}
+
+// ListAccounts returns the accounts logged in with "githubby login", sorted
+// by hostname with the default account of each host first
+func (s *Storage) ListAccounts() ([]Account, error) {
+	data, err := s.readAccounts()
+	if err != nil {
+		return nil, err
+	}
+
+	accounts := make([]Account, 0, len(data.Accounts))
+	for _, entry := range data.Accounts {
+		accounts = append(accounts, entry.Account)
+	}
+	sort.SliceStable(accounts, func(i, j int) bool {
+		if accounts[i].Hostname != accounts[j].Hostname {
+			return accounts[i].Hostname < accounts[j].Hostname
+		}
+		return accounts[i].Name < accounts[j].Name
+	})
+	return accounts, nil
+}
+
+// SaveAccount adds an account to the accounts list, or updates its login
+func (s *Storage) SaveAccount(account Account) error {
+	if account.Hostname == "" {
+		account.Hostname = DefaultHostname
+	}
+	return s.updateAccounts(func(data *accountsFileData) {
+		if i := data.find(account.Name, account.Hostname); i >= 0 {
+			data.Accounts[i].Account = account
+			return
+		}
+		data.Accounts = append(data.Accounts, accountEntry{Account: account})
+	})
+}
+
+// RemoveAccount removes an account from the accounts list
+func (s *Storage) RemoveAccount(name, hostname string) error {
+	if hostname == "" {
+		hostname = DefaultHostname
+	}
+	return s.updateAccounts(func(data *accountsFileData) {
+		if i := data.find(name, hostname); i >= 0 {
+			data.Accounts = append(data.Accounts[:i], data.Accounts[i+1:]...)
+		}
+	})
+}
+
+// getTokenFromAccounts retrieves the token of a named account from the
+// accounts file
+func (s *Storage) getTokenFromAccounts(name, hostname string) (string, error) {
+	data, err := s.readAccounts()
+	if err != nil {
+		return "", err
+	}
+	if i := data.find(name, hostname); i >= 0 {
+		return data.Accounts[i].Token, nil
+	}
+	return "", nil
+}
+
+// setTokenInAccounts stores the token of a named account in the accounts file
+func (s *Storage) setTokenInAccounts(name, hostname, token string) error {
+	return s.updateAccounts(func(data *accountsFileData) {
+		if i := data.find(name, hostname); i >= 0 {
+			data.Accounts[i].Token = token
+			return
+		}
+		data.Accounts = append(data.Accounts, accountEntry{
+			Account: Account{Name: name, Hostname: hostname},
+			Token:   token,
+		})
+	})
+}
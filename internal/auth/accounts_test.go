@@ -0,0 +1,86 @@
+package auth
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateAccountName(t *testing.T) {
+	for _, name := range []string{"", "work", "ci-bot", "acme.corp_2"} {
+		assert.NoError(t, ValidateAccountName(name), name)
+	}
+	for _, name := range []string{"default", "work@github.com", "-work", "my account", "a/b"} {
+		assert.Error(t, ValidateAccountName(name), name)
+	}
+}
+
+func TestAccount_DisplayName(t *testing.T) {
+	assert.Equal(t, "default", Account{Hostname: DefaultHostname}.DisplayName())
+	assert.Equal(t, "work", Account{Name: "work", Hostname: DefaultHostname}.DisplayName())
+}
+
+func TestStorage_AccountTokens_ConfigFile(t *testing.T) {
+	configPath, cleanup := createTempConfig(t)
+	defer cleanup()
+
+	storage := NewStorageWithConfig(configPath, false)
+	require.NoError(t, storage.SetToken(DefaultHostname, "ghp_personal"))
+	require.NoError(t, storage.SetAccountToken("work", DefaultHostname, "ghp_work"))
+	require.NoError(t, storage.SaveAccount(Account{Name: "work", Hostname: DefaultHostname, Login: "alice-acme"}))
+	require.NoError(t, storage.SaveAccount(Account{Hostname: DefaultHostname, Login: "alice"}))
+
+	token, source, err := storage.GetAccountToken("work", DefaultHostname)
+	require.NoError(t, err)
+	assert.Equal(t, "ghp_work", token)
+	assert.Equal(t, TokenSourceConfig, source)
+	token, _, err = storage.GetToken(DefaultHostname)
+	require.NoError(t, err)
+	assert.Equal(t, "ghp_personal", token, "the default account is unaffected")
+
+	// Named accounts don't fall back to the default account's token
+	_, _, err = storage.GetAccountToken("ci", DefaultHostname)
+	assert.Error(t, err)
+	_, _, err = storage.GetAccountToken("work", "github.mycompany.com")
+	assert.Error(t, err)
+
+	accounts, err := storage.ListAccounts()
+	require.NoError(t, err)
+	assert.Equal(t, []Account{
+		{Hostname: DefaultHostname, Login: "alice"},
+		{Name: "work", Hostname: DefaultHostname, Login: "alice-acme"},
+	}, accounts)
+
+	// Saving the account again keeps its token
+	require.NoError(t, storage.SaveAccount(Account{Name: "work", Hostname: DefaultHostname, Login: "alice-acme"}))
+	token, _, err = storage.GetAccountToken("work", DefaultHostname)
+	require.NoError(t, err)
+	assert.Equal(t, "ghp_work", token)
+
+	require.NoError(t, storage.DeleteAccountToken("work", DefaultHostname))
+	_, _, err = storage.GetAccountToken("work", DefaultHostname)
+	assert.Error(t, err)
+	accounts, err = storage.ListAccounts()
+	require.NoError(t, err)
+	assert.Equal(t, []Account{{Hostname: DefaultHostname, Login: "alice"}}, accounts)
+}
+
+func TestGetAccountToken_IgnoresEnvironment(t *testing.T) {
+	t.Setenv(EnvGitHubToken, "ghp_env")
+	t.Setenv("HOME", t.TempDir())
+
+	result, err := GetAccountToken(context.Background(), "", "", DefaultHostname)
+	require.NoError(t, err)
+	assert.Equal(t, "ghp_env", result.Token)
+
+	result, err = GetAccountToken(context.Background(), "", "work", DefaultHostname)
+	require.NoError(t, err)
+	assert.Empty(t, result.Token, "named accounts never use GITHUB_TOKEN")
+	assert.Equal(t, "work", result.Account)
+
+	result, err = GetAccountToken(context.Background(), "ghp_flag", "work", DefaultHostname)
+	require.NoError(t, err)
+	assert.Equal(t, "ghp_flag", result.Token)
+}
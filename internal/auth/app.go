@@ -0,0 +1,282 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	gh "github.com/google/go-github/v68/github"
+	"golang.org/x/oauth2"
+)
+
+const (
+	// EnvGitHubAppID is the environment variable for the ID of a GitHub App
+	// to authenticate as
+	EnvGitHubAppID = "GITHUB_APP_ID"
+
+	// EnvGitHubAppPrivateKey is the environment variable for the App's
+	// private key (PEM)
+	EnvGitHubAppPrivateKey = "GITHUB_APP_PRIVATE_KEY"
+
+	// EnvGitHubAppPrivateKeyFile is the environment variable for the path to
+	// the App's private key (PEM)
+	EnvGitHubAppPrivateKeyFile = "GITHUB_APP_PRIVATE_KEY_FILE"
+
+	// EnvGitHubAppInstallationID is the environment variable for the
+	// installation to authenticate as, if the App has more than one
+	EnvGitHubAppInstallationID = "GITHUB_APP_INSTALLATION_ID"
+
+	// appJWTLifetime is how long App JWTs are valid. GitHub accepts up to 10
+	// minutes; the rest is left for clock drift.
+	appJWTLifetime = 9 * time.Minute
+
+	// appTokenRefreshMargin is how long before their expiry installation
+	// tokens are replaced, so requests and git commands never start with a
+	// token that's about to expire
+	appTokenRefreshMargin = 5 * time.Minute
+)
+
+// AppConfig identifies a GitHub App installation to authenticate as
+type AppConfig struct {
+	// AppID is the App's ID, shown on its settings page
+	AppID int64
+
+	// PrivateKey signs the JWTs that authenticate as the App
+	PrivateKey *rsa.PrivateKey
+
+	// InstallationID is the installation to get tokens for (0 = the
+	// installation on Owner, or the App's only installation)
+	InstallationID int64
+
+	// Owner is the user or organization whose repositories are synced; its
+	// installation is used if InstallationID isn't set (optional)
+	Owner string
+
+	// Hostname is the normalized hostname of the GitHub instance (empty =
+	// github.com)
+	Hostname string
+
+	// HTTPClient sends the App's requests (optional)
+	HTTPClient *http.Client
+}
+
+// AppConfigFromEnv reads the GitHub App to authenticate as on hostname from
+// GITHUB_APP_ID, GITHUB_APP_PRIVATE_KEY or GITHUB_APP_PRIVATE_KEY_FILE, and
+// GITHUB_APP_INSTALLATION_ID. Returns nil if GITHUB_APP_ID isn't set.
+func AppConfigFromEnv(hostname string) (*AppConfig, error) {
+	appID := strings.TrimSpace(os.Getenv(EnvGitHubAppID))
+	if appID == "" {
+		return nil, nil
+	}
+
+	config := &AppConfig{Hostname: hostname}
+	var err error
+	if config.AppID, err = strconv.ParseInt(appID, 10, 64); err != nil || config.AppID <= 0 {
+		return nil, fmt.Errorf("invalid %s %q: expected the numeric App ID", EnvGitHubAppID, appID)
+	}
+	if id := strings.TrimSpace(os.Getenv(EnvGitHubAppInstallationID)); id != "" {
+		if config.InstallationID, err = strconv.ParseInt(id, 10, 64); err != nil || config.InstallationID <= 0 {
+			return nil, fmt.Errorf("invalid %s %q: expected a numeric installation ID", EnvGitHubAppInstallationID, id)
+		}
+	}
+
+	key := []byte(os.Getenv(EnvGitHubAppPrivateKey))
+	if path := os.Getenv(EnvGitHubAppPrivateKeyFile); len(key) == 0 && path != "" {
+		if key, err = os.ReadFile(path); err != nil {
+			return nil, fmt.Errorf("failed to read GitHub App private key: %w", err)
+		}
+	}
+	if len(key) == 0 {
+		return nil, fmt.Errorf("%s is set, but neither %s nor %s is", EnvGitHubAppID, EnvGitHubAppPrivateKey, EnvGitHubAppPrivateKeyFile)
+	}
+	if config.PrivateKey, err = ParseAppPrivateKey(key); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// ParseAppPrivateKey parses a GitHub App private key: PEM encoded PKCS#1, as
+// GitHub generates them, or PKCS#8
+func ParseAppPrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("invalid GitHub App private key: no PEM data found")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid GitHub App private key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("invalid GitHub App private key: not an RSA key")
+	}
+	return key, nil
+}
+
+// JWT returns a JSON Web Token that authenticates as the App itself
+func (c *AppConfig) JWT(now time.Time) (string, error) {
+	encode := func(v any) (string, error) {
+		data, err := json.Marshal(v)
+		return base64.RawURLEncoding.EncodeToString(data), err
+	}
+	header, err := encode(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	// Backdated a minute against clock drift, as GitHub recommends
+	claims, err := encode(map[string]any{
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(appJWTLifetime).Unix(),
+		"iss": strconv.FormatInt(c.AppID, 10),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	signed := header + "." + claims
+	digest := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, c.PrivateKey, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign GitHub App JWT: %w", err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// TokenSource returns a source of installation tokens that gets a new token
+// shortly before the current one expires (after an hour), so long syncs
+// keep working. Tokens outlive ctx: only its values are used.
+func (c *AppConfig) TokenSource(ctx context.Context) oauth2.TokenSource {
+	return oauth2.ReuseTokenSourceWithExpiry(nil, &appTokenSource{ctx: context.WithoutCancel(ctx), config: c}, appTokenRefreshMargin)
+}
+
+// ownerKey is the context key of the owner App tokens are for
+type ownerKey struct{}
+
+// WithOwner returns a context whose GitHub App tokens are for the
+// installation on owner, a user or organization, unless
+// GITHUB_APP_INSTALLATION_ID chooses one
+func WithOwner(ctx context.Context, owner string) context.Context {
+	return context.WithValue(ctx, ownerKey{}, owner)
+}
+
+// ownerFrom returns the owner set with WithOwner, or an empty string
+func ownerFrom(ctx context.Context) string {
+	owner, _ := ctx.Value(ownerKey{}).(string)
+	return owner
+}
+
+// appTokenSource gets a new installation token every time it's asked
+type appTokenSource struct {
+	ctx    context.Context
+	config *AppConfig
+
+	// installationID is the installation found, if none was configured
+	mu             sync.Mutex
+	installationID int64
+}
+
+// appClient returns a client that authenticates as the App itself
+func (s *appTokenSource) appClient() (*gh.Client, error) {
+	jwt, err := s.config.JWT(time.Now())
+	if err != nil {
+		return nil, err
+	}
+	ctx := s.ctx
+	if s.config.HTTPClient != nil {
+		ctx = context.WithValue(ctx, oauth2.HTTPClient, s.config.HTTPClient)
+	}
+	client := gh.NewClient(oauth2.NewClient(ctx, oauth2.StaticTokenSource(&oauth2.Token{AccessToken: jwt, TokenType: "Bearer"})))
+	client.BaseURL = APIURL(s.config.Hostname)
+	client.UploadURL = UploadURL(s.config.Hostname)
+	return client, nil
+}
+
+// installation returns the ID of the installation to get tokens for
+func (s *appTokenSource) installation(client *gh.Client) (int64, error) {
+	if s.config.InstallationID != 0 {
+		return s.config.InstallationID, nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.installationID != 0 {
+		return s.installationID, nil
+	}
+	if s.config.Owner != "" {
+		id, err := s.ownerInstallation(client)
+		if err != nil {
+			return 0, err
+		}
+		s.installationID = id
+		return id, nil
+	}
+
+	installations, _, err := client.Apps.ListInstallations(s.ctx, &gh.ListOptions{PerPage: 100})
+	if err != nil {
+		return 0, fmt.Errorf("failed to list the GitHub App's installations: %w", err)
+	}
+	switch len(installations) {
+	case 0:
+		return 0, fmt.Errorf("GitHub App %d isn't installed on any account", s.config.AppID)
+	case 1:
+		s.installationID = installations[0].GetID()
+		return s.installationID, nil
+	}
+	accounts := make([]string, 0, len(installations))
+	for _, installation := range installations {
+		accounts = append(accounts, fmt.Sprintf("%s (%d)", installation.GetAccount().GetLogin(), installation.GetID()))
+	}
+	return 0, fmt.Errorf("GitHub App %d is installed on several accounts: %s; set %s to choose one",
+		s.config.AppID, strings.Join(accounts, ", "), EnvGitHubAppInstallationID)
+}
+
+// ownerInstallation returns the ID of the installation on the owner, an
+// organization or a user
+func (s *appTokenSource) ownerInstallation(client *gh.Client) (int64, error) {
+	owner := s.config.Owner
+	installation, resp, err := client.Apps.FindOrganizationInstallation(s.ctx, owner)
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		installation, resp, err = client.Apps.FindUserInstallation(s.ctx, owner)
+	}
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return 0, fmt.Errorf("GitHub App %d isn't installed on %s", s.config.AppID, owner)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to find the GitHub App's installation on %s: %w", owner, err)
+	}
+	return installation.GetID(), nil
+}
+
+// Token gets a new installation token
+func (s *appTokenSource) Token() (*oauth2.Token, error) {
+	client, err := s.appClient()
+	if err != nil {
+		return nil, err
+	}
+	id, err := s.installation(client)
+	if err != nil {
+		return nil, fmt.Errorf("GitHub App authentication failed: %w", err)
+	}
+
+	token, _, err := client.Apps.CreateInstallationToken(s.ctx, id, nil)
+	if err != nil {
+		return nil, fmt.Errorf("GitHub App authentication failed: failed to get a token for installation %d: %w", id, err)
+	}
+	return &oauth2.Token{AccessToken: token.GetToken(), TokenType: "token", Expiry: token.GetExpiresAt().Time}, nil
+}
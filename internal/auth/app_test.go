@@ -0,0 +1,277 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testAppKey creates a private key and its PEM encoding (PKCS#1)
+func testAppKey(t *testing.T) (*rsa.PrivateKey, []byte) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	return key, pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+}
+
+func TestAppConfig_JWT(t *testing.T) {
+	key, _ := testAppKey(t)
+	config := &AppConfig{AppID: 42, PrivateKey: key}
+
+	now := time.Unix(1_700_000_000, 0)
+	jwt, err := config.JWT(now)
+	require.NoError(t, err)
+	parts := strings.Split(jwt, ".")
+	require.Len(t, parts, 3)
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	require.NoError(t, err)
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	assert.NoError(t, rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], signature))
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	require.NoError(t, err)
+	var claims struct {
+		IssuedAt  int64  `json:"iat"`
+		ExpiresAt int64  `json:"exp"`
+		Issuer    string `json:"iss"`
+	}
+	require.NoError(t, json.Unmarshal(payload, &claims))
+	assert.Equal(t, "42", claims.Issuer)
+	assert.Equal(t, now.Add(-time.Minute).Unix(), claims.IssuedAt)
+	assert.Equal(t, now.Add(appJWTLifetime).Unix(), claims.ExpiresAt)
+}
+
+func TestParseAppPrivateKey(t *testing.T) {
+	key, pkcs1 := testAppKey(t)
+	parsed, err := ParseAppPrivateKey(pkcs1)
+	require.NoError(t, err)
+	assert.True(t, key.Equal(parsed))
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	parsed, err = ParseAppPrivateKey(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	require.NoError(t, err)
+	assert.True(t, key.Equal(parsed))
+
+	_, err = ParseAppPrivateKey([]byte("not a key"))
+	assert.ErrorContains(t, err, "no PEM data")
+}
+
+func TestAppConfigFromEnv(t *testing.T) {
+	_, keyPEM := testAppKey(t)
+	t.Setenv(EnvGitHubAppID, "")
+	t.Setenv(EnvGitHubAppPrivateKey, "")
+	t.Setenv(EnvGitHubAppPrivateKeyFile, "")
+	t.Setenv(EnvGitHubAppInstallationID, "")
+
+	config, err := AppConfigFromEnv(DefaultHostname)
+	require.NoError(t, err)
+	assert.Nil(t, config, "no App is configured")
+
+	t.Setenv(EnvGitHubAppID, "42")
+	_, err = AppConfigFromEnv(DefaultHostname)
+	assert.ErrorContains(t, err, EnvGitHubAppPrivateKey)
+
+	keyFile := filepath.Join(t.TempDir(), "app.pem")
+	require.NoError(t, os.WriteFile(keyFile, keyPEM, 0600))
+	t.Setenv(EnvGitHubAppPrivateKeyFile, keyFile)
+	t.Setenv(EnvGitHubAppInstallationID, "7")
+	config, err = AppConfigFromEnv("github.mycompany.com")
+	require.NoError(t, err)
+	assert.Equal(t, int64(42), config.AppID)
+	assert.Equal(t, int64(7), config.InstallationID)
+	assert.Equal(t, "github.mycompany.com", config.Hostname)
+	assert.NotNil(t, config.PrivateKey)
+
+	t.Setenv(EnvGitHubAppID, "my-app")
+	_, err = AppConfigFromEnv(DefaultHostname)
+	assert.ErrorContains(t, err, "numeric App ID")
+}
+
+// newTestAppServer serves the App endpoints of a GitHub Enterprise Server
+// with the given installations, handing out tokens that expire after
+// tokenLifetime
+func newTestAppServer(t *testing.T, installations []map[string]any, tokenLifetime time.Duration) (*httptest.Server, *int32) {
+	t.Helper()
+	var issued int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v3/app/installations", func(w http.ResponseWriter, r *http.Request) {
+		assert.True(t, strings.HasPrefix(r.Header.Get("Authorization"), "Bearer "), "authenticated as the App")
+		w.Header().Set("Content-Type", "application/json")
+		require.NoError(t, json.NewEncoder(w).Encode(installations))
+	})
+	mux.HandleFunc("/api/v3/app/installations/7/access_tokens", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		n := atomic.AddInt32(&issued, 1)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"token":"ghs_token%d","expires_at":%q}`, n, time.Now().Add(tokenLifetime).UTC().Format(time.RFC3339))
+	})
+	// Installations on an account are found by login and account type;
+	// other installations' tokens are named after them
+	findInstallation := func(accountType string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			for _, installation := range installations {
+				account, _ := installation["account"].(map[string]any)
+				if account["login"] == r.PathValue("login") && account["type"] == accountType {
+					w.Header().Set("Content-Type", "application/json")
+					require.NoError(t, json.NewEncoder(w).Encode(installation))
+					return
+				}
+			}
+			http.Error(w, `{"message":"Not Found"}`, http.StatusNotFound)
+		}
+	}
+	mux.HandleFunc("/api/v3/orgs/{login}/installation", findInstallation("Organization"))
+	mux.HandleFunc("/api/v3/users/{login}/installation", findInstallation("User"))
+	mux.HandleFunc("/api/v3/app/installations/{id}/access_tokens", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"token":"ghs_installation%s","expires_at":%q}`, r.PathValue("id"), time.Now().Add(tokenLifetime).UTC().Format(time.RFC3339))
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server, &issued
+}
+
+func TestAppTokenSource(t *testing.T) {
+	key, _ := testAppKey(t)
+	installation := map[string]any{"id": 7, "account": map[string]any{"login": "acme"}}
+
+	t.Run("finds the only installation and reuses tokens", func(t *testing.T) {
+		server, issued := newTestAppServer(t, []map[string]any{installation}, time.Hour)
+		config := &AppConfig{AppID: 42, PrivateKey: key, Hostname: server.URL}
+
+		tokens := config.TokenSource(context.Background())
+		token, err := tokens.Token()
+		require.NoError(t, err)
+		assert.Equal(t, "ghs_token1", token.AccessToken)
+		assert.WithinDuration(t, time.Now().Add(time.Hour), token.Expiry, time.Minute)
+
+		token, err = tokens.Token()
+		require.NoError(t, err)
+		assert.Equal(t, "ghs_token1", token.AccessToken)
+		assert.Equal(t, int32(1), atomic.LoadInt32(issued))
+	})
+
+	t.Run("replaces tokens before they expire", func(t *testing.T) {
+		server, issued := newTestAppServer(t, []map[string]any{installation}, appTokenRefreshMargin-time.Minute)
+		config := &AppConfig{AppID: 42, PrivateKey: key, InstallationID: 7, Hostname: server.URL}
+
+		tokens := config.TokenSource(context.Background())
+		first, err := tokens.Token()
+		require.NoError(t, err)
+		second, err := tokens.Token()
+		require.NoError(t, err)
+		assert.NotEqual(t, first.AccessToken, second.AccessToken)
+		assert.Equal(t, int32(2), atomic.LoadInt32(issued))
+	})
+
+	t.Run("several installations need an ID", func(t *testing.T) {
+		other := map[string]any{"id": 8, "account": map[string]any{"login": "globex"}}
+		server, _ := newTestAppServer(t, []map[string]any{installation, other}, time.Hour)
+		config := &AppConfig{AppID: 42, PrivateKey: key, Hostname: server.URL}
+
+		_, err := config.TokenSource(context.Background()).Token()
+		assert.ErrorContains(t, err, "acme (7), globex (8)")
+		assert.ErrorContains(t, err, EnvGitHubAppInstallationID)
+	})
+
+	t.Run("uses the installation on the owner", func(t *testing.T) {
+		installations := []map[string]any{
+			{"id": 7, "account": map[string]any{"login": "acme", "type": "Organization"}},
+			{"id": 8, "account": map[string]any{"login": "bob", "type": "User"}},
+		}
+		server, _ := newTestAppServer(t, installations, time.Hour)
+
+		token := func(owner string) (string, error) {
+			config := &AppConfig{AppID: 42, PrivateKey: key, Hostname: server.URL, Owner: owner}
+			token, err := config.TokenSource(context.Background()).Token()
+			if err != nil {
+				return "", err
+			}
+			return token.AccessToken, nil
+		}
+		org, err := token("acme")
+		require.NoError(t, err)
+		assert.Equal(t, "ghs_token1", org)
+		user, err := token("bob")
+		require.NoError(t, err)
+		assert.Equal(t, "ghs_installation8", user)
+		_, err = token("globex")
+		assert.ErrorContains(t, err, "isn't installed on globex")
+	})
+
+	t.Run("outlives the context", func(t *testing.T) {
+		server, _ := newTestAppServer(t, []map[string]any{installation}, time.Hour)
+		config := &AppConfig{AppID: 42, PrivateKey: key, InstallationID: 7, Hostname: server.URL}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		tokens := config.TokenSource(ctx)
+		cancel()
+		_, err := tokens.Token()
+		assert.NoError(t, err, "tokens are renewed after the command that resolved them finishes")
+	})
+}
+
+func TestGetToken_GitHubAppOwner(t *testing.T) {
+	_, keyPEM := testAppKey(t)
+	installations := []map[string]any{
+		{"id": 7, "account": map[string]any{"login": "acme", "type": "Organization"}},
+		{"id": 8, "account": map[string]any{"login": "bob", "type": "User"}},
+	}
+	server, _ := newTestAppServer(t, installations, time.Hour)
+	t.Setenv(EnvGitHubAppID, "42")
+	t.Setenv(EnvGitHubAppPrivateKey, string(keyPEM))
+	t.Setenv(EnvGitHubAppPrivateKeyFile, "")
+	t.Setenv(EnvGitHubAppInstallationID, "")
+
+	result, err := GetToken(WithOwner(context.Background(), "bob"), "", server.URL)
+	require.NoError(t, err)
+	assert.Equal(t, "ghs_installation8", result.Token)
+
+	_, err = GetToken(context.Background(), "", server.URL)
+	assert.ErrorContains(t, err, "several accounts", "without an owner, the App's only installation is used")
+}
+
+func TestGetToken_GitHubApp(t *testing.T) {
+	_, keyPEM := testAppKey(t)
+	server, _ := newTestAppServer(t, []map[string]any{{"id": 7}}, time.Hour)
+	host := server.URL
+	t.Setenv(EnvGitHubAppID, "42")
+	t.Setenv(EnvGitHubAppPrivateKey, string(keyPEM))
+	t.Setenv(EnvGitHubAppPrivateKeyFile, "")
+	t.Setenv(EnvGitHubAppInstallationID, "")
+	t.Setenv(EnvGitHubEnterpriseToken, "ghp_env")
+
+	result, err := GetToken(context.Background(), "", host)
+	require.NoError(t, err)
+	assert.Equal(t, "ghs_token1", result.Token)
+	assert.Equal(t, TokenSourceApp, result.Source)
+	require.NotNil(t, result.Tokens)
+
+	// An explicit token still comes first
+	result, err = GetToken(context.Background(), "ghp_flag", host)
+	require.NoError(t, err)
+	assert.Equal(t, TokenSourceFlag, result.Source)
+	assert.Nil(t, result.Tokens)
+}
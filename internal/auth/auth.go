@@ -3,6 +3,7 @@ package auth
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 
@@ -16,17 +17,27 @@ const (
 
 	// EnvGitHubToken is the environment variable for GitHub token
 	EnvGitHubToken = "GITHUB_TOKEN"
+
+	// EnvGitHubEnterpriseToken is the environment variable for the token of
+	// GitHub Enterprise Server hosts, so a github.com token is never sent to
+	// another host
+	EnvGitHubEnterpriseToken = "GITHUB_ENTERPRISE_TOKEN"
 )
 
 // TokenSource represents where the token was obtained from
 type TokenSource string
 
 const (
-	TokenSourceFlag     TokenSource = "flag"
-	TokenSourceEnv      TokenSource = "environment"
-	TokenSourceKeychain TokenSource = "keychain"
-	TokenSourceConfig   TokenSource = "config"
-	TokenSourceNone     TokenSource = "none"
+	TokenSourceFlag      TokenSource = "flag"
+	TokenSourceEnv       TokenSource = "environment"
+	TokenSourceEnvGHES   TokenSource = "enterprise-environment"
+	TokenSourceApp       TokenSource = "github-app"
+	TokenSourceFile      TokenSource = "file"
+	TokenSourceCommand   TokenSource = "command"
+	TokenSourceKeychain  TokenSource = "keychain"
+	TokenSourceEncrypted TokenSource = "encrypted-file"
+	TokenSourceConfig    TokenSource = "config"
+	TokenSourceNone      TokenSource = "none"
 )
 
 // TokenResult contains the resolved token and its source
@@ -34,6 +45,14 @@ type TokenResult struct {
 	Token    string
 	Source   TokenSource
 	Hostname string
+	// Account is the account name, empty for the default account
+	Account string
+	// Origin is the token file or token command the token came from
+	Origin string
+	// Tokens refreshes Token before it expires, such as the installation
+	// tokens of a GitHub App or expiring OAuth tokens; nil for tokens that
+	// don't expire
+	Tokens oauth2.TokenSource
 }
 
 // AuthenticatedUser contains information about the authenticated user
@@ -44,50 +63,133 @@ type AuthenticatedUser struct {
 	AvatarURL string
 }
 
-// GetToken resolves the GitHub token using the following priority:
-// 1. Explicit token (from --token flag)
-// 2. GITHUB_TOKEN environment variable
-// 3. Stored token (keychain or config file)
+// GetToken resolves the GitHub token of the default account of a hostname.
+// See GetAccountToken.
 func GetToken(ctx context.Context, explicitToken string, hostname string) (*TokenResult, error) {
+	return GetAccountToken(ctx, explicitToken, "", hostname)
+}
+
+// GetAccountToken resolves the GitHub token of an account (empty for the
+// default account) using the following priority:
+// 1. Explicit token (from --token flag)
+// 2. GitHub App installation tokens (GITHUB_APP_ID and its private key), for
+// the default account only, of the installation on the owner set with
+// WithOwner
+// 3. GITHUB_TOKEN environment variable (GITHUB_ENTERPRISE_TOKEN for GitHub
+// Enterprise Server hosts), for the default account only
+// 4. File named by GITHUB_TOKEN_FILE (GITHUB_ENTERPRISE_TOKEN_FILE for GitHub
+// Enterprise Server hosts), for the default account only
+// 5. Output of the token_command of the config file, for the default
+// account only
+// 6. Stored token of the account (keychain or config file), renewed with
+// its refresh token if it's an expiring OAuth token
+//
+// Fails only if a GitHub App, token file or token command is configured but
+// no token could be had from it, if the encrypted token store can't be
+// unlocked, or if an expired token can't be renewed (a RefreshError).
+func GetAccountToken(ctx context.Context, explicitToken, account, hostname string) (*TokenResult, error) {
 	if hostname == "" {
 		hostname = DefaultHostname
 	}
+	result := &TokenResult{Source: TokenSourceNone, Hostname: hostname, Account: account}
 
 	// 1. Check explicit token (from --token flag)
 	if explicitToken != "" {
-		return &TokenResult{
-			Token:    explicitToken,
-			Source:   TokenSourceFlag,
-			Hostname: hostname,
-		}, nil
+		result.Token, result.Source = explicitToken, TokenSourceFlag
+		return result, nil
+	}
+
+	// 2. Check for a GitHub App; like the environment variables below, named
+	// accounts never use it
+	if account == "" {
+		app, err := AppConfigFromEnv(hostname)
+		if err != nil {
+			return nil, err
+		}
+		if app != nil {
+			app.Owner = ownerFrom(ctx)
+			tokens := app.TokenSource(ctx)
+			appToken, err := tokens.Token()
+			if err != nil {
+				return nil, err
+			}
+			result.Token, result.Source, result.Tokens = appToken.AccessToken, TokenSourceApp, tokens
+			return result, nil
+		}
+	}
+
+	// 3. Check environment variable; named accounts never fall back to it,
+	// so a profile never silently syncs with another account's token
+	if account == "" {
+		envName, envSource := EnvGitHubToken, TokenSourceEnv
+		if IsEnterprise(hostname) {
+			envName, envSource = EnvGitHubEnterpriseToken, TokenSourceEnvGHES
+		}
+		if envToken := os.Getenv(envName); envToken != "" {
+			result.Token, result.Source = envToken, envSource
+			return result, nil
+		}
+	}
+
+	// 4. Check token file
+	if account == "" {
+		envName := EnvGitHubTokenFile
+		if IsEnterprise(hostname) {
+			envName = EnvGitHubEnterpriseTokenFile
+		}
+		if path := os.Getenv(envName); path != "" {
+			fileToken, err := ReadTokenFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", envName, err)
+			}
+			result.Token, result.Source, result.Origin = fileToken, TokenSourceFile, path
+			return result, nil
+		}
 	}
 
-	// 2. Check environment variable
-	if envToken := os.Getenv(EnvGitHubToken); envToken != "" {
-		return &TokenResult{
-			Token:    envToken,
-			Source:   TokenSourceEnv,
-			Hostname: hostname,
-		}, nil
+	// 5. Check token command
+	if account == "" {
+		command, err := NewStorageWithConfig("", false).GetTokenCommand()
+		if err != nil {
+			return nil, err
+		}
+		if command != nil {
+			tokens := command.TokenSource(hostname)
+			commandToken, err := tokens.Token()
+			if err != nil {
+				return nil, err
+			}
+			result.Token, result.Source, result.Origin, result.Tokens = commandToken.AccessToken, TokenSourceCommand, command.Command, tokens
+			return result, nil
+		}
 	}
 
-	// 3. Check stored token (keychain first, then config file)
+	// 6. Check stored token (keychain first, then config file)
 	storage := NewStorage()
-	storedToken, source, err := storage.GetToken(hostname)
+	storedToken, source, err := storage.GetAccountToken(account, hostname)
+	var storeErr *TokenStoreError
+	if errors.As(err, &storeErr) {
+		return nil, err
+	}
 	if err == nil && storedToken != "" {
-		return &TokenResult{
-			Token:    storedToken,
-			Source:   source,
-			Hostname: hostname,
-		}, nil
+		result.Token, result.Source = storedToken, source
+
+		// Expiring OAuth tokens are renewed with their refresh token
+		creds, err := storage.GetAccountRefresh(account, hostname)
+		if err != nil {
+			return nil, err
+		}
+		if creds != nil {
+			tokens := storage.refreshingTokenSource(ctx, account, hostname, storedToken, creds)
+			current, err := tokens.Token()
+			if err != nil {
+				return nil, err
+			}
+			result.Token, result.Tokens = current.AccessToken, tokens
+		}
 	}
 
-	// No token found
-	return &TokenResult{
-		Token:    "",
-		Source:   TokenSourceNone,
-		Hostname: hostname,
-	}, nil
+	return result, nil
 }
 
 // ValidateToken checks if a token is valid by making a test API call
@@ -100,19 +202,9 @@ func ValidateToken(ctx context.Context, token string, hostname string) (*Authent
 	ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
 	tc := oauth2.NewClient(ctx, ts)
 
-	var client *gh.Client
-	if hostname == "" || hostname == DefaultHostname {
-		client = gh.NewClient(tc)
-	} else {
-		// GitHub Enterprise Server
-		baseURL := fmt.Sprintf("https://%s/api/v3/", hostname)
-		uploadURL := fmt.Sprintf("https://%s/api/uploads/", hostname)
-		var err error
-		client, err = gh.NewClient(tc).WithEnterpriseURLs(baseURL, uploadURL)
-		if err != nil {
-			return nil, fmt.Errorf("failed to create enterprise client: %w", err)
-		}
-	}
+	client := gh.NewClient(tc)
+	client.BaseURL = APIURL(hostname)
+	client.UploadURL = UploadURL(hostname)
 
 	// Get authenticated user
 	user, _, err := client.Users.Get(ctx, "")
@@ -135,8 +227,18 @@ func FormatTokenSource(source TokenSource) string {
 		return "command line flag"
 	case TokenSourceEnv:
 		return "environment variable (GITHUB_TOKEN)"
+	case TokenSourceEnvGHES:
+		return "environment variable (GITHUB_ENTERPRISE_TOKEN)"
+	case TokenSourceApp:
+		return "GitHub App installation (GITHUB_APP_ID)"
+	case TokenSourceFile:
+		return "token file"
+	case TokenSourceCommand:
+		return "token command (token_command)"
 	case TokenSourceKeychain:
 		return "keychain"
+	case TokenSourceEncrypted:
+		return "encrypted file (~/.githubby/tokens.enc)"
 	case TokenSourceConfig:
 		return "config file (~/.githubby.yaml)"
 	default:
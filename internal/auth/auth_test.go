@@ -129,3 +129,26 @@ func TestGetTokenWithStoredToken(t *testing.T) {
 	assert.Equal(t, testToken, token)
 	assert.Equal(t, TokenSourceConfig, source)
 }
+
+func TestDetectTokenKind(t *testing.T) {
+	tests := []struct {
+		token     string
+		kind      TokenKind
+		hasScopes bool
+	}{
+		{"ghp_abc123", TokenKindClassic, true},
+		{"0123456789abcdef0123456789abcdef01234567", TokenKindClassic, true},
+		{"github_pat_11ABC_xyz", TokenKindFineGrained, false},
+		{"gho_abc123", TokenKindOAuth, true},
+		{"ghu_abc123", TokenKindAppUser, false},
+		{"ghs_abc123", TokenKindAppInstallation, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.token, func(t *testing.T) {
+			kind := DetectTokenKind(tt.token)
+			assert.Equal(t, tt.kind, kind)
+			assert.Equal(t, tt.hasScopes, kind.HasScopes())
+		})
+	}
+}
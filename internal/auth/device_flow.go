@@ -1,11 +1,18 @@
 package auth
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"mime"
 	"net/http"
+	"net/url"
 	"os/exec"
 	"runtime"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/cli/oauth/device"
@@ -42,6 +49,29 @@ type DeviceFlowResult struct {
 	Token     string
 	TokenType string
 	Scopes    []string
+
+	// RefreshToken renews the token, if the OAuth app issues expiring tokens
+	RefreshToken string
+	// Expiry is when the token expires (zero if it doesn't)
+	Expiry time.Time
+	// RefreshExpiry is when the refresh token expires (zero if unknown)
+	RefreshExpiry time.Time
+	// ClientID is the OAuth app the token was issued to
+	ClientID string
+}
+
+// RefreshCredentials returns what's needed to renew the token, nil if it
+// doesn't expire
+func (r *DeviceFlowResult) RefreshCredentials() *RefreshCredentials {
+	if r.RefreshToken == "" {
+		return nil
+	}
+	return &RefreshCredentials{
+		RefreshToken:  r.RefreshToken,
+		Expiry:        r.Expiry,
+		RefreshExpiry: r.RefreshExpiry,
+		ClientID:      r.ClientID,
+	}
 }
 
 // DefaultDeviceFlowOptions returns options with sensible defaults
@@ -77,10 +107,7 @@ func RunDeviceFlow(ctx context.Context, opts *DeviceFlowOptions) (*DeviceFlowRes
 	}
 
 	// Determine the device code URL based on hostname
-	codeURL := "https://github.com/login/device/code"
-	if opts.Hostname != DefaultHostname {
-		codeURL = fmt.Sprintf("https://%s/login/device/code", opts.Hostname)
-	}
+	codeURL := WebURL(opts.Hostname, "login/device/code").String()
 
 	// Request device code
 	code, err := device.RequestCode(httpClient, codeURL, opts.ClientID, opts.Scopes)
@@ -94,26 +121,10 @@ func RunDeviceFlow(ctx context.Context, opts *DeviceFlowOptions) (*DeviceFlowRes
 	}
 
 	// Determine the token URL
-	tokenURL := "https://github.com/login/oauth/access_token"
-	if opts.Hostname != DefaultHostname {
-		tokenURL = fmt.Sprintf("https://%s/login/oauth/access_token", opts.Hostname)
-	}
-
-	// Poll for token using Wait function
-	waitOpts := device.WaitOptions{
-		ClientID:   opts.ClientID,
-		DeviceCode: code,
-	}
-
-	accessToken, err := device.Wait(ctx, httpClient, tokenURL, waitOpts)
-	if err != nil {
-		return nil, fmt.Errorf("failed to complete device flow: %w", err)
-	}
+	tokenURL := WebURL(opts.Hostname, "login/oauth/access_token").String()
 
-	return &DeviceFlowResult{
-		Token:     accessToken.Token,
-		TokenType: accessToken.Type,
-	}, nil
+	// Poll for the token
+	return waitForToken(ctx, tokenURL, opts.ClientID, code)
 }
 
 // OpenBrowser opens the default browser to the specified URL
@@ -138,11 +149,7 @@ func OpenBrowser(url string) error {
 func GetDeviceFlowInstructions(code *device.CodeResponse, hostname string) string {
 	verificationURL := code.VerificationURI
 	if verificationURL == "" {
-		if hostname == "" || hostname == DefaultHostname {
-			verificationURL = "https://github.com/login/device"
-		} else {
-			verificationURL = fmt.Sprintf("https://%s/login/device", hostname)
-		}
+		verificationURL = WebURL(hostname, "login/device").String()
 	}
 
 	return fmt.Sprintf(`! First, copy your one-time code: %s
@@ -170,24 +177,102 @@ func RequestDeviceCode(ctx context.Context) (*device.CodeResponse, error) {
 // PollForToken polls GitHub waiting for user to authorize the device code
 // This blocks until the user completes authorization or the code expires
 func PollForToken(ctx context.Context, code *device.CodeResponse) (*DeviceFlowResult, error) {
-	httpClient := &http.Client{
-		Timeout: 30 * time.Second,
+	tokenURL := "https://github.com/login/oauth/access_token"
+
+	return waitForToken(ctx, tokenURL, GitHubOAuthClientID, code)
+}
+
+// tokenResponseRecorder is the HTTP client the device flow polls for the
+// token with. It records the expiry times of the token response, which the
+// device flow package doesn't return.
+type tokenResponseRecorder struct {
+	client *http.Client
+
+	expiresIn        time.Duration
+	refreshExpiresIn time.Duration
+}
+
+// PostForm posts a form and records the expiry times of a token response
+func (r *tokenResponseRecorder) PostForm(uri string, data url.Values) (*http.Response, error) {
+	resp, err := r.client.PostForm(uri, data)
+	if err != nil {
+		return resp, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, err
 	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
 
-	tokenURL := "https://github.com/login/oauth/access_token"
+	values := parseTokenResponse(resp.Header.Get("Content-Type"), body)
+	if values.Get("access_token") != "" {
+		r.expiresIn = seconds(values.Get("expires_in"))
+		r.refreshExpiresIn = seconds(values.Get("refresh_token_expires_in"))
+	}
+	return resp, nil
+}
+
+// parseTokenResponse returns the fields of a form-encoded or JSON token
+// response
+func parseTokenResponse(contentType string, body []byte) url.Values {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if mediaType != "application/json" {
+		values, _ := url.ParseQuery(string(body))
+		return values
+	}
 
+	var fields map[string]any
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return url.Values{}
+	}
+	values := url.Values{}
+	for key, value := range fields {
+		switch v := value.(type) {
+		case string:
+			values.Set(key, v)
+		case float64:
+			values.Set(key, strconv.FormatFloat(v, 'f', -1, 64))
+		}
+	}
+	return values
+}
+
+// seconds parses a number of seconds, zero if it's not a number
+func seconds(value string) time.Duration {
+	n, _ := strconv.ParseInt(value, 10, 64)
+	return time.Duration(n) * time.Second
+}
+
+// waitForToken polls tokenURL until the user authorizes the device code,
+// and returns the token with its refresh token and expiry, if any
+func waitForToken(ctx context.Context, tokenURL, clientID string, code *device.CodeResponse) (*DeviceFlowResult, error) {
+	recorder := &tokenResponseRecorder{client: &http.Client{Timeout: 30 * time.Second}}
 	waitOpts := device.WaitOptions{
-		ClientID:   GitHubOAuthClientID,
+		ClientID:   clientID,
 		DeviceCode: code,
 	}
 
-	accessToken, err := device.Wait(ctx, httpClient, tokenURL, waitOpts)
+	accessToken, err := device.Wait(ctx, recorder, tokenURL, waitOpts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to complete device flow: %w", err)
 	}
 
-	return &DeviceFlowResult{
-		Token:     accessToken.Token,
-		TokenType: accessToken.Type,
-	}, nil
+	result := &DeviceFlowResult{
+		Token:        accessToken.Token,
+		TokenType:    accessToken.Type,
+		RefreshToken: accessToken.RefreshToken,
+		ClientID:     clientID,
+	}
+	if accessToken.Scope != "" {
+		result.Scopes = strings.Split(accessToken.Scope, ",")
+	}
+	now := time.Now()
+	if recorder.expiresIn > 0 {
+		result.Expiry = now.Add(recorder.expiresIn)
+	}
+	if recorder.refreshExpiresIn > 0 {
+		result.RefreshExpiry = now.Add(recorder.refreshExpiresIn)
+	}
+	return result, nil
 }
@@ -0,0 +1,388 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mitchellh/go-homedir"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/chacha20poly1305"
+
+	"github.com/Didstopia/githubby/internal/lock"
+	"github.com/Didstopia/githubby/pkg/util"
+)
+
+const (
+	// EnvTokenPassphrase is the environment variable for the passphrase of
+	// the encrypted token store
+	EnvTokenPassphrase = "GITHUBBY_TOKEN_PASSPHRASE"
+
+	// EnvTokenKeyFile is the environment variable for the path to the key
+	// file of the encrypted token store
+	EnvTokenKeyFile = "GITHUBBY_TOKEN_KEY_FILE"
+
+	// EncryptedStoreFileName is the name of the encrypted token store, kept
+	// next to the accounts file
+	EncryptedStoreFileName = "tokens.enc"
+
+	// TokenKeyFileName is the name of the key file generated for the
+	// encrypted token store when no passphrase is set
+	TokenKeyFileName = "token.key"
+
+	// encryptedStoreVersion is the format version of the encrypted token store
+	encryptedStoreVersion = 1
+
+	// Ways to unlock the encrypted token store
+	keyModePassphrase = "passphrase"
+	keyModeKeyFile    = "key-file"
+
+	// Argon2id parameters, the second recommended option of RFC 9106
+	argonTime    = 3
+	argonMemory  = 64 * 1024
+	argonThreads = 4
+
+	// storeLockTimeout bounds how long an update waits for another process
+	// to finish writing the store
+	storeLockTimeout = 30 * time.Second
+)
+
+// encryptedStoreAD binds the ciphertext to the format, as additional data
+var encryptedStoreAD = []byte("githubby-tokens-v1")
+
+// PassphrasePrompt asks for the passphrase of the encrypted token store when
+// GITHUBBY_TOKEN_PASSPHRASE isn't set. Nil, the default, never asks, for
+// non-interactive use.
+var PassphrasePrompt func() (string, error)
+
+// TokenStoreError is a failure to read or write the encrypted token store,
+// such as a missing or wrong passphrase
+type TokenStoreError struct {
+	Err error
+}
+
+func (e *TokenStoreError) Error() string {
+	return "encrypted token store: " + e.Err.Error()
+}
+
+func (e *TokenStoreError) Unwrap() error {
+	return e.Err
+}
+
+// encryptedFile is the encrypted token store on disk
+type encryptedFile struct {
+	Version int `json:"version"`
+
+	// KeyMode is how the store is unlocked: with a passphrase or a key file
+	KeyMode string `json:"key"`
+
+	// KDF holds the Argon2id parameters the encryption key was derived with
+	KDF struct {
+		Salt    []byte `json:"salt"`
+		Time    uint32 `json:"time"`
+		Memory  uint32 `json:"memory"`
+		Threads uint8  `json:"threads"`
+	} `json:"kdf"`
+
+	// Nonce and Ciphertext are the XChaCha20-Poly1305 encrypted tokens by
+	// keychain entry name
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// derivedKeys caches the keys of unlocked stores by path and salt, so the
+// passphrase is asked for and Argon2id runs only once per process
+var (
+	derivedKeysMu sync.Mutex
+	derivedKeys   = map[string][]byte{}
+)
+
+// encryptedStore keeps tokens in a file encrypted with XChaCha20-Poly1305,
+// with the key derived by Argon2id from a passphrase or a key file
+type encryptedStore struct {
+	// path is the store's path (empty = ~/.githubby/tokens.enc)
+	path string
+
+	// keyFile is the default key file (empty = ~/.githubby/token.key)
+	keyFile string
+}
+
+// storePath returns the path to the store
+func (e *encryptedStore) storePath() (string, error) {
+	if e.path != "" {
+		return e.path, nil
+	}
+	home, err := homedir.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, AccountsDirName, EncryptedStoreFileName), nil
+}
+
+// keyFilePath returns the path to the key file: GITHUBBY_TOKEN_KEY_FILE if
+// set, otherwise the default
+func (e *encryptedStore) keyFilePath() (string, error) {
+	if path := os.Getenv(EnvTokenKeyFile); path != "" {
+		return homedir.Expand(path)
+	}
+	if e.keyFile != "" {
+		return e.keyFile, nil
+	}
+	home, err := homedir.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, AccountsDirName, TokenKeyFileName), nil
+}
+
+// exists reports whether the store has been created
+func (e *encryptedStore) exists() bool {
+	path, err := e.storePath()
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(path)
+	return err == nil
+}
+
+// secret returns the passphrase or key file contents that unlock a store
+// of the given key mode. With create, a missing key file is generated.
+func (e *encryptedStore) secret(mode string, create bool) ([]byte, error) {
+	if mode == keyModePassphrase {
+		if passphrase := os.Getenv(EnvTokenPassphrase); passphrase != "" {
+			return []byte(passphrase), nil
+		}
+		if PassphrasePrompt != nil {
+			passphrase, err := PassphrasePrompt()
+			if err != nil {
+				return nil, err
+			}
+			if passphrase != "" {
+				return []byte(passphrase), nil
+			}
+		}
+		return nil, fmt.Errorf("the store is locked with a passphrase: set %s", EnvTokenPassphrase)
+	}
+
+	path, err := e.keyFilePath()
+	if err != nil {
+		return nil, err
+	}
+	key, err := os.ReadFile(path)
+	if os.IsNotExist(err) && create {
+		return generateKeyFile(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file (set %s to its path): %w", EnvTokenKeyFile, err)
+	}
+	if len(strings.TrimSpace(string(key))) == 0 {
+		return nil, fmt.Errorf("key file %s is empty", path)
+	}
+	return []byte(strings.TrimSpace(string(key))), nil
+}
+
+// generateKeyFile writes a random key, readable only by the user, to path
+func generateKeyFile(path string) ([]byte, error) {
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return nil, err
+	}
+	key := []byte(hex.EncodeToString(random))
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, append(key, '\n'), 0600); err != nil {
+		return nil, fmt.Errorf("failed to write key file: %w", err)
+	}
+	return key, nil
+}
+
+// deriveKey returns the encryption key of a store file
+func (e *encryptedStore) deriveKey(path string, file *encryptedFile, create bool) ([]byte, error) {
+	cacheKey := path + "\x00" + string(file.KDF.Salt)
+	derivedKeysMu.Lock()
+	defer derivedKeysMu.Unlock()
+	if key, ok := derivedKeys[cacheKey]; ok {
+		return key, nil
+	}
+
+	secret, err := e.secret(file.KeyMode, create)
+	if err != nil {
+		return nil, err
+	}
+	key := argon2.IDKey(secret, file.KDF.Salt, file.KDF.Time, file.KDF.Memory, file.KDF.Threads, chacha20poly1305.KeySize)
+	derivedKeys[cacheKey] = key
+	return key, nil
+}
+
+// forgetKey removes a key that failed to decrypt from the cache
+func forgetKey(path string, file *encryptedFile) {
+	derivedKeysMu.Lock()
+	defer derivedKeysMu.Unlock()
+	delete(derivedKeys, path+"\x00"+string(file.KDF.Salt))
+}
+
+// read decrypts the store. A store that doesn't exist yet has no tokens and
+// a nil file.
+func (e *encryptedStore) read() (map[string]string, *encryptedFile, error) {
+	path, err := e.storePath()
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return map[string]string{}, nil, nil
+		}
+		return nil, nil, err
+	}
+
+	var file encryptedFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if file.Version != encryptedStoreVersion {
+		return nil, nil, fmt.Errorf("%s has unsupported version %d", path, file.Version)
+	}
+
+	key, err := e.deriveKey(path, &file, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	plaintext, err := aead.Open(nil, file.Nonce, file.Ciphertext, encryptedStoreAD)
+	if err != nil {
+		forgetKey(path, &file)
+		if file.KeyMode == keyModePassphrase {
+			return nil, nil, errors.New("wrong passphrase, or the store was modified")
+		}
+		return nil, nil, errors.New("wrong key file, or the store was modified")
+	}
+
+	tokens := map[string]string{}
+	if err := json.Unmarshal(plaintext, &tokens); err != nil {
+		return nil, nil, err
+	}
+	return tokens, &file, nil
+}
+
+// get returns the token of a keychain entry name, empty if there is none
+func (e *encryptedStore) get(name string) (string, error) {
+	if !e.exists() {
+		return "", nil
+	}
+	tokens, _, err := e.read()
+	if err != nil {
+		return "", &TokenStoreError{Err: err}
+	}
+	return tokens[name], nil
+}
+
+// update applies fn to the tokens of the store and encrypts them again,
+// creating the store if needed: locked with GITHUBBY_TOKEN_PASSPHRASE if
+// it's set, otherwise with a key file. The store is read and replaced under
+// a cross-process lock, so concurrent logins and refreshes don't lose each
+// other's tokens.
+func (e *encryptedStore) update(fn func(tokens map[string]string)) error {
+	path, err := e.storePath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), storeLockTimeout)
+	defer cancel()
+	l, err := lock.Acquire(ctx, path+".lock", nil)
+	if err != nil {
+		return &TokenStoreError{Err: fmt.Errorf("failed to lock token store: %w", err)}
+	}
+	defer l.Release()
+
+	tokens, file, err := e.read()
+	if err != nil {
+		return &TokenStoreError{Err: err}
+	}
+	if file == nil {
+		file, err = newEncryptedFile()
+		if err != nil {
+			return &TokenStoreError{Err: err}
+		}
+	}
+	fn(tokens)
+
+	key, err := e.deriveKey(path, file, true)
+	if err != nil {
+		return &TokenStoreError{Err: err}
+	}
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return err
+	}
+	plaintext, err := json.Marshal(tokens)
+	if err != nil {
+		return err
+	}
+	file.Nonce = make([]byte, aead.NonceSize())
+	if _, err := rand.Read(file.Nonce); err != nil {
+		return err
+	}
+	file.Ciphertext = aead.Seal(nil, file.Nonce, plaintext, encryptedStoreAD)
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+	return util.WriteFileAtomic(path, data, 0600)
+}
+
+// newEncryptedFile returns an empty store with a new salt, locked with a
+// passphrase if GITHUBBY_TOKEN_PASSPHRASE is set
+func newEncryptedFile() (*encryptedFile, error) {
+	file := &encryptedFile{Version: encryptedStoreVersion, KeyMode: keyModeKeyFile}
+	if os.Getenv(EnvTokenPassphrase) != "" {
+		file.KeyMode = keyModePassphrase
+	}
+	file.KDF.Salt = make([]byte, 16)
+	if _, err := rand.Read(file.KDF.Salt); err != nil {
+		return nil, err
+	}
+	file.KDF.Time, file.KDF.Memory, file.KDF.Threads = argonTime, argonMemory, argonThreads
+	return file, nil
+}
+
+// describe returns where the store is and how it's unlocked
+func (e *encryptedStore) describe() string {
+	mode := keyModeKeyFile
+	if os.Getenv(EnvTokenPassphrase) != "" {
+		mode = keyModePassphrase
+	}
+	if path, err := e.storePath(); err == nil {
+		if data, err := os.ReadFile(path); err == nil {
+			var file encryptedFile
+			if json.Unmarshal(data, &file) == nil {
+				mode = file.KeyMode
+			}
+		}
+	}
+
+	if mode == keyModePassphrase {
+		return "encrypted file (~/.githubby/tokens.enc, unlocked with a passphrase)"
+	}
+	keyFile, err := e.keyFilePath()
+	if err != nil {
+		return "encrypted file (~/.githubby/tokens.enc, unlocked with a key file)"
+	}
+	return fmt.Sprintf("encrypted file (~/.githubby/tokens.enc, unlocked with key file %s)", keyFile)
+}
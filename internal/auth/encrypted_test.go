@@ -0,0 +1,198 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newEncryptedTestStorage returns a Storage with an encrypted store in a
+// temporary directory, and the paths of its config file, store and key file
+func newEncryptedTestStorage(t *testing.T) (storage *Storage, configPath, storePath, keyFile string) {
+	t.Helper()
+	t.Setenv(EnvTokenPassphrase, "")
+	t.Setenv(EnvTokenKeyFile, "")
+	dir := t.TempDir()
+	configPath = filepath.Join(dir, ConfigFileName)
+	storePath = filepath.Join(dir, EncryptedStoreFileName)
+	keyFile = filepath.Join(dir, TokenKeyFileName)
+	return NewStorageWithEncryptedStore(configPath, storePath, keyFile), configPath, storePath, keyFile
+}
+
+// forgetDerivedKeys empties the key cache, as if githubby ran again
+func forgetDerivedKeys() {
+	derivedKeysMu.Lock()
+	defer derivedKeysMu.Unlock()
+	derivedKeys = map[string][]byte{}
+}
+
+func TestStorage_EncryptedStore_KeyFile(t *testing.T) {
+	storage, configPath, storePath, keyFile := newEncryptedTestStorage(t)
+
+	require.NoError(t, storage.SetToken(DefaultHostname, "ghp_personal"))
+	require.NoError(t, storage.SetAccountToken("work", "github.mycompany.com", "ghp_work"))
+
+	// The key file is generated, and nothing is written in plaintext
+	info, err := os.Stat(keyFile)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	data, err := os.ReadFile(storePath)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "ghp_")
+	_, err = os.Stat(configPath)
+	assert.True(t, os.IsNotExist(err), "the config file isn't written")
+
+	forgetDerivedKeys()
+	token, source, err := storage.GetToken(DefaultHostname)
+	require.NoError(t, err)
+	assert.Equal(t, "ghp_personal", token)
+	assert.Equal(t, TokenSourceEncrypted, source)
+	token, _, err = storage.GetAccountToken("work", "github.mycompany.com")
+	require.NoError(t, err)
+	assert.Equal(t, "ghp_work", token)
+	assert.Contains(t, storage.GetStorageLocation(), keyFile)
+
+	require.NoError(t, storage.DeleteAccountToken("work", "github.mycompany.com"))
+	_, _, err = storage.GetAccountToken("work", "github.mycompany.com")
+	assert.Error(t, err)
+
+	// Without its key file, the store can't be read
+	require.NoError(t, os.Remove(keyFile))
+	forgetDerivedKeys()
+	_, _, err = storage.GetToken(DefaultHostname)
+	var storeErr *TokenStoreError
+	assert.True(t, errors.As(err, &storeErr), "got %v", err)
+}
+
+func TestStorage_EncryptedStore_ConcurrentUpdates(t *testing.T) {
+	storage, configPath, storePath, keyFile := newEncryptedTestStorage(t)
+	require.NoError(t, storage.SetToken(DefaultHostname, "ghp_personal"))
+
+	// Separate storages stand in for separate githubby processes
+	const processes, updates = 8, 5
+	var wg sync.WaitGroup
+	errs := make(chan error, processes*updates)
+	for i := 0; i < processes; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			other := NewStorageWithEncryptedStore(configPath, storePath, keyFile)
+			for j := 0; j < updates; j++ {
+				errs <- other.SetAccountToken(fmt.Sprintf("account%d-%d", i, j), DefaultHostname, fmt.Sprintf("ghp_%d_%d", i, j))
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	for i := 0; i < processes; i++ {
+		for j := 0; j < updates; j++ {
+			token, _, err := storage.GetAccountToken(fmt.Sprintf("account%d-%d", i, j), DefaultHostname)
+			require.NoError(t, err)
+			assert.Equal(t, fmt.Sprintf("ghp_%d_%d", i, j), token, "no update is lost")
+		}
+	}
+	_, err := os.Stat(storePath + ".tmp")
+	assert.True(t, os.IsNotExist(err), "the store is replaced atomically")
+}
+
+func TestStorage_EncryptedStore_Passphrase(t *testing.T) {
+	storage, _, _, keyFile := newEncryptedTestStorage(t)
+	t.Setenv(EnvTokenPassphrase, "correct horse battery staple")
+	t.Cleanup(func() { PassphrasePrompt = nil })
+
+	require.NoError(t, storage.SetToken(DefaultHostname, "ghp_secret"))
+	_, err := os.Stat(keyFile)
+	assert.True(t, os.IsNotExist(err), "no key file for passphrase stores")
+	assert.Contains(t, storage.GetStorageLocation(), "passphrase")
+
+	// Without the environment variable, the passphrase is asked for
+	t.Setenv(EnvTokenPassphrase, "")
+	forgetDerivedKeys()
+	_, _, err = storage.GetToken(DefaultHostname)
+	assert.ErrorContains(t, err, EnvTokenPassphrase)
+
+	prompts := 0
+	PassphrasePrompt = func() (string, error) {
+		prompts++
+		return "correct horse battery staple", nil
+	}
+	for range 2 {
+		token, _, err := storage.GetToken(DefaultHostname)
+		require.NoError(t, err)
+		assert.Equal(t, "ghp_secret", token)
+	}
+	assert.Equal(t, 1, prompts, "the unlocked store is remembered")
+
+	forgetDerivedKeys()
+	PassphrasePrompt = func() (string, error) { return "wrong", nil }
+	_, _, err = storage.GetToken(DefaultHostname)
+	assert.ErrorContains(t, err, "wrong passphrase")
+}
+
+func TestStorage_MigratePlaintextTokens(t *testing.T) {
+	storage, configPath, _, _ := newEncryptedTestStorage(t)
+	config := `# Sync defaults
+token: ghp_plain
+hosts:
+  github.mycompany.com: ghp_ghes
+target: ~/repos
+notifications:
+  on: failure
+`
+	require.NoError(t, os.WriteFile(configPath, []byte(config), 0600))
+	plaintext := NewStorageWithConfig(configPath, false)
+	require.NoError(t, plaintext.SetAccountToken("work", DefaultHostname, "ghp_work"))
+	require.NoError(t, plaintext.SaveAccount(Account{Name: "work", Hostname: DefaultHostname, Login: "alice-acme"}))
+
+	// Plaintext tokens are still read until they're migrated
+	token, source, err := storage.GetToken(DefaultHostname)
+	require.NoError(t, err)
+	assert.Equal(t, "ghp_plain", token)
+	assert.Equal(t, TokenSourceConfig, source)
+
+	accounts, err := storage.PlaintextTokens()
+	require.NoError(t, err)
+	assert.Equal(t, []Account{
+		{Hostname: DefaultHostname},
+		{Hostname: "github.mycompany.com"},
+		{Name: "work", Hostname: DefaultHostname, Login: "alice-acme"},
+	}, accounts)
+
+	migrated, err := storage.MigratePlaintextTokens()
+	require.NoError(t, err)
+	assert.Equal(t, accounts, migrated)
+
+	for _, account := range accounts {
+		_, source, err := storage.GetAccountToken(account.Name, account.Hostname)
+		require.NoError(t, err)
+		assert.Equal(t, TokenSourceEncrypted, source, account.DisplayName())
+	}
+	token, _, err = storage.GetAccountToken("work", DefaultHostname)
+	require.NoError(t, err)
+	assert.Equal(t, "ghp_work", token)
+
+	// The plaintext tokens are gone; everything else is kept
+	data, err := os.ReadFile(configPath)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "ghp_")
+	assert.Contains(t, string(data), "# Sync defaults")
+	assert.Contains(t, string(data), "on: failure")
+	data, err = os.ReadFile(filepath.Join(filepath.Dir(configPath), AccountsFileName))
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "ghp_")
+	assert.Contains(t, string(data), "alice-acme")
+
+	accounts, err = storage.PlaintextTokens()
+	require.NoError(t, err)
+	assert.Empty(t, accounts)
+}
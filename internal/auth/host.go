@@ -0,0 +1,86 @@
+package auth
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// NormalizeHostname returns the canonical form of a GitHub hostname as used
+// for keychain entries and profiles: lowercase, without the https:// scheme
+// or a trailing /api/v3. An empty hostname means github.com. Instances
+// served over plain HTTP keep their http:// scheme.
+func NormalizeHostname(hostname string) (string, error) {
+	hostname = strings.TrimSpace(hostname)
+	if hostname == "" {
+		return DefaultHostname, nil
+	}
+
+	scheme, rest, found := strings.Cut(hostname, "://")
+	if !found {
+		scheme, rest = "https", hostname
+	}
+	scheme = strings.ToLower(scheme)
+	if scheme != "https" && scheme != "http" {
+		return "", fmt.Errorf("invalid GitHub hostname %q: unsupported scheme %s", hostname, scheme)
+	}
+
+	// Accept API URLs pasted from GitHub Enterprise Server settings
+	rest = strings.TrimSuffix(rest, "/")
+	rest = strings.TrimSuffix(rest, "/api/v3")
+
+	u, err := url.Parse(scheme + "://" + rest)
+	if err != nil || u.Host == "" || u.Path != "" || u.User != nil || u.RawQuery != "" || u.Fragment != "" {
+		return "", fmt.Errorf("invalid GitHub hostname %q (expected a hostname such as github.mycompany.com)", hostname)
+	}
+
+	host := strings.ToLower(u.Host)
+	switch host {
+	case DefaultHostname, "api.github.com", "www.github.com":
+		return DefaultHostname, nil
+	}
+	if scheme == "http" {
+		return "http://" + host, nil
+	}
+	return host, nil
+}
+
+// IsEnterprise reports whether a normalized hostname is a GitHub Enterprise
+// Server instance rather than github.com
+func IsEnterprise(hostname string) bool {
+	return hostname != "" && hostname != DefaultHostname
+}
+
+// WebURL returns the URL of path on the web interface of hostname
+func WebURL(hostname, path string) *url.URL {
+	if hostname == "" {
+		hostname = DefaultHostname
+	}
+	return hostURL(hostname, "/"+strings.TrimPrefix(path, "/"))
+}
+
+// APIURL returns the REST API base URL of hostname: api.github.com, or
+// /api/v3/ on GitHub Enterprise Server
+func APIURL(hostname string) *url.URL {
+	if !IsEnterprise(hostname) {
+		return &url.URL{Scheme: "https", Host: "api.github.com", Path: "/"}
+	}
+	return hostURL(hostname, "/api/v3/")
+}
+
+// UploadURL returns the upload API base URL of hostname
+func UploadURL(hostname string) *url.URL {
+	if !IsEnterprise(hostname) {
+		return &url.URL{Scheme: "https", Host: "uploads.github.com", Path: "/"}
+	}
+	return hostURL(hostname, "/api/uploads/")
+}
+
+// hostURL returns the URL of path on a normalized hostname
+func hostURL(hostname, path string) *url.URL {
+	scheme, host := "https", hostname
+	if rest, ok := strings.CutPrefix(hostname, "http://"); ok {
+		scheme, host = "http", rest
+	}
+	return &url.URL{Scheme: scheme, Host: host, Path: path}
+}
@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeHostname(t *testing.T) {
+	for input, want := range map[string]string{
+		"":                                     DefaultHostname,
+		"github.com":                           DefaultHostname,
+		"https://api.github.com/":              DefaultHostname,
+		"GitHub.MyCompany.com":                 "github.mycompany.com",
+		"https://github.mycompany.com/":        "github.mycompany.com",
+		"https://github.mycompany.com/api/v3/": "github.mycompany.com",
+		"github.mycompany.com:8443":            "github.mycompany.com:8443",
+		"http://127.0.0.1:8080":                "http://127.0.0.1:8080",
+	} {
+		got, err := NormalizeHostname(input)
+		require.NoError(t, err, input)
+		assert.Equal(t, want, got, input)
+	}
+
+	for _, input := range []string{"ssh://github.mycompany.com", "github.mycompany.com/org", "user@github.mycompany.com"} {
+		_, err := NormalizeHostname(input)
+		assert.Error(t, err, input)
+	}
+}
+
+func TestHostURLs(t *testing.T) {
+	assert.Equal(t, "https://api.github.com/", APIURL(DefaultHostname).String())
+	assert.Equal(t, "https://uploads.github.com/", UploadURL("").String())
+	assert.Equal(t, "https://github.com/login/device/code", WebURL("", "login/device/code").String())
+
+	assert.Equal(t, "https://ghes.example.com/api/v3/", APIURL("ghes.example.com").String())
+	assert.Equal(t, "https://ghes.example.com/api/uploads/", UploadURL("ghes.example.com").String())
+	assert.Equal(t, "https://ghes.example.com/login/device", WebURL("ghes.example.com", "/login/device").String())
+	assert.Equal(t, "http://127.0.0.1:8080/api/v3/", APIURL("http://127.0.0.1:8080").String())
+}
+
+func TestGetToken_EnterpriseEnvVar(t *testing.T) {
+	t.Setenv(EnvGitHubToken, "ghp_dotcom")
+	t.Setenv(EnvGitHubEnterpriseToken, "ghp_enterprise")
+
+	result, err := GetToken(context.Background(), "", "github.mycompany.com")
+	require.NoError(t, err)
+	assert.Equal(t, "ghp_enterprise", result.Token)
+	assert.Equal(t, TokenSourceEnvGHES, result.Source)
+
+	result, err = GetToken(context.Background(), "", DefaultHostname)
+	require.NoError(t, err)
+	assert.Equal(t, "ghp_dotcom", result.Token)
+}
+
+func TestValidateToken_Enterprise(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v3/user" || r.Header.Get("Authorization") != "Bearer ghp_enterprise" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"login":"alice"}`)
+	}))
+	defer server.Close()
+
+	host, err := NormalizeHostname(server.URL)
+	require.NoError(t, err)
+	user, err := ValidateToken(context.Background(), "ghp_enterprise", host)
+	require.NoError(t, err)
+	assert.Equal(t, "alice", user.Login)
+}
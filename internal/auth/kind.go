@@ -0,0 +1,65 @@
+package auth
+
+import "strings"
+
+// TokenKind is the kind of a GitHub token, told apart by its prefix
+type TokenKind string
+
+const (
+	// TokenKindClassic is a classic personal access token (ghp_), or a
+	// token of the older format without a prefix
+	TokenKindClassic TokenKind = "classic"
+
+	// TokenKindFineGrained is a fine-grained personal access token
+	// (github_pat_), limited to the repositories and permissions chosen
+	// when it was created instead of scopes
+	TokenKindFineGrained TokenKind = "fine-grained"
+
+	// TokenKindOAuth is an OAuth app token (gho_), such as one from
+	// githubby login
+	TokenKindOAuth TokenKind = "oauth"
+
+	// TokenKindAppUser is a GitHub App user access token (ghu_)
+	TokenKindAppUser TokenKind = "app-user"
+
+	// TokenKindAppInstallation is a GitHub App installation token (ghs_)
+	TokenKindAppInstallation TokenKind = "app-installation"
+)
+
+// DetectTokenKind returns the kind of token
+func DetectTokenKind(token string) TokenKind {
+	switch {
+	case strings.HasPrefix(token, "github_pat_"):
+		return TokenKindFineGrained
+	case strings.HasPrefix(token, "gho_"):
+		return TokenKindOAuth
+	case strings.HasPrefix(token, "ghu_"):
+		return TokenKindAppUser
+	case strings.HasPrefix(token, "ghs_"):
+		return TokenKindAppInstallation
+	default:
+		return TokenKindClassic
+	}
+}
+
+// HasScopes reports whether tokens of this kind have OAuth scopes. Others
+// get their permissions from the token's or the App's settings.
+func (k TokenKind) HasScopes() bool {
+	return k == TokenKindClassic || k == TokenKindOAuth
+}
+
+// String describes the kind of token
+func (k TokenKind) String() string {
+	switch k {
+	case TokenKindFineGrained:
+		return "fine-grained personal access token"
+	case TokenKindOAuth:
+		return "OAuth app token"
+	case TokenKindAppUser:
+		return "GitHub App user token"
+	case TokenKindAppInstallation:
+		return "GitHub App installation token"
+	default:
+		return "classic personal access token"
+	}
+}
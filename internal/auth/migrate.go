@@ -0,0 +1,151 @@
+package auth
+
+import (
+	"errors"
+	"os"
+	"sort"
+
+	"github.com/zalando/go-keyring"
+	"gopkg.in/yaml.v3"
+)
+
+// plaintextToken is a token stored in plaintext
+type plaintextToken struct {
+	Account
+	token string
+}
+
+// plaintextTokens returns the tokens stored in plaintext in the config file
+// and the accounts file
+func (s *Storage) plaintextTokens() ([]plaintextToken, error) {
+	var tokens []plaintextToken
+
+	path, err := s.getConfigPath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	var cfg configFileData
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, err
+	}
+	if cfg.Token != "" {
+		tokens = append(tokens, plaintextToken{Account{Hostname: DefaultHostname}, cfg.Token})
+	}
+	hosts := make([]string, 0, len(cfg.Hosts))
+	for host := range cfg.Hosts {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	for _, host := range hosts {
+		if cfg.Hosts[host] != "" {
+			tokens = append(tokens, plaintextToken{Account{Hostname: host}, cfg.Hosts[host]})
+		}
+	}
+
+	accounts, err := s.readAccounts()
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range accounts.Accounts {
+		if entry.Token != "" {
+			tokens = append(tokens, plaintextToken{entry.Account, entry.Token})
+		}
+	}
+	return tokens, nil
+}
+
+// PlaintextTokens returns the accounts whose tokens are stored in plaintext,
+// in the config file or the accounts file
+func (s *Storage) PlaintextTokens() ([]Account, error) {
+	tokens, err := s.plaintextTokens()
+	if err != nil {
+		return nil, err
+	}
+	accounts := make([]Account, 0, len(tokens))
+	for _, token := range tokens {
+		accounts = append(accounts, token.Account)
+	}
+	return accounts, nil
+}
+
+// MigratePlaintextTokens moves the tokens stored in plaintext into the
+// keychain, or the encrypted store where there is none, and removes them
+// from the config file and the accounts file. Nothing is removed unless
+// every token was moved.
+func (s *Storage) MigratePlaintextTokens() ([]Account, error) {
+	tokens, err := s.plaintextTokens()
+	if err != nil || len(tokens) == 0 {
+		return nil, err
+	}
+
+	migrated := make([]Account, 0, len(tokens))
+	for _, token := range tokens {
+		key := accountKey(token.Name, token.Hostname)
+		switch {
+		case s.keychainAvailable && keyring.Set(KeyringServiceName, key, token.token) == nil:
+		case s.encrypted != nil:
+			if err := s.encrypted.update(func(stored map[string]string) {
+				stored[key] = token.token
+			}); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, errors.New("no keychain or encrypted token store to move tokens to")
+		}
+		migrated = append(migrated, token.Account)
+	}
+
+	if err := s.scrubConfigTokens(); err != nil {
+		return migrated, err
+	}
+	return migrated, s.updateAccounts(func(data *accountsFileData) {
+		for i := range data.Accounts {
+			data.Accounts[i].Token = ""
+		}
+	})
+}
+
+// scrubConfigTokens empties the token of the config file and removes the
+// tokens of Enterprise Server hosts, keeping everything else as it is
+func (s *Storage) scrubConfigTokens() error {
+	path, err := s.getConfigPath()
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil
+	}
+	mapping := doc.Content[0]
+	for i := 0; i+1 < len(mapping.Content); {
+		switch mapping.Content[i].Value {
+		case "token":
+			mapping.Content[i+1].SetString("")
+		case "hosts":
+			mapping.Content = append(mapping.Content[:i], mapping.Content[i+2:]...)
+			continue
+		}
+		i += 2
+	}
+
+	scrubbed, err := yaml.Marshal(&doc)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, scrubbed, 0600)
+}
@@ -0,0 +1,351 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/zalando/go-keyring"
+	"golang.org/x/oauth2"
+
+	"github.com/Didstopia/githubby/internal/lock"
+)
+
+const (
+	// tokenRefreshMargin is how long before an OAuth user token expires it's
+	// renewed, so requests of long syncs never use an expired token
+	tokenRefreshMargin = 5 * time.Minute
+
+	// refreshKeySuffix is appended to the keychain entry name of an account
+	// to name the entry of its refresh credentials
+	refreshKeySuffix = ":refresh"
+
+	// refreshLockFileName is the lock taken while a token is renewed, kept
+	// next to the encrypted token store
+	refreshLockFileName = "refresh.lock"
+
+	// refreshLockTimeout bounds how long a renewal waits for another
+	// process to finish renewing
+	refreshLockTimeout = time.Minute
+)
+
+// errNoSecureStorage is returned when refresh credentials can't be stored,
+// as they're never kept in plaintext
+var errNoSecureStorage = errors.New("refresh tokens are only stored in the keychain or the encrypted token store")
+
+// RefreshCredentials renew an expiring OAuth user token. GitHub issues them
+// with the token when the OAuth app has token expiration enabled.
+type RefreshCredentials struct {
+	// RefreshToken gets a new token; GitHub replaces it with every use
+	RefreshToken string `json:"refresh_token"`
+
+	// Expiry is when the access token expires
+	Expiry time.Time `json:"expiry"`
+
+	// RefreshExpiry is when the refresh token expires (zero if unknown)
+	RefreshExpiry time.Time `json:"refresh_expiry,omitzero"`
+
+	// ClientID is the OAuth app the token was issued to
+	ClientID string `json:"client_id"`
+
+	// AccessToken is the renewed token, stored with the credentials so
+	// both are replaced together (empty for credentials stored at login)
+	AccessToken string `json:"access_token,omitempty"`
+}
+
+// RefreshError is a failure to renew an expiring OAuth user token, e.g.
+// because its refresh token expired or was revoked
+type RefreshError struct {
+	Account  string
+	Hostname string
+	Err      error
+}
+
+func (e *RefreshError) Error() string {
+	who, login := e.Hostname, "githubby login"
+	if IsEnterprise(e.Hostname) {
+		login += " --hostname " + e.Hostname
+	}
+	if e.Account != "" {
+		who += fmt.Sprintf(" (account %q)", e.Account)
+		login += " --account " + e.Account
+	}
+	return fmt.Sprintf(`the token for %s expired and couldn't be renewed: %v
+
+To log in again:
+  %s`, who, e.Err, login)
+}
+
+func (e *RefreshError) Unwrap() error {
+	return e.Err
+}
+
+// refreshKey returns the keychain entry name of an account's refresh
+// credentials
+func refreshKey(name, hostname string) string {
+	return accountKey(name, hostname) + refreshKeySuffix
+}
+
+// GetAccountRefresh returns the refresh credentials of an account's stored
+// token (empty name for the default account of the hostname), nil if the
+// token doesn't expire
+func (s *Storage) GetAccountRefresh(name, hostname string) (*RefreshCredentials, error) {
+	if hostname == "" {
+		hostname = DefaultHostname
+	}
+	key := refreshKey(name, hostname)
+
+	var data string
+	if s.keychainAvailable {
+		if value, err := keyring.Get(KeyringServiceName, key); err == nil {
+			data = value
+		}
+	}
+	if data == "" && s.encrypted != nil {
+		value, err := s.encrypted.get(key)
+		if err != nil {
+			return nil, err
+		}
+		data = value
+	}
+	if data == "" {
+		return nil, nil
+	}
+
+	var creds RefreshCredentials
+	if err := json.Unmarshal([]byte(data), &creds); err != nil {
+		return nil, fmt.Errorf("failed to read refresh token: %w", err)
+	}
+	return &creds, nil
+}
+
+// SetAccountRefresh stores the refresh credentials of an account's token
+// (empty name for the default account of the hostname), or removes them
+// if creds is nil, as when a token that doesn't expire is stored
+func (s *Storage) SetAccountRefresh(name, hostname string, creds *RefreshCredentials) error {
+	if hostname == "" {
+		hostname = DefaultHostname
+	}
+	key := refreshKey(name, hostname)
+
+	if creds == nil {
+		if s.keychainAvailable {
+			_ = keyring.Delete(KeyringServiceName, key)
+		}
+		if s.encrypted != nil && s.encrypted.exists() {
+			return s.encrypted.update(func(tokens map[string]string) {
+				delete(tokens, key)
+			})
+		}
+		return nil
+	}
+
+	data, err := json.Marshal(creds)
+	if err != nil {
+		return err
+	}
+	if s.keychainAvailable && keyring.Set(KeyringServiceName, key, string(data)) == nil {
+		return nil
+	}
+	if s.encrypted != nil {
+		return s.encrypted.update(func(tokens map[string]string) {
+			tokens[key] = string(data)
+		})
+	}
+	return errNoSecureStorage
+}
+
+// setRenewedToken stores a renewed token and its refresh credentials in a
+// single update of the encrypted store. The keychain can't replace two
+// entries at once, so there the credentials, which hold the token too, are
+// written first and take precedence.
+func (s *Storage) setRenewedToken(name, hostname string, creds *RefreshCredentials) error {
+	data, err := json.Marshal(creds)
+	if err != nil {
+		return err
+	}
+	if s.keychainAvailable && keyring.Set(KeyringServiceName, refreshKey(name, hostname), string(data)) == nil {
+		return keyring.Set(KeyringServiceName, accountKey(name, hostname), creds.AccessToken)
+	}
+	if s.encrypted != nil {
+		return s.encrypted.update(func(tokens map[string]string) {
+			tokens[accountKey(name, hostname)] = creds.AccessToken
+			tokens[refreshKey(name, hostname)] = string(data)
+		})
+	}
+	return errNoSecureStorage
+}
+
+// lockRefresh takes the cross-process lock held while a token is renewed
+func (s *Storage) lockRefresh() (*lock.Lock, error) {
+	var dir string
+	if s.encrypted != nil {
+		path, err := s.encrypted.storePath()
+		if err != nil {
+			return nil, err
+		}
+		dir = filepath.Dir(path)
+	} else {
+		path, err := s.getConfigPath()
+		if err != nil {
+			return nil, err
+		}
+		dir = filepath.Dir(path)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), refreshLockTimeout)
+	defer cancel()
+	l, err := lock.Acquire(ctx, filepath.Join(dir, refreshLockFileName), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock the token store: %w", err)
+	}
+	return l, nil
+}
+
+// StoredTokenSource returns a source of the stored token of an account
+// (empty name for the default account of the hostname) that renews it
+// before it expires; nil if it doesn't expire
+func (s *Storage) StoredTokenSource(ctx context.Context, name, hostname string) (oauth2.TokenSource, error) {
+	if hostname == "" {
+		hostname = DefaultHostname
+	}
+	creds, err := s.GetAccountRefresh(name, hostname)
+	if err != nil || creds == nil {
+		return nil, err
+	}
+	token, _, err := s.GetAccountToken(name, hostname)
+	if err != nil {
+		return nil, err
+	}
+	return s.refreshingTokenSource(ctx, name, hostname, token, creds), nil
+}
+
+// refreshingSources holds a source of renewed tokens per account and
+// refresh token, so everything resolving an account's token shares it:
+// GitHub accepts each refresh token only once
+var (
+	refreshingSourcesMu sync.Mutex
+	refreshingSources   = map[string]oauth2.TokenSource{}
+)
+
+// refreshingTokenSource returns a source of an account's tokens that renews
+// the stored token with its refresh credentials shortly before it expires,
+// and stores the renewed token
+func (s *Storage) refreshingTokenSource(ctx context.Context, name, hostname, token string, creds *RefreshCredentials) oauth2.TokenSource {
+	key := accountKey(name, hostname) + "\x00" + creds.RefreshToken
+
+	refreshingSourcesMu.Lock()
+	defer refreshingSourcesMu.Unlock()
+	if tokens, ok := refreshingSources[key]; ok {
+		return tokens
+	}
+	if creds.AccessToken != "" {
+		token = creds.AccessToken
+	}
+	current := &oauth2.Token{AccessToken: token, RefreshToken: creds.RefreshToken, Expiry: creds.Expiry}
+	// The source outlives the request it was created for, e.g. in the TUI
+	tokens := oauth2.ReuseTokenSourceWithExpiry(current, &refresher{
+		ctx:      context.WithoutCancel(ctx),
+		storage:  s,
+		name:     name,
+		hostname: hostname,
+		clientID: creds.ClientID,
+	}, tokenRefreshMargin)
+	refreshingSources[key] = tokens
+	return tokens
+}
+
+// refresher renews an account's token every time it's asked
+type refresher struct {
+	ctx      context.Context
+	storage  *Storage
+	name     string
+	hostname string
+	clientID string
+}
+
+// Token renews the token. Renewals are serialized across processes: if
+// another githubby process renewed it already, the token it stored is used
+// instead, as the refresh token it used is no longer valid.
+func (r *refresher) Token() (*oauth2.Token, error) {
+	l, err := r.storage.lockRefresh()
+	if err != nil {
+		return nil, &RefreshError{Account: r.name, Hostname: r.hostname, Err: err}
+	}
+	defer l.Release()
+
+	creds, err := r.storage.GetAccountRefresh(r.name, r.hostname)
+	if err == nil && creds == nil {
+		err = errors.New("no refresh token is stored")
+	}
+	if err != nil {
+		return nil, &RefreshError{Account: r.name, Hostname: r.hostname, Err: err}
+	}
+	if time.Until(creds.Expiry) > tokenRefreshMargin {
+		stored := creds.AccessToken
+		if stored == "" {
+			stored, _, err = r.storage.GetAccountToken(r.name, r.hostname)
+		}
+		if err == nil {
+			return &oauth2.Token{AccessToken: stored, RefreshToken: creds.RefreshToken, Expiry: creds.Expiry}, nil
+		}
+	}
+	if !creds.RefreshExpiry.IsZero() && time.Now().After(creds.RefreshExpiry) {
+		return nil, &RefreshError{Account: r.name, Hostname: r.hostname, Err: errors.New("the refresh token expired")}
+	}
+
+	clientID := r.clientID
+	if creds.ClientID != "" {
+		clientID = creds.ClientID
+	}
+	config := &oauth2.Config{
+		ClientID: clientID,
+		Endpoint: oauth2.Endpoint{
+			TokenURL:  WebURL(r.hostname, "login/oauth/access_token").String(),
+			AuthStyle: oauth2.AuthStyleInParams,
+		},
+	}
+	// Without an access token, the source always renews it
+	token, err := config.TokenSource(r.ctx, &oauth2.Token{RefreshToken: creds.RefreshToken}).Token()
+	if err != nil {
+		return nil, &RefreshError{Account: r.name, Hostname: r.hostname, Err: err}
+	}
+
+	renewed := &RefreshCredentials{
+		RefreshToken: token.RefreshToken,
+		Expiry:       token.Expiry,
+		ClientID:     clientID,
+		AccessToken:  token.AccessToken,
+	}
+	if renewed.RefreshToken == "" {
+		renewed.RefreshToken = creds.RefreshToken
+	}
+	if expiresIn := extraSeconds(token, "refresh_token_expires_in"); expiresIn > 0 {
+		renewed.RefreshExpiry = time.Now().Add(expiresIn)
+	} else {
+		renewed.RefreshExpiry = creds.RefreshExpiry
+	}
+	if err := r.storage.setRenewedToken(r.name, r.hostname, renewed); err != nil {
+		return nil, &RefreshError{Account: r.name, Hostname: r.hostname, Err: fmt.Errorf("failed to store the renewed token: %w", err)}
+	}
+	return token, nil
+}
+
+// extraSeconds returns a duration in seconds from a field of a token
+// response, which is a string in form-encoded responses and a number in
+// JSON ones
+func extraSeconds(token *oauth2.Token, key string) time.Duration {
+	var seconds int64
+	switch value := token.Extra(key).(type) {
+	case string:
+		seconds, _ = strconv.ParseInt(value, 10, 64)
+	case float64:
+		seconds = int64(value)
+	}
+	return time.Duration(seconds) * time.Second
+}
@@ -0,0 +1,234 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cli/oauth/device"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestTokenServer serves the OAuth token endpoint of a GitHub Enterprise
+// Server, answering with respond, and counts the requests
+func newTestTokenServer(t *testing.T, respond func(w http.ResponseWriter, r *http.Request, n int32)) (*httptest.Server, *int32) {
+	t.Helper()
+	var requests int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login/oauth/access_token", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		require.NoError(t, r.ParseForm())
+		respond(w, r, atomic.AddInt32(&requests, 1))
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestWaitForToken(t *testing.T) {
+	server, _ := newTestTokenServer(t, func(w http.ResponseWriter, r *http.Request, _ int32) {
+		assert.Equal(t, "device-code", r.Form.Get("device_code"))
+		w.Header().Set("Content-Type", "application/x-www-form-urlencoded")
+		fmt.Fprint(w, "access_token=ghu_token&refresh_token=ghr_refresh&expires_in=28800&refresh_token_expires_in=15897600&scope=repo,read:org&token_type=bearer")
+	})
+
+	code := &device.CodeResponse{DeviceCode: "device-code", ExpiresIn: 60}
+	before := time.Now()
+	result, err := waitForToken(context.Background(), server.URL+"/login/oauth/access_token", "client-id", code)
+	require.NoError(t, err)
+
+	assert.Equal(t, "ghu_token", result.Token)
+	assert.Equal(t, []string{"repo", "read:org"}, result.Scopes)
+	assert.WithinDuration(t, before.Add(8*time.Hour), result.Expiry, time.Minute)
+	assert.WithinDuration(t, before.Add(184*24*time.Hour), result.RefreshExpiry, time.Minute)
+
+	creds := result.RefreshCredentials()
+	require.NotNil(t, creds)
+	assert.Equal(t, "ghr_refresh", creds.RefreshToken)
+	assert.Equal(t, "client-id", creds.ClientID)
+	assert.Equal(t, result.Expiry, creds.Expiry)
+
+	// Tokens that don't expire have nothing to renew them with
+	assert.Nil(t, (&DeviceFlowResult{Token: "gho_token"}).RefreshCredentials())
+}
+
+func TestParseTokenResponse(t *testing.T) {
+	values := parseTokenResponse("application/json; charset=utf-8", []byte(`{"access_token":"ghu_token","expires_in":28800}`))
+	assert.Equal(t, "ghu_token", values.Get("access_token"))
+	assert.Equal(t, 8*time.Hour, seconds(values.Get("expires_in")))
+
+	values = parseTokenResponse("application/x-www-form-urlencoded", []byte("error=authorization_pending"))
+	assert.Equal(t, "authorization_pending", values.Get("error"))
+	assert.Empty(t, values.Get("access_token"))
+}
+
+func TestStorage_AccountRefresh(t *testing.T) {
+	storage, _, _, _ := newEncryptedTestStorage(t)
+
+	creds, err := storage.GetAccountRefresh("work", DefaultHostname)
+	require.NoError(t, err)
+	assert.Nil(t, creds, "tokens that don't expire have no refresh credentials")
+
+	stored := &RefreshCredentials{RefreshToken: "ghr_refresh", Expiry: time.Now().Add(time.Hour).UTC().Truncate(time.Second), ClientID: "client-id"}
+	require.NoError(t, storage.SetAccountToken("work", DefaultHostname, "ghu_token"))
+	require.NoError(t, storage.SetAccountRefresh("work", DefaultHostname, stored))
+	creds, err = storage.GetAccountRefresh("work", DefaultHostname)
+	require.NoError(t, err)
+	assert.Equal(t, stored, creds)
+
+	// Logging out removes them with the token
+	require.NoError(t, storage.DeleteAccountToken("work", DefaultHostname))
+	creds, err = storage.GetAccountRefresh("work", DefaultHostname)
+	require.NoError(t, err)
+	assert.Nil(t, creds)
+
+	// Refresh tokens are never stored in plaintext
+	plaintext := NewStorageWithConfig(filepath.Join(t.TempDir(), ConfigFileName), false)
+	assert.ErrorIs(t, plaintext.SetAccountRefresh("", DefaultHostname, stored), errNoSecureStorage)
+}
+
+func TestStoredTokenSource_Renews(t *testing.T) {
+	server, requests := newTestTokenServer(t, func(w http.ResponseWriter, r *http.Request, n int32) {
+		assert.Equal(t, "refresh_token", r.Form.Get("grant_type"))
+		assert.Equal(t, "ghr_old", r.Form.Get("refresh_token"))
+		assert.Equal(t, "client-id", r.Form.Get("client_id"))
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"access_token":"ghu_new%d","refresh_token":"ghr_new","expires_in":28800,"refresh_token_expires_in":15897600,"token_type":"bearer"}`, n)
+	})
+	storage, _, _, _ := newEncryptedTestStorage(t)
+	host := server.URL
+
+	// The token expires within the refresh margin
+	require.NoError(t, storage.SetAccountToken("", host, "ghu_old"))
+	require.NoError(t, storage.SetAccountRefresh("", host, &RefreshCredentials{
+		RefreshToken: "ghr_old",
+		Expiry:       time.Now().Add(time.Minute),
+		ClientID:     "client-id",
+	}))
+
+	tokens, err := storage.StoredTokenSource(context.Background(), "", host)
+	require.NoError(t, err)
+	for range 2 {
+		token, err := tokens.Token()
+		require.NoError(t, err)
+		assert.Equal(t, "ghu_new1", token.AccessToken)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(requests), "the renewed token is reused")
+
+	// The renewed token and refresh token are stored for the next run
+	stored, _, err := storage.GetAccountToken("", host)
+	require.NoError(t, err)
+	assert.Equal(t, "ghu_new1", stored)
+	creds, err := storage.GetAccountRefresh("", host)
+	require.NoError(t, err)
+	assert.Equal(t, "ghr_new", creds.RefreshToken)
+	assert.WithinDuration(t, time.Now().Add(8*time.Hour), creds.Expiry, time.Minute)
+	assert.WithinDuration(t, time.Now().Add(184*24*time.Hour), creds.RefreshExpiry, time.Minute)
+
+	// A token that doesn't expire soon is used as it is
+	tokens, err = storage.StoredTokenSource(context.Background(), "", host)
+	require.NoError(t, err)
+	token, err := tokens.Token()
+	require.NoError(t, err)
+	assert.Equal(t, "ghu_new1", token.AccessToken)
+	assert.Equal(t, int32(1), atomic.LoadInt32(requests))
+}
+
+func TestRefresher_ConcurrentRenewals(t *testing.T) {
+	server, requests := newTestTokenServer(t, func(w http.ResponseWriter, r *http.Request, n int32) {
+		if r.Form.Get("refresh_token") != "ghr_old" {
+			// GitHub accepts each refresh token only once
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"error":"bad_refresh_token"}`)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"access_token":"ghu_new%d","refresh_token":"ghr_new%d","expires_in":28800,"token_type":"bearer"}`, n, n)
+	})
+	storage, configPath, storePath, keyFile := newEncryptedTestStorage(t)
+	host := server.URL
+	require.NoError(t, storage.SetAccountToken("", host, "ghu_old"))
+	require.NoError(t, storage.SetAccountRefresh("", host, &RefreshCredentials{
+		RefreshToken: "ghr_old",
+		Expiry:       time.Now().Add(time.Minute),
+		ClientID:     "client-id",
+	}))
+
+	// Separate storages and refreshers stand in for separate githubby processes
+	var wg sync.WaitGroup
+	tokens := make([]string, 4)
+	errs := make([]error, len(tokens))
+	for i := range tokens {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r := &refresher{ctx: context.Background(), storage: NewStorageWithEncryptedStore(configPath, storePath, keyFile), hostname: host, clientID: "client-id"}
+			token, err := r.Token()
+			if err == nil {
+				tokens[i] = token.AccessToken
+			}
+			errs[i] = err
+		}()
+	}
+	wg.Wait()
+
+	for i := range tokens {
+		require.NoError(t, errs[i])
+		assert.Equal(t, "ghu_new1", tokens[i], "the first renewal's token is used by the others")
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(requests))
+
+	// The token and its refresh credentials are stored together
+	creds, err := storage.GetAccountRefresh("", host)
+	require.NoError(t, err)
+	assert.Equal(t, "ghr_new1", creds.RefreshToken)
+	assert.Equal(t, "ghu_new1", creds.AccessToken)
+	stored, _, err := storage.GetAccountToken("", host)
+	require.NoError(t, err)
+	assert.Equal(t, "ghu_new1", stored)
+}
+
+func TestStoredTokenSource_RefreshFails(t *testing.T) {
+	server, _ := newTestTokenServer(t, func(w http.ResponseWriter, _ *http.Request, _ int32) {
+		// GitHub reports OAuth errors with a 200 response
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"error":"bad_refresh_token","error_description":"The refresh token passed is incorrect or expired."}`)
+	})
+	storage, _, _, _ := newEncryptedTestStorage(t)
+	host := server.URL
+
+	require.NoError(t, storage.SetAccountToken("work", host, "ghu_old"))
+	require.NoError(t, storage.SetAccountRefresh("work", host, &RefreshCredentials{
+		RefreshToken: "ghr_revoked",
+		Expiry:       time.Now().Add(-time.Hour),
+		ClientID:     "client-id",
+	}))
+
+	tokens, err := storage.StoredTokenSource(context.Background(), "work", host)
+	require.NoError(t, err)
+	_, err = tokens.Token()
+	var refreshErr *RefreshError
+	require.True(t, errors.As(err, &refreshErr), "got %v", err)
+	assert.Equal(t, "work", refreshErr.Account)
+	assert.ErrorContains(t, err, "bad_refresh_token")
+	assert.ErrorContains(t, err, "githubby login --hostname "+host+" --account work")
+
+	// Expired refresh tokens aren't even tried
+	require.NoError(t, storage.SetAccountRefresh("work", host, &RefreshCredentials{
+		RefreshToken:  "ghr_expired",
+		Expiry:        time.Now().Add(-time.Hour),
+		RefreshExpiry: time.Now().Add(-time.Minute),
+	}))
+	tokens, err = storage.StoredTokenSource(context.Background(), "work", host)
+	require.NoError(t, err)
+	_, err = tokens.Token()
+	assert.ErrorContains(t, err, "the refresh token expired")
+}
@@ -0,0 +1,99 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+)
+
+// EnvOAuthClientSecret is the environment variable holding the client secret
+// of the OAuth app that issued device flow tokens. Logging in doesn't need
+// it, but revoking a token does.
+const EnvOAuthClientSecret = "GITHUBBY_OAUTH_CLIENT_SECRET"
+
+var (
+	// ErrNotRevocable is returned for tokens that weren't issued by an OAuth
+	// app, such as personal access tokens, which can only be deleted on
+	// GitHub
+	ErrNotRevocable = errors.New("only OAuth tokens can be revoked")
+
+	// ErrNoClientSecret is returned when the OAuth app's client secret isn't
+	// set in EnvOAuthClientSecret
+	ErrNoClientSecret = errors.New("revoking a token needs the OAuth app's client secret (" + EnvOAuthClientSecret + ")")
+
+	// ErrTokenNotFound is returned when GitHub doesn't know the token: it
+	// was revoked already, or was issued by another OAuth app
+	ErrTokenNotFound = errors.New("the token was revoked already or issued by another OAuth app")
+)
+
+// revokeHTTPClient is the HTTP client tokens are revoked with
+var revokeHTTPClient = &http.Client{Timeout: 30 * time.Second}
+
+// RevokeToken revokes an OAuth token on GitHub with the OAuth app token
+// deletion endpoint, which authenticates with the client ID and secret of
+// the app that issued it. The secret is read from EnvOAuthClientSecret.
+func RevokeToken(ctx context.Context, hostname, clientID, token string) error {
+	if kind := DetectTokenKind(token); kind != TokenKindOAuth && kind != TokenKindAppUser {
+		return ErrNotRevocable
+	}
+	secret := os.Getenv(EnvOAuthClientSecret)
+	if secret == "" {
+		return ErrNoClientSecret
+	}
+	if clientID == "" {
+		clientID = GitHubOAuthClientID
+	}
+
+	body, err := json.Marshal(map[string]string{"access_token": token})
+	if err != nil {
+		return err
+	}
+	endpoint := APIURL(hostname).JoinPath("applications", clientID, "token")
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, endpoint.String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(clientID, secret)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := revokeHTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to revoke token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNoContent:
+		return nil
+	case http.StatusNotFound:
+		return ErrTokenNotFound
+	case http.StatusUnauthorized:
+		return fmt.Errorf("GitHub rejected the client ID and secret of OAuth app %s", clientID)
+	default:
+		return fmt.Errorf("failed to revoke token: GitHub responded %s", resp.Status)
+	}
+}
+
+// RevokeAccountToken revokes the stored token of an account on GitHub (empty
+// name for the default account of the hostname), using the OAuth app it was
+// issued to if that's known
+func (s *Storage) RevokeAccountToken(ctx context.Context, name, hostname string) error {
+	if hostname == "" {
+		hostname = DefaultHostname
+	}
+	token, _, err := s.GetAccountToken(name, hostname)
+	if err != nil {
+		return err
+	}
+	clientID := GitHubOAuthClientID
+	if creds, err := s.GetAccountRefresh(name, hostname); err == nil && creds != nil && creds.ClientID != "" {
+		clientID = creds.ClientID
+	}
+	return RevokeToken(ctx, hostname, clientID, token)
+}
@@ -0,0 +1,78 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestRevokeServer serves the OAuth app token deletion endpoint of a
+// GitHub Enterprise Server, which knows the given tokens of the OAuth app
+// clientID with the client secret "secret"
+func newTestRevokeServer(t *testing.T, clientID string, tokens map[string]bool) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("DELETE /api/v3/applications/{client}/token", func(w http.ResponseWriter, r *http.Request) {
+		user, secret, ok := r.BasicAuth()
+		if !ok || user != clientID || r.PathValue("client") != clientID || secret != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var body struct {
+			AccessToken string `json:"access_token"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		if !tokens[body.AccessToken] {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		delete(tokens, body.AccessToken)
+		w.WriteHeader(http.StatusNoContent)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestRevokeToken(t *testing.T) {
+	tokens := map[string]bool{"gho_live": true}
+	server := newTestRevokeServer(t, GitHubOAuthClientID, tokens)
+	ctx := context.Background()
+
+	t.Setenv(EnvOAuthClientSecret, "")
+	assert.ErrorIs(t, RevokeToken(ctx, server.URL, "", "gho_live"), ErrNoClientSecret)
+
+	t.Setenv(EnvOAuthClientSecret, "secret")
+	assert.ErrorIs(t, RevokeToken(ctx, server.URL, "", "ghp_classic"), ErrNotRevocable)
+	assert.ErrorIs(t, RevokeToken(ctx, server.URL, "", "github_pat_finegrained"), ErrNotRevocable)
+
+	require.NoError(t, RevokeToken(ctx, server.URL, "", "gho_live"))
+	assert.Empty(t, tokens)
+	assert.ErrorIs(t, RevokeToken(ctx, server.URL, "", "gho_live"), ErrTokenNotFound, "revoked already")
+
+	t.Setenv(EnvOAuthClientSecret, "wrong")
+	assert.ErrorContains(t, RevokeToken(ctx, server.URL, "", "gho_other"), "rejected the client ID and secret")
+}
+
+func TestStorage_RevokeAccountToken(t *testing.T) {
+	t.Setenv(EnvOAuthClientSecret, "secret")
+	tokens := map[string]bool{"ghu_expiring": true}
+	server := newTestRevokeServer(t, "other-app", tokens)
+	storage, _, _, _ := newEncryptedTestStorage(t)
+
+	// Tokens are revoked with the OAuth app they were issued to
+	require.NoError(t, storage.SetAccountToken("work", server.URL, "ghu_expiring"))
+	require.NoError(t, storage.SetAccountRefresh("work", server.URL, &RefreshCredentials{
+		RefreshToken: "ghr_refresh",
+		Expiry:       time.Now().Add(time.Hour),
+		ClientID:     "other-app",
+	}))
+	require.NoError(t, storage.RevokeAccountToken(context.Background(), "work", server.URL))
+	assert.Empty(t, tokens)
+}
@@ -0,0 +1,158 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mitchellh/go-homedir"
+	"golang.org/x/oauth2"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// EnvGitHubTokenFile is the environment variable for the path to a file
+	// holding the GitHub token, such as a Docker or Kubernetes secret
+	EnvGitHubTokenFile = "GITHUB_TOKEN_FILE"
+
+	// EnvGitHubEnterpriseTokenFile is the environment variable for the path
+	// to a file holding the token of GitHub Enterprise Server hosts
+	EnvGitHubEnterpriseTokenFile = "GITHUB_ENTERPRISE_TOKEN_FILE"
+
+	// EnvTokenCommandHost is set to the hostname the token is for when the
+	// token command runs, e.g. for "gh auth token --hostname $GITHUB_HOST"
+	EnvTokenCommandHost = "GITHUB_HOST"
+
+	// DefaultTokenCommandTTL is how long the output of the token command is
+	// used before it's run again
+	DefaultTokenCommandTTL = 5 * time.Minute
+
+	// tokenCommandTimeout is how long the token command may take
+	tokenCommandTimeout = 30 * time.Second
+)
+
+// ReadTokenFile reads a token from a file, ignoring surrounding whitespace
+func ReadTokenFile(path string) (string, error) {
+	expanded, err := homedir.Expand(path)
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(expanded)
+	if err != nil {
+		return "", fmt.Errorf("failed to read token file: %w", err)
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return "", fmt.Errorf("token file %s is empty", path)
+	}
+	return token, nil
+}
+
+// TokenCommand is a command that prints a GitHub token, configured with
+// token_command in the config file
+type TokenCommand struct {
+	// Command is run by the shell
+	Command string
+
+	// TTL is how long its output is used before it's run again
+	TTL time.Duration
+}
+
+// GetTokenCommand returns the token command of the config file, or nil if
+// there is none
+func (s *Storage) GetTokenCommand() (*TokenCommand, error) {
+	path, err := s.getConfigPath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var cfg configFileData
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(cfg.TokenCommand) == "" {
+		return nil, nil
+	}
+
+	command := &TokenCommand{Command: cfg.TokenCommand, TTL: DefaultTokenCommandTTL}
+	if cfg.TokenCommandTTL != "" {
+		if command.TTL, err = time.ParseDuration(cfg.TokenCommandTTL); err != nil || command.TTL < 0 {
+			return nil, fmt.Errorf("invalid token_command_ttl %q: expected a duration such as 5m", cfg.TokenCommandTTL)
+		}
+	}
+	return command, nil
+}
+
+// tokenCommandSources holds a token source per command and hostname, so the
+// command's output is shared by everything resolving tokens until it expires
+var (
+	tokenCommandSourcesMu sync.Mutex
+	tokenCommandSources   = map[string]oauth2.TokenSource{}
+)
+
+// TokenSource returns a source of the command's tokens for hostname that
+// runs the command again once its output is older than the TTL
+func (c *TokenCommand) TokenSource(hostname string) oauth2.TokenSource {
+	key := c.Command + "\x00" + c.TTL.String() + "\x00" + hostname
+
+	tokenCommandSourcesMu.Lock()
+	defer tokenCommandSourcesMu.Unlock()
+	if tokens, ok := tokenCommandSources[key]; ok {
+		return tokens
+	}
+	tokens := oauth2.ReuseTokenSourceWithExpiry(nil, &commandTokenSource{command: c, hostname: hostname}, 0)
+	tokenCommandSources[key] = tokens
+	return tokens
+}
+
+// commandTokenSource runs the token command every time it's asked
+type commandTokenSource struct {
+	command  *TokenCommand
+	hostname string
+}
+
+// Token runs the token command
+func (s *commandTokenSource) Token() (*oauth2.Token, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), tokenCommandTimeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", s.command.Command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", s.command.Command)
+	}
+	cmd.Env = append(os.Environ(), EnvTokenCommandHost+"="+s.hostname)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	output, err := cmd.Output()
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, fmt.Errorf("token command timed out after %s", tokenCommandTimeout)
+		}
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return nil, fmt.Errorf("token command failed: %w: %s", err, message)
+		}
+		return nil, fmt.Errorf("token command failed: %w", err)
+	}
+	token := strings.TrimSpace(string(output))
+	if token == "" {
+		return nil, errors.New("token command printed no token")
+	}
+	return &oauth2.Token{AccessToken: token, Expiry: time.Now().Add(s.command.TTL)}, nil
+}
@@ -0,0 +1,135 @@
+package auth
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadTokenFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "token")
+	require.NoError(t, os.WriteFile(path, []byte("ghp_secret\n"), 0600))
+
+	token, err := ReadTokenFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "ghp_secret", token)
+
+	require.NoError(t, os.WriteFile(path, []byte("  \n"), 0600))
+	_, err = ReadTokenFile(path)
+	assert.ErrorContains(t, err, "is empty")
+
+	_, err = ReadTokenFile(filepath.Join(dir, "missing"))
+	assert.Error(t, err)
+}
+
+func TestGetToken_FromTokenFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(path, []byte("ghp_file\n"), 0600))
+	t.Setenv(EnvGitHubAppID, "")
+	t.Setenv(EnvGitHubToken, "")
+	t.Setenv(EnvGitHubTokenFile, path)
+
+	result, err := GetToken(context.Background(), "", DefaultHostname)
+	require.NoError(t, err)
+	assert.Equal(t, "ghp_file", result.Token)
+	assert.Equal(t, TokenSourceFile, result.Source)
+	assert.Equal(t, path, result.Origin)
+
+	// GITHUB_TOKEN comes first
+	t.Setenv(EnvGitHubToken, "ghp_env")
+	result, err = GetToken(context.Background(), "", DefaultHostname)
+	require.NoError(t, err)
+	assert.Equal(t, TokenSourceEnv, result.Source)
+
+	// An unreadable token file is an error, not a silent fallback
+	t.Setenv(EnvGitHubToken, "")
+	t.Setenv(EnvGitHubTokenFile, path+".missing")
+	_, err = GetToken(context.Background(), "", DefaultHostname)
+	assert.ErrorContains(t, err, EnvGitHubTokenFile)
+}
+
+func TestStorage_GetTokenCommand(t *testing.T) {
+	configPath, cleanup := createTempConfig(t)
+	defer cleanup()
+	storage := NewStorageWithConfig(configPath, false)
+
+	command, err := storage.GetTokenCommand()
+	require.NoError(t, err)
+	assert.Nil(t, command, "no config file")
+
+	require.NoError(t, os.WriteFile(configPath, []byte("token_command: gh auth token\n"), 0600))
+	command, err = storage.GetTokenCommand()
+	require.NoError(t, err)
+	assert.Equal(t, &TokenCommand{Command: "gh auth token", TTL: DefaultTokenCommandTTL}, command)
+
+	require.NoError(t, os.WriteFile(configPath, []byte("token_command: gh auth token\ntoken_command_ttl: 1h\n"), 0600))
+	command, err = storage.GetTokenCommand()
+	require.NoError(t, err)
+	assert.Equal(t, time.Hour, command.TTL)
+
+	// Storing a token keeps the token command
+	require.NoError(t, storage.SetToken(DefaultHostname, "ghp_stored"))
+	command, err = storage.GetTokenCommand()
+	require.NoError(t, err)
+	assert.Equal(t, "gh auth token", command.Command)
+
+	require.NoError(t, os.WriteFile(configPath, []byte("token_command: gh auth token\ntoken_command_ttl: soon\n"), 0600))
+	_, err = storage.GetTokenCommand()
+	assert.ErrorContains(t, err, "token_command_ttl")
+}
+
+func TestTokenCommand_TokenSource(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a POSIX shell")
+	}
+	runs := filepath.Join(t.TempDir(), "runs")
+	script := "echo run >> " + runs + "; echo ghp_cmd_$" + EnvTokenCommandHost
+
+	// runCount returns how often the command ran
+	runCount := func() int {
+		data, _ := os.ReadFile(runs)
+		return strings.Count(string(data), "run")
+	}
+
+	t.Run("output is reused until the TTL passes", func(t *testing.T) {
+		command := &TokenCommand{Command: script, TTL: time.Hour}
+		for range 3 {
+			token, err := command.TokenSource("github.mycompany.com").Token()
+			require.NoError(t, err)
+			assert.Equal(t, "ghp_cmd_github.mycompany.com", token.AccessToken)
+		}
+		assert.Equal(t, 1, runCount())
+
+		_, err := command.TokenSource(DefaultHostname).Token()
+		require.NoError(t, err)
+		assert.Equal(t, 2, runCount(), "each host gets its own token")
+	})
+
+	t.Run("no TTL runs the command every time", func(t *testing.T) {
+		command := &TokenCommand{Command: script, TTL: 0}
+		before := runCount()
+		for range 2 {
+			_, err := command.TokenSource(DefaultHostname).Token()
+			require.NoError(t, err)
+		}
+		assert.Equal(t, before+2, runCount())
+	})
+
+	t.Run("failures include the command's error output", func(t *testing.T) {
+		command := &TokenCommand{Command: "echo 'not logged in' >&2; exit 1", TTL: time.Hour}
+		_, err := command.TokenSource(DefaultHostname).Token()
+		assert.ErrorContains(t, err, "not logged in")
+
+		command = &TokenCommand{Command: "true", TTL: time.Hour}
+		_, err = command.TokenSource(DefaultHostname).Token()
+		assert.ErrorContains(t, err, "printed no token")
+	})
+}
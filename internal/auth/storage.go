@@ -23,15 +23,29 @@ const (
 type Storage struct {
 	keychainAvailable bool
 	configPath        string // Custom config path (for testing)
+
+	// encrypted is the encrypted token store, used where there is no
+	// keychain; nil stores tokens in plaintext
+	encrypted *encryptedStore
 }
 
 // NewStorage creates a new Storage instance
 func NewStorage() *Storage {
-	s := &Storage{}
+	s := &Storage{encrypted: &encryptedStore{}}
 	s.keychainAvailable = s.checkKeychainAvailable()
 	return s
 }
 
+// NewStorageWithEncryptedStore creates a Storage instance without keychain
+// that keeps tokens in an encrypted store at storePath, unlocked with the
+// key file at keyFile unless GITHUBBY_TOKEN_PASSPHRASE is set (for testing)
+func NewStorageWithEncryptedStore(configPath, storePath, keyFile string) *Storage {
+	return &Storage{
+		configPath: configPath,
+		encrypted:  &encryptedStore{path: storePath, keyFile: keyFile},
+	}
+}
+
 // NewStorageWithConfig creates a Storage instance with a custom config path (for testing)
 func NewStorageWithConfig(configPath string, useKeychain bool) *Storage {
 	return &Storage{
@@ -70,64 +84,132 @@ func (s *Storage) checkKeychainAvailable() bool {
 	return true
 }
 
-// GetToken retrieves the stored token for a hostname
+// GetToken retrieves the stored token of the default account of a hostname
 func (s *Storage) GetToken(hostname string) (string, TokenSource, error) {
+	return s.GetAccountToken("", hostname)
+}
+
+// GetAccountToken retrieves the stored token of an account (empty name for
+// the default account of the hostname)
+func (s *Storage) GetAccountToken(name, hostname string) (string, TokenSource, error) {
 	if hostname == "" {
 		hostname = DefaultHostname
 	}
 
 	// Try keychain first
 	if s.keychainAvailable {
-		token, err := keyring.Get(KeyringServiceName, hostname)
+		token, err := keyring.Get(KeyringServiceName, accountKey(name, hostname))
 		if err == nil && token != "" {
 			return token, TokenSourceKeychain, nil
 		}
-		// If not found in keychain, fall through to config file
+		// If not found in keychain, fall through to the encrypted store
 	}
 
-	// Fall back to config file
-	token, err := s.getTokenFromConfig()
+	// Then the encrypted store
+	if s.encrypted != nil {
+		token, err := s.encrypted.get(accountKey(name, hostname))
+		if err != nil {
+			return "", TokenSourceNone, err
+		}
+		if token != "" {
+			return token, TokenSourceEncrypted, nil
+		}
+	}
+
+	// Fall back to tokens stored in plaintext (before "githubby auth
+	// migrate"): the config file, or the accounts file for named accounts
+	var token string
+	var err error
+	if name == "" {
+		token, err = s.getTokenFromConfig(hostname)
+	} else {
+		token, err = s.getTokenFromAccounts(name, hostname)
+	}
 	if err == nil && token != "" {
 		return token, TokenSourceConfig, nil
 	}
 
+	if name != "" {
+		return "", TokenSourceNone, fmt.Errorf("no token found for account %q", name)
+	}
 	return "", TokenSourceNone, fmt.Errorf("no token found")
 }
 
-// SetToken stores a token for a hostname
+// SetToken stores the token of the default account of a hostname
 func (s *Storage) SetToken(hostname, token string) error {
+	return s.SetAccountToken("", hostname, token)
+}
+
+// SetAccountToken stores the token of an account (empty name for the default
+// account of the hostname)
+func (s *Storage) SetAccountToken(name, hostname, token string) error {
 	if hostname == "" {
 		hostname = DefaultHostname
 	}
 
 	// Try keychain first
 	if s.keychainAvailable {
-		err := keyring.Set(KeyringServiceName, hostname, token)
+		err := keyring.Set(KeyringServiceName, accountKey(name, hostname), token)
 		if err == nil {
 			return nil
 		}
-		// Fall through to config file if keychain fails
+		// Fall through to the encrypted store if keychain fails
+	}
+
+	if s.encrypted != nil {
+		return s.encrypted.update(func(tokens map[string]string) {
+			tokens[accountKey(name, hostname)] = token
+		})
 	}
 
-	// Fall back to config file
-	return s.setTokenInConfig(token)
+	// Fall back to config file, or the accounts file for named accounts
+	if name != "" {
+		return s.setTokenInAccounts(name, hostname, token)
+	}
+	return s.setTokenInConfig(hostname, token)
 }
 
-// DeleteToken removes the stored token for a hostname
+// DeleteToken removes the stored token of the default account of a hostname
 func (s *Storage) DeleteToken(hostname string) error {
+	return s.DeleteAccountToken("", hostname)
+}
+
+// DeleteAccountToken removes the stored token of an account (empty name for
+// the default account of the hostname) and removes it from the accounts list
+func (s *Storage) DeleteAccountToken(name, hostname string) error {
 	if hostname == "" {
 		hostname = DefaultHostname
 	}
 
 	var keychainErr, configErr error
 
+	// Expiring tokens can't be renewed once they're gone
+	if err := s.SetAccountRefresh(name, hostname, nil); err != nil {
+		return err
+	}
+
 	// Try to delete from keychain
 	if s.keychainAvailable {
-		keychainErr = keyring.Delete(KeyringServiceName, hostname)
+		keychainErr = keyring.Delete(KeyringServiceName, accountKey(name, hostname))
+	}
+
+	// And from the encrypted store
+	if s.encrypted != nil && s.encrypted.exists() {
+		if err := s.encrypted.update(func(tokens map[string]string) {
+			delete(tokens, accountKey(name, hostname))
+		}); err != nil {
+			return err
+		}
 	}
 
-	// Also try to clear from config file
-	configErr = s.clearTokenFromConfig()
+	// Also try to clear from config file; named accounts are cleared with
+	// their entry in the accounts file
+	if name == "" {
+		configErr = s.clearTokenFromConfig(hostname)
+	}
+	if err := s.RemoveAccount(name, hostname); err != nil && name != "" {
+		configErr = err
+	}
 
 	// Return error only if both failed
 	if keychainErr != nil && configErr != nil {
@@ -156,6 +238,9 @@ func (s *Storage) GetStorageLocation() string {
 			return "System Keychain"
 		}
 	}
+	if s.encrypted != nil {
+		return s.encrypted.describe()
+	}
 	return "config file (~/.githubby.yaml)"
 }
 
@@ -176,6 +261,13 @@ func (s *Storage) getConfigPath() (string, error) {
 // configFileData represents the structure of the config file
 type configFileData struct {
 	Token string `yaml:"token"`
+	// Hosts holds the tokens of GitHub Enterprise Server hosts by hostname
+	Hosts map[string]string `yaml:"hosts,omitempty"`
+	// TokenCommand prints the token of the default account; see TokenCommand
+	TokenCommand    string `yaml:"token_command,omitempty"`
+	TokenCommandTTL string `yaml:"token_command_ttl,omitempty"`
+	// Network is preserved as written; see package network
+	Network yaml.Node `yaml:"network,omitempty"`
 	// Other fields are preserved but not used here
 	Verbose        bool     `yaml:"verbose,omitempty"`
 	DryRun         bool     `yaml:"dry-run,omitempty"`
@@ -190,8 +282,8 @@ type configFileData struct {
 	Exclude        []string `yaml:"exclude,omitempty"`
 }
 
-// getTokenFromConfig retrieves the token from the config file
-func (s *Storage) getTokenFromConfig() (string, error) {
+// getTokenFromConfig retrieves the token for a hostname from the config file
+func (s *Storage) getTokenFromConfig(hostname string) (string, error) {
 	path, err := s.getConfigPath()
 	if err != nil {
 		return "", err
@@ -207,11 +299,14 @@ func (s *Storage) getTokenFromConfig() (string, error) {
 		return "", err
 	}
 
+	if IsEnterprise(hostname) {
+		return cfg.Hosts[hostname], nil
+	}
 	return cfg.Token, nil
 }
 
-// setTokenInConfig stores the token in the config file
-func (s *Storage) setTokenInConfig(token string) error {
+// setTokenInConfig stores the token for a hostname in the config file
+func (s *Storage) setTokenInConfig(hostname, token string) error {
 	path, err := s.getConfigPath()
 	if err != nil {
 		return err
@@ -223,7 +318,14 @@ func (s *Storage) setTokenInConfig(token string) error {
 		_ = yaml.Unmarshal(data, &cfg)
 	}
 
-	cfg.Token = token
+	if IsEnterprise(hostname) {
+		if cfg.Hosts == nil {
+			cfg.Hosts = make(map[string]string)
+		}
+		cfg.Hosts[hostname] = token
+	} else {
+		cfg.Token = token
+	}
 
 	data, err := yaml.Marshal(&cfg)
 	if err != nil {
@@ -234,8 +336,8 @@ func (s *Storage) setTokenInConfig(token string) error {
 	return os.WriteFile(path, data, 0600)
 }
 
-// clearTokenFromConfig removes the token from the config file
-func (s *Storage) clearTokenFromConfig() error {
+// clearTokenFromConfig removes the token for a hostname from the config file
+func (s *Storage) clearTokenFromConfig(hostname string) error {
 	path, err := s.getConfigPath()
 	if err != nil {
 		return err
@@ -255,7 +357,11 @@ func (s *Storage) clearTokenFromConfig() error {
 		return err
 	}
 
-	cfg.Token = ""
+	if IsEnterprise(hostname) {
+		delete(cfg.Hosts, hostname)
+	} else {
+		cfg.Token = ""
+	}
 
 	newData, err := yaml.Marshal(&cfg)
 	if err != nil {
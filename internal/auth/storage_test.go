@@ -98,6 +98,7 @@ func TestFormatTokenSource(t *testing.T) {
 	}{
 		{TokenSourceFlag, "command line flag"},
 		{TokenSourceEnv, "environment variable (GITHUB_TOKEN)"},
+		{TokenSourceEnvGHES, "environment variable (GITHUB_ENTERPRISE_TOKEN)"},
 		{TokenSourceKeychain, "keychain"},
 		{TokenSourceConfig, "config file (~/.githubby.yaml)"},
 		{TokenSourceNone, "unknown"},
@@ -202,6 +203,9 @@ func TestStorage_PreservesOtherConfigFields(t *testing.T) {
 dry-run: true
 user: testuser
 token: old_token
+network:
+  proxy: http://proxy.example.com:3128
+  no_proxy: [github.example.com]
 `
 	err := os.WriteFile(configPath, []byte(initialConfig), 0600)
 	require.NoError(t, err)
@@ -221,6 +225,8 @@ token: old_token
 	assert.Contains(t, content, "verbose: true")
 	assert.Contains(t, content, "dry-run: true")
 	assert.Contains(t, content, "user: testuser")
+	assert.Contains(t, content, "proxy: http://proxy.example.com:3128")
+	assert.Contains(t, content, "no_proxy: [github.example.com]")
 	assert.Contains(t, content, newToken)
 }
 
@@ -261,6 +267,33 @@ func TestStorage_ClearTokenFromNonExistentConfig(t *testing.T) {
 	storage := NewStorageWithConfig(configPath, false)
 
 	// Clear token from non-existent config should not error
-	err := storage.clearTokenFromConfig()
+	err := storage.clearTokenFromConfig(DefaultHostname)
 	assert.NoError(t, err)
 }
+
+func TestStorage_EnterpriseHostTokens_ConfigFile(t *testing.T) {
+	configPath, cleanup := createTempConfig(t)
+	defer cleanup()
+
+	storage := NewStorageWithConfig(configPath, false)
+	require.NoError(t, storage.SetToken(DefaultHostname, "ghp_dotcom"))
+	require.NoError(t, storage.SetToken("github.mycompany.com", "ghp_enterprise"))
+
+	token, _, err := storage.GetToken(DefaultHostname)
+	require.NoError(t, err)
+	assert.Equal(t, "ghp_dotcom", token)
+	token, _, err = storage.GetToken("github.mycompany.com")
+	require.NoError(t, err)
+	assert.Equal(t, "ghp_enterprise", token)
+
+	// Hosts without a token of their own don't get github.com's
+	_, _, err = storage.GetToken("other.mycompany.com")
+	assert.Error(t, err)
+
+	require.NoError(t, storage.DeleteToken("github.mycompany.com"))
+	_, _, err = storage.GetToken("github.mycompany.com")
+	assert.Error(t, err)
+	token, _, err = storage.GetToken(DefaultHostname)
+	require.NoError(t, err)
+	assert.Equal(t, "ghp_dotcom", token)
+}
@@ -0,0 +1,112 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Didstopia/githubby/internal/state"
+)
+
+var (
+	applyFile  string
+	applyPrune bool
+)
+
+var applyCmd = &cobra.Command{
+	Use:   "apply",
+	Short: "Apply a declarative profiles file",
+	Long: `Reconcile the saved sync profiles with a declarative profiles file.
+
+Profiles are matched by name: new ones are added and existing ones are updated
+in place, keeping their IDs and sync history. Profiles that aren't in the file
+are kept unless --prune is passed. Use --dry-run to only show the changes.
+
+Example profiles file:
+  version: 1
+  profiles:
+    - name: personal
+      type: user
+      source: <username>
+      target_dir: ~/repos
+      include_private: true
+      exclude_filter: ["archive-*"]
+    - name: tools
+      type: org
+      source: <orgname>
+      target_dir: /backup/tools
+      selected_repos: [<orgname>/repo1, <orgname>/repo2]
+
+Examples:
+  # Preview the changes
+  githubby apply -f githubby.profiles.yaml --dry-run
+
+  # Apply the file and remove profiles it doesn't declare
+  githubby apply -f githubby.profiles.yaml --prune`,
+	Args: cobra.NoArgs,
+	RunE: runApply,
+}
+
+func init() {
+	applyCmd.Flags().StringVarP(&applyFile, "file", "f", state.DefaultProfilesFileName, "Profiles file to apply")
+	applyCmd.Flags().BoolVar(&applyPrune, "prune", false, "Remove saved profiles that aren't declared in the file")
+
+	rootCmd.AddCommand(applyCmd)
+}
+
+func runApply(cmd *cobra.Command, args []string) error {
+	storage, err := loadStateStorage()
+	if err != nil {
+		return err
+	}
+
+	_, err = applyProfilesFile(storage, applyFile, applyPrune, dryRun)
+	return err
+}
+
+// applyProfilesFile applies a profiles file to storage and prints the changes.
+// Returns the parsed file.
+func applyProfilesFile(storage *state.Storage, path string, prune, preview bool) (*state.ProfilesFile, error) {
+	file, err := state.LoadProfilesFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	plan, err := storage.ApplyProfiles(file, prune, preview)
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply profiles: %w", err)
+	}
+
+	printProfilesPlan(plan, preview)
+	return file, nil
+}
+
+// printProfilesPlan prints the added, changed and removed profiles of a plan
+func printProfilesPlan(plan *state.ProfilesPlan, preview bool) {
+	for _, p := range plan.Added {
+		fmt.Printf("+ %s (%s: %s -> %s)\n", p.Name, p.Type, p.Source, p.TargetDir)
+	}
+	for _, change := range plan.Changed {
+		fmt.Printf("~ %s\n", change.Name)
+		for _, field := range change.Fields {
+			fmt.Printf("    %s: %s -> %s\n", field.Field, field.Old, field.New)
+		}
+	}
+	for _, p := range plan.Removed {
+		fmt.Printf("- %s\n", p.Name)
+	}
+	for _, name := range plan.Unmanaged {
+		fmt.Printf("  %s (not in file, kept; use --prune to remove)\n", name)
+	}
+
+	verb := "Applied"
+	if preview {
+		verb = "Would apply"
+	}
+	if plan.Empty() {
+		fmt.Printf("✓ Profiles are up to date (%d unchanged)\n", len(plan.Unchanged))
+		return
+	}
+	fmt.Printf("✓ %s: %d added, %d changed, %d removed, %d unchanged\n",
+		verb, len(plan.Added), len(plan.Changed), len(plan.Removed), len(plan.Unchanged))
+}
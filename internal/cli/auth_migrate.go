@@ -0,0 +1,117 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	"github.com/Didstopia/githubby/internal/auth"
+)
+
+var authMigratePassphrase bool
+
+var authMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Move tokens stored in plaintext to secure storage",
+	Long: `Move tokens stored in plaintext in ~/.githubby.yaml and
+~/.githubby/accounts.yaml into the system keychain, and remove them from
+those files.
+
+Where there is no keychain (e.g. headless Linux), tokens are moved to an
+encrypted file, ~/.githubby/tokens.enc, instead. It's unlocked with the
+passphrase in GITHUBBY_TOKEN_PASSPHRASE if set, or with a key file
+(GITHUBBY_TOKEN_KEY_FILE, generated at ~/.githubby/token.key by default).
+
+Examples:
+  # Move plaintext tokens to the keychain or the encrypted file
+  githubby auth migrate
+
+  # Show which tokens would be moved
+  githubby auth migrate --dry-run
+
+  # Lock a new encrypted file with a passphrase instead of a key file
+  githubby auth migrate --passphrase`,
+	RunE: runAuthMigrate,
+}
+
+func init() {
+	authMigrateCmd.Flags().BoolVar(&authMigratePassphrase, "passphrase", false, "Ask for the passphrase of the encrypted token store")
+	authCmd.AddCommand(authMigrateCmd)
+}
+
+func runAuthMigrate(cmd *cobra.Command, args []string) error {
+	storage := auth.NewStorage()
+	accounts, err := storage.PlaintextTokens()
+	if err != nil {
+		return fmt.Errorf("failed to read stored tokens: %w", err)
+	}
+	if len(accounts) == 0 {
+		fmt.Println("No tokens are stored in plaintext.")
+		return nil
+	}
+
+	if dryRun {
+		for _, account := range accounts {
+			fmt.Printf("Would move the token of %s%s\n", account.Hostname, accountSuffix(account.Name))
+		}
+		fmt.Printf("Tokens would be stored in %s\n", storage.GetStorageLocation())
+		return nil
+	}
+
+	if authMigratePassphrase && !storage.IsKeychainAvailable() && os.Getenv(auth.EnvTokenPassphrase) == "" {
+		passphrase, err := promptNewPassphrase()
+		if err != nil {
+			return err
+		}
+		// Locks a new store with the passphrase, or unlocks an existing one
+		_ = os.Setenv(auth.EnvTokenPassphrase, passphrase)
+	}
+
+	migrated, err := storage.MigratePlaintextTokens()
+	for _, account := range migrated {
+		fmt.Printf("✓ Moved the token of %s%s\n", account.Hostname, accountSuffix(account.Name))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to move tokens: %w", err)
+	}
+	fmt.Printf("✓ Tokens stored in %s\n", storage.GetStorageLocation())
+	fmt.Println("✓ Removed plaintext tokens from ~/.githubby.yaml and ~/.githubby/accounts.yaml")
+	return nil
+}
+
+// promptPassphrase reads a passphrase from the terminal without echoing it
+func promptPassphrase(prompt string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", errors.New("can't ask for a passphrase without a terminal")
+	}
+	fmt.Fprint(os.Stderr, prompt)
+	passphrase, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+	return string(passphrase), nil
+}
+
+// promptNewPassphrase asks for a new passphrase twice
+func promptNewPassphrase() (string, error) {
+	passphrase, err := promptPassphrase("Passphrase for the encrypted token store: ")
+	if err != nil {
+		return "", err
+	}
+	if passphrase == "" {
+		return "", errors.New("the passphrase can't be empty")
+	}
+	confirmation, err := promptPassphrase("Repeat the passphrase: ")
+	if err != nil {
+		return "", err
+	}
+	if confirmation != passphrase {
+		return "", errors.New("the passphrases don't match")
+	}
+	return passphrase, nil
+}
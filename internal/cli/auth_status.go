@@ -1,15 +1,16 @@
 package cli
 
 import (
+	"context"
 	"fmt"
+	"time"
 
 	"github.com/spf13/cobra"
 
 	"github.com/Didstopia/githubby/internal/auth"
+	"github.com/Didstopia/githubby/internal/github"
 )
 
-var authStatusHostname string
-
 // authCmd is the parent command for auth subcommands
 var authCmd = &cobra.Command{
 	Use:   "auth",
@@ -22,20 +23,33 @@ var authStatusCmd = &cobra.Command{
 	Short: "View authentication status",
 	Long: `Display the authentication status for GitHub.
 
-Shows the logged-in user and token source (keychain, config file, etc.).
+Shows the logged-in user and token source (keychain, config file, etc.) of
+every account, or only of the account selected with --hostname and --account.
+
+With --check, also shows what each token can access: its kind (classic or
+fine-grained) and OAuth scopes, whether it's authorized for the SAML SSO of
+each organization, and which profiles synced with it and which of their
+repositories will fail to sync, and why.
 
 Examples:
-  # Check authentication status
+  # Check authentication status of all accounts
   githubby auth status
 
   # Check status for GitHub Enterprise Server
-  githubby auth status --hostname github.mycompany.com`,
+  githubby auth status --hostname github.mycompany.com
+
+  # Check status of a named account
+  githubby auth status --account work
+
+  # Check the scopes, SSO authorization and repository access of tokens
+  githubby auth status --check`,
 	RunE: runAuthStatus,
 }
 
-func init() {
-	authStatusCmd.Flags().StringVar(&authStatusHostname, "hostname", auth.DefaultHostname, "GitHub hostname (for GitHub Enterprise)")
+var authStatusCheck bool
 
+func init() {
+	authStatusCmd.Flags().BoolVar(&authStatusCheck, "check", false, "Check the token's scopes, SSO authorization and access to the repositories of profiles")
 	authCmd.AddCommand(authStatusCmd)
 	rootCmd.AddCommand(authCmd)
 }
@@ -43,32 +57,98 @@ func init() {
 func runAuthStatus(cmd *cobra.Command, args []string) error {
 	ctx := cmd.Context()
 
+	// Show only the selected account
+	if cmd.Flags().Changed("hostname") || cmd.Flags().Changed("account") {
+		printAccountStatus(ctx, resolveAccount(nil), resolveHostname(nil))
+		return nil
+	}
+
+	accounts, err := auth.NewStorage().ListAccounts()
+	if err != nil {
+		return fmt.Errorf("failed to read accounts: %w", err)
+	}
+	// The default github.com account may come from GITHUB_TOKEN, or have been
+	// logged in to before accounts were listed
+	if !hasDefaultAccount(accounts) {
+		accounts = append([]auth.Account{{Hostname: auth.DefaultHostname}}, accounts...)
+	}
+
+	for i, account := range accounts {
+		if i > 0 {
+			fmt.Println()
+		}
+		printAccountStatus(ctx, account.Name, account.Hostname)
+	}
+	return nil
+}
+
+// hasDefaultAccount reports whether accounts include the default github.com
+// account
+func hasDefaultAccount(accounts []auth.Account) bool {
+	for _, account := range accounts {
+		if account.Name == "" && account.Hostname == auth.DefaultHostname {
+			return true
+		}
+	}
+	return false
+}
+
+// printAccountStatus prints the status of an account (empty name for the
+// default account) on host
+func printAccountStatus(ctx context.Context, name, host string) {
+	suffix := accountSuffix(name)
+
 	// Try to get token
-	result, err := auth.GetToken(ctx, "", authStatusHostname)
-	if err != nil || result.Token == "" {
-		fmt.Printf("✗ Not logged in to %s\n", authStatusHostname)
+	result, err := auth.GetAccountToken(ctx, "", name, host)
+	if err != nil {
+		fmt.Printf("✗ Failed to authenticate to %s%s\n", host, suffix)
+		fmt.Printf("  %v\n", err)
+		return
+	}
+	if result.Token == "" {
+		fmt.Printf("✗ Not logged in to %s%s\n", host, suffix)
 		fmt.Println()
 		fmt.Println("To log in, run:")
-		fmt.Println("  githubby login")
-		return nil
+		fmt.Println("  " + loginCommand(host, name))
+		return
+	}
+
+	// Installation tokens belong to no user; getting one proved the App's
+	// credentials are valid
+	if result.Source == auth.TokenSourceApp {
+		fmt.Printf("✓ Authenticated to %s as a GitHub App installation\n", host)
+		fmt.Printf("  Token: %s\n", auth.MaskToken(result.Token))
+		fmt.Printf("  Token source: %s\n", formatTokenOrigin(result))
+		if current, err := result.Tokens.Token(); err == nil && !current.Expiry.IsZero() {
+			fmt.Printf("  Token expires: %s (renewed automatically)\n", current.Expiry.Local().Format(time.Kitchen))
+		}
+		if authStatusCheck {
+			printAccessCheck(ctx, result, name, host)
+		}
+		return
 	}
 
 	// Validate token and get user info
 	user, err := auth.ValidateToken(ctx, result.Token, result.Hostname)
 	if err != nil {
-		fmt.Printf("✗ Logged in to %s but token is invalid or expired\n", authStatusHostname)
+		fmt.Printf("✗ Logged in to %s%s but token is invalid or expired\n", host, suffix)
 		fmt.Printf("  Token: %s\n", auth.MaskToken(result.Token))
-		fmt.Printf("  Source: %s\n", auth.FormatTokenSource(result.Source))
+		fmt.Printf("  Source: %s\n", formatTokenOrigin(result))
 		fmt.Println()
 		fmt.Println("To re-authenticate, run:")
-		fmt.Println("  githubby login")
-		return nil
+		fmt.Println("  " + loginCommand(host, name))
+		return
 	}
 
 	// Display status
-	fmt.Printf("✓ Logged in to %s as %s\n", authStatusHostname, user.Login)
+	fmt.Printf("✓ Logged in to %s as %s%s\n", host, user.Login, suffix)
 	fmt.Printf("  Token: %s\n", auth.MaskToken(result.Token))
-	fmt.Printf("  Token source: %s\n", auth.FormatTokenSource(result.Source))
+	fmt.Printf("  Token source: %s\n", formatTokenOrigin(result))
+	if result.Tokens != nil {
+		if current, err := result.Tokens.Token(); err == nil && !current.Expiry.IsZero() {
+			fmt.Printf("  Token expires: %s (renewed automatically)\n", current.Expiry.Local().Format(time.Kitchen))
+		}
+	}
 
 	if user.Name != "" {
 		fmt.Printf("  Name: %s\n", user.Name)
@@ -76,6 +156,108 @@ func runAuthStatus(cmd *cobra.Command, args []string) error {
 	if user.Email != "" {
 		fmt.Printf("  Email: %s\n", user.Email)
 	}
+	if result.Source == auth.TokenSourceConfig {
+		fmt.Println("  The token is stored in plaintext; run 'githubby auth migrate' to move it to secure storage")
+	}
+	if authStatusCheck {
+		printAccessCheck(ctx, result, name, host)
+	}
+}
 
-	return nil
+// recommendedScopes are the OAuth scopes syncs need, and what fails without
+// them
+var recommendedScopes = []struct {
+	scope   string
+	without string
+}{
+	{"repo", "private repositories can't be synced"},
+	{"read:org", "organizations with private membership aren't listed"},
+}
+
+// printAccessCheck prints what the token of an account (empty name for the
+// default account) on host can access: its kind and scopes, its SAML SSO
+// authorization for each organization, and what the profiles synced with it
+// will fail to read
+func printAccessCheck(ctx context.Context, result *auth.TokenResult, name, host string) {
+	client := newGitHubClient(result, host)
+	kind := auth.DetectTokenKind(result.Token)
+	fmt.Printf("  Token kind: %s\n", kind)
+
+	// Installation tokens belong to no user and aren't subject to SAML SSO
+	if kind != auth.TokenKindAppInstallation {
+		info, err := client.GetTokenInfo(ctx)
+		if err != nil {
+			fmt.Printf("  ✗ %s\n", github.DescribeAccessError(err, kind))
+			return
+		}
+		switch {
+		case info.Scopes != nil:
+			fmt.Printf("  Scopes: %s\n", github.FormatScopes(info.Scopes))
+			for _, recommended := range recommendedScopes {
+				if !info.HasScope(recommended.scope) {
+					fmt.Printf("  ✗ Missing scope %s: %s\n", recommended.scope, recommended.without)
+				}
+			}
+		case !kind.HasScopes():
+			fmt.Println("  Scopes: none (the token's permissions are set where it was created)")
+		}
+		if !info.Expires.IsZero() {
+			fmt.Printf("  Token expires: %s\n", info.Expires.Local().Format(time.DateOnly))
+		}
+
+		orgs, err := client.ListUserOrgs(ctx)
+		if err != nil {
+			fmt.Printf("  ✗ Can't list organizations: %s\n", github.DescribeAccessError(err, kind))
+		}
+		for _, org := range orgs {
+			if err := client.CheckOrgAccess(ctx, org.GetLogin()); err != nil {
+				fmt.Printf("  ✗ Organization %s: %s\n", org.GetLogin(), github.DescribeAccessError(err, kind))
+			} else {
+				fmt.Printf("  ✓ Organization %s\n", org.GetLogin())
+			}
+		}
+	}
+
+	storage, err := loadStateStorage()
+	if err != nil {
+		fmt.Printf("  ✗ %v\n", err)
+		return
+	}
+	var targets []github.PreflightTarget
+	for _, profile := range storage.GetProfiles() {
+		if profile.Account == name && profile.Hostname() == host {
+			targets = append(targets, preflightTarget(profile))
+		}
+	}
+	if len(targets) == 0 {
+		return
+	}
+	issues := preflight(ctx, client, result, targets)
+	for _, issue := range issues {
+		fmt.Println("  " + formatPreflightIssue(issue))
+	}
+	if len(issues) == 0 {
+		fmt.Printf("  ✓ The %d profile(s) synced with this account can read their repositories\n", len(targets))
+	}
+}
+
+// formatTokenOrigin describes where a token came from, naming the token file
+// or token command
+func formatTokenOrigin(result *auth.TokenResult) string {
+	if result.Origin == "" {
+		return auth.FormatTokenSource(result.Source)
+	}
+	return fmt.Sprintf("%s: %s", auth.FormatTokenSource(result.Source), result.Origin)
+}
+
+// loginCommand returns the command that logs in to an account on host
+func loginCommand(host, name string) string {
+	command := "githubby login"
+	if auth.IsEnterprise(host) {
+		command += " --hostname " + host
+	}
+	if name != "" {
+		command += " --account " + name
+	}
+	return command
 }
@@ -0,0 +1,58 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Didstopia/githubby/internal/github"
+)
+
+// cacheCmd is the parent command for cache subcommands
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage the GitHub API response cache",
+	Long: `Manage the GitHub API response cache.
+
+Repository listings and lookups are cached in ~/.githubby/cache/http and
+revalidated with conditional requests. GitHub doesn't count requests for
+unchanged responses against the rate limit, so scheduled syncs of large
+organizations cost far fewer requests. The cache is limited to 100 MB; the
+least recently used responses are removed first.
+
+Pass --no-cache to any command to bypass it.`,
+}
+
+var cacheClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Remove all cached API responses",
+	Long: `Remove all cached GitHub API responses.
+
+Examples:
+  githubby cache clear`,
+	Args: cobra.NoArgs,
+	RunE: runCacheClear,
+}
+
+func init() {
+	cacheCmd.AddCommand(cacheClearCmd)
+	rootCmd.AddCommand(cacheCmd)
+}
+
+func runCacheClear(cmd *cobra.Command, args []string) error {
+	dir, err := github.DefaultCacheDir()
+	if err != nil {
+		return err
+	}
+
+	entries, size, err := github.NewHTTPCache(dir, 0).Clear()
+	if err != nil {
+		return fmt.Errorf("failed to clear the cache: %w", err)
+	}
+	if entries == 0 {
+		fmt.Println("The cache is already empty")
+		return nil
+	}
+	fmt.Printf("✓ Removed %d cached responses (%.1f MB)\n", entries, float64(size)/(1<<20))
+	return nil
+}
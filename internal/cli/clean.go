@@ -11,7 +11,6 @@ import (
 
 	"github.com/Didstopia/githubby/internal/auth"
 	gherrors "github.com/Didstopia/githubby/internal/errors"
-	"github.com/Didstopia/githubby/internal/github"
 	"github.com/Didstopia/githubby/pkg/util"
 )
 
@@ -57,25 +56,25 @@ func runClean(cmd *cobra.Command, args []string) error {
 		return gherrors.ErrMissingFilter
 	}
 
-	// Get token using auth resolution (flag > env > stored)
-	resolvedToken, err := auth.GetToken(ctx, token, "")
-	if err != nil || resolvedToken.Token == "" {
-		return gherrors.NewAuthError()
-	}
-	authToken := resolvedToken.Token
-
 	// Validate repository
 	owner, repo, err := util.ValidateGitHubRepository(repository)
 	if err != nil {
 		return err
 	}
 
+	// Get token using auth resolution (flag > env > stored)
+	host := resolveHostname(nil)
+	resolvedToken, err := resolveToken(auth.WithOwner(ctx, owner), nil, host)
+	if err != nil {
+		return err
+	}
+
 	if verbose {
 		fmt.Printf("Validation succeeded for repository %s/%s\n", owner, repo)
 	}
 
 	// Create GitHub client
-	client := github.NewClient(authToken)
+	client := newGitHubClient(resolvedToken, host)
 
 	// Notify user
 	if !verbose {
@@ -85,8 +84,8 @@ func runClean(cmd *cobra.Command, args []string) error {
 	// Fetch all releases
 	releases, err := client.GetReleases(ctx, owner, repo)
 	if err != nil {
-		if gherrors.IsUnauthorized(err) || gherrors.IsForbidden(err) {
-			return gherrors.NewExpiredTokenError(auth.FormatTokenSource(resolvedToken.Source))
+		if tokenErr := tokenError(resolvedToken, err); tokenErr != nil {
+			return tokenErr
 		}
 		return fmt.Errorf("failed to fetch releases: %w", err)
 	}
@@ -145,11 +144,11 @@ func runClean(cmd *cobra.Command, args []string) error {
 
 		if !dryRun {
 			if err := client.RemoveRelease(ctx, owner, repo, release); err != nil {
-				if gherrors.IsUnauthorized(err) || gherrors.IsForbidden(err) {
+				if tokenErr := tokenError(resolvedToken, err); tokenErr != nil {
 					if progressBar != nil {
 						progressBar.Finish()
 					}
-					return gherrors.NewExpiredTokenError(auth.FormatTokenSource(resolvedToken.Source))
+					return tokenErr
 				}
 				fmt.Printf("Error deleting release: %v\n", err)
 			} else {
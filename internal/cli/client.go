@@ -0,0 +1,129 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Didstopia/githubby/internal/auth"
+	gherrors "github.com/Didstopia/githubby/internal/errors"
+	"github.com/Didstopia/githubby/internal/github"
+	"github.com/Didstopia/githubby/internal/state"
+)
+
+// resolveHostname returns the normalized hostname of the GitHub instance to
+// use: --hostname if it's set, otherwise the profile's host (profile may be
+// nil), defaulting to github.com
+func resolveHostname(profile *state.SyncProfile) string {
+	if hostname == "" && profile != nil {
+		return profile.Hostname()
+	}
+	// --hostname was checked before the command ran
+	host, err := auth.NormalizeHostname(hostname)
+	if err != nil {
+		return hostname
+	}
+	return host
+}
+
+// resolveAccount returns the name of the account to use: --account if it's
+// set, otherwise the profile's account (profile may be nil). An empty name
+// is the host's default account.
+func resolveAccount(profile *state.SyncProfile) string {
+	if account == "" && profile != nil {
+		return profile.Account
+	}
+	return account
+}
+
+// accountToken resolves the token of an account on host, preferring the
+// token given with --token-file or --token. The token file is read every time,
+// so rotated secrets are picked up.
+func accountToken(ctx context.Context, name, host string) (*auth.TokenResult, error) {
+	if tokenFile == "" {
+		return auth.GetAccountToken(ctx, token, name, host)
+	}
+	fileToken, err := auth.ReadTokenFile(tokenFile)
+	if err != nil {
+		return nil, err
+	}
+	return &auth.TokenResult{
+		Token:    fileToken,
+		Source:   auth.TokenSourceFile,
+		Hostname: host,
+		Account:  name,
+		Origin:   tokenFile,
+	}, nil
+}
+
+// resolveToken resolves the token of the account to use for profile (may be
+// nil) on host, failing with login guidance if there is none. GitHub App
+// tokens are for the installation on the profile's owner.
+func resolveToken(ctx context.Context, profile *state.SyncProfile, host string) (*auth.TokenResult, error) {
+	if profile != nil {
+		ctx = auth.WithOwner(ctx, profile.Source)
+	}
+	name := resolveAccount(profile)
+	result, err := accountToken(ctx, name, host)
+	if err != nil {
+		return nil, err
+	}
+	if result.Token == "" {
+		if name != "" {
+			return nil, gherrors.NewAccountAuthError(name, host)
+		}
+		return nil, gherrors.NewAuthError()
+	}
+	return result, nil
+}
+
+// tokenError explains a failure caused by the token from resolved and how to
+// fix it, or returns nil if err isn't one: the token couldn't be renewed,
+// or was rejected
+func tokenError(resolved *auth.TokenResult, err error) error {
+	var refreshErr *auth.RefreshError
+	switch {
+	case errors.As(err, &refreshErr):
+		return refreshErr
+	case gherrors.IsUnauthorized(err) || gherrors.IsForbidden(err):
+		return gherrors.NewTokenError(auth.FormatTokenSource(resolved.Source), err)
+	default:
+		return nil
+	}
+}
+
+// newGitHubClient creates a client for the GitHub instance at host that
+// caches responses unless --no-cache is set, uses GraphQL if --graphql is
+// set, and waits out the rate limit instead of failing.
+// Syncs keep --rate-limit-reserve requests for interactive use.
+func newGitHubClient(resolved *auth.TokenResult, host string) github.Client {
+	opts := &github.ClientOptions{RateLimitReserve: rateLimitReserve, GraphQL: useGraphQL, Hostname: host, TokenSource: resolved.Tokens}
+	if !noCache {
+		if dir, err := github.DefaultCacheDir(); err == nil {
+			opts.Cache = github.NewHTTPCache(dir, 0)
+		} else {
+			log.Debugf("Response cache unavailable: %v", err)
+		}
+	}
+
+	client := github.NewRetryableClient(github.NewClientWithOptions(resolved.Token, opts), nil)
+	client.RateLimiter().OnWait(func(wait github.RateLimitWait) {
+		fmt.Println(formatRateLimitWait(wait))
+	})
+	return client
+}
+
+// formatRateLimitWait describes a pause for the rate limit
+func formatRateLimitWait(wait github.RateLimitWait) string {
+	var reason string
+	switch {
+	case wait.Secondary:
+		reason = "GitHub's secondary rate limit was hit"
+	case wait.Reserve:
+		reason = fmt.Sprintf("Only the %d API requests reserved for interactive use are left", rateLimitReserve)
+	default:
+		reason = fmt.Sprintf("The GitHub API rate limit (%s) ran out", wait.Resource)
+	}
+	return fmt.Sprintf("⏸ %s, resuming at %s (in %s)", reason, wait.Until.Format(time.Kitchen), wait.Remaining())
+}
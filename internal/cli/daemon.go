@@ -0,0 +1,200 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Didstopia/githubby/internal/lock"
+	"github.com/Didstopia/githubby/internal/schedule"
+	"github.com/Didstopia/githubby/internal/state"
+)
+
+var (
+	daemonMaxConcurrent   int
+	daemonMaxPerHost      int
+	daemonDefaultSchedule string
+	daemonReloadInterval  time.Duration
+	daemonSyncOnStart     bool
+)
+
+var daemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "Sync every profile on its own schedule",
+	Long: `Run every saved profile on its own schedule in a single long-running process.
+
+Each profile syncs on the cron schedule set with "githubby profile edit
+--schedule". Profiles without one use --default-schedule, or aren't synced if
+it isn't set. Changes to the state file (e.g. from "githubby profile" or the
+interactive TUI) are picked up without a restart.
+
+When a profile's schedule is due while it's still syncing, the run is skipped
+or queued according to the profile's --overlap setting. --max-concurrent and
+--max-per-host limit how many profiles sync at once; profiles waiting for a
+slot start as soon as one frees up.
+
+Examples:
+  # Give profiles their own schedules, then run them all
+  githubby profile edit work --schedule "*/30 * * * *"
+  githubby profile edit personal --schedule "@every 6h"
+  githubby daemon
+
+  # Sync profiles without a schedule of their own nightly
+  githubby daemon --default-schedule "@daily"
+
+  # Sync one profile at a time, starting at the first scheduled run
+  githubby daemon --max-concurrent 1 --sync-on-start=false
+
+  # Serve health checks and Prometheus metrics for monitoring
+  githubby daemon --listen :9090`,
+	Args: cobra.NoArgs,
+	RunE: runDaemon,
+}
+
+func init() {
+	daemonCmd.Flags().IntVar(&daemonMaxConcurrent, "max-concurrent", 4, "Maximum profiles syncing at once (0 = no limit)")
+	daemonCmd.Flags().IntVar(&daemonMaxPerHost, "max-per-host", 2, "Maximum profiles syncing from the same GitHub host at once (0 = no limit)")
+	daemonCmd.Flags().StringVar(&daemonDefaultSchedule, "default-schedule", "", "Cron expression for profiles without a schedule of their own (default: don't sync them)")
+	daemonCmd.Flags().DurationVar(&daemonReloadInterval, "reload-interval", 30*time.Second, "How often to check the state file for profile changes")
+	daemonCmd.Flags().BoolVar(&daemonSyncOnStart, "sync-on-start", true, "Sync profiles when the daemon starts or they're added, before their first scheduled run")
+	daemonCmd.Flags().StringVar(&syncLockPolicy, "lock-policy", string(lock.PolicyWait), "What to do when another githubby process is syncing the same target: wait, skip or fail")
+	daemonCmd.Flags().DurationVar(&syncLockTimeout, "lock-timeout", 0, "Maximum time to wait for a locked target with --lock-policy wait (0 waits indefinitely)")
+	daemonCmd.Flags().BoolVar(&syncNoPreflight, "no-preflight", false, "Don't check that the token can read what's synced before a profile's first sync")
+	daemonCmd.Flags().StringVar(&syncListen, "listen", "", "Serve /healthz, /readyz and Prometheus /metrics on this address (e.g., \":9090\")")
+
+	rootCmd.AddCommand(daemonCmd)
+}
+
+func runDaemon(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+
+	if daemonDefaultSchedule != "" {
+		if err := schedule.ValidateSpec(daemonDefaultSchedule); err != nil {
+			return err
+		}
+	}
+	if _, err := lock.ParsePolicy(syncLockPolicy); err != nil {
+		return err
+	}
+	if daemonReloadInterval <= 0 {
+		return fmt.Errorf("--reload-interval must be positive")
+	}
+
+	storage, err := loadStateStorage()
+	if err != nil {
+		return err
+	}
+
+	daemon := schedule.NewDaemon(schedule.DaemonOptions{
+		MaxConcurrent: daemonMaxConcurrent,
+		MaxPerHost:    daemonMaxPerHost,
+		RunOnStart:    daemonSyncOnStart,
+	})
+	if err := setDaemonJobs(daemon, storage); err != nil {
+		return err
+	}
+
+	if err := startMetrics(ctx); err != nil {
+		return err
+	}
+
+	fmt.Printf("Watching %s for profile changes\n", storage.Path())
+	fmt.Println("Press Ctrl+C to stop")
+
+	go watchDaemonState(ctx, daemon, storage)
+
+	setSchedulerUp(true)
+	defer setSchedulerUp(false)
+	return daemon.Run(ctx)
+}
+
+// watchDaemonState reschedules the daemon's jobs whenever the state file
+// changes, until ctx is cancelled. A state file that can't be read keeps the
+// current jobs running.
+func watchDaemonState(ctx context.Context, daemon *schedule.Daemon, storage *state.Storage) {
+	ticker := time.NewTicker(daemonReloadInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		reloaded, err := storage.Reload()
+		if err != nil {
+			log.Warnf("Failed to reload %s, keeping the current schedules: %v", storage.Path(), err)
+			continue
+		}
+		if !reloaded {
+			continue
+		}
+
+		log.Infof("Profiles changed, updating schedules")
+		if err := setDaemonJobs(daemon, storage); err != nil {
+			log.Warnf("Failed to update schedules, keeping the current ones: %v", err)
+		}
+	}
+}
+
+// setDaemonJobs schedules the saved profiles on the daemon
+func setDaemonJobs(daemon *schedule.Daemon, storage *state.Storage) error {
+	jobs, unscheduled := daemonJobs(storage, daemonDefaultSchedule)
+	if len(unscheduled) > 0 {
+		log.Infof("Not syncing profiles without a schedule: %s (set one with \"githubby profile edit --schedule\" or use --default-schedule)",
+			strings.Join(unscheduled, ", "))
+	}
+	if len(jobs) == 0 {
+		log.Infof("No scheduled profiles yet; waiting for changes to %s", storage.Path())
+	}
+
+	if err := daemon.SetJobs(jobs); err != nil {
+		return err
+	}
+
+	// Deleted and renamed profiles shouldn't keep affecting readiness
+	if syncMetrics != nil {
+		var names []string
+		for _, profile := range storage.GetProfiles() {
+			names = append(names, profile.Name)
+		}
+		syncMetrics.Retain(names)
+	}
+	return nil
+}
+
+// daemonJobs builds a job for each saved profile with a schedule, falling
+// back to defaultSpec. Returns the jobs and the names of unscheduled profiles.
+func daemonJobs(storage *state.Storage, defaultSpec string) ([]schedule.Job, []string) {
+	var jobs []schedule.Job
+	var unscheduled []string
+
+	for _, profile := range storage.GetProfiles() {
+		spec := profile.Schedule
+		if spec == "" {
+			spec = defaultSpec
+		}
+		if spec == "" {
+			unscheduled = append(unscheduled, profile.Name)
+			continue
+		}
+
+		profile := profile.Clone()
+		jobs = append(jobs, schedule.Job{
+			ID:      profile.ID,
+			Name:    fmt.Sprintf("profile %q", profile.Name),
+			Spec:    spec,
+			Host:    resolveHostname(profile),
+			Overlap: profile.OverlapPolicy(),
+			Run: func(ctx context.Context) error {
+				return executeSyncForProfiles(ctx, []*state.SyncProfile{profile}, storage)
+			},
+		})
+	}
+
+	return jobs, unscheduled
+}
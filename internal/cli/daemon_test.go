@@ -0,0 +1,42 @@
+package cli
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Didstopia/githubby/internal/schedule"
+	"github.com/Didstopia/githubby/internal/state"
+)
+
+func TestDaemonJobs(t *testing.T) {
+	storage := state.NewStorageWithPath(filepath.Join(t.TempDir(), "state.yaml"))
+	require.NoError(t, storage.Load())
+
+	work := state.NewProfile("work", "org", "acme", "/tmp/a", false)
+	work.Schedule = "*/30 * * * *"
+	work.Overlap = string(schedule.OverlapQueue)
+	require.NoError(t, storage.AddProfile(work))
+	require.NoError(t, storage.AddProfile(state.NewProfile("home", "user", "alice", "/tmp/b", false)))
+
+	t.Run("profiles without a schedule are left out", func(t *testing.T) {
+		jobs, unscheduled := daemonJobs(storage, "")
+		require.Len(t, jobs, 1)
+		assert.Equal(t, work.ID, jobs[0].ID)
+		assert.Equal(t, "*/30 * * * *", jobs[0].Spec)
+		assert.Equal(t, schedule.OverlapQueue, jobs[0].Overlap)
+		assert.NotEmpty(t, jobs[0].Host)
+		assert.Equal(t, []string{"home"}, unscheduled)
+	})
+
+	t.Run("default schedule", func(t *testing.T) {
+		jobs, unscheduled := daemonJobs(storage, "@daily")
+		require.Len(t, jobs, 2)
+		assert.Equal(t, "*/30 * * * *", jobs[0].Spec)
+		assert.Equal(t, "@daily", jobs[1].Spec)
+		assert.Equal(t, schedule.OverlapSkip, jobs[1].Overlap)
+		assert.Empty(t, unscheduled)
+	})
+}
@@ -2,6 +2,7 @@ package cli
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/spf13/cobra"
@@ -57,6 +58,11 @@ func RunTUI() error {
 
 	// Load existing state
 	if err := storage.Load(); err != nil {
+		// Never start fresh over a state file from a newer githubby, since
+		// saving would discard whatever the newer version stored
+		if errors.Is(err, state.ErrUnsupportedVersion) {
+			return err
+		}
 		log.WithError(err).Debug("Failed to load state, starting fresh")
 		// Continue with fresh state
 	}
@@ -68,21 +74,27 @@ func RunTUI() error {
 		tui.WithVersion(Version, Commit, BuildDate),
 	}
 
-	// Check authentication status
-	result, _ := auth.GetToken(ctx, token, "")
+	// Check authentication status of the account to start with
+	account := auth.Account{Name: resolveAccount(nil), Hostname: resolveHostname(nil)}
+	opts = append(opts, tui.WithAccount(account))
+	result, err := accountToken(ctx, account.Name, account.Hostname)
+	// The encrypted token store is unlocked now if it will be; the TUI owns
+	// the terminal from here on
+	auth.PassphrasePrompt = nil
 	var ghClient github.Client
 	var username string
 	var authToken string
 	isAuthenticated := false
 
-	if result.Source != auth.TokenSourceNone {
+	if err != nil {
+		log.WithError(err).Debug("Failed to get token")
+	} else if result.Source != auth.TokenSourceNone {
 		// Validate token and get username
-		user, err := auth.ValidateToken(ctx, result.Token, "")
-		if err == nil {
+		if user, err := auth.ValidateToken(ctx, result.Token, account.Hostname); err == nil {
 			isAuthenticated = true
 			username = user.Login
 			authToken = result.Token
-			ghClient = github.NewClient(result.Token)
+			ghClient = github.NewDefaultClientWithTokenSource(result.Token, account.Hostname, result.Tokens)
 			log.WithField("user", username).Debug("Token valid")
 		} else {
 			log.WithError(err).Debug("Token validation failed")
@@ -94,6 +106,7 @@ func RunTUI() error {
 	// Add auth state to options
 	if isAuthenticated {
 		opts = append(opts, tui.WithAuth(true, username, authToken))
+		opts = append(opts, tui.WithTokenSource(result.Tokens))
 		opts = append(opts, tui.WithGitHubClient(ghClient))
 	}
 
@@ -154,4 +167,9 @@ func registerScreenFactories(app *tui.App) {
 	app.RegisterScreenFactory(tui.ScreenConfirmDelete, func(ctx context.Context, a *tui.App) tui.ScreenModel {
 		return screens.NewConfirmDeleteScreen(ctx, a)
 	})
+
+	// Profile settings screen factory
+	app.RegisterScreenFactory(tui.ScreenProfileSettings, func(ctx context.Context, a *tui.App) tui.ScreenModel {
+		return screens.NewProfileSettings(ctx, a)
+	})
 }
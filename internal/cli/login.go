@@ -16,7 +16,6 @@ import (
 
 var (
 	loginWithToken bool
-	loginHostname  string
 	loginScopes    []string
 	loginNoPrompt  bool
 )
@@ -39,6 +38,9 @@ Examples:
   # Login to GitHub Enterprise Server
   githubby login --hostname github.mycompany.com
 
+  # Login to a second, named account (use it with --account or a profile's account)
+  githubby login --account work
+
   # Login without browser prompt
   githubby login --no-prompt`,
 	RunE: runLogin,
@@ -46,7 +48,6 @@ Examples:
 
 func init() {
 	loginCmd.Flags().BoolVar(&loginWithToken, "with-token", false, "Read token from stdin")
-	loginCmd.Flags().StringVar(&loginHostname, "hostname", auth.DefaultHostname, "GitHub hostname (for GitHub Enterprise)")
 	loginCmd.Flags().StringSliceVar(&loginScopes, "scopes", []string{"repo", "read:org"}, "OAuth scopes to request")
 	loginCmd.Flags().BoolVar(&loginNoPrompt, "no-prompt", false, "Skip browser open prompt")
 
@@ -55,17 +56,18 @@ func init() {
 
 func runLogin(cmd *cobra.Command, args []string) error {
 	ctx := cmd.Context()
+	host := resolveHostname(nil)
 
 	// Check if user wants to provide token via stdin
 	if loginWithToken {
-		return loginWithTokenFromStdin(ctx)
+		return loginWithTokenFromStdin(ctx, host)
 	}
 
 	// Otherwise, use device flow
-	return loginWithDeviceFlow(ctx)
+	return loginWithDeviceFlow(ctx, host)
 }
 
-func loginWithTokenFromStdin(ctx context.Context) error {
+func loginWithTokenFromStdin(ctx context.Context, host string) error {
 	// Read token from stdin
 	reader := bufio.NewReader(os.Stdin)
 	token, err := reader.ReadString('\n')
@@ -80,32 +82,32 @@ func loginWithTokenFromStdin(ctx context.Context) error {
 
 	// Validate token
 	fmt.Println("Validating token...")
-	user, err := auth.ValidateToken(ctx, token, loginHostname)
+	user, err := auth.ValidateToken(ctx, token, host)
 	if err != nil {
 		return fmt.Errorf("token validation failed: %w", err)
 	}
 
 	// Store token
 	storage := auth.NewStorage()
-	if err := storage.SetToken(loginHostname, token); err != nil {
-		return fmt.Errorf("failed to store token: %w", err)
+	if err := storeAccountToken(storage, host, token, nil, user); err != nil {
+		return err
 	}
 
-	fmt.Printf("✓ Logged in as %s\n", user.Login)
+	fmt.Printf("✓ Logged in as %s%s\n", user.Login, accountSuffix(resolveAccount(nil)))
 	fmt.Printf("✓ Token stored in %s\n", storage.GetStorageLocation())
 
 	return nil
 }
 
-func loginWithDeviceFlow(ctx context.Context) error {
-	fmt.Printf("Logging in to %s...\n\n", loginHostname)
+func loginWithDeviceFlow(ctx context.Context, host string) error {
+	fmt.Printf("Logging in to %s...\n\n", host)
 
 	opts := &auth.DeviceFlowOptions{
 		ClientID: auth.GitHubOAuthClientID,
 		Scopes:   loginScopes,
-		Hostname: loginHostname,
+		Hostname: host,
 		OnCode: func(code *device.CodeResponse) {
-			instructions := auth.GetDeviceFlowInstructions(code, loginHostname)
+			instructions := auth.GetDeviceFlowInstructions(code, host)
 			fmt.Println(instructions)
 
 			// Wait for user to press Enter (unless --no-prompt)
@@ -117,7 +119,7 @@ func loginWithDeviceFlow(ctx context.Context) error {
 			// Open browser
 			verificationURL := code.VerificationURI
 			if verificationURL == "" {
-				verificationURL = "https://github.com/login/device"
+				verificationURL = auth.WebURL(host, "login/device").String()
 			}
 			if err := auth.OpenBrowser(verificationURL); err != nil {
 				fmt.Printf("Failed to open browser: %v\n", err)
@@ -138,21 +140,54 @@ func loginWithDeviceFlow(ctx context.Context) error {
 	fmt.Println() // New line after polling dots
 
 	// Validate the token to get user info
-	user, err := auth.ValidateToken(ctx, result.Token, loginHostname)
+	user, err := auth.ValidateToken(ctx, result.Token, host)
 	if err != nil {
 		return fmt.Errorf("failed to validate token: %w", err)
 	}
 
 	// Store the token
 	storage := auth.NewStorage()
-	if err := storage.SetToken(loginHostname, result.Token); err != nil {
-		return fmt.Errorf("failed to store token: %w", err)
+	refresh := result.RefreshCredentials()
+	if err := storeAccountToken(storage, host, result.Token, refresh, user); err != nil {
+		return err
 	}
 
 	fmt.Println()
 	fmt.Println("✓ Authentication complete.")
-	fmt.Printf("✓ Logged in as %s\n", user.Login)
+	fmt.Printf("✓ Logged in as %s%s\n", user.Login, accountSuffix(resolveAccount(nil)))
 	fmt.Printf("✓ Token stored in %s\n", storage.GetStorageLocation())
+	if refresh != nil {
+		fmt.Printf("✓ Token expires %s and is renewed automatically\n", refresh.Expiry.Local().Format(time.Kitchen))
+	}
+
+	return nil
+}
 
+// storeAccountToken stores the token of the account being logged in to,
+// with its refresh credentials if it expires, and adds the account to the
+// accounts list
+func storeAccountToken(storage *auth.Storage, host, authToken string, refresh *auth.RefreshCredentials, user *auth.AuthenticatedUser) error {
+	name := resolveAccount(nil)
+	if err := storage.SetAccountToken(name, host, authToken); err != nil {
+		return fmt.Errorf("failed to store token: %w", err)
+	}
+	if err := storage.SetAccountRefresh(name, host, refresh); err != nil {
+		if refresh == nil {
+			return fmt.Errorf("failed to remove the previous token's refresh token: %w", err)
+		}
+		// The token works until it expires
+		fmt.Printf("⚠ The token expires %s and can't be renewed: %v\n", refresh.Expiry.Local().Format(time.Kitchen), err)
+	}
+	if err := storage.SaveAccount(auth.Account{Name: name, Hostname: host, Login: user.Login}); err != nil {
+		return fmt.Errorf("failed to save account: %w", err)
+	}
 	return nil
 }
+
+// accountSuffix describes a named account after a username
+func accountSuffix(name string) string {
+	if name == "" {
+		return ""
+	}
+	return fmt.Sprintf(" (account %q)", name)
+}
@@ -1,14 +1,21 @@
 package cli
 
 import (
+	"context"
+	"errors"
 	"fmt"
 
 	"github.com/spf13/cobra"
 
 	"github.com/Didstopia/githubby/internal/auth"
+	"github.com/Didstopia/githubby/internal/config"
 )
 
-var logoutHostname string
+var (
+	logoutRevoke bool
+	logoutAll    bool
+	logoutForce  bool
+)
 
 var logoutCmd = &cobra.Command{
 	Use:   "logout",
@@ -16,37 +23,166 @@ var logoutCmd = &cobra.Command{
 	Long: `Remove authentication credentials for GitHub.
 
 This removes the stored token from your system keychain or config file.
+With --revoke, the token is also revoked on GitHub first, so it stops
+working everywhere. Tokens from the device flow (githubby login) are
+revoked with the OAuth app's token deletion endpoint, which needs the app's
+client secret in ` + auth.EnvOAuthClientSecret + `. Personal access tokens
+can only be deleted on GitHub. A token that couldn't be revoked is kept, so
+it can still be revoked later, unless --force is given.
 
 Examples:
   # Log out of github.com
   githubby logout
 
   # Log out of GitHub Enterprise Server
-  githubby logout --hostname github.mycompany.com`,
+  githubby logout --hostname github.mycompany.com
+
+  # Log out of a named account
+  githubby logout --account work
+
+  # Revoke the token on GitHub and log out
+  githubby logout --revoke
+
+  # Log out of every host and account, revoking their tokens
+  githubby logout --all --revoke
+
+  # Remove the token even if it can't be revoked
+  githubby logout --revoke --force`,
 	RunE: runLogout,
 }
 
 func init() {
-	logoutCmd.Flags().StringVar(&logoutHostname, "hostname", auth.DefaultHostname, "GitHub hostname (for GitHub Enterprise)")
-
 	rootCmd.AddCommand(logoutCmd)
+
+	logoutCmd.Flags().BoolVar(&logoutRevoke, "revoke", false, "Also revoke the token on GitHub")
+	logoutCmd.Flags().BoolVar(&logoutAll, "all", false, "Log out of every host and account")
+	logoutCmd.Flags().BoolVar(&logoutForce, "force", false, "With --revoke, remove tokens that couldn't be revoked too")
+	config.SkipConfig(logoutCmd, "revoke", "all", "force")
 }
 
 func runLogout(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
 	storage := auth.NewStorage()
 
-	// Check if there's a token to remove
-	existingToken, _, err := storage.GetToken(logoutHostname)
+	if !logoutAll {
+		host := resolveHostname(nil)
+		name := resolveAccount(nil)
+		return logout(ctx, storage, name, host)
+	}
+
+	if hostname != "" || account != "" {
+		return fmt.Errorf("--all can't be combined with --hostname or --account")
+	}
+	accounts, err := loggedInAccounts(storage)
+	if err != nil {
+		return err
+	}
+	if len(accounts) == 0 {
+		fmt.Println("Not logged in to any account")
+		return nil
+	}
+	var kept, failed int
+	for _, account := range accounts {
+		err := logout(ctx, storage, account.Name, account.Hostname)
+		switch {
+		case errors.Is(err, errTokenKept):
+			kept++
+		case err != nil:
+			failed++
+		}
+	}
+	switch {
+	case failed > 0:
+		return fmt.Errorf("failed to log out of %d of %d account(s)", failed+kept, len(accounts))
+	case kept > 0:
+		return fmt.Errorf("%d token(s) weren't revoked and were kept; pass --force to remove them anyway", kept)
+	}
+	return nil
+}
+
+// errTokenKept is returned by logout for a token that wasn't revoked and was
+// kept
+var errTokenKept = errors.New("wasn't revoked and was kept; pass --force to remove it anyway")
+
+// logout removes the stored token of an account, revoking it first with
+// --revoke, and reports what was done. It returns errTokenKept if the token
+// should have been revoked but wasn't, in which case it's kept unless --force
+// is set, and an error if it couldn't be removed.
+func logout(ctx context.Context, storage *auth.Storage, name, host string) error {
+	existingToken, _, err := storage.GetAccountToken(name, host)
 	if err != nil || existingToken == "" {
-		fmt.Printf("Not logged in to %s\n", logoutHostname)
+		// Forget accounts whose token is gone already
+		_ = storage.RemoveAccount(name, host)
+		fmt.Printf("Not logged in to %s%s\n", host, accountSuffix(name))
 		return nil
 	}
 
-	// Remove the token
-	if err := storage.DeleteToken(logoutHostname); err != nil {
-		return fmt.Errorf("failed to remove credentials: %w", err)
+	if logoutRevoke && !revoke(ctx, storage, name, host) && !logoutForce {
+		fmt.Printf("✗ Kept the credentials of %s%s, so the token can still be revoked\n", host, accountSuffix(name))
+		return fmt.Errorf("the token of %s%s %w", host, accountSuffix(name), errTokenKept)
 	}
 
-	fmt.Printf("✓ Logged out of %s\n", logoutHostname)
+	if err := storage.DeleteAccountToken(name, host); err != nil {
+		fmt.Printf("✗ Failed to remove the credentials of %s%s: %v\n", host, accountSuffix(name), err)
+		return fmt.Errorf("failed to remove the credentials of %s%s: %w", host, accountSuffix(name), err)
+	}
+	fmt.Printf("✓ Logged out of %s%s\n", host, accountSuffix(name))
 	return nil
 }
+
+// revoke revokes the stored token of an account on GitHub and reports the
+// outcome, returning false if it's still valid
+func revoke(ctx context.Context, storage *auth.Storage, name, host string) bool {
+	err := storage.RevokeAccountToken(ctx, name, host)
+	switch {
+	case err == nil:
+		fmt.Printf("✓ Revoked the token of %s%s on GitHub\n", host, accountSuffix(name))
+		return true
+	case errors.Is(err, auth.ErrTokenNotFound):
+		fmt.Printf("✓ The token of %s%s isn't valid on GitHub: %v\n", host, accountSuffix(name), err)
+		return true
+	case errors.Is(err, auth.ErrNotRevocable):
+		fmt.Printf("⚠ The token of %s%s isn't an OAuth token and wasn't revoked; delete it at %s\n",
+			host, accountSuffix(name), auth.WebURL(host, "settings/tokens"))
+	case errors.Is(err, auth.ErrNoClientSecret):
+		fmt.Printf("⚠ The token of %s%s wasn't revoked: %v; or revoke githubby's access at %s\n",
+			host, accountSuffix(name), err, auth.WebURL(host, "settings/applications"))
+	default:
+		fmt.Printf("⚠ The token of %s%s wasn't revoked: %v\n", host, accountSuffix(name), err)
+	}
+	return false
+}
+
+// loggedInAccounts returns every account with a stored token: the listed
+// accounts, those whose token is in the config file, and github.com's
+// default account. Listed accounts without a token are forgotten.
+func loggedInAccounts(storage *auth.Storage) ([]auth.Account, error) {
+	listed, err := storage.ListAccounts()
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := storage.PlaintextTokens()
+	if err != nil {
+		return nil, err
+	}
+
+	var accounts []auth.Account
+	seen := map[auth.Account]bool{}
+	candidates := append(append([]auth.Account{{Hostname: auth.DefaultHostname}}, listed...), plaintext...)
+	for _, candidate := range candidates {
+		key := auth.Account{Name: candidate.Name, Hostname: candidate.Hostname}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		if token, _, err := storage.GetAccountToken(key.Name, key.Hostname); err == nil && token != "" {
+			accounts = append(accounts, candidate)
+		} else {
+			_ = storage.RemoveAccount(key.Name, key.Hostname)
+		}
+	}
+	return accounts, nil
+}
@@ -0,0 +1,55 @@
+package cli
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Didstopia/githubby/internal/auth"
+)
+
+func TestLogout_RevokeFails(t *testing.T) {
+	t.Setenv(auth.EnvTokenPassphrase, "")
+	defer func(revoke, force bool) { logoutRevoke, logoutForce = revoke, force }(logoutRevoke, logoutForce)
+
+	dir := t.TempDir()
+	storage := auth.NewStorageWithEncryptedStore(filepath.Join(dir, auth.ConfigFileName),
+		filepath.Join(dir, auth.EncryptedStoreFileName), filepath.Join(dir, auth.TokenKeyFileName))
+	// Personal access tokens can't be revoked by githubby
+	require.NoError(t, storage.SetAccountToken("work", auth.DefaultHostname, "ghp_personal"))
+
+	logoutRevoke, logoutForce = true, false
+	err := logout(context.Background(), storage, "work", auth.DefaultHostname)
+	assert.ErrorIs(t, err, errTokenKept)
+	token, _, err := storage.GetAccountToken("work", auth.DefaultHostname)
+	require.NoError(t, err)
+	assert.Equal(t, "ghp_personal", token, "the token is kept so it can still be revoked")
+
+	logoutForce = true
+	assert.NoError(t, logout(context.Background(), storage, "work", auth.DefaultHostname))
+	_, _, err = storage.GetAccountToken("work", auth.DefaultHostname)
+	assert.Error(t, err, "--force removes it anyway")
+}
+
+func TestLogout_RemoveFails(t *testing.T) {
+	t.Setenv(auth.EnvTokenPassphrase, "")
+	defer func(revoke bool) { logoutRevoke = revoke }(logoutRevoke)
+	logoutRevoke = false
+
+	dir := t.TempDir()
+	storePath := filepath.Join(dir, auth.EncryptedStoreFileName)
+	storage := auth.NewStorageWithEncryptedStore(filepath.Join(dir, auth.ConfigFileName),
+		storePath, filepath.Join(dir, auth.TokenKeyFileName))
+	require.NoError(t, storage.SetAccountToken("work", auth.DefaultHostname, "ghp_personal"))
+
+	// The token store can't be locked, so the token can't be removed
+	require.NoError(t, os.Mkdir(storePath+".lock", 0700))
+	err := logout(context.Background(), storage, "work", auth.DefaultHostname)
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, errTokenKept)
+	assert.Contains(t, err.Error(), "failed to remove the credentials")
+}
@@ -0,0 +1,197 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Didstopia/githubby/internal/config"
+	"github.com/Didstopia/githubby/internal/notify"
+	"github.com/Didstopia/githubby/internal/sync"
+)
+
+var notifyTestProfile string
+
+// notifyCmd is the parent command for notification subcommands
+var notifyCmd = &cobra.Command{
+	Use:   "notify",
+	Short: "Manage sync notifications",
+	Long: `Manage the notifications sent after syncs.
+
+Global notifications are set in the config file (~/.githubby.yaml) and are sent
+for every sync:
+
+  notifications:
+    targets:
+      - type: slack            # webhook, slack, discord or email
+        url: https://hooks.slack.com/services/...
+        on: on-failure         # on-failure (default), on-change or always
+      - type: email
+        to: [ops@example.com]
+    smtp:
+      host: smtp.example.com
+      port: 587
+      username: githubby
+      from: githubby@example.com   # password: or $GITHUBBY_SMTP_PASSWORD
+
+Profiles can add their own with "githubby profile edit <name> --notify".`,
+}
+
+var notifyTestCmd = &cobra.Command{
+	Use:   "test",
+	Short: "Send a test notification",
+	Long: `Send a test notification to every global notification target, and to a
+profile's own targets with --profile, regardless of their triggers.
+
+Examples:
+  # Test the global notifications
+  githubby notify test
+
+  # Test a profile's notifications too
+  githubby notify test --profile work`,
+	Args: cobra.NoArgs,
+	RunE: runNotifyTest,
+}
+
+func init() {
+	notifyTestCmd.Flags().StringVar(&notifyTestProfile, "profile", "", "Also test this profile's notifications")
+
+	notifyCmd.AddCommand(notifyTestCmd)
+	rootCmd.AddCommand(notifyCmd)
+}
+
+func runNotifyTest(cmd *cobra.Command, args []string) error {
+	cfg, err := loadNotifyConfig()
+	if err != nil {
+		return err
+	}
+
+	targets := cfg.Targets
+	name := "test"
+	if notifyTestProfile != "" {
+		storage, err := loadStateStorage()
+		if err != nil {
+			return err
+		}
+		profile, err := findProfile(storage, notifyTestProfile)
+		if err != nil {
+			return err
+		}
+		targets = append(append([]notify.Target(nil), targets...), profile.Notifications...)
+		name = profile.Name
+	}
+
+	if len(targets) == 0 {
+		return fmt.Errorf("no notifications configured; add them under \"notifications\" in the config file or with \"githubby profile edit <name> --notify\"")
+	}
+
+	sender := notify.NewSender(cfg.SMTP)
+	event := notify.TestEvent(name)
+	failed := 0
+	for _, target := range targets {
+		if err := sender.Send(cmd.Context(), target, event); err != nil {
+			fmt.Printf("✗ %s: %v\n", target, err)
+			failed++
+			continue
+		}
+		fmt.Printf("✓ %s\n", target)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d notifications failed", failed, len(targets))
+	}
+	return nil
+}
+
+// loadNotifyConfig reads the global notification settings from the config
+// file in use, if any
+func loadNotifyConfig() (notify.Config, error) {
+	path := configLoader.Viper().ConfigFileUsed()
+	if path == "" {
+		return notify.Config{}, nil
+	}
+
+	cfg, err := config.LoadFrom(path)
+	if err != nil {
+		return notify.Config{}, fmt.Errorf("failed to read notifications from %s: %w", path, err)
+	}
+	if err := cfg.Notifications.Validate(); err != nil {
+		return notify.Config{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return cfg.Notifications, nil
+}
+
+// notifySyncRun sends the notifications for a finished sync: the global ones
+// plus the profile's own. Failing notifications are logged rather than
+// failing the sync. Dry runs and skipped syncs aren't notified.
+func notifySyncRun(ctx context.Context, name string, started time.Time, result *sync.Result, err error, targets []notify.Target) {
+	if dryRun || errors.Is(err, errSyncSkipped) {
+		return
+	}
+
+	cfg, cfgErr := loadNotifyConfig()
+	if cfgErr != nil {
+		log.Warnf("Skipping global notifications: %v", cfgErr)
+	}
+	targets = append(append([]notify.Target(nil), cfg.Targets...), targets...)
+	if len(targets) == 0 {
+		return
+	}
+
+	// Still notify about runs that were cancelled by a shutdown
+	ctx = context.WithoutCancel(ctx)
+
+	event := syncEvent(name, started, time.Now(), result, err)
+	if err := notify.NewSender(cfg.SMTP).Notify(ctx, targets, event); err != nil {
+		log.Warnf("Failed to send notifications for %s: %v", name, err)
+	}
+}
+
+// syncEvent describes a finished sync for notifications
+func syncEvent(name string, started, finished time.Time, result *sync.Result, err error) *notify.Event {
+	event := &notify.Event{
+		Profile:     name,
+		Status:      notify.StatusSuccess,
+		StartedAt:   started,
+		CompletedAt: finished,
+	}
+
+	if result != nil {
+		event.Cloned = len(result.Cloned)
+		event.Updated = len(result.Updated)
+		event.UpToDate = len(result.UpToDate)
+		event.Skipped = len(result.Skipped)
+		event.Failed = len(result.Failed)
+		event.Archived = len(result.Archived)
+		event.TotalRepos = event.Cloned + event.Updated + event.UpToDate + event.Skipped + event.Failed
+
+		for _, repo := range result.Cloned {
+			event.Results = append(event.Results, notify.RepoResult{FullName: repo, Status: "cloned"})
+		}
+		for _, repo := range result.Updated {
+			event.Results = append(event.Results, notify.RepoResult{FullName: repo, Status: "updated"})
+		}
+		failed := make([]string, 0, len(result.Failed))
+		for repo := range result.Failed {
+			failed = append(failed, repo)
+		}
+		sort.Strings(failed)
+		for _, repo := range failed {
+			event.Results = append(event.Results, notify.RepoResult{FullName: repo, Status: "failed", Error: result.Failed[repo].Error()})
+		}
+
+		if event.Failed > 0 {
+			event.Status = notify.StatusPartial
+		}
+	}
+
+	if err != nil {
+		event.Status = notify.StatusFailure
+		event.Error = err.Error()
+	}
+	return event
+}
@@ -0,0 +1,78 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Didstopia/githubby/internal/notify"
+	"github.com/Didstopia/githubby/internal/sync"
+)
+
+func TestSyncEvent(t *testing.T) {
+	started := time.Now()
+	finished := started.Add(time.Minute)
+
+	result := sync.NewResult()
+	result.Cloned = []string{"acme/new"}
+	result.UpToDate = []string{"acme/old"}
+	result.Archived = []string{"acme/gone"}
+	result.Failed["acme/b"] = errors.New("timeout")
+	result.Failed["acme/a"] = errors.New("exit status 128")
+
+	event := syncEvent("work", started, finished, result, nil)
+	assert.Equal(t, notify.StatusPartial, event.Status)
+	assert.Equal(t, 4, event.TotalRepos)
+	assert.Equal(t, 1, event.Cloned)
+	assert.Equal(t, 2, event.Failed)
+	assert.Equal(t, 1, event.Archived)
+	assert.Equal(t, []notify.RepoResult{
+		{FullName: "acme/new", Status: "cloned"},
+		{FullName: "acme/a", Status: "failed", Error: "exit status 128"},
+		{FullName: "acme/b", Status: "failed", Error: "timeout"},
+	}, event.Results)
+
+	event = syncEvent("work", started, finished, sync.NewResult(), nil)
+	assert.Equal(t, notify.StatusSuccess, event.Status)
+
+	event = syncEvent("work", started, finished, nil, errors.New("not authenticated"))
+	assert.Equal(t, notify.StatusFailure, event.Status)
+	assert.Equal(t, "not authenticated", event.Error)
+}
+
+func TestNotifySyncRun(t *testing.T) {
+	var events []notify.Event
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event notify.Event
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&event))
+		events = append(events, event)
+	}))
+	defer server.Close()
+
+	targets := []notify.Target{{Type: notify.TypeWebhook, URL: server.URL}}
+	started := time.Now()
+
+	// Successful runs don't match the default on-failure trigger
+	notifySyncRun(context.Background(), "work", started, sync.NewResult(), nil, targets)
+	assert.Empty(t, events)
+
+	// Skipped syncs aren't notified
+	notifySyncRun(context.Background(), "work", started, nil, errSyncSkipped, targets)
+	assert.Empty(t, events)
+
+	// Notifications are still sent after the sync was cancelled
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	notifySyncRun(ctx, "work", started, nil, context.Canceled, targets)
+	require.Len(t, events, 1)
+	assert.Equal(t, "work", events[0].Profile)
+	assert.Equal(t, notify.StatusFailure, events[0].Status)
+	assert.Equal(t, "context canceled", events[0].Error)
+}
@@ -0,0 +1,186 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/Didstopia/githubby/internal/auth"
+	"github.com/Didstopia/githubby/internal/github"
+	"github.com/Didstopia/githubby/internal/state"
+)
+
+// preflightTarget returns what a profile's sync reads
+func preflightTarget(profile *state.SyncProfile) github.PreflightTarget {
+	target := github.PreflightTarget{
+		Name:           fmt.Sprintf("profile %q", profile.Name),
+		Owner:          profile.Source,
+		Org:            profile.Type == "org",
+		IncludePrivate: profile.IncludePrivate,
+	}
+	if !profile.SyncAllRepos {
+		target.Repos = profile.SelectedRepos
+	}
+	return target
+}
+
+// preflighted records the syncs whose access was checked, so scheduled
+// syncs and the daemon only check each one on its first run
+var (
+	preflightedMu sync.Mutex
+	preflighted   = map[string]bool{}
+)
+
+// firstPreflight reports whether the sync identified by key hasn't had its
+// access checked by this process yet, and records that it has
+func firstPreflight(key string) bool {
+	preflightedMu.Lock()
+	defer preflightedMu.Unlock()
+	if preflighted[key] {
+		return false
+	}
+	preflighted[key] = true
+	return true
+}
+
+// preflightDue returns the profiles whose access is checked before they
+// sync: those not checked yet by this process, except incremental profiles,
+// whose runs are meant to make as few requests as possible
+func preflightDue(profiles []*state.SyncProfile) []*state.SyncProfile {
+	var due []*state.SyncProfile
+	for _, profile := range profiles {
+		key := "profile " + profile.ID
+		if profile.ID == "" {
+			key = "profile " + profile.Name
+		}
+		if !profile.Incremental && firstPreflight(key) {
+			due = append(due, profile)
+		}
+	}
+	return due
+}
+
+// preflightProfiles checks that the tokens of profiles can read what they
+// sync, and prints which profiles and repositories will fail and why.
+// Profiles synced with the same token share its checks.
+func preflightProfiles(ctx context.Context, profiles []*state.SyncProfile) {
+	groups, issues := groupPreflightTargets(profiles, func(profile *state.SyncProfile, host string) (*auth.TokenResult, error) {
+		return resolveToken(ctx, profile, host)
+	})
+	for _, g := range groups {
+		issues += printPreflightIssues(preflight(ctx, newGitHubClient(g.token, g.host), g.token, g.targets))
+	}
+	printPreflightResult(len(profiles), issues)
+}
+
+// preflightGroup is the targets checked with one token
+type preflightGroup struct {
+	host    string
+	token   *auth.TokenResult
+	targets []github.PreflightTarget
+}
+
+// groupPreflightTargets groups the targets of profiles by the token they sync
+// with, resolving each account's token once. GitHub App tokens belong to one
+// installation, so an App account has a group per owner. Returns the groups
+// and how many profiles have no token, which are printed.
+func groupPreflightTargets(profiles []*state.SyncProfile, resolve func(profile *state.SyncProfile, host string) (*auth.TokenResult, error)) ([]*preflightGroup, int) {
+	var groups []*preflightGroup
+	byToken := map[string]*preflightGroup{}
+	appAccounts := map[string]bool{}
+	issues := 0
+
+	for _, profile := range profiles {
+		host := resolveHostname(profile)
+		account := resolveAccount(profile) + "@" + host
+		key := account
+		if appAccounts[account] {
+			key += "/" + strings.ToLower(profile.Source)
+		}
+		g, ok := byToken[key]
+		if !ok {
+			resolved, err := resolve(profile, host)
+			if err != nil {
+				fmt.Printf("✗ Can't sync profile %q: %v\n", profile.Name, err)
+				issues++
+				continue
+			}
+			if resolved.Source == auth.TokenSourceApp && !appAccounts[account] {
+				appAccounts[account] = true
+				key += "/" + strings.ToLower(profile.Source)
+			}
+			g = &preflightGroup{host: host, token: resolved}
+			byToken[key] = g
+			groups = append(groups, g)
+		}
+		g.targets = append(g.targets, preflightTarget(profile))
+	}
+	return groups, issues
+}
+
+// preflightFlagSync checks that the token of a sync configured with flags
+// can read what it syncs, and prints what will fail and why
+func preflightFlagSync(ctx context.Context, client github.Client, resolved *auth.TokenResult) {
+	target := github.PreflightTarget{
+		Name:           "user " + syncUser,
+		Owner:          syncUser,
+		IncludePrivate: syncIncludePrivate,
+	}
+	if syncOrg != "" {
+		target.Name, target.Owner, target.Org = "organization "+syncOrg, syncOrg, true
+	}
+	printPreflightResult(1, printPreflightIssues(preflight(ctx, client, resolved, []github.PreflightTarget{target})))
+}
+
+// preflight checks that a token can read targets, and returns what will
+// fail and why
+func preflight(ctx context.Context, client github.Client, resolved *auth.TokenResult, targets []github.PreflightTarget) []github.PreflightIssue {
+	kind := auth.DetectTokenKind(resolved.Token)
+
+	// Installation tokens belong to no user, so they have no user to look up
+	var info *github.TokenInfo
+	if kind != auth.TokenKindAppInstallation {
+		var err error
+		info, err = client.GetTokenInfo(ctx)
+		if err != nil {
+			issues := make([]github.PreflightIssue, 0, len(targets))
+			for _, target := range targets {
+				issues = append(issues, github.PreflightIssue{Target: target.Name, Reason: github.DescribeAccessError(err, kind)})
+			}
+			return issues
+		}
+	}
+
+	issues, err := github.Preflight(ctx, client, kind, info, targets)
+	if err != nil {
+		log.Debugf("Access check canceled: %v", err)
+	}
+	return issues
+}
+
+// printPreflightIssues prints what will fail and why, and returns how many
+// issues there were
+func printPreflightIssues(issues []github.PreflightIssue) int {
+	for _, issue := range issues {
+		fmt.Println(formatPreflightIssue(issue))
+	}
+	return len(issues)
+}
+
+// formatPreflightIssue describes a profile or repository that will fail
+func formatPreflightIssue(issue github.PreflightIssue) string {
+	if issue.Repo == "" {
+		return fmt.Sprintf("✗ Can't sync %s: %s", issue.Target, issue.Reason)
+	}
+	return fmt.Sprintf("✗ Can't sync %s of %s: %s", issue.Repo, issue.Target, issue.Reason)
+}
+
+// printPreflightResult sums up the checks of a number of syncs
+func printPreflightResult(syncs, issues int) {
+	if issues == 0 {
+		fmt.Printf("✓ Token access checked for %d sync(s)\n", syncs)
+		return
+	}
+	fmt.Printf("⚠ Found %d access issue(s); syncing what the tokens can read\n", issues)
+}
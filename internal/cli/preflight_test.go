@@ -0,0 +1,111 @@
+package cli
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Didstopia/githubby/internal/auth"
+	gherrors "github.com/Didstopia/githubby/internal/errors"
+	"github.com/Didstopia/githubby/internal/github"
+	"github.com/Didstopia/githubby/internal/state"
+)
+
+func TestPreflightTarget(t *testing.T) {
+	profile := &state.SyncProfile{
+		Name:          "work",
+		Type:          "org",
+		Source:        "acme",
+		SyncAllRepos:  false,
+		SelectedRepos: []string{"acme/api"},
+	}
+	assert.Equal(t, github.PreflightTarget{
+		Name:  `profile "work"`,
+		Owner: "acme",
+		Org:   true,
+		Repos: []string{"acme/api"},
+	}, preflightTarget(profile))
+
+	profile.SyncAllRepos = true
+	assert.Empty(t, preflightTarget(profile).Repos)
+}
+
+func TestPreflightDue(t *testing.T) {
+	t.Cleanup(func() { preflighted = map[string]bool{} })
+
+	full := &state.SyncProfile{ID: "p1", Name: "full"}
+	incremental := &state.SyncProfile{ID: "p2", Name: "incremental", Incremental: true}
+	unsaved := &state.SyncProfile{Name: "unsaved"}
+
+	assert.Equal(t, []*state.SyncProfile{full, unsaved}, preflightDue([]*state.SyncProfile{full, incremental, unsaved}),
+		"incremental profiles aren't checked")
+	assert.Empty(t, preflightDue([]*state.SyncProfile{full, incremental, unsaved}), "profiles are only checked on their first run")
+
+	assert.True(t, firstPreflight("flags"))
+	assert.False(t, firstPreflight("flags"))
+}
+
+func TestGroupPreflightTargets(t *testing.T) {
+	resolveWith := func(source auth.TokenSource) (func(*state.SyncProfile, string) (*auth.TokenResult, error), *[]string) {
+		var resolved []string
+		return func(profile *state.SyncProfile, host string) (*auth.TokenResult, error) {
+			resolved = append(resolved, profile.Source)
+			return &auth.TokenResult{Token: "token-" + profile.Source, Source: source}, nil
+		}, &resolved
+	}
+	profiles := []*state.SyncProfile{
+		state.NewProfile("acme", "org", "acme", "/tmp/a", false),
+		state.NewProfile("globex", "org", "globex", "/tmp/b", false),
+		state.NewProfile("acme-tools", "org", "Acme", "/tmp/c", false),
+	}
+
+	// A personal token is checked once for all owners
+	resolve, resolved := resolveWith(auth.TokenSourceKeychain)
+	groups, issues := groupPreflightTargets(profiles, resolve)
+	assert.Zero(t, issues)
+	require.Len(t, groups, 1)
+	assert.Len(t, groups[0].targets, 3)
+	assert.Equal(t, []string{"acme"}, *resolved)
+
+	// App tokens belong to one installation each
+	resolve, resolved = resolveWith(auth.TokenSourceApp)
+	groups, issues = groupPreflightTargets(profiles, resolve)
+	assert.Zero(t, issues)
+	require.Len(t, groups, 2)
+	assert.Equal(t, "token-acme", groups[0].token.Token)
+	assert.Len(t, groups[0].targets, 2)
+	assert.Equal(t, "token-globex", groups[1].token.Token)
+	assert.Len(t, groups[1].targets, 1)
+	assert.Equal(t, []string{"acme", "globex"}, *resolved)
+}
+
+func TestPreflight_RejectedToken(t *testing.T) {
+	client := github.NewMockClient()
+	client.GetTokenInfoFunc = func(ctx context.Context) (*github.TokenInfo, error) {
+		return nil, gherrors.ErrUnauthorized
+	}
+	targets := []github.PreflightTarget{{Name: `profile "a"`, Owner: "alice"}, {Name: `profile "b"`, Owner: "bob"}}
+
+	issues := preflight(context.Background(), client, &auth.TokenResult{Token: "ghp_expired"}, targets)
+	assert.Equal(t, []github.PreflightIssue{
+		{Target: `profile "a"`, Reason: "the token is invalid or expired"},
+		{Target: `profile "b"`, Reason: "the token is invalid or expired"},
+	}, issues)
+
+	// Installation tokens have no user to look up
+	client.Reset()
+	issues = preflight(context.Background(), client, &auth.TokenResult{Token: "ghs_installation"}, targets[:1])
+	assert.Empty(t, issues)
+	for _, call := range client.Calls {
+		assert.NotEqual(t, "GetTokenInfo", call.Method)
+	}
+}
+
+func TestFormatPreflightIssue(t *testing.T) {
+	assert.Equal(t, `✗ Can't sync profile "work": organization acme: requires SAML SSO`,
+		formatPreflightIssue(github.PreflightIssue{Target: `profile "work"`, Reason: "organization acme: requires SAML SSO"}))
+	assert.Equal(t, `✗ Can't sync alice/secret of profile "personal": the token has no read permission`,
+		formatPreflightIssue(github.PreflightIssue{Target: `profile "personal"`, Repo: "alice/secret", Reason: "the token has no read permission"}))
+}
@@ -0,0 +1,827 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/Didstopia/githubby/internal/auth"
+	"github.com/Didstopia/githubby/internal/config"
+	"github.com/Didstopia/githubby/internal/notify"
+	"github.com/Didstopia/githubby/internal/state"
+	tuiutil "github.com/Didstopia/githubby/internal/tui/util"
+)
+
+var (
+	profileUser           string
+	profileOrg            string
+	profileTarget         string
+	profileIncludePrivate bool
+	profileRepos          []string
+	profileAllRepos       bool
+	profileInclude        []string
+	profileExclude        []string
+	profileConcurrency    int
+	profileRepoTimeout    time.Duration
+	profileLFS            bool
+	profileCloneMode      string
+	profileTransport      string
+	profileSSHKey         string
+	profileSSHKnownHosts  string
+	profileSchedule       string
+	profileOverlap        string
+	profileIncremental    bool
+	profileFullSync       time.Duration
+	profileNotify         []string
+	profileNotifyOn       string
+	profileDeleteYes      bool
+	profileExportOutput   string
+	profileExportHistory  int
+	profileExportSecrets  bool
+	profileImportConflict string
+	profileImportMapPaths []string
+)
+
+// profileCmd is the parent command for profile subcommands
+var profileCmd = &cobra.Command{
+	Use:   "profile",
+	Short: "Manage sync profiles",
+	Long: `Manage the saved sync profiles used by "githubby sync --profile" and "--all-profiles".
+
+Profiles are stored in ~/.githubby/state.yaml, the same place the interactive TUI saves them.`,
+}
+
+var profileListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List sync profiles",
+	Args:    cobra.NoArgs,
+	RunE:    runProfileList,
+}
+
+var profileShowCmd = &cobra.Command{
+	Use:   "show <name>",
+	Short: "Show a sync profile",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runProfileShow,
+}
+
+var profileCreateCmd = &cobra.Command{
+	Use:   "create <name>",
+	Short: "Create a sync profile",
+	Long: `Create a sync profile.
+
+Without --repos the profile syncs every repository of the user or organization
+(narrowed down by --include/--exclude); with --repos it syncs only those.
+
+Examples:
+  # Sync all repositories of a user
+  githubby profile create personal --user <username> --target ~/repos --include-private
+
+  # Sync all repositories of an organization, skipping archives
+  githubby profile create work --org <orgname> --target ~/work --exclude "archive-*"
+
+  # Sync specific repositories
+  githubby profile create tools --user <username> --target ~/tools --repos owner/repo1,owner/repo2
+
+  # Mirror a large organization with more parallelism, every night
+  githubby profile create org-mirror --org <orgname> --target /backup/org \
+    --clone-mode mirror --concurrency 8 --repo-timeout 30m --schedule "@daily"
+
+  # Sync an organization over SSH with a deploy key
+  githubby profile create deploy --org <orgname> --target ~/deploy \
+    --transport ssh --ssh-key ~/.ssh/deploy_key --ssh-known-hosts ~/.ssh/known_hosts
+
+  # Sync an organization on GitHub Enterprise Server
+  githubby profile create ghes --org <orgname> --target ~/ghes --hostname github.mycompany.com
+
+  # Sync an organization with the account logged in with "githubby login --account work"
+  githubby profile create work --org <orgname> --target ~/work --account work`,
+	Args: cobra.ExactArgs(1),
+	RunE: runProfileCreate,
+}
+
+var profileEditCmd = &cobra.Command{
+	Use:   "edit <name>",
+	Short: "Edit a sync profile",
+	Long: `Edit a sync profile. Only the settings passed as flags are changed.
+
+Examples:
+  # Move a profile to a different target directory
+  githubby profile edit personal --target /mnt/backup/repos
+
+  # Stop syncing private repositories
+  githubby profile edit personal --include-private=false
+
+  # Switch from specific repositories to all repositories
+  githubby profile edit tools --all-repos
+
+  # Skip Git LFS downloads and sync every 6 hours
+  githubby profile edit personal --lfs=false --schedule "0 */6 * * *"
+
+  # Poll a large organization every 5 minutes, syncing only what changed
+  githubby profile edit work --incremental --schedule "@every 5m"
+
+  # Sync with a named account, or with the default account again
+  githubby profile edit work --account work
+  githubby profile edit work --account ""
+
+  # Go back to the default concurrency and no schedule
+  githubby profile edit personal --concurrency 0 --schedule ""`,
+	Args: cobra.ExactArgs(1),
+	RunE: runProfileEdit,
+}
+
+var profileRenameCmd = &cobra.Command{
+	Use:   "rename <name> <new-name>",
+	Short: "Rename a sync profile",
+	Args:  cobra.ExactArgs(2),
+	RunE:  runProfileRename,
+}
+
+var profileDeleteCmd = &cobra.Command{
+	Use:     "delete <name>",
+	Aliases: []string{"rm"},
+	Short:   "Delete a sync profile",
+	Long: `Delete a sync profile and its sync history.
+
+Repositories already synced to the profile's target directory are not removed.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runProfileDelete,
+}
+
+var profileDuplicateCmd = &cobra.Command{
+	Use:   "duplicate <name> <new-name>",
+	Short: "Copy a sync profile under a new name",
+	Long: `Copy a sync profile under a new name. The copy starts without sync history.
+
+Examples:
+  # Copy a profile to sync the same repositories to a second location
+  githubby profile duplicate personal personal-backup --target /mnt/backup/repos`,
+	Args: cobra.ExactArgs(2),
+	RunE: runProfileDuplicate,
+}
+
+var profileExportCmd = &cobra.Command{
+	Use:   "export [name...]",
+	Short: "Export sync profiles to a portable bundle",
+	Long: `Export sync profiles, and optionally their recent sync history, to a bundle
+file that can be imported on another machine with "githubby profile import".
+
+Exports all profiles unless names are given. Writes to stdout unless --output is set.
+
+Notification secrets are left out unless --include-secrets is set: webhook,
+Slack and Discord notifications are dropped, since their URLs and headers hold
+credentials, and SMTP passwords are cleared.
+
+Examples:
+  # Export all profiles
+  githubby profile export -o profiles.bundle.yaml
+
+  # Export one profile with its last 10 syncs
+  githubby profile export personal --history 10 -o personal.bundle.yaml
+
+  # Export all profiles with their notification webhooks and passwords
+  githubby profile export --include-secrets -o profiles.bundle.yaml`,
+	RunE: runProfileExport,
+}
+
+var profileImportCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Import sync profiles from a bundle",
+	Long: `Import sync profiles from a bundle written by "githubby profile export".
+
+Profiles are matched by name. By default the import fails if any profile
+already exists; use --on-conflict to skip, overwrite or rename them instead.
+Use --map-path to rewrite target directories for this machine, and --dry-run
+to only show what would be imported.
+
+Examples:
+  # Import a laptop's profiles into a Docker container's state volume
+  githubby profile import profiles.bundle.yaml --map-path /Users/me/repos=/repos
+
+  # Update profiles that already exist
+  githubby profile import profiles.bundle.yaml --on-conflict overwrite`,
+	Args: cobra.ExactArgs(1),
+	RunE: runProfileImport,
+}
+
+func init() {
+	for _, cmd := range []*cobra.Command{profileCreateCmd, profileEditCmd} {
+		cmd.Flags().StringVarP(&profileUser, "user", "u", "", "GitHub username to sync repositories from")
+		cmd.Flags().StringVarP(&profileOrg, "org", "o", "", "GitHub organization to sync repositories from")
+		cmd.Flags().StringVarP(&profileTarget, "target", "T", "", "Target directory for synced repositories")
+		cmd.Flags().BoolVarP(&profileIncludePrivate, "include-private", "p", false, "Include private repositories")
+		cmd.Flags().StringSliceVar(&profileRepos, "repos", nil, "Sync only these repositories (owner/repo)")
+		cmd.Flags().StringSliceVarP(&profileInclude, "include", "i", nil, "Include repositories matching pattern (glob-style)")
+		cmd.Flags().StringSliceVarP(&profileExclude, "exclude", "e", nil, "Exclude repositories matching pattern (glob-style)")
+		cmd.Flags().IntVar(&profileConcurrency, "concurrency", 0, fmt.Sprintf("Repositories to sync in parallel, up to %d (0 = default of %d)", state.MaxConcurrency, state.DefaultConcurrency))
+		cmd.Flags().DurationVar(&profileRepoTimeout, "repo-timeout", 0, "Maximum time to clone or update a single repository (0 = no limit)")
+		cmd.Flags().BoolVar(&profileLFS, "lfs", true, "Download Git LFS objects")
+		cmd.Flags().StringVar(&profileCloneMode, "clone-mode", "", "How to clone new repositories: full (default), shallow or mirror")
+		cmd.Flags().StringVar(&profileTransport, "transport", "", "How to clone and fetch repositories: https (default) or ssh")
+		cmd.Flags().StringVar(&profileSSHKey, "ssh-key", "", "Private key to authenticate with over SSH, such as a deploy key (\"\" = ssh's default keys)")
+		cmd.Flags().StringVar(&profileSSHKnownHosts, "ssh-known-hosts", "", "known_hosts file to check host keys against over SSH (\"\" = ssh's default)")
+		cmd.Flags().StringVar(&profileSchedule, "schedule", "", "Cron expression for recurring syncs of this profile (e.g., \"@every 6h\")")
+		cmd.Flags().StringVar(&profileOverlap, "overlap", "", "What the daemon does when the schedule is due while the profile is still syncing: skip (default) or queue")
+		cmd.Flags().BoolVar(&profileIncremental, "incremental", false, "Sync only repositories with new events since the last sync, listing all of them periodically")
+		cmd.Flags().DurationVar(&profileFullSync, "full-sync-interval", 0, fmt.Sprintf("How often incremental syncs list every repository (0 = default of %s)", state.DefaultFullSyncInterval))
+		cmd.Flags().StringArrayVar(&profileNotify, "notify", nil, "Send notifications after syncs to TYPE=URL (webhook, slack, discord) or email=ADDRESS (repeatable; replaces the existing ones, \"\" removes them)")
+		cmd.Flags().StringVar(&profileNotifyOn, "notify-on", "", "When to send the profile's notifications: on-failure (default), on-change or always")
+		cmd.MarkFlagsMutuallyExclusive("user", "org")
+		config.SkipConfig(cmd, "user", "org", "target", "include-private", "include", "exclude",
+			"concurrency", "repo-timeout", "lfs", "clone-mode", "transport", "ssh-key", "ssh-known-hosts", "schedule", "overlap", "incremental", "full-sync-interval", "notify", "notify-on")
+	}
+	profileEditCmd.Flags().BoolVar(&profileAllRepos, "all-repos", false, "Sync all repositories instead of the ones set with --repos")
+	profileEditCmd.MarkFlagsMutuallyExclusive("repos", "all-repos")
+
+	profileDuplicateCmd.Flags().StringVarP(&profileTarget, "target", "T", "", "Target directory for the copy (defaults to the original's)")
+	config.SkipConfig(profileDuplicateCmd, "target")
+
+	profileDeleteCmd.Flags().BoolVarP(&profileDeleteYes, "yes", "y", false, "Delete without asking for confirmation")
+
+	profileExportCmd.Flags().StringVarP(&profileExportOutput, "output", "o", "", "File to write the bundle to (default: stdout)")
+	profileExportCmd.Flags().IntVar(&profileExportHistory, "history", 0, "Include up to N of each profile's most recent syncs")
+	profileExportCmd.Flags().BoolVar(&profileExportSecrets, "include-secrets", false, "Include notification webhook URLs, headers and SMTP passwords")
+	config.SkipConfig(profileExportCmd, "include-secrets")
+
+	profileImportCmd.Flags().StringVar(&profileImportConflict, "on-conflict", string(state.ConflictFail), "What to do with profiles that already exist: fail, skip, overwrite or rename")
+	profileImportCmd.Flags().StringArrayVar(&profileImportMapPaths, "map-path", nil, "Rewrite target directories starting with FROM to start with TO (FROM=TO, repeatable)")
+
+	profileCmd.AddCommand(profileListCmd, profileShowCmd, profileCreateCmd, profileEditCmd,
+		profileRenameCmd, profileDeleteCmd, profileDuplicateCmd, profileExportCmd, profileImportCmd)
+	rootCmd.AddCommand(profileCmd)
+}
+
+func runProfileList(cmd *cobra.Command, args []string) error {
+	storage, err := loadStateStorage()
+	if err != nil {
+		return err
+	}
+
+	profiles := storage.GetProfiles()
+	if len(profiles) == 0 {
+		fmt.Println("No sync profiles yet. Create one with:")
+		fmt.Println("  githubby profile create <name> --user <username> --target <dir>")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tSOURCE\tTARGET\tREPOS\tLAST SYNC")
+	for _, p := range profiles {
+		fmt.Fprintf(w, "%s\t%s:%s\t%s\t%s\t%s\n", p.Name, p.Type, p.Source, p.TargetDir, formatProfileRepos(p), formatLastSync(p))
+	}
+	return w.Flush()
+}
+
+func runProfileShow(cmd *cobra.Command, args []string) error {
+	storage, err := loadStateStorage()
+	if err != nil {
+		return err
+	}
+
+	profile, err := findProfile(storage, args[0])
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Name:             %s\n", profile.Name)
+	fmt.Printf("ID:               %s\n", profile.ID)
+	fmt.Printf("Type:             %s\n", profile.Type)
+	fmt.Printf("Source:           %s\n", profile.Source)
+	fmt.Printf("Target:           %s\n", profile.TargetDir)
+	fmt.Printf("Host:             %s\n", profile.Hostname())
+	fmt.Printf("Account:          %s\n", auth.Account{Name: profile.Account}.DisplayName())
+	fmt.Printf("Include private:  %t\n", profile.IncludePrivate)
+	fmt.Printf("Repositories:     %s\n", formatProfileRepos(profile))
+	for _, repo := range profile.SelectedRepos {
+		fmt.Printf("  - %s\n", repo)
+	}
+	fmt.Printf("Include filter:   %s\n", formatPatterns(profile.IncludeFilter))
+	fmt.Printf("Exclude filter:   %s\n", formatPatterns(profile.ExcludeFilter))
+	fmt.Printf("Concurrency:      %d\n", profile.SyncConcurrency())
+	fmt.Printf("Repo timeout:     %s\n", formatRepoTimeout(profile))
+	fmt.Printf("Git LFS:          %s\n", formatLFS(profile))
+	fmt.Printf("Clone mode:       %s\n", formatCloneMode(profile))
+	fmt.Printf("Transport:        %s\n", formatTransport(profile))
+	fmt.Printf("Schedule:         %s\n", formatSchedule(profile))
+	fmt.Printf("Overlap:          %s\n", profile.OverlapPolicy())
+	fmt.Printf("Incremental:      %s\n", formatIncremental(profile))
+	fmt.Printf("Notifications:    %s\n", formatNotifyCount(profile))
+	for _, target := range profile.Notifications {
+		fmt.Printf("  - %s (%s)\n", target, target.Trigger())
+	}
+	fmt.Printf("Created:          %s\n", profile.CreatedAt.Local().Format("2006-01-02 15:04:05"))
+	fmt.Printf("Last sync:        %s\n", formatLastSync(profile))
+
+	return nil
+}
+
+func runProfileCreate(cmd *cobra.Command, args []string) error {
+	storage, err := loadStateStorage()
+	if err != nil {
+		return err
+	}
+
+	if profileUser == "" && profileOrg == "" {
+		return fmt.Errorf("either --user or --org must be specified")
+	}
+	if profileTarget == "" {
+		return fmt.Errorf("--target is required")
+	}
+
+	profile := state.NewProfile(args[0], "", "", "", false)
+	profile.SyncAllRepos = true
+	if _, err := applyProfileFlags(cmd, profile); err != nil {
+		return err
+	}
+
+	if err := storage.ValidateProfile(profile); err != nil {
+		return err
+	}
+	if err := storage.AddProfile(profile); err != nil {
+		return fmt.Errorf("failed to save profile: %w", err)
+	}
+
+	fmt.Printf("✓ Created profile %q (%s: %s -> %s)\n", profile.Name, profile.Type, profile.Source, profile.TargetDir)
+	return nil
+}
+
+func runProfileEdit(cmd *cobra.Command, args []string) error {
+	storage, err := loadStateStorage()
+	if err != nil {
+		return err
+	}
+
+	existing, err := findProfile(storage, args[0])
+	if err != nil {
+		return err
+	}
+
+	profile := existing.Clone()
+	changed, err := applyProfileFlags(cmd, profile)
+	if err != nil {
+		return err
+	}
+	if !changed {
+		return fmt.Errorf("nothing to change; pass the settings to edit as flags (see --help)")
+	}
+
+	if err := storage.ValidateProfile(profile); err != nil {
+		return err
+	}
+	if err := storage.UpdateProfile(profile); err != nil {
+		return fmt.Errorf("failed to save profile: %w", err)
+	}
+
+	fmt.Printf("✓ Updated profile %q\n", profile.Name)
+	return nil
+}
+
+func runProfileRename(cmd *cobra.Command, args []string) error {
+	storage, err := loadStateStorage()
+	if err != nil {
+		return err
+	}
+
+	profile, err := findProfile(storage, args[0])
+	if err != nil {
+		return err
+	}
+
+	if err := storage.RenameProfile(profile.ID, args[1]); err != nil {
+		return err
+	}
+
+	fmt.Printf("✓ Renamed profile %q to %q\n", args[0], args[1])
+	return nil
+}
+
+func runProfileDelete(cmd *cobra.Command, args []string) error {
+	storage, err := loadStateStorage()
+	if err != nil {
+		return err
+	}
+
+	profile, err := findProfile(storage, args[0])
+	if err != nil {
+		return err
+	}
+
+	if !profileDeleteYes {
+		if !tuiutil.IsInteractive() {
+			return fmt.Errorf("refusing to delete profile %q without confirmation; pass --yes", profile.Name)
+		}
+		fmt.Printf("Delete profile %q and its sync history? [y/N] ", profile.Name)
+		reader := bufio.NewReader(os.Stdin)
+		answer, _ := reader.ReadString('\n')
+		answer = strings.ToLower(strings.TrimSpace(answer))
+		if answer != "y" && answer != "yes" {
+			fmt.Println("Aborted")
+			return nil
+		}
+	}
+
+	if err := storage.DeleteProfile(profile.ID); err != nil {
+		return err
+	}
+
+	fmt.Printf("✓ Deleted profile %q\n", profile.Name)
+	fmt.Printf("  Synced repositories in %s were left in place\n", profile.TargetDir)
+	return nil
+}
+
+func runProfileDuplicate(cmd *cobra.Command, args []string) error {
+	storage, err := loadStateStorage()
+	if err != nil {
+		return err
+	}
+
+	original, err := findProfile(storage, args[0])
+	if err != nil {
+		return err
+	}
+
+	duplicate := original.Duplicate(args[1])
+	if cmd.Flags().Changed("target") {
+		duplicate.TargetDir = state.ExpandPath(profileTarget)
+	}
+
+	if err := storage.ValidateProfile(duplicate); err != nil {
+		return err
+	}
+	if err := storage.AddProfile(duplicate); err != nil {
+		return fmt.Errorf("failed to save profile: %w", err)
+	}
+
+	fmt.Printf("✓ Created profile %q from %q (target: %s)\n", duplicate.Name, original.Name, duplicate.TargetDir)
+	return nil
+}
+
+func runProfileExport(cmd *cobra.Command, args []string) error {
+	storage, err := loadStateStorage()
+	if err != nil {
+		return err
+	}
+
+	profiles := storage.GetProfiles()
+	if len(args) > 0 {
+		profiles = make([]*state.SyncProfile, 0, len(args))
+		for _, name := range args {
+			profile, err := findProfile(storage, name)
+			if err != nil {
+				return err
+			}
+			profiles = append(profiles, profile)
+		}
+	}
+	if len(profiles) == 0 {
+		return fmt.Errorf("no profiles to export")
+	}
+
+	bundle := storage.ExportBundle(profiles, profileExportHistory)
+	if profileExportSecrets {
+		fmt.Fprintln(os.Stderr, "⚠ The bundle includes notification secrets; keep it private")
+	} else if stripped := bundle.StripSecrets(); stripped > 0 {
+		fmt.Fprintf(os.Stderr, "⚠ Left out the secrets of %d notification(s); pass --include-secrets to export them\n", stripped)
+	}
+
+	data, err := yaml.Marshal(bundle)
+	if err != nil {
+		return fmt.Errorf("failed to serialize bundle: %w", err)
+	}
+
+	if profileExportOutput == "" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	if err := os.WriteFile(profileExportOutput, data, 0600); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+
+	fmt.Printf("✓ Exported %d profile(s) to %s\n", len(profiles), profileExportOutput)
+	return nil
+}
+
+func runProfileImport(cmd *cobra.Command, args []string) error {
+	policy, err := state.ParseConflictPolicy(profileImportConflict)
+	if err != nil {
+		return err
+	}
+
+	opts := state.ImportOptions{OnConflict: policy}
+	for _, m := range profileImportMapPaths {
+		mapping, err := state.ParsePathMapping(m)
+		if err != nil {
+			return err
+		}
+		opts.PathMappings = append(opts.PathMappings, mapping)
+	}
+
+	bundle, err := state.LoadBundle(args[0])
+	if err != nil {
+		return err
+	}
+
+	storage, err := loadStateStorage()
+	if err != nil {
+		return err
+	}
+
+	result, err := storage.ImportBundle(bundle, opts, dryRun)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range result.Added {
+		fmt.Printf("+ %s (%s: %s -> %s)\n", p.Name, p.Type, p.Source, p.TargetDir)
+	}
+	for oldName, newName := range result.Renamed {
+		fmt.Printf("  %s was imported as %s\n", oldName, newName)
+	}
+	for _, p := range result.Overwritten {
+		fmt.Printf("~ %s (%s: %s -> %s)\n", p.Name, p.Type, p.Source, p.TargetDir)
+	}
+	for _, name := range result.Skipped {
+		fmt.Printf("  %s (already exists, skipped)\n", name)
+	}
+
+	verb := "Imported"
+	if dryRun {
+		verb = "Would import"
+	}
+	fmt.Printf("✓ %s %d new and %d overwritten profile(s), %d skipped, %d sync record(s)\n",
+		verb, len(result.Added), len(result.Overwritten), len(result.Skipped), result.HistoryImported)
+	return nil
+}
+
+// applyProfileFlags copies the flags set on cmd onto profile.
+// Returns false if no profile setting flags were set.
+func applyProfileFlags(cmd *cobra.Command, profile *state.SyncProfile) (bool, error) {
+	flags := cmd.Flags()
+	changed := false
+
+	if flags.Changed("user") {
+		profile.Type = state.ProfileTypeUser
+		profile.Source = profileUser
+		changed = true
+	}
+	if flags.Changed("org") {
+		profile.Type = state.ProfileTypeOrg
+		profile.Source = profileOrg
+		changed = true
+	}
+	if flags.Changed("target") {
+		profile.TargetDir = state.ExpandPath(profileTarget)
+		changed = true
+	}
+	if flags.Changed("hostname") {
+		profile.Host = ""
+		if host := resolveHostname(nil); auth.IsEnterprise(host) {
+			profile.Host = host
+		}
+		changed = true
+	}
+	if flags.Changed("account") {
+		profile.Account = resolveAccount(nil)
+		changed = true
+	}
+	if flags.Changed("include-private") {
+		profile.IncludePrivate = profileIncludePrivate
+		changed = true
+	}
+	if flags.Changed("repos") {
+		profile.SyncAllRepos = false
+		profile.SelectedRepos = profileRepos
+		changed = true
+	}
+	if flags.Lookup("all-repos") != nil && flags.Changed("all-repos") {
+		profile.SyncAllRepos = profileAllRepos
+		if profileAllRepos {
+			profile.SelectedRepos = nil
+		}
+		changed = true
+	}
+	if flags.Changed("include") {
+		profile.IncludeFilter = profileInclude
+		changed = true
+	}
+	if flags.Changed("exclude") {
+		profile.ExcludeFilter = profileExclude
+		changed = true
+	}
+	if flags.Changed("concurrency") {
+		profile.Concurrency = profileConcurrency
+		changed = true
+	}
+	if flags.Changed("repo-timeout") {
+		profile.RepoTimeout = profileRepoTimeout
+		changed = true
+	}
+	if flags.Changed("lfs") {
+		profile.SkipLFS = !profileLFS
+		changed = true
+	}
+	if flags.Changed("clone-mode") {
+		profile.CloneMode = profileCloneMode
+		changed = true
+	}
+	if flags.Changed("transport") {
+		profile.Transport = profileTransport
+		changed = true
+	}
+	if flags.Changed("ssh-key") {
+		profile.SSHKey = state.ExpandPath(profileSSHKey)
+		changed = true
+	}
+	if flags.Changed("ssh-known-hosts") {
+		profile.SSHKnownHosts = state.ExpandPath(profileSSHKnownHosts)
+		changed = true
+	}
+	if flags.Changed("schedule") {
+		profile.Schedule = profileSchedule
+		changed = true
+	}
+	if flags.Changed("overlap") {
+		profile.Overlap = profileOverlap
+		changed = true
+	}
+	if flags.Changed("incremental") {
+		profile.Incremental = profileIncremental
+		changed = true
+	}
+	if flags.Changed("full-sync-interval") {
+		profile.FullSyncInterval = profileFullSync
+		changed = true
+	}
+	if flags.Changed("notify") || flags.Changed("notify-on") {
+		if err := applyNotifyFlags(flags.Changed("notify"), profile); err != nil {
+			return false, err
+		}
+		changed = true
+	}
+
+	return changed, nil
+}
+
+// applyNotifyFlags sets the profile's notifications from --notify, or just
+// their trigger if only --notify-on was set
+func applyNotifyFlags(replace bool, profile *state.SyncProfile) error {
+	on, err := notify.ParseTrigger(profileNotifyOn)
+	if err != nil {
+		return err
+	}
+
+	if !replace {
+		for i := range profile.Notifications {
+			profile.Notifications[i].On = profileNotifyOn
+		}
+		return nil
+	}
+
+	profile.Notifications = nil
+	for _, spec := range profileNotify {
+		if spec == "" {
+			continue
+		}
+		target, err := notify.ParseTarget(spec, on)
+		if err != nil {
+			return err
+		}
+		profile.Notifications = append(profile.Notifications, target)
+	}
+	return nil
+}
+
+// loadStateStorage opens and loads the state file
+func loadStateStorage() (*state.Storage, error) {
+	storage, err := state.NewStorage()
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize state storage: %w", err)
+	}
+	if err := storage.Load(); err != nil {
+		return nil, fmt.Errorf("failed to load state: %w", err)
+	}
+	return storage, nil
+}
+
+// findProfile looks up a profile by name, falling back to its ID
+func findProfile(storage *state.Storage, ref string) (*state.SyncProfile, error) {
+	if profile := storage.GetProfileByName(ref); profile != nil {
+		return profile, nil
+	}
+	if profile := storage.GetProfile(ref); profile != nil {
+		return profile, nil
+	}
+
+	// List available profiles in error message
+	available := storage.GetProfiles()
+	if len(available) == 0 {
+		return nil, fmt.Errorf("profile %q not found; no profiles exist yet", ref)
+	}
+	names := make([]string, len(available))
+	for i, p := range available {
+		names[i] = p.Name
+	}
+	return nil, fmt.Errorf("profile %q not found; available profiles: %s", ref, strings.Join(names, ", "))
+}
+
+// formatProfileRepos describes which repositories a profile syncs
+func formatProfileRepos(profile *state.SyncProfile) string {
+	if profile.SyncAllRepos || len(profile.SelectedRepos) == 0 {
+		return "all"
+	}
+	return fmt.Sprintf("%d selected", len(profile.SelectedRepos))
+}
+
+// formatLastSync returns the profile's last sync time, or "never"
+func formatLastSync(profile *state.SyncProfile) string {
+	if profile.LastSyncAt.IsZero() {
+		return "never"
+	}
+	return profile.LastSyncAt.Local().Format("2006-01-02 15:04:05")
+}
+
+// formatPatterns joins glob patterns for display
+func formatPatterns(patterns []string) string {
+	if len(patterns) == 0 {
+		return "none"
+	}
+	return strings.Join(patterns, ", ")
+}
+
+// formatRepoTimeout returns the profile's per-repository timeout, or "none"
+func formatRepoTimeout(profile *state.SyncProfile) string {
+	if profile.RepoTimeout <= 0 {
+		return "none"
+	}
+	return profile.RepoTimeout.String()
+}
+
+// formatLFS describes whether the profile downloads Git LFS objects
+func formatLFS(profile *state.SyncProfile) string {
+	if profile.SkipLFS {
+		return "skipped"
+	}
+	return "enabled"
+}
+
+// formatCloneMode returns the profile's clone mode
+func formatCloneMode(profile *state.SyncProfile) string {
+	if profile.CloneMode == "" {
+		return state.CloneModeFull
+	}
+	return profile.CloneMode
+}
+
+// formatTransport describes how the profile clones and fetches, with its SSH
+// key and known_hosts file
+func formatTransport(profile *state.SyncProfile) string {
+	if !profile.UseSSH() {
+		return state.TransportHTTPS
+	}
+	var details []string
+	if profile.SSHKey != "" {
+		details = append(details, "key "+profile.SSHKey)
+	}
+	if profile.SSHKnownHosts != "" {
+		details = append(details, "known_hosts "+profile.SSHKnownHosts)
+	}
+	if len(details) == 0 {
+		return state.TransportSSH
+	}
+	return fmt.Sprintf("%s (%s)", state.TransportSSH, strings.Join(details, ", "))
+}
+
+// formatIncremental describes whether the profile syncs incrementally
+func formatIncremental(profile *state.SyncProfile) string {
+	if !profile.Incremental {
+		return "no"
+	}
+	if !profile.SyncAllRepos && len(profile.SelectedRepos) > 0 {
+		return "no (only applies to profiles syncing all repositories)"
+	}
+	return fmt.Sprintf("yes, full sync every %s", profile.FullSyncEvery())
+}
+
+// formatSchedule returns the profile's cron schedule, or "none"
+func formatSchedule(profile *state.SyncProfile) string {
+	if profile.Schedule == "" {
+		return "none"
+	}
+	return profile.Schedule
+}
+
+// formatNotifyCount describes how many notification targets a profile has
+func formatNotifyCount(profile *state.SyncProfile) string {
+	if len(profile.Notifications) == 0 {
+		return "none"
+	}
+	return fmt.Sprintf("%d", len(profile.Notifications))
+}
@@ -0,0 +1,180 @@
+package cli
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Didstopia/githubby/internal/notify"
+	"github.com/Didstopia/githubby/internal/state"
+)
+
+// newProfileFlagsCmd returns a command with the profile edit flags, so tests
+// don't share flag state with the real commands
+func newProfileFlagsCmd() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Flags().StringVarP(&profileUser, "user", "u", "", "")
+	cmd.Flags().StringVarP(&profileOrg, "org", "o", "", "")
+	cmd.Flags().StringVarP(&profileTarget, "target", "T", "", "")
+	cmd.Flags().BoolVarP(&profileIncludePrivate, "include-private", "p", false, "")
+	cmd.Flags().StringSliceVar(&profileRepos, "repos", nil, "")
+	cmd.Flags().BoolVar(&profileAllRepos, "all-repos", false, "")
+	cmd.Flags().StringSliceVarP(&profileInclude, "include", "i", nil, "")
+	cmd.Flags().StringSliceVarP(&profileExclude, "exclude", "e", nil, "")
+	cmd.Flags().IntVar(&profileConcurrency, "concurrency", 0, "")
+	cmd.Flags().DurationVar(&profileRepoTimeout, "repo-timeout", 0, "")
+	cmd.Flags().BoolVar(&profileLFS, "lfs", true, "")
+	cmd.Flags().StringVar(&profileCloneMode, "clone-mode", "", "")
+	cmd.Flags().StringVar(&profileSchedule, "schedule", "", "")
+	cmd.Flags().StringVar(&profileOverlap, "overlap", "", "")
+	cmd.Flags().BoolVar(&profileIncremental, "incremental", false, "")
+	cmd.Flags().DurationVar(&profileFullSync, "full-sync-interval", 0, "")
+	cmd.Flags().StringArrayVar(&profileNotify, "notify", nil, "")
+	cmd.Flags().StringVar(&profileNotifyOn, "notify-on", "", "")
+	cmd.Flags().StringVar(&account, "account", "", "")
+	return cmd
+}
+
+func TestApplyProfileFlags(t *testing.T) {
+	t.Run("no flags", func(t *testing.T) {
+		profile := state.NewProfile("test", "user", "alice", "/tmp/a", true)
+		changed, err := applyProfileFlags(newProfileFlagsCmd(), profile)
+		require.NoError(t, err)
+		assert.False(t, changed)
+	})
+
+	t.Run("only changed flags are applied", func(t *testing.T) {
+		profile := state.NewProfile("test", "user", "alice", "/tmp/a", true)
+		profile.ExcludeFilter = []string{"old-*"}
+
+		cmd := newProfileFlagsCmd()
+		require.NoError(t, cmd.ParseFlags([]string{"--org", "acme", "--include-private=false", "--repos", "acme/one,acme/two"}))
+
+		changed, err := applyProfileFlags(cmd, profile)
+		require.NoError(t, err)
+		assert.True(t, changed)
+		assert.Equal(t, "org", profile.Type)
+		assert.Equal(t, "acme", profile.Source)
+		assert.Equal(t, "/tmp/a", profile.TargetDir)
+		assert.False(t, profile.IncludePrivate)
+		assert.False(t, profile.SyncAllRepos)
+		assert.Equal(t, []string{"acme/one", "acme/two"}, profile.SelectedRepos)
+		assert.Equal(t, []string{"old-*"}, profile.ExcludeFilter)
+	})
+
+	t.Run("all repos clears selection", func(t *testing.T) {
+		profile := state.NewProfile("test", "user", "alice", "/tmp/a", false)
+		profile.SelectedRepos = []string{"alice/one"}
+
+		cmd := newProfileFlagsCmd()
+		require.NoError(t, cmd.ParseFlags([]string{"--all-repos"}))
+
+		changed, err := applyProfileFlags(cmd, profile)
+		require.NoError(t, err)
+		assert.True(t, changed)
+		assert.True(t, profile.SyncAllRepos)
+		assert.Empty(t, profile.SelectedRepos)
+	})
+
+	t.Run("sync settings", func(t *testing.T) {
+		profile := state.NewProfile("test", "user", "alice", "/tmp/a", true)
+
+		cmd := newProfileFlagsCmd()
+		require.NoError(t, cmd.ParseFlags([]string{
+			"--concurrency", "6", "--repo-timeout", "5m", "--lfs=false", "--clone-mode", "shallow", "--schedule", "@hourly", "--overlap", "queue",
+			"--incremental", "--full-sync-interval", "12h",
+		}))
+
+		changed, err := applyProfileFlags(cmd, profile)
+		require.NoError(t, err)
+		assert.True(t, changed)
+		assert.Equal(t, 6, profile.Concurrency)
+		assert.Equal(t, 5*time.Minute, profile.RepoTimeout)
+		assert.True(t, profile.SkipLFS)
+		assert.Equal(t, state.CloneModeShallow, profile.CloneMode)
+		assert.Equal(t, "@hourly", profile.Schedule)
+		assert.Equal(t, "queue", profile.Overlap)
+		assert.True(t, profile.Incremental)
+		assert.Equal(t, 12*time.Hour, profile.FullSyncInterval)
+	})
+
+	t.Run("account", func(t *testing.T) {
+		t.Cleanup(func() { account = "" })
+		profile := state.NewProfile("test", "user", "alice", "/tmp/a", true)
+
+		cmd := newProfileFlagsCmd()
+		require.NoError(t, cmd.ParseFlags([]string{"--account", "work"}))
+		changed, err := applyProfileFlags(cmd, profile)
+		require.NoError(t, err)
+		assert.True(t, changed)
+		assert.Equal(t, "work", profile.Account)
+
+		// An empty --account goes back to the default account
+		cmd = newProfileFlagsCmd()
+		require.NoError(t, cmd.ParseFlags([]string{"--account", ""}))
+		_, err = applyProfileFlags(cmd, profile)
+		require.NoError(t, err)
+		assert.Empty(t, profile.Account)
+	})
+
+	t.Run("notifications", func(t *testing.T) {
+		profile := state.NewProfile("test", "user", "alice", "/tmp/a", true)
+
+		cmd := newProfileFlagsCmd()
+		require.NoError(t, cmd.ParseFlags([]string{
+			"--notify", "slack=https://hooks.slack.com/services/x", "--notify", "email=ops@example.com", "--notify-on", "always",
+		}))
+		changed, err := applyProfileFlags(cmd, profile)
+		require.NoError(t, err)
+		assert.True(t, changed)
+		assert.Equal(t, []notify.Target{
+			{Type: notify.TypeSlack, URL: "https://hooks.slack.com/services/x", On: "always"},
+			{Type: notify.TypeEmail, To: []string{"ops@example.com"}, On: "always"},
+		}, profile.Notifications)
+
+		// --notify-on alone changes the trigger of the existing targets
+		cmd = newProfileFlagsCmd()
+		require.NoError(t, cmd.ParseFlags([]string{"--notify-on", "on-change"}))
+		_, err = applyProfileFlags(cmd, profile)
+		require.NoError(t, err)
+		assert.Equal(t, notify.OnChange, profile.Notifications[1].Trigger())
+
+		// An empty --notify removes them
+		cmd = newProfileFlagsCmd()
+		require.NoError(t, cmd.ParseFlags([]string{"--notify", ""}))
+		_, err = applyProfileFlags(cmd, profile)
+		require.NoError(t, err)
+		assert.Empty(t, profile.Notifications)
+
+		cmd = newProfileFlagsCmd()
+		require.NoError(t, cmd.ParseFlags([]string{"--notify", "https://example.com"}))
+		_, err = applyProfileFlags(cmd, profile)
+		assert.ErrorContains(t, err, "expected TYPE=URL")
+	})
+}
+
+func TestFindProfile(t *testing.T) {
+	storage := state.NewStorageWithPath(filepath.Join(t.TempDir(), "state.yaml"))
+	require.NoError(t, storage.Load())
+
+	_, err := findProfile(storage, "missing")
+	assert.ErrorContains(t, err, "no profiles exist yet")
+
+	profile := state.NewProfile("work", "org", "acme", "/tmp/a", false)
+	require.NoError(t, storage.AddProfile(profile))
+
+	found, err := findProfile(storage, "work")
+	require.NoError(t, err)
+	assert.Equal(t, profile.ID, found.ID)
+
+	found, err = findProfile(storage, profile.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "work", found.Name)
+
+	_, err = findProfile(storage, "missing")
+	assert.ErrorContains(t, err, "available profiles: work")
+}
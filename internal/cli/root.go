@@ -14,6 +14,9 @@ import (
 
 	"github.com/Didstopia/githubby/internal/auth"
 	"github.com/Didstopia/githubby/internal/config"
+	"github.com/Didstopia/githubby/internal/github"
+	"github.com/Didstopia/githubby/internal/network"
+	tuiutil "github.com/Didstopia/githubby/internal/tui/util"
 	"github.com/Didstopia/githubby/internal/update"
 )
 
@@ -32,7 +35,14 @@ var (
 	verbose    bool
 	dryRun     bool
 	token      string
+	tokenFile  string
 	repository string
+	hostname   string
+	account    string
+	noCache    bool
+	useGraphQL bool
+
+	rateLimitReserve int
 )
 
 // Global logger
@@ -54,7 +64,27 @@ var rootCmd = &cobra.Command{
 		verbose, _ = cmd.Flags().GetBool("verbose")
 		dryRun, _ = cmd.Flags().GetBool("dry-run")
 		token, _ = cmd.Flags().GetString("token")
+		tokenFile, _ = cmd.Flags().GetString("token-file")
 		repository, _ = cmd.Flags().GetString("repository")
+		hostname, _ = cmd.Flags().GetString("hostname")
+		if _, err := auth.NormalizeHostname(hostname); err != nil {
+			return err
+		}
+		account, _ = cmd.Flags().GetString("account")
+		if err := auth.ValidateAccountName(account); err != nil {
+			return err
+		}
+		noCache, _ = cmd.Flags().GetBool("no-cache")
+		useGraphQL, _ = cmd.Flags().GetBool("graphql")
+		rateLimitReserve, _ = cmd.Flags().GetInt("rate-limit-reserve")
+
+		// Ask for the passphrase of the encrypted token store when it's needed,
+		// unless nobody is there to answer
+		if tuiutil.IsInteractive() {
+			auth.PassphrasePrompt = func() (string, error) {
+				return promptPassphrase("Passphrase for the encrypted token store: ")
+			}
+		}
 
 		// Set log level
 		if verbose {
@@ -76,6 +106,15 @@ func init() {
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Enable verbose output")
 	rootCmd.PersistentFlags().BoolVarP(&dryRun, "dry-run", "D", false, "Simulate running without making changes")
 	rootCmd.PersistentFlags().StringVarP(&token, "token", "t", "", "GitHub API token")
+	rootCmd.PersistentFlags().StringVar(&tokenFile, "token-file", "", "Read the GitHub API token from a file (takes precedence over --token)")
+	rootCmd.PersistentFlags().StringVar(&hostname, "hostname", "", "GitHub Enterprise Server hostname (default: github.com, or the profile's host)")
+	// HOSTNAME is the machine's name in most environments
+	_ = rootCmd.PersistentFlags().SetAnnotation("hostname", config.NoConfigAnnotation, []string{"true"})
+	rootCmd.PersistentFlags().StringVar(&account, "account", "", "Named account to use (default: the host's default account, or the profile's account)")
+	_ = rootCmd.PersistentFlags().SetAnnotation("account", config.NoConfigAnnotation, []string{"true"})
+	rootCmd.PersistentFlags().BoolVar(&noCache, "no-cache", false, "Don't cache GitHub API responses")
+	rootCmd.PersistentFlags().BoolVar(&useGraphQL, "graphql", false, "List and fetch repositories with GraphQL, falling back to the REST API")
+	rootCmd.PersistentFlags().IntVar(&rateLimitReserve, "rate-limit-reserve", github.DefaultRateLimitReserve, "API requests syncs leave for interactive use")
 
 	// Bind to viper after initialization
 }
@@ -97,6 +136,27 @@ func initConfig() {
 	viper.SetDefault("token", "")
 }
 
+// configureNetwork applies the network section of the config file to HTTP
+// clients and git. A missing config file leaves the defaults; one that can't
+// be read or parsed fails, rather than silently bypassing the proxy.
+func configureNetwork() error {
+	path, err := config.GetConfigFilePath()
+	if err != nil {
+		return nil
+	}
+	cfg, err := config.LoadFrom(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if err := network.Configure(cfg.Network); err != nil {
+		return fmt.Errorf("%s: network: %w", path, err)
+	}
+	return nil
+}
+
 // Execute runs the root command
 func Execute() {
 	// Create context with signal handling for graceful shutdown
@@ -116,6 +176,13 @@ func Execute() {
 	// Store context for subcommands
 	rootCmd.SetContext(ctx)
 
+	// Apply the proxy and TLS settings before anything talks to GitHub,
+	// including the TUI and the update check below
+	if err := configureNetwork(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
 	// Check if running with no arguments - launch TUI (TUI handles its own update)
 	if len(os.Args) == 1 {
 		if err := RunTUI(); err != nil {
@@ -277,9 +344,9 @@ func GetToken() string {
 }
 
 // GetTokenWithAuth returns the token using the auth package's resolution logic
-// This checks CLI flag, environment variable, and stored tokens
+// This checks CLI flags, environment variables, and stored tokens
 func GetTokenWithAuth(ctx context.Context) (string, error) {
-	result, err := auth.GetToken(ctx, token, "")
+	result, err := accountToken(ctx, resolveAccount(nil), resolveHostname(nil))
 	if err != nil {
 		return "", err
 	}
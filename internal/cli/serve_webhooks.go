@@ -0,0 +1,247 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Didstopia/githubby/internal/auth"
+	"github.com/Didstopia/githubby/internal/config"
+	"github.com/Didstopia/githubby/internal/lock"
+	"github.com/Didstopia/githubby/internal/state"
+	"github.com/Didstopia/githubby/internal/webhook"
+)
+
+// webhookSecretEnv holds the webhook secret when --secret isn't set
+const webhookSecretEnv = "GITHUBBY_WEBHOOK_SECRET"
+
+var (
+	webhookAddr     string
+	webhookPath     string
+	webhookSecret   string
+	webhookDebounce time.Duration
+	webhookWorkers  int
+)
+
+var serveWebhooksCmd = &cobra.Command{
+	Use:   "serve-webhooks",
+	Short: "Sync repositories as soon as GitHub reports changes",
+	Long: `Receive GitHub webhooks and sync the affected repository right away.
+
+Add a webhook to a repository, organization or GitHub App that sends push,
+create, delete, repository and release events to this server, with the content
+type "application/json" and a secret. Deliveries are verified against the
+secret (--secret or $GITHUBBY_WEBHOOK_SECRET) and rejected if they don't match.
+
+Each event is matched to the saved profiles that sync its repository: profiles
+syncing all repositories of its owner, or profiles that selected it, on the
+GitHub instance that sent it. Only that repository is synced, with the
+profile's settings. Events about a repository that arrive within --debounce of
+each other are synced once.
+
+Syncs lock the profile's whole target directory, like "githubby sync" does, so
+--workers only syncs repositories of different targets at once, and a sync of
+a target that another process is syncing waits for it (see --lock-policy).
+
+Renamed and transferred repositories have their local copy moved to the new
+name before syncing. Deleted repositories are left on disk.
+
+Examples:
+  # Receive webhooks on port 8080
+  export GITHUBBY_WEBHOOK_SECRET=...
+  githubby serve-webhooks
+
+  # Receive webhooks on a custom address and path
+  githubby serve-webhooks --addr 127.0.0.1:9000 --path /github
+
+  # Sync up to 4 repositories at once, a minute after the last push
+  githubby serve-webhooks --workers 4 --debounce 1m`,
+	Args: cobra.NoArgs,
+	RunE: runServeWebhooks,
+}
+
+func init() {
+	serveWebhooksCmd.Flags().StringVar(&webhookAddr, "addr", ":8080", "Address to receive webhooks on")
+	serveWebhooksCmd.Flags().StringVar(&webhookPath, "path", "/webhook", "URL path to receive webhooks on")
+	serveWebhooksCmd.Flags().StringVar(&webhookSecret, "secret", "", "Webhook secret (default: $"+webhookSecretEnv+")")
+	serveWebhooksCmd.Flags().DurationVar(&webhookDebounce, "debounce", webhook.DefaultDebounce, "How long to wait for more events about a repository before syncing it")
+	serveWebhooksCmd.Flags().IntVar(&webhookWorkers, "workers", 2, "Maximum repositories syncing at once")
+	serveWebhooksCmd.Flags().StringVar(&syncLockPolicy, "lock-policy", string(lock.PolicyWait), "What to do when another githubby process is syncing the same target: wait, skip or fail")
+	serveWebhooksCmd.Flags().DurationVar(&syncLockTimeout, "lock-timeout", 0, "Maximum time to wait for a locked target with --lock-policy wait (0 waits indefinitely)")
+
+	// Config values are also read from the environment, where PATH means something else
+	config.SkipConfig(serveWebhooksCmd, "path")
+
+	rootCmd.AddCommand(serveWebhooksCmd)
+}
+
+func runServeWebhooks(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+
+	secret := webhookSecret
+	if secret == "" {
+		secret = os.Getenv(webhookSecretEnv)
+	}
+	if secret == "" {
+		return fmt.Errorf("a webhook secret is required (use --secret or $%s)", webhookSecretEnv)
+	}
+	if !strings.HasPrefix(webhookPath, "/") {
+		return fmt.Errorf("--path must start with /")
+	}
+	if webhookDebounce < 0 {
+		return fmt.Errorf("--debounce can't be negative")
+	}
+	if webhookWorkers <= 0 {
+		return fmt.Errorf("--workers must be positive")
+	}
+	if _, err := lock.ParsePolicy(syncLockPolicy); err != nil {
+		return err
+	}
+
+	storage, err := loadStateStorage()
+	if err != nil {
+		return err
+	}
+
+	queue := webhook.NewQueue(webhook.QueueOptions{
+		Debounce: webhookDebounce,
+		Workers:  webhookWorkers,
+	}, func(ctx context.Context, task webhook.Task) {
+		runWebhookTask(ctx, storage, task)
+	})
+
+	mux := http.NewServeMux()
+	mux.Handle(webhookPath, webhook.Handler([]byte(secret), func(event *webhook.Event) {
+		dispatchWebhookEvent(storage, queue, event)
+	}))
+	server := &http.Server{
+		Addr:              webhookAddr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.ListenAndServe()
+	}()
+
+	fmt.Printf("Receiving webhooks on %s%s\n", webhookAddr, webhookPath)
+	fmt.Println("Press Ctrl+C to stop")
+
+	queueCtx, stopQueue := context.WithCancel(ctx)
+	queueDone := make(chan struct{})
+	go func() {
+		queue.Run(queueCtx)
+		close(queueDone)
+	}()
+
+	select {
+	case <-ctx.Done():
+	case err = <-serveErr:
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_ = server.Shutdown(shutdownCtx)
+	stopQueue()
+	<-queueDone
+
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("webhook server failed: %w", err)
+	}
+	return nil
+}
+
+// dispatchWebhookEvent queues a sync of the event's repository for every
+// profile that syncs it
+func dispatchWebhookEvent(storage *state.Storage, queue *webhook.Queue, event *webhook.Event) {
+	if event.Type == webhook.EventRepository && event.Action == webhook.ActionDeleted {
+		fmt.Printf("Repository %s was deleted; keeping its local copies\n", event.Repo)
+		return
+	}
+
+	// Pick up profile changes made since the server started
+	if _, err := storage.Reload(); err != nil {
+		log.Warnf("Failed to reload %s, using the loaded profiles: %v", storage.Path(), err)
+	}
+
+	tasks := webhookTasks(storage.GetProfiles(), event)
+	if len(tasks) == 0 {
+		log.Debugf("No profile syncs %s, ignoring %s event %s", event.Repo, event.Type, event.Delivery)
+		return
+	}
+	for _, task := range tasks {
+		queue.Add(task)
+	}
+}
+
+// webhookTasks returns the syncs an event triggers: one for each profile on
+// the event's host that syncs all repositories of the event's owner, or
+// selected its repository. Include and exclude filters are applied when the
+// task is synced.
+func webhookTasks(profiles []*state.SyncProfile, event *webhook.Event) []webhook.Task {
+	owner, _, _ := strings.Cut(event.Repo, "/")
+	host, err := auth.NormalizeHostname(event.Host)
+	if err != nil {
+		log.Warnf("Ignoring %s event %s from invalid host: %v", event.Type, event.Delivery, err)
+		return nil
+	}
+
+	var tasks []webhook.Task
+	for _, profile := range profiles {
+		if profile.Hostname() != host {
+			continue
+		}
+		if !profile.SyncAllRepos && len(profile.SelectedRepos) > 0 {
+			switch {
+			case containsRepo(profile.SelectedRepos, event.Repo):
+				tasks = append(tasks, webhook.Task{ProfileID: profile.ID, Repo: event.Repo, PreviousRepo: event.PreviousRepo})
+			case event.PreviousRepo != "" && containsRepo(profile.SelectedRepos, event.PreviousRepo):
+				log.Warnf("Profile %q selects %s, which is now %s; update its repositories with \"githubby profile edit\"",
+					profile.Name, event.PreviousRepo, event.Repo)
+			}
+			continue
+		}
+
+		if !strings.EqualFold(profile.Source, owner) {
+			continue
+		}
+		if event.Private && !profile.IncludePrivate {
+			continue
+		}
+		tasks = append(tasks, webhook.Task{ProfileID: profile.ID, Repo: event.Repo, PreviousRepo: event.PreviousRepo})
+	}
+	return tasks
+}
+
+// containsRepo reports whether repos contains fullName, ignoring case
+func containsRepo(repos []string, fullName string) bool {
+	for _, repo := range repos {
+		if strings.EqualFold(repo, fullName) {
+			return true
+		}
+	}
+	return false
+}
+
+// runWebhookTask syncs a queued repository for its profile
+func runWebhookTask(ctx context.Context, storage *state.Storage, task webhook.Task) {
+	profile := storage.GetProfile(task.ProfileID)
+	if profile == nil {
+		log.Warnf("Profile %s was removed, not syncing %s", task.ProfileID, task.Repo)
+		return
+	}
+
+	fmt.Printf("\nSyncing %s for profile %q (%s)\n", task.Repo, profile.Name, profile.TargetDir)
+
+	err := executeProfileSync(ctx, profile, storage, &task)
+	if err != nil && !errors.Is(err, errSyncSkipped) && ctx.Err() == nil {
+		log.Warnf("Profile %q sync of %s failed: %v", profile.Name, task.Repo, err)
+	}
+}
@@ -0,0 +1,72 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Didstopia/githubby/internal/state"
+	"github.com/Didstopia/githubby/internal/webhook"
+)
+
+func TestWebhookTasks(t *testing.T) {
+	public := state.NewProfile("public", "org", "Acme", "/tmp/a", false)
+	private := state.NewProfile("private", "org", "acme", "/tmp/b", true)
+	selected := state.NewProfile("selected", "user", "alice", "/tmp/c", true)
+	selected.SelectedRepos = []string{"acme/api", "bob/tools"}
+	other := state.NewProfile("other", "user", "bob", "/tmp/d", true)
+	profiles := []*state.SyncProfile{public, private, selected, other}
+
+	ids := func(tasks []webhook.Task) []string {
+		var ids []string
+		for _, task := range tasks {
+			ids = append(ids, task.ProfileID)
+		}
+		return ids
+	}
+
+	t.Run("public repository", func(t *testing.T) {
+		tasks := webhookTasks(profiles, &webhook.Event{Type: webhook.EventPush, Repo: "acme/web"})
+		assert.Equal(t, []string{public.ID, private.ID}, ids(tasks))
+		assert.Equal(t, "acme/web", tasks[0].Repo)
+	})
+
+	t.Run("private repository", func(t *testing.T) {
+		tasks := webhookTasks(profiles, &webhook.Event{Type: webhook.EventPush, Repo: "acme/web", Private: true})
+		assert.Equal(t, []string{private.ID}, ids(tasks))
+	})
+
+	t.Run("selected repository", func(t *testing.T) {
+		tasks := webhookTasks(profiles, &webhook.Event{Type: webhook.EventPush, Repo: "ACME/api"})
+		assert.Equal(t, []string{public.ID, private.ID, selected.ID}, ids(tasks))
+	})
+
+	t.Run("renamed repository", func(t *testing.T) {
+		event := &webhook.Event{Type: webhook.EventRepository, Action: webhook.ActionRenamed, Repo: "bob/utils", PreviousRepo: "bob/tools"}
+		tasks := webhookTasks(profiles, event)
+		assert.Equal(t, []webhook.Task{{ProfileID: other.ID, Repo: "bob/utils", PreviousRepo: "bob/tools"}}, tasks,
+			"profiles selecting the old name aren't synced")
+	})
+
+	t.Run("unknown owner", func(t *testing.T) {
+		assert.Empty(t, webhookTasks(profiles, &webhook.Event{Type: webhook.EventPush, Repo: "carol/x"}))
+	})
+}
+
+func TestWebhookTasks_Hosts(t *testing.T) {
+	dotcom := state.NewProfile("dotcom", "org", "acme", "/tmp/a", false)
+	enterprise := state.NewProfile("enterprise", "org", "acme", "/tmp/b", false)
+	enterprise.Host = "github.mycompany.com"
+	selected := state.NewProfile("selected", "user", "alice", "/tmp/c", false)
+	selected.Host = "github.mycompany.com"
+	selected.SelectedRepos = []string{"acme/api"}
+	profiles := []*state.SyncProfile{dotcom, enterprise, selected}
+
+	tasks := webhookTasks(profiles, &webhook.Event{Type: webhook.EventPush, Repo: "acme/api"})
+	assert.Equal(t, []webhook.Task{{ProfileID: dotcom.ID, Repo: "acme/api"}}, tasks)
+
+	tasks = webhookTasks(profiles, &webhook.Event{Type: webhook.EventPush, Repo: "acme/api", Host: "github.mycompany.com"})
+	assert.Equal(t, []webhook.Task{{ProfileID: enterprise.ID, Repo: "acme/api"}, {ProfileID: selected.ID, Repo: "acme/api"}}, tasks)
+
+	assert.Empty(t, webhookTasks(profiles, &webhook.Event{Type: webhook.EventPush, Repo: "acme/api", Host: "github.other.com"}))
+}
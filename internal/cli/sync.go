@@ -2,18 +2,22 @@ package cli
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 
 	"github.com/Didstopia/githubby/internal/auth"
-	gherrors "github.com/Didstopia/githubby/internal/errors"
 	gitpkg "github.com/Didstopia/githubby/internal/git"
 	"github.com/Didstopia/githubby/internal/github"
+	"github.com/Didstopia/githubby/internal/lock"
+	"github.com/Didstopia/githubby/internal/metrics"
 	"github.com/Didstopia/githubby/internal/schedule"
 	"github.com/Didstopia/githubby/internal/state"
 	"github.com/Didstopia/githubby/internal/sync"
+	"github.com/Didstopia/githubby/internal/webhook"
 )
 
 var (
@@ -24,10 +28,19 @@ var (
 	syncInclude        []string
 	syncExclude        []string
 	syncSchedule       string
+	syncOnce           bool
 	syncProfile        string
 	syncAllProfiles    bool
+	syncLockPolicy     string
+	syncLockTimeout    time.Duration
+	syncProfilesFile   string
+	syncListen         string
+	syncNoPreflight    bool
 )
 
+// syncMetrics collects metrics for --listen; nil when it isn't set
+var syncMetrics *metrics.Metrics
+
 var syncCmd = &cobra.Command{
 	Use:   "sync",
 	Short: "Sync GitHub repositories locally",
@@ -55,12 +68,27 @@ Examples:
   # Sync all saved profiles
   githubby sync --all-profiles
 
+  # Apply a declarative profiles file, then sync the profiles it declares
+  githubby sync --profiles-file githubby.profiles.yaml
+
   # Schedule recurring sync (cron syntax)
   githubby sync --user <username> --target ~/repos --schedule "0 */6 * * *"
 
   # Schedule profile-based sync
   githubby sync --all-profiles --schedule "@every 30m"
 
+  # Sync a profile once, even if it has a schedule of its own
+  githubby sync --profile "my-profile" --once
+
+  # Serve health checks and Prometheus metrics while syncing on a schedule
+  githubby sync --all-profiles --schedule "@every 1h" --listen :9090
+
+  # Sync without first checking what the token can read
+  githubby sync --all-profiles --no-preflight
+
+  # Skip instead of waiting if another githubby is syncing the same target
+  githubby sync --all-profiles --lock-policy skip
+
   # Dry run
   githubby sync --user <username> --target ~/repos --dry-run`,
 	RunE: runSync,
@@ -82,9 +110,19 @@ func init() {
 	// Profile flags
 	syncCmd.Flags().StringVar(&syncProfile, "profile", "", "Sync using a saved profile")
 	syncCmd.Flags().BoolVar(&syncAllProfiles, "all-profiles", false, "Sync all saved profiles")
+	syncCmd.Flags().StringVar(&syncProfilesFile, "profiles-file", "", "Apply a declarative profiles file and sync the profiles it declares (see \"githubby apply\")")
 
 	// Schedule flag
-	syncCmd.Flags().StringVar(&syncSchedule, "schedule", "", "Cron expression for recurring sync (e.g., \"0 */6 * * *\", \"@every 30m\")")
+	syncCmd.Flags().StringVar(&syncSchedule, "schedule", "", "Cron expression for recurring sync (e.g., \"0 */6 * * *\", \"@every 30m\"); overrides a profile's own schedule")
+	syncCmd.Flags().BoolVar(&syncOnce, "once", false, "Sync a single time, ignoring the profile's schedule")
+	syncCmd.Flags().StringVar(&syncListen, "listen", "", "Serve /healthz, /readyz and Prometheus /metrics on this address while syncing on a schedule (e.g., \":9090\")")
+
+	// Preflight flag
+	syncCmd.Flags().BoolVar(&syncNoPreflight, "no-preflight", false, "Don't check that the token can read what's synced before the first sync")
+
+	// Lock flags
+	syncCmd.Flags().StringVar(&syncLockPolicy, "lock-policy", string(lock.PolicyWait), "What to do when another githubby process is syncing the same target: wait, skip or fail")
+	syncCmd.Flags().DurationVar(&syncLockTimeout, "lock-timeout", 0, "Maximum time to wait for a locked target with --lock-policy wait (0 waits indefinitely)")
 
 	// Mutual exclusivity
 	syncCmd.MarkFlagsMutuallyExclusive("profile", "all-profiles")
@@ -92,6 +130,11 @@ func init() {
 	syncCmd.MarkFlagsMutuallyExclusive("profile", "org")
 	syncCmd.MarkFlagsMutuallyExclusive("all-profiles", "user")
 	syncCmd.MarkFlagsMutuallyExclusive("all-profiles", "org")
+	syncCmd.MarkFlagsMutuallyExclusive("profiles-file", "profile")
+	syncCmd.MarkFlagsMutuallyExclusive("profiles-file", "all-profiles")
+	syncCmd.MarkFlagsMutuallyExclusive("profiles-file", "user")
+	syncCmd.MarkFlagsMutuallyExclusive("profiles-file", "org")
+	syncCmd.MarkFlagsMutuallyExclusive("once", "schedule")
 
 	// Add to root
 	rootCmd.AddCommand(syncCmd)
@@ -107,7 +150,14 @@ func runSync(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	if _, err := lock.ParsePolicy(syncLockPolicy); err != nil {
+		return err
+	}
+
 	// Dispatch based on mode
+	if syncProfilesFile != "" {
+		return runProfilesFileSync(ctx)
+	}
 	if syncProfile != "" || syncAllProfiles {
 		return runProfileSync(ctx)
 	}
@@ -124,22 +174,22 @@ func runSync(cmd *cobra.Command, args []string) error {
 	}
 
 	if syncSchedule != "" {
-		return runScheduled(ctx, func(ctx context.Context) error {
+		return runScheduled(ctx, syncSchedule, func(ctx context.Context) error {
 			return executeSyncWithFlags(ctx)
 		})
 	}
+	if err := checkListenScheduled(); err != nil {
+		return err
+	}
 
 	return executeSyncWithFlags(ctx)
 }
 
 // runProfileSync handles --profile and --all-profiles modes
 func runProfileSync(ctx context.Context) error {
-	storage, err := state.NewStorage()
+	storage, err := loadStateStorage()
 	if err != nil {
-		return fmt.Errorf("failed to initialize state storage: %w", err)
-	}
-	if err := storage.Load(); err != nil {
-		return fmt.Errorf("failed to load state: %w", err)
+		return err
 	}
 
 	var profiles []*state.SyncProfile
@@ -147,39 +197,82 @@ func runProfileSync(ctx context.Context) error {
 	if syncAllProfiles {
 		profiles = storage.GetProfiles()
 		if len(profiles) == 0 {
-			return fmt.Errorf("no sync profiles found; create one using the interactive TUI first")
+			return fmt.Errorf("no sync profiles found; create one with \"githubby profile create\" or the interactive TUI first")
 		}
 		fmt.Printf("Syncing %d profile(s)\n", len(profiles))
 	} else {
-		profile := storage.GetProfileByName(syncProfile)
-		if profile == nil {
-			// List available profiles in error message
-			available := storage.GetProfiles()
-			if len(available) == 0 {
-				return fmt.Errorf("profile %q not found; no profiles exist yet", syncProfile)
-			}
-			names := make([]string, len(available))
-			for i, p := range available {
-				names[i] = p.Name
-			}
-			return fmt.Errorf("profile %q not found; available profiles: %s", syncProfile, strings.Join(names, ", "))
+		profile, err := findProfile(storage, syncProfile)
+		if err != nil {
+			return err
 		}
 		profiles = []*state.SyncProfile{profile}
 	}
 
-	if syncSchedule != "" {
-		return runScheduled(ctx, func(ctx context.Context) error {
+	// A single profile follows its own schedule unless overridden
+	spec := syncSchedule
+	if spec == "" && !syncAllProfiles && !syncOnce {
+		spec = profiles[0].Schedule
+	}
+
+	if spec != "" {
+		return runScheduled(ctx, spec, func(ctx context.Context) error {
 			return executeSyncForProfiles(ctx, profiles, storage)
 		})
 	}
+	if err := checkListenScheduled(); err != nil {
+		return err
+	}
 
 	return executeSyncForProfiles(ctx, profiles, storage)
 }
 
+// runProfilesFileSync handles --profiles-file mode. The file is re-applied
+// before every scheduled run, so changes to it are picked up without a restart.
+func runProfilesFileSync(ctx context.Context) error {
+	storage, err := loadStateStorage()
+	if err != nil {
+		return err
+	}
+
+	syncFn := func(ctx context.Context) error {
+		file, err := applyProfilesFile(storage, syncProfilesFile, false, dryRun)
+		if err != nil {
+			return err
+		}
+
+		profiles := make([]*state.SyncProfile, 0, len(file.Profiles))
+		for _, spec := range file.Profiles {
+			profile := storage.GetProfileByName(spec.Name)
+			if profile == nil {
+				// Dry runs don't save new profiles
+				profile = spec.ToProfile()
+			}
+			profiles = append(profiles, profile)
+		}
+
+		return executeSyncForProfiles(ctx, profiles, storage)
+	}
+
+	if syncSchedule != "" {
+		return runScheduled(ctx, syncSchedule, syncFn)
+	}
+	if err := checkListenScheduled(); err != nil {
+		return err
+	}
+
+	return syncFn(ctx)
+}
+
 // executeSyncForProfiles runs sync for each profile, continuing on per-profile errors
 func executeSyncForProfiles(ctx context.Context, profiles []*state.SyncProfile, storage *state.Storage) error {
 	var lastErr error
 
+	if !syncNoPreflight {
+		if due := preflightDue(profiles); len(due) > 0 {
+			preflightProfiles(github.WithRateLimitReserve(ctx), due)
+		}
+	}
+
 	for _, profile := range profiles {
 		if ctx.Err() != nil {
 			return ctx.Err()
@@ -187,7 +280,10 @@ func executeSyncForProfiles(ctx context.Context, profiles []*state.SyncProfile,
 
 		fmt.Printf("\nSyncing profile %q (%s: %s -> %s)\n", profile.Name, profile.Type, profile.Source, profile.TargetDir)
 
-		err := executeSyncForProfile(ctx, profile)
+		err := executeSyncForProfile(ctx, profile, storage)
+		if errors.Is(err, errSyncSkipped) {
+			continue
+		}
 		if err != nil {
 			log.Warnf("Profile %q sync failed: %v", profile.Name, err)
 			lastErr = err
@@ -204,22 +300,47 @@ func executeSyncForProfiles(ctx context.Context, profiles []*state.SyncProfile,
 }
 
 // executeSyncForProfile runs sync for a single profile
-func executeSyncForProfile(ctx context.Context, profile *state.SyncProfile) error {
-	// Resolve token
-	resolvedToken, err := auth.GetToken(ctx, token, "")
-	if err != nil || resolvedToken.Token == "" {
-		return gherrors.NewAuthError()
+func executeSyncForProfile(ctx context.Context, profile *state.SyncProfile, storage *state.Storage) error {
+	return executeProfileSync(ctx, profile, storage, nil)
+}
+
+// executeProfileSync syncs a profile's repositories, or only the repository
+// of task if it's set (moving its local copy first after a rename or transfer)
+func executeProfileSync(ctx context.Context, profile *state.SyncProfile, storage *state.Storage, task *webhook.Task) (err error) {
+	// Leave some of the rate limit for interactive use
+	ctx = github.WithRateLimitReserve(ctx)
+	started := time.Now()
+	var ghClient github.Client
+	var result *sync.Result
+	defer func() {
+		recordSyncRun(ctx, profile.Name, started, result, err, ghClient)
+		notifySyncRun(ctx, profile.Name, started, result, err, profile.Notifications)
+	}()
+
+	// Resolve token for the profile's GitHub instance
+	host := resolveHostname(profile)
+	resolvedToken, err := resolveToken(ctx, profile, host)
+	if err != nil {
+		return err
 	}
-	authToken := resolvedToken.Token
 
-	// Initialize git
-	git, err := gitpkg.New()
+	// Initialize git with the account's token
+	git, err := gitpkg.NewWithToken(resolvedToken.Token)
 	if err != nil {
 		return fmt.Errorf("git initialization failed: %w", err)
 	}
+	git.Host = host
+	git.TokenSource = resolvedToken.Tokens
+	git.SSH = gitpkg.SSHOptions{IdentityFile: profile.SSHKey, KnownHostsFile: profile.SSHKnownHosts}
+
+	release, err := lockSyncTarget(ctx, profile.TargetDir)
+	if err != nil {
+		return err
+	}
+	defer release()
 
 	// Create GitHub client
-	ghClient := github.NewClient(authToken)
+	ghClient = newGitHubClient(resolvedToken, host)
 
 	// Build sync options from profile
 	opts := &sync.Options{
@@ -229,18 +350,42 @@ func executeSyncForProfile(ctx context.Context, profile *state.SyncProfile) erro
 		IncludePrivate: profile.IncludePrivate,
 		DryRun:         dryRun,
 		Verbose:        verbose,
+		Concurrency:    profile.SyncConcurrency(),
+		RepoTimeout:    profile.RepoTimeout,
+		SkipLFS:        profile.SkipLFS,
+		Mirror:         profile.Mirror(),
+		Depth:          profile.CloneDepth(),
+		SSH:            profile.UseSSH(),
+		// The target is swept by the profile's full syncs
+		SkipRecovery: task != nil,
 	}
 
 	syncer := sync.New(ghClient, git, opts)
 
-	var result *sync.Result
 	var syncErr error
 
-	switch profile.Type {
-	case "user":
+	switch {
+	case task != nil:
+		if task.PreviousRepo != "" {
+			moved, moveErr := syncer.MoveLocal(task.PreviousRepo, task.Repo)
+			if moveErr != nil {
+				log.Warnf("Failed to move %s to %s: %v", task.PreviousRepo, task.Repo, moveErr)
+			} else if moved {
+				fmt.Printf("Moved %s to %s\n", task.PreviousRepo, task.Repo)
+			}
+		}
+		fmt.Printf("Syncing repository: %s\n", task.Repo)
+		result, syncErr = syncer.SyncRepos(ctx, []string{task.Repo})
+	case !profile.SyncAllRepos && len(profile.SelectedRepos) > 0:
+		fmt.Printf("Syncing %d selected repositories\n", len(profile.SelectedRepos))
+		result, syncErr = syncer.SyncRepos(ctx, profile.SelectedRepos)
+	case profile.Incremental && (profile.Type == "user" || profile.Type == "org"):
+		fmt.Printf("Syncing changed repositories for %s: %s\n", sourceKind(profile), profile.Source)
+		result, syncErr = syncIncremental(ctx, syncer, profile, storage)
+	case profile.Type == "user":
 		fmt.Printf("Syncing repositories for user: %s\n", profile.Source)
 		result, syncErr = syncer.SyncUserRepos(ctx, profile.Source)
-	case "org":
+	case profile.Type == "org":
 		fmt.Printf("Syncing repositories for organization: %s\n", profile.Source)
 		result, syncErr = syncer.SyncOrgRepos(ctx, profile.Source)
 	default:
@@ -249,30 +394,112 @@ func executeSyncForProfile(ctx context.Context, profile *state.SyncProfile) erro
 
 	printSyncSummary(result)
 
-	if syncErr != nil && (gherrors.IsUnauthorized(syncErr) || gherrors.IsForbidden(syncErr)) {
-		return gherrors.NewExpiredTokenError(auth.FormatTokenSource(resolvedToken.Source))
+	if err := tokenError(resolvedToken, syncErr); err != nil {
+		return err
 	}
 
 	return syncErr
 }
 
+// syncIncremental syncs the repositories with new events since the profile's
+// last sync and saves how far it got. Dry runs don't move the cursor.
+func syncIncremental(ctx context.Context, syncer *sync.Syncer, profile *state.SyncProfile, storage *state.Storage) (*sync.Result, error) {
+	var cursor sync.EventCursor
+	if saved := storage.GetEventCursor(profile); saved != nil {
+		cursor = sync.EventCursor{
+			LastEventID:  saved.LastEventID,
+			ETag:         saved.ETag,
+			LastFullSync: saved.LastFullSync,
+			Retry:        saved.Retry,
+		}
+	}
+
+	result, run, err := syncer.SyncIncremental(ctx, profile.Source, profile.Type == "org", cursor, profile.FullSyncEvery())
+	if err != nil {
+		return result, err
+	}
+	switch {
+	case run.NotModified:
+		fmt.Println("No new events since the last sync")
+	case run.FullReason != "":
+		fmt.Printf("Synced all repositories: %s\n", run.FullReason)
+	default:
+		fmt.Printf("Synced %d repositories with new events\n", len(run.Changed))
+	}
+	if dryRun {
+		return result, nil
+	}
+
+	saved := &state.EventCursor{
+		LastEventID:  run.Cursor.LastEventID,
+		ETag:         run.Cursor.ETag,
+		LastFullSync: run.Cursor.LastFullSync,
+		Retry:        run.Cursor.Retry,
+	}
+	if err := storage.SetEventCursor(profile, saved); err != nil {
+		log.Warnf("Failed to save the sync position of profile %q: %v", profile.Name, err)
+	}
+	return result, nil
+}
+
+// sourceKind names the kind of account a profile syncs from
+func sourceKind(profile *state.SyncProfile) string {
+	if profile.Type == "org" {
+		return "organization"
+	}
+	return "user"
+}
+
 // executeSyncWithFlags runs sync using CLI flag values (existing behavior)
-func executeSyncWithFlags(ctx context.Context) error {
+func executeSyncWithFlags(ctx context.Context) (err error) {
+	// Leave some of the rate limit for interactive use
+	ctx = github.WithRateLimitReserve(ctx)
+	started := time.Now()
+	var ghClient github.Client
+	var result *sync.Result
+	skipped := false
+	defer func() {
+		if !skipped {
+			recordSyncRun(ctx, flagSyncName(), started, result, err, ghClient)
+			notifySyncRun(ctx, flagSyncName(), started, result, err, nil)
+		}
+	}()
+
 	// Get token using auth resolution (flag > env > stored)
-	resolvedToken, err := auth.GetToken(ctx, token, "")
-	if err != nil || resolvedToken.Token == "" {
-		return gherrors.NewAuthError()
+	host := resolveHostname(nil)
+	owner := syncUser
+	if owner == "" {
+		owner = syncOrg
+	}
+	resolvedToken, err := resolveToken(auth.WithOwner(ctx, owner), nil, host)
+	if err != nil {
+		return err
 	}
-	authToken := resolvedToken.Token
 
-	// Initialize git
-	git, err := gitpkg.New()
+	// Initialize git with the account's token
+	git, err := gitpkg.NewWithToken(resolvedToken.Token)
 	if err != nil {
 		return fmt.Errorf("git initialization failed: %w", err)
 	}
+	git.Host = host
+	git.TokenSource = resolvedToken.Tokens
+
+	release, err := lockSyncTarget(ctx, syncTarget)
+	if errors.Is(err, errSyncSkipped) {
+		skipped = true
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer release()
 
 	// Create GitHub client
-	ghClient := github.NewClient(authToken)
+	ghClient = newGitHubClient(resolvedToken, host)
+
+	if !syncNoPreflight && firstPreflight("flags") {
+		preflightFlagSync(ctx, ghClient, resolvedToken)
+	}
 
 	// Create sync options
 	opts := &sync.Options{
@@ -288,7 +515,6 @@ func executeSyncWithFlags(ctx context.Context) error {
 	syncer := sync.New(ghClient, git, opts)
 
 	// Determine what to sync
-	var result *sync.Result
 	var syncErr error
 
 	if syncUser != "" {
@@ -302,26 +528,142 @@ func executeSyncWithFlags(ctx context.Context) error {
 	// Print summary
 	printSyncSummary(result)
 
-	if syncErr != nil && (gherrors.IsUnauthorized(syncErr) || gherrors.IsForbidden(syncErr)) {
-		return gherrors.NewExpiredTokenError(auth.FormatTokenSource(resolvedToken.Source))
+	if err := tokenError(resolvedToken, syncErr); err != nil {
+		return err
 	}
 
 	return syncErr
 }
 
-// runScheduled wraps a sync function in a cron scheduler
-func runScheduled(ctx context.Context, syncFn func(ctx context.Context) error) error {
-	fmt.Printf("Starting scheduled sync with schedule: %s\n", syncSchedule)
-	fmt.Println("Press Ctrl+C to stop")
+// errSyncSkipped is returned by lockSyncTarget when a locked target is skipped
+var errSyncSkipped = errors.New("sync skipped: target is locked")
+
+// lockSyncTarget takes the cross-process lock on a target directory according
+// to --lock-policy. Returns errSyncSkipped if the target is locked and the
+// policy is to skip it. Dry runs don't touch the target and are never locked.
+func lockSyncTarget(ctx context.Context, target string) (release func(), err error) {
+	noop := func() {}
+	if dryRun {
+		return noop, nil
+	}
+
+	policy, err := lock.ParsePolicy(syncLockPolicy)
+	if err != nil {
+		return noop, err
+	}
 
-	scheduler, err := schedule.New(syncSchedule, syncFn)
+	lockCtx := ctx
+	if policy == lock.PolicyWait && syncLockTimeout > 0 {
+		var cancel context.CancelFunc
+		lockCtx, cancel = context.WithTimeout(ctx, syncLockTimeout)
+		defer cancel()
+	}
+
+	l, err := sync.LockTarget(lockCtx, target, policy, func(holder *lock.Holder) {
+		fmt.Printf("Target %s is locked by %s; waiting...\n", target, holder)
+	})
+	if err != nil {
+		if errors.Is(err, lock.ErrLocked) && policy == lock.PolicySkip {
+			fmt.Printf("Skipping %s: locked by %s\n", target, lock.HolderOf(err))
+			return noop, errSyncSkipped
+		}
+		return noop, err
+	}
+
+	if l.Stale != nil {
+		log.Warnf("Recovered stale lock on %s left by %s", target, l.Stale)
+	}
+
+	return func() { _ = l.Release() }, nil
+}
+
+// runScheduled wraps a sync function in a cron scheduler
+func runScheduled(ctx context.Context, spec string, syncFn func(ctx context.Context) error) error {
+	scheduler, err := schedule.New(spec, syncFn)
 	if err != nil {
 		return err
 	}
 
+	if err := startMetrics(ctx); err != nil {
+		return err
+	}
+
+	fmt.Printf("Starting scheduled sync with schedule: %s\n", spec)
+	fmt.Println("Press Ctrl+C to stop")
+
+	setSchedulerUp(true)
+	defer setSchedulerUp(false)
 	return scheduler.Run(ctx)
 }
 
+// checkListenScheduled rejects --listen for one-off syncs, which exit
+// before anything could scrape them
+func checkListenScheduled() error {
+	if syncListen != "" {
+		return fmt.Errorf("--listen only applies to scheduled syncs (use --schedule or \"githubby daemon\")")
+	}
+	return nil
+}
+
+// startMetrics starts the health and metrics listener if --listen is set
+func startMetrics(ctx context.Context) error {
+	if syncListen == "" {
+		return nil
+	}
+
+	syncMetrics = metrics.New()
+	if _, err := syncMetrics.Start(ctx, syncListen); err != nil {
+		syncMetrics = nil
+		return err
+	}
+	return nil
+}
+
+// setSchedulerUp records whether the scheduler is running in the metrics
+func setSchedulerUp(up bool) {
+	if syncMetrics != nil {
+		syncMetrics.SetSchedulerUp(up)
+	}
+}
+
+// flagSyncName names a sync configured with --user or --org in the metrics
+func flagSyncName() string {
+	if syncUser != "" {
+		return "user:" + syncUser
+	}
+	return "org:" + syncOrg
+}
+
+// recordSyncRun records a finished sync in the metrics, if they're enabled.
+// A run succeeds if it didn't fail as a whole; failed repositories are
+// counted separately.
+func recordSyncRun(ctx context.Context, name string, started time.Time, result *sync.Result, err error, ghClient github.Client) {
+	if syncMetrics == nil || errors.Is(err, errSyncSkipped) {
+		return
+	}
+
+	run := metrics.Run{Profile: name, Started: started, Finished: time.Now(), Err: err}
+	if result != nil {
+		run.Repos = map[string]int{
+			metrics.StatusCloned:   len(result.Cloned),
+			metrics.StatusUpdated:  len(result.Updated),
+			metrics.StatusUpToDate: len(result.UpToDate),
+			metrics.StatusSkipped:  len(result.Skipped),
+			metrics.StatusFailed:   len(result.Failed),
+			metrics.StatusArchived: len(result.Archived),
+		}
+		run.BytesFetched = result.BytesFetched
+	}
+	syncMetrics.RecordRun(run)
+
+	// Checking the rate limit doesn't count against it
+	if ghClient != nil {
+		if limits, err := ghClient.GetRateLimit(ctx); err == nil && limits.GetCore() != nil {
+			syncMetrics.SetRateLimitRemaining(limits.GetCore().Remaining)
+		}
+	}
+}
+
 func printSyncSummary(result *sync.Result) {
 	if result == nil {
 		return
@@ -1,10 +1,17 @@
 package cli
 
 import (
+	"context"
+	"errors"
+	"strings"
 	"testing"
+	"time"
 
+	gh "github.com/google/go-github/v68/github"
 	"github.com/stretchr/testify/assert"
 
+	"github.com/Didstopia/githubby/internal/github"
+	"github.com/Didstopia/githubby/internal/metrics"
 	"github.com/Didstopia/githubby/internal/schedule"
 	"github.com/Didstopia/githubby/internal/state"
 	synpkg "github.com/Didstopia/githubby/internal/sync"
@@ -176,3 +183,42 @@ func TestProfileSyncSetsOpts(t *testing.T) {
 	assert.Equal(t, []string{"*-archive"}, opts.Exclude)
 	assert.True(t, opts.IncludePrivate)
 }
+
+func TestRecordSyncRun(t *testing.T) {
+	syncMetrics = metrics.New()
+	t.Cleanup(func() { syncMetrics = nil })
+
+	ghClient := github.NewMockClient()
+	ghClient.GetRateLimitFunc = func(ctx context.Context) (*gh.RateLimits, error) {
+		return &gh.RateLimits{Core: &gh.Rate{Remaining: 4321}}, nil
+	}
+
+	result := synpkg.NewResult()
+	result.Cloned = []string{"acme/one"}
+	result.Failed["acme/two"] = errors.New("boom")
+	result.BytesFetched = 1024
+
+	recordSyncRun(context.Background(), "work", time.Now(), result, nil, ghClient)
+	recordSyncRun(context.Background(), "home", time.Now(), nil, errors.New("not authenticated"), nil)
+	recordSyncRun(context.Background(), "locked", time.Now(), nil, errSyncSkipped, nil)
+
+	var b strings.Builder
+	syncMetrics.WriteText(&b)
+	out := b.String()
+
+	assert.Contains(t, out, `githubby_repos_synced_total{profile="work",status="cloned"} 1`)
+	assert.Contains(t, out, `githubby_repos_synced_total{profile="work",status="failed"} 1`)
+	assert.Contains(t, out, `githubby_sync_runs_total{profile="work",result="success"} 1`)
+	assert.Contains(t, out, `githubby_sync_runs_total{profile="home",result="failure"} 1`)
+	assert.Contains(t, out, `githubby_fetched_bytes_total{profile="work"} 1024`)
+	assert.Contains(t, out, "githubby_rate_limit_remaining 4321")
+	assert.NotContains(t, out, "locked", "skipped syncs aren't runs")
+}
+
+func TestCheckListenScheduled(t *testing.T) {
+	assert.NoError(t, checkListenScheduled())
+
+	syncListen = ":9090"
+	t.Cleanup(func() { syncListen = "" })
+	assert.ErrorContains(t, checkListenScheduled(), "only applies to scheduled syncs")
+}
@@ -7,6 +7,9 @@ import (
 
 	"github.com/mitchellh/go-homedir"
 	"gopkg.in/yaml.v3"
+
+	"github.com/Didstopia/githubby/internal/network"
+	"github.com/Didstopia/githubby/internal/notify"
 )
 
 const (
@@ -23,6 +26,11 @@ type Config struct {
 	DryRun  bool   `yaml:"dry-run"`
 	Token   string `yaml:"token"`
 
+	// TokenCommand prints the GitHub token (e.g. "gh auth token"); its
+	// output is reused for TokenCommandTTL (default 5m)
+	TokenCommand    string `yaml:"token_command,omitempty"`
+	TokenCommandTTL string `yaml:"token_command_ttl,omitempty"`
+
 	// Clean command
 	Repository  string `yaml:"repository"`
 	FilterDays  int    `yaml:"filter-days"`
@@ -35,6 +43,12 @@ type Config struct {
 	IncludePrivate bool     `yaml:"include-private"`
 	Include        []string `yaml:"include"`
 	Exclude        []string `yaml:"exclude"`
+
+	// Notifications are sent after every sync
+	Notifications notify.Config `yaml:"notifications,omitempty"`
+
+	// Network holds the proxy and TLS settings of API requests and git
+	Network network.Config `yaml:"network,omitempty"`
 }
 
 // DefaultConfig returns a new Config with default values
@@ -130,5 +144,14 @@ func (c *Config) Clone() *Config {
 		clone.Exclude = make([]string, len(c.Exclude))
 		copy(clone.Exclude, c.Exclude)
 	}
+	clone.Notifications.Targets = notify.CloneTargets(c.Notifications.Targets)
+	if c.Notifications.SMTP != nil {
+		smtp := *c.Notifications.SMTP
+		clone.Notifications.SMTP = &smtp
+	}
+	if c.Network.NoProxy != nil {
+		clone.Network.NoProxy = make([]string, len(c.Network.NoProxy))
+		copy(clone.Network.NoProxy, c.Network.NoProxy)
+	}
 	return &clone
 }
@@ -157,3 +157,82 @@ func TestLoadFrom_InvalidYAML(t *testing.T) {
 		t.Error("expected error for invalid YAML")
 	}
 }
+
+func TestLoadFrom_Notifications(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "notify.yaml")
+
+	data := `user: testuser
+notifications:
+  targets:
+    - type: slack
+      url: https://hooks.slack.com/services/T0/B0/secret
+    - type: email
+      to: [ops@example.com]
+      on: always
+  smtp:
+    host: smtp.example.com
+    from: githubby@example.com
+`
+	if err := os.WriteFile(configPath, []byte(data), 0600); err != nil {
+		t.Fatalf("failed to write test file: %v", err)
+	}
+
+	loaded, err := LoadFrom(configPath)
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+
+	if len(loaded.Notifications.Targets) != 2 {
+		t.Fatalf("expected 2 notification targets, got %d", len(loaded.Notifications.Targets))
+	}
+	if loaded.Notifications.Targets[1].On != "always" {
+		t.Errorf("trigger mismatch: got %q, want %q", loaded.Notifications.Targets[1].On, "always")
+	}
+	if loaded.Notifications.SMTP == nil || loaded.Notifications.SMTP.Host != "smtp.example.com" {
+		t.Errorf("SMTP settings not loaded: %+v", loaded.Notifications.SMTP)
+	}
+	if err := loaded.Notifications.Validate(); err != nil {
+		t.Errorf("notifications should be valid: %v", err)
+	}
+
+	// Cloning deep copies the targets
+	clone := loaded.Clone()
+	clone.Notifications.Targets[1].To[0] = "dev@example.com"
+	clone.Notifications.SMTP.Host = "mail.example.com"
+	if loaded.Notifications.Targets[1].To[0] != "ops@example.com" || loaded.Notifications.SMTP.Host != "smtp.example.com" {
+		t.Error("notifications should be deep copied")
+	}
+}
+
+func TestLoadFrom_Network(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "network.yaml")
+
+	data := `network:
+  proxy: http://proxy.example.com:3128
+  no_proxy: [github.example.com, 10.0.0.0/8]
+  insecure_skip_verify: true
+`
+	if err := os.WriteFile(configPath, []byte(data), 0600); err != nil {
+		t.Fatalf("failed to write test file: %v", err)
+	}
+
+	loaded, err := LoadFrom(configPath)
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	if loaded.Network.Proxy != "http://proxy.example.com:3128" || !loaded.Network.InsecureSkipVerify {
+		t.Errorf("network settings not loaded: %+v", loaded.Network)
+	}
+	if len(loaded.Network.NoProxy) != 2 {
+		t.Fatalf("expected 2 no-proxy entries, got %d", len(loaded.Network.NoProxy))
+	}
+
+	// Cloning deep copies the no-proxy list
+	clone := loaded.Clone()
+	clone.Network.NoProxy[0] = "other.example.com"
+	if loaded.Network.NoProxy[0] != "github.example.com" {
+		t.Error("network settings should be deep copied")
+	}
+}
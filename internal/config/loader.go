@@ -81,10 +81,26 @@ func (l *Loader) IsSet(key string) bool {
 	return l.viper.IsSet(key)
 }
 
+// NoConfigAnnotation marks a flag that must only be set on the command line.
+// Use it for flags that share a name with a config key but mean something
+// else, e.g. the profile commands' --user, which must not pick up the
+// sync default from the config file.
+const NoConfigAnnotation = "githubby_no_config"
+
+// SkipConfig marks the named flags of cmd with NoConfigAnnotation
+func SkipConfig(cmd *cobra.Command, names ...string) {
+	for _, name := range names {
+		_ = cmd.Flags().SetAnnotation(name, NoConfigAnnotation, []string{"true"})
+	}
+}
+
 // InjectToCommand injects viper config values into command flags
 // that weren't explicitly set via command line
 func (l *Loader) InjectToCommand(cmd *cobra.Command) {
 	cmd.Flags().VisitAll(func(f *pflag.Flag) {
+		if _, skip := f.Annotations[NoConfigAnnotation]; skip {
+			return
+		}
 		if !f.Changed && l.viper.IsSet(f.Name) {
 			cmd.Flags().Set(f.Name, l.viper.GetString(f.Name))
 		}
@@ -140,6 +140,21 @@ func TestLoader_InjectToCommand(t *testing.T) {
 		assert.Equal(t, "value1", result1)
 		assert.Equal(t, "value2", result2)
 	})
+
+	t.Run("skips flags marked with SkipConfig", func(t *testing.T) {
+		loader := NewLoader()
+		loader.SetDefault("user", "config-user")
+
+		cmd := &cobra.Command{}
+		cmd.Flags().String("user", "", "test flag")
+		SkipConfig(cmd, "user")
+
+		loader.InjectToCommand(cmd)
+
+		result, _ := cmd.Flags().GetString("user")
+		assert.Equal(t, "", result)
+		assert.False(t, cmd.Flags().Changed("user"))
+	})
 }
 
 func TestLoader_Viper(t *testing.T) {
@@ -41,6 +41,21 @@ Or provide a token:
 	}
 }
 
+// NewAccountAuthError creates an authentication error for a named account
+// that isn't logged in
+func NewAccountAuthError(account, hostname string) *AuthError {
+	login := "githubby login --account " + account
+	if hostname != "" && hostname != "github.com" {
+		login += " --hostname " + hostname
+	}
+	return &AuthError{
+		Message: fmt.Sprintf(`not logged in to account %q
+
+To log in:
+  %s`, account, login),
+	}
+}
+
 // ValidationError represents an input validation error
 type ValidationError struct {
 	Field   string
@@ -138,3 +153,55 @@ To fix this:
   2. githubby login`, source),
 	}
 }
+
+// SSOError is a 403 from an organization that enforces SAML single sign-on,
+// for a token that hasn't been authorized for it. It matches ErrForbidden.
+type SSOError struct {
+	// URL is where the token can be authorized, if GitHub said so
+	URL string
+}
+
+func (e *SSOError) Error() string {
+	if e.URL != "" {
+		return fmt.Sprintf("%v: the organization requires SAML SSO; authorize the token at %s", ErrForbidden, e.URL)
+	}
+	return fmt.Sprintf("%v: the organization requires SAML SSO; authorize the token for it", ErrForbidden)
+}
+
+func (e *SSOError) Unwrap() error {
+	return ErrForbidden
+}
+
+// IsSSORequired checks if the error is a SAML SSO authorization error
+func IsSSORequired(err error) bool {
+	var ssoErr *SSOError
+	return errors.As(err, &ssoErr)
+}
+
+// NewTokenError creates an auth error for a token (from source) that GitHub
+// rejected with err, saying why instead of assuming the token expired
+func NewTokenError(source string, err error) *AuthError {
+	var ssoErr *SSOError
+	switch {
+	case errors.As(err, &ssoErr):
+		authorize := "  Authorize the token for the organization's SAML single sign-on"
+		if ssoErr.URL != "" {
+			authorize += ":\n  " + ssoErr.URL
+		}
+		return &AuthError{
+			Message: fmt.Sprintf(`token from %s isn't authorized for an organization that requires SAML SSO
+
+To fix this:
+%s`, source, authorize),
+		}
+	case IsForbidden(err):
+		return &AuthError{
+			Message: fmt.Sprintf(`token from %s lacks permission: %v
+
+To see which scopes, organizations and repositories it can't access:
+  githubby auth status --check`, source, err),
+		}
+	default:
+		return NewExpiredTokenError(source)
+	}
+}
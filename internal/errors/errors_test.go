@@ -271,6 +271,21 @@ func TestNewAuthErrorWithReason(t *testing.T) {
 	}
 }
 
+func TestNewAccountAuthError(t *testing.T) {
+	err := NewAccountAuthError("work", "github.com")
+	if !strings.Contains(err.Error(), `account "work"`) {
+		t.Error("expected error to name the account")
+	}
+	if !strings.HasSuffix(err.Error(), "githubby login --account work") {
+		t.Error("expected error to contain login instruction for the account")
+	}
+
+	err = NewAccountAuthError("work", "github.mycompany.com")
+	if !strings.Contains(err.Error(), "githubby login --account work --hostname github.mycompany.com") {
+		t.Error("expected error to contain the hostname")
+	}
+}
+
 func TestNewExpiredTokenError(t *testing.T) {
 	err := NewExpiredTokenError("keychain")
 	if !strings.Contains(err.Error(), "keychain") {
@@ -283,3 +298,54 @@ func TestNewExpiredTokenError(t *testing.T) {
 		t.Error("expected error to contain logout instruction")
 	}
 }
+
+func TestSSOError(t *testing.T) {
+	err := fmt.Errorf("listing repos: %w", &SSOError{URL: "https://github.com/orgs/acme/sso?authorization_request=1"})
+	if !IsSSORequired(err) {
+		t.Error("expected IsSSORequired to be true")
+	}
+	if !IsForbidden(err) {
+		t.Error("expected an SSO error to be forbidden")
+	}
+	if IsSSORequired(ErrForbidden) {
+		t.Error("expected IsSSORequired to be false for other 403s")
+	}
+}
+
+func TestNewTokenError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected []string
+	}{
+		{
+			name:     "sso",
+			err:      &SSOError{URL: "https://github.com/orgs/acme/sso"},
+			expected: []string{"SAML SSO", "https://github.com/orgs/acme/sso"},
+		},
+		{
+			name:     "forbidden",
+			err:      fmt.Errorf("%w: Resource not accessible by personal access token", ErrForbidden),
+			expected: []string{"lacks permission", "Resource not accessible", "githubby auth status --check"},
+		},
+		{
+			name:     "unauthorized",
+			err:      ErrUnauthorized,
+			expected: []string{"invalid or expired", "githubby login"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			message := NewTokenError("keychain", tt.err).Error()
+			if !strings.Contains(message, "token from keychain") {
+				t.Errorf("expected %q to name the token source", message)
+			}
+			for _, expected := range tt.expected {
+				if !strings.Contains(message, expected) {
+					t.Errorf("expected %q to contain %q", message, expected)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// SSHOptions configures how git connects to remotes over SSH
+type SSHOptions struct {
+	// IdentityFile is the private key to authenticate with, such as a
+	// deploy key (empty = ssh's default keys and agent)
+	IdentityFile string
+
+	// KnownHostsFile is the known_hosts file host keys are checked against
+	// (empty = ssh's default)
+	KnownHostsFile string
+}
+
+// command returns the GIT_SSH_COMMAND that applies the options, building on
+// the one in the environment, or empty if no options are set
+func (o SSHOptions) command() string {
+	if o.IdentityFile == "" && o.KnownHostsFile == "" {
+		return ""
+	}
+
+	command := os.Getenv("GIT_SSH_COMMAND")
+	if command == "" {
+		command = "ssh"
+	}
+	args := []string{command}
+	if o.IdentityFile != "" {
+		// Offer only this key, so the agent's keys don't shadow a deploy key
+		args = append(args, "-i", shellQuote(o.IdentityFile), "-o", "IdentitiesOnly=yes")
+	}
+	if o.KnownHostsFile != "" {
+		// Unknown hosts fail instead of prompting
+		args = append(args, "-o", shellQuote("UserKnownHostsFile="+o.KnownHostsFile), "-o", "StrictHostKeyChecking=yes")
+	}
+	return strings.Join(args, " ")
+}
+
+// shellQuote quotes s for the shell git runs GIT_SSH_COMMAND with
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// IsSSHURL reports whether url is an SSH remote URL, either ssh:// or the
+// scp-like git@host:owner/repo form
+func IsSSHURL(url string) bool {
+	if strings.HasPrefix(strings.ToLower(url), "ssh://") {
+		return true
+	}
+	if strings.Contains(url, "://") {
+		return false
+	}
+	// scp-like URLs have a colon before the first slash
+	colon := strings.Index(url, ":")
+	slash := strings.Index(url, "/")
+	return colon > 0 && (slash == -1 || colon < slash)
+}
+
+// SetRemoteURL points the origin remote of a repository at url. HTTPS URLs
+// are authenticated like clones.
+func (g *Git) SetRemoteURL(ctx context.Context, repoDir, url string) error {
+	cmd := exec.CommandContext(ctx, g.GitPath, "-C", repoDir, "remote", "set-url", "origin", g.authenticateURL(url))
+	if output, err := cmd.CombinedOutput(); err != nil {
+		if msg := strings.TrimSpace(string(output)); msg != "" {
+			return fmt.Errorf("failed to set remote URL: %s", msg)
+		}
+		return fmt.Errorf("failed to set remote URL: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,367 @@
+package github
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultCacheMaxSize is the default limit for all cached responses
+	DefaultCacheMaxSize int64 = 100 << 20
+
+	// cacheDirName is the cache directory inside the GitHubby config directory
+	cacheDirName = "cache/http"
+
+	// cacheEntryExt is the file extension of cached responses
+	cacheEntryExt = ".json"
+
+	// cacheHeader marks responses served from the cache
+	cacheHeader = "X-Githubby-Cache"
+)
+
+// HTTPCache stores GitHub API responses on disk and revalidates them with
+// conditional requests. GitHub doesn't count requests answered with
+// 304 Not Modified against the rate limit, so unchanged listings are free.
+//
+// Responses are keyed by URL and token, so accounts never see each other's
+// responses. When the cache grows past its size limit, the least recently
+// used responses are removed.
+type HTTPCache struct {
+	dir     string
+	maxSize int64
+
+	// Guards pruning; entries are written atomically so other processes
+	// sharing the directory only ever see complete files
+	mu sync.Mutex
+}
+
+// cacheEntry is a cached response
+type cacheEntry struct {
+	URL        string      `json:"url"`
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header"`
+	Body       []byte      `json:"body"`
+	Stored     time.Time   `json:"stored"`
+}
+
+// NewHTTPCache creates a cache in dir that holds up to maxSize bytes
+// (DefaultCacheMaxSize if maxSize <= 0)
+func NewHTTPCache(dir string, maxSize int64) *HTTPCache {
+	if maxSize <= 0 {
+		maxSize = DefaultCacheMaxSize
+	}
+	return &HTTPCache{dir: dir, maxSize: maxSize}
+}
+
+// DefaultCacheDir returns the default cache directory (~/.githubby/cache/http)
+func DefaultCacheDir() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, ".githubby", filepath.FromSlash(cacheDirName)), nil
+}
+
+// Dir returns the cache directory
+func (c *HTTPCache) Dir() string {
+	return c.dir
+}
+
+// Transport returns a RoundTripper that serves requests through the cache,
+// sending them with base (http.DefaultTransport if nil). It must see the
+// Authorization header, so it goes below the transport that adds it.
+func (c *HTTPCache) Transport(base http.RoundTripper) http.RoundTripper {
+	return &cacheTransport{cache: c, base: base}
+}
+
+// Stats returns the number and total size of cached responses
+func (c *HTTPCache) Stats() (entries int, size int64, err error) {
+	files, err := c.entries()
+	for _, file := range files {
+		entries++
+		size += file.size
+	}
+	return entries, size, err
+}
+
+// Clear removes every cached response and returns how many were removed and
+// their total size
+func (c *HTTPCache) Clear() (entries int, size int64, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	files, err := c.entries()
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, file := range files {
+		if err := os.Remove(file.path); err != nil && !os.IsNotExist(err) {
+			return entries, size, fmt.Errorf("failed to remove cached response: %w", err)
+		}
+		entries++
+		size += file.size
+	}
+	return entries, size, nil
+}
+
+// cacheKey identifies the response to req. Responses differ by token and by
+// the media type asked for, so both are part of the key.
+func cacheKey(req *http.Request) string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{
+		req.Header.Get("Authorization"),
+		req.URL.String(),
+		req.Header.Get("Accept"),
+	}, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+// path returns the file of the response with key
+func (c *HTTPCache) path(key string) string {
+	return filepath.Join(c.dir, key+cacheEntryExt)
+}
+
+// load returns the cached response with key, or nil if there is none
+func (c *HTTPCache) load(key string) *cacheEntry {
+	data, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return nil
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		// Left by an older version or damaged; it's replaced on the next store
+		return nil
+	}
+	return &entry
+}
+
+// touch marks the response with key as recently used
+func (c *HTTPCache) touch(key string) {
+	now := time.Now()
+	_ = os.Chtimes(c.path(key), now, now)
+}
+
+// store saves a response and prunes the cache if it grew too large
+func (c *HTTPCache) store(key string, entry *cacheEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if int64(len(data)) > c.maxSize/10 {
+		// A single response shouldn't push most of the cache out
+		return nil
+	}
+
+	if err := os.MkdirAll(c.dir, 0700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(c.dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), c.path(key)); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	return c.prune()
+}
+
+// cacheFile is a cached response on disk
+type cacheFile struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// entries lists the cached responses
+func (c *HTTPCache) entries() ([]cacheFile, error) {
+	dirEntries, err := os.ReadDir(c.dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cache directory: %w", err)
+	}
+
+	var files []cacheFile
+	for _, dirEntry := range dirEntries {
+		if dirEntry.IsDir() || !strings.HasSuffix(dirEntry.Name(), cacheEntryExt) {
+			continue
+		}
+		info, err := dirEntry.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, cacheFile{
+			path:    filepath.Join(c.dir, dirEntry.Name()),
+			size:    info.Size(),
+			modTime: info.ModTime(),
+		})
+	}
+	return files, nil
+}
+
+// prune removes the least recently used responses until the cache is below
+// its size limit
+func (c *HTTPCache) prune() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	files, err := c.entries()
+	if err != nil {
+		return err
+	}
+	var total int64
+	for _, file := range files {
+		total += file.size
+	}
+	if total <= c.maxSize {
+		return nil
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].modTime.Before(files[j].modTime) })
+	// Make room for more than one response so the next store doesn't prune again
+	target := c.maxSize * 9 / 10
+	for _, file := range files {
+		if total <= target {
+			break
+		}
+		if err := os.Remove(file.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		total -= file.size
+	}
+	return nil
+}
+
+// cacheTransport serves GET requests from an HTTPCache
+type cacheTransport struct {
+	cache *HTTPCache
+	base  http.RoundTripper
+}
+
+// RoundTrip sends req, revalidating a cached response if there is one
+func (t *cacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	// Requests the caller revalidates itself are passed through untouched
+	if req.Method != http.MethodGet || req.Header.Get("Range") != "" ||
+		req.Header.Get("If-None-Match") != "" || req.Header.Get("If-Modified-Since") != "" {
+		return base.RoundTrip(req)
+	}
+
+	key := cacheKey(req)
+	cached := t.cache.load(key)
+	if cached != nil {
+		req = req.Clone(req.Context())
+		if etag := cached.Header.Get("ETag"); etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		if modified := cached.Header.Get("Last-Modified"); modified != "" {
+			req.Header.Set("If-Modified-Since", modified)
+		}
+	}
+
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		resp.Body.Close()
+		t.cache.touch(key)
+		return cached.response(req, resp), nil
+	}
+
+	if cacheable(resp) {
+		return t.storeResponse(key, resp), nil
+	}
+	return resp, nil
+}
+
+// cacheable reports whether resp can be stored and revalidated later
+func cacheable(resp *http.Response) bool {
+	if resp.StatusCode != http.StatusOK {
+		return false
+	}
+	if resp.Header.Get("ETag") == "" && resp.Header.Get("Last-Modified") == "" {
+		return false
+	}
+	return !strings.Contains(strings.ToLower(resp.Header.Get("Cache-Control")), "no-store")
+}
+
+// storeResponse stores resp and returns it with its body intact. Bodies
+// larger than the cache allows are streamed to the caller without storing.
+func (t *cacheTransport) storeResponse(key string, resp *http.Response) *http.Response {
+	limit := t.cache.maxSize / 10
+	reader := bufio.NewReader(resp.Body)
+	body, err := io.ReadAll(io.LimitReader(reader, limit+1))
+	if err != nil || int64(len(body)) > limit {
+		resp.Body = readCloser{io.MultiReader(bytes.NewReader(body), reader), resp.Body}
+		return resp
+	}
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	// Failing to cache only costs a full request next time
+	_ = t.cache.store(key, &cacheEntry{
+		URL:        resp.Request.URL.String(),
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header.Clone(),
+		Body:       body,
+		Stored:     time.Now(),
+	})
+	return resp
+}
+
+// response rebuilds the cached response to req. Headers of the 304, such as
+// the current rate limit, replace the cached ones.
+func (e *cacheEntry) response(req *http.Request, notModified *http.Response) *http.Response {
+	header := e.Header.Clone()
+	for name, values := range notModified.Header {
+		header[name] = values
+	}
+	header.Set(cacheHeader, "hit")
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode)),
+		StatusCode:    e.StatusCode,
+		Proto:         notModified.Proto,
+		ProtoMajor:    notModified.ProtoMajor,
+		ProtoMinor:    notModified.ProtoMinor,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+		Request:       req,
+	}
+}
+
+// readCloser reads from one reader and closes another
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
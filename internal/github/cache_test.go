@@ -0,0 +1,168 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// cacheServer serves body with an ETag and answers matching conditional
+// requests with 304s, counting full responses
+func cacheServer(t *testing.T, body *string, header http.Header) (*httptest.Server, *int32) {
+	var full int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		etag := fmt.Sprintf(`"%x"`, len(*body)) + r.Header.Get("Authorization")
+		w.Header().Set("X-RateLimit-Remaining", "4999")
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		atomic.AddInt32(&full, 1)
+		for name, values := range header {
+			w.Header()[name] = values
+		}
+		w.Header().Set("ETag", etag)
+		fmt.Fprint(w, *body)
+	}))
+	t.Cleanup(server.Close)
+	return server, &full
+}
+
+// get requests url through transport with token
+func get(t *testing.T, transport http.RoundTripper, url, token string) (*http.Response, string) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := transport.RoundTrip(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp, string(body)
+}
+
+func TestHTTPCache(t *testing.T) {
+	t.Run("serves 304s from the cache", func(t *testing.T) {
+		body := `[{"name":"api"}]`
+		server, full := cacheServer(t, &body, nil)
+		cache := NewHTTPCache(t.TempDir(), 0)
+		transport := cache.Transport(nil)
+
+		resp, got := get(t, transport, server.URL, "a")
+		assert.Equal(t, body, got)
+		assert.Empty(t, resp.Header.Get(cacheHeader))
+
+		resp, got = get(t, transport, server.URL, "a")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, body, got)
+		assert.Equal(t, "hit", resp.Header.Get(cacheHeader))
+		assert.Equal(t, "4999", resp.Header.Get("X-RateLimit-Remaining"))
+		assert.Equal(t, int32(1), atomic.LoadInt32(full))
+
+		body = `[{"name":"api"},{"name":"web"}]`
+		_, got = get(t, transport, server.URL, "a")
+		assert.Equal(t, body, got)
+		assert.Equal(t, int32(2), atomic.LoadInt32(full))
+	})
+
+	t.Run("keys responses by token", func(t *testing.T) {
+		body := `{"private":true}`
+		server, full := cacheServer(t, &body, nil)
+		transport := NewHTTPCache(t.TempDir(), 0).Transport(nil)
+
+		get(t, transport, server.URL, "a")
+		resp, _ := get(t, transport, server.URL, "b")
+		assert.Empty(t, resp.Header.Get(cacheHeader))
+		assert.Equal(t, int32(2), atomic.LoadInt32(full))
+	})
+
+	t.Run("does not store no-store responses", func(t *testing.T) {
+		body := `{}`
+		server, full := cacheServer(t, &body, http.Header{"Cache-Control": {"no-store"}})
+		cache := NewHTTPCache(t.TempDir(), 0)
+
+		get(t, cache.Transport(nil), server.URL, "a")
+		get(t, cache.Transport(nil), server.URL, "a")
+		assert.Equal(t, int32(2), atomic.LoadInt32(full))
+		entries, _, err := cache.Stats()
+		require.NoError(t, err)
+		assert.Zero(t, entries)
+	})
+
+	t.Run("passes through conditional requests of the caller", func(t *testing.T) {
+		body := `{}`
+		server, _ := cacheServer(t, &body, nil)
+		cache := NewHTTPCache(t.TempDir(), 0)
+		resp, _ := get(t, cache.Transport(nil), server.URL, "a")
+
+		req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer a")
+		req.Header.Set("If-None-Match", resp.Header.Get("ETag"))
+		resp, err = cache.Transport(nil).RoundTrip(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusNotModified, resp.StatusCode)
+	})
+
+	t.Run("streams responses too large to store", func(t *testing.T) {
+		body := strings.Repeat("x", 2000)
+		server, _ := cacheServer(t, &body, nil)
+		cache := NewHTTPCache(t.TempDir(), 10000)
+
+		_, got := get(t, cache.Transport(nil), server.URL, "a")
+		assert.Equal(t, body, got)
+		entries, _, err := cache.Stats()
+		require.NoError(t, err)
+		assert.Zero(t, entries)
+	})
+
+	t.Run("evicts the least recently used responses", func(t *testing.T) {
+		body := strings.Repeat("x", 500)
+		server, _ := cacheServer(t, &body, nil)
+		cache := NewHTTPCache(t.TempDir(), 10000)
+		transport := cache.Transport(nil)
+
+		for i := 0; i < 30; i++ {
+			get(t, transport, server.URL+"/repos?page="+url.QueryEscape(fmt.Sprint(i)), "a")
+		}
+		entries, size, err := cache.Stats()
+		require.NoError(t, err)
+		assert.LessOrEqual(t, size, int64(10000))
+		assert.Less(t, entries, 30)
+
+		cleared, clearedSize, err := cache.Clear()
+		require.NoError(t, err)
+		assert.Equal(t, entries, cleared)
+		assert.Equal(t, size, clearedSize)
+		entries, _, err = cache.Stats()
+		require.NoError(t, err)
+		assert.Zero(t, entries)
+	})
+}
+
+func TestNewClientWithOptions_Cache(t *testing.T) {
+	body := `{"name":"api","full_name":"owner/api"}`
+	server, full := cacheServer(t, &body, nil)
+
+	c := NewClientWithOptions("token", &ClientOptions{Cache: NewHTTPCache(t.TempDir(), 0)}).(*client)
+	baseURL, err := url.Parse(server.URL + "/")
+	require.NoError(t, err)
+	c.ghClient.BaseURL = baseURL
+
+	for i := 0; i < 3; i++ {
+		repo, err := c.GetRepository(context.Background(), "owner", "api")
+		require.NoError(t, err)
+		assert.Equal(t, "owner/api", repo.GetFullName())
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(full))
+}
@@ -4,30 +4,119 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/http"
 	"net/url"
+	"strings"
+	"sync"
 
 	gh "github.com/google/go-github/v68/github"
 	"golang.org/x/oauth2"
 
+	"github.com/Didstopia/githubby/internal/auth"
 	gherrors "github.com/Didstopia/githubby/internal/errors"
 )
 
 // client implements the Client interface
 type client struct {
 	ghClient *gh.Client
+	limiter  *RateLimiter
+	graphQL  bool
+
+	// login of the token's user, looked up when first needed
+	loginMu sync.Mutex
+	login   string
+}
+
+// ClientOptions configures a GitHub client
+type ClientOptions struct {
+	// Cache stores responses and revalidates them instead of downloading
+	// them again (optional)
+	Cache *HTTPCache
+
+	// RateLimitReserve is how many requests are left for interactive use;
+	// requests made with WithRateLimitReserve wait while only these are left
+	RateLimitReserve int
+
+	// GraphQL lists and fetches repositories with GraphQL queries that return
+	// only the fields syncs need, falling back to REST if they fail
+	GraphQL bool
+
+	// Hostname is the normalized hostname of the GitHub instance to talk to
+	// (empty = github.com); see auth.NormalizeHostname
+	Hostname string
+
+	// TokenSource provides tokens that expire, such as GitHub App
+	// installation tokens, in place of the static token (optional)
+	TokenSource oauth2.TokenSource
 }
 
 // NewClient creates a new GitHub client with the provided token
 func NewClient(token string) Client {
-	ctx := context.Background()
-	ts := oauth2.StaticTokenSource(
-		&oauth2.Token{AccessToken: token},
-	)
+	return NewClientWithOptions(token, nil)
+}
+
+// NewClientWithOptions creates a new GitHub client with the provided token.
+// Its requests wait for the rate limit to reset instead of failing when
+// GitHub says it ran out.
+func NewClientWithOptions(token string, opts *ClientOptions) Client {
+	if opts == nil {
+		opts = &ClientOptions{}
+	}
+
+	// The cache goes below the token so it can key responses by it
+	limiter := NewRateLimiter(opts.RateLimitReserve)
+	var transport http.RoundTripper
+	if opts.Cache != nil {
+		transport = opts.Cache.Transport(nil)
+	}
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, &http.Client{Transport: limiter.Transport(transport)})
+
+	ts := opts.TokenSource
+	if ts == nil {
+		ts = oauth2.StaticTokenSource(
+			&oauth2.Token{AccessToken: token},
+		)
+	}
 	tc := oauth2.NewClient(ctx, ts)
 
+	ghClient := gh.NewClient(tc)
+	ghClient.BaseURL = auth.APIURL(opts.Hostname)
+	ghClient.UploadURL = auth.UploadURL(opts.Hostname)
+
 	return &client{
-		ghClient: gh.NewClient(tc),
+		ghClient: ghClient,
+		limiter:  limiter,
+		graphQL:  opts.GraphQL,
+	}
+}
+
+// NewDefaultClient creates a new GitHub client that caches responses in the
+// default cache directory (if it's available), keeps the
+// default rate limit reserve and retries requests that hit the rate limit
+func NewDefaultClient(token string) Client {
+	return NewDefaultClientForHost(token, "")
+}
+
+// NewDefaultClientForHost creates a client like NewDefaultClient for the
+// GitHub instance at hostname (empty = github.com)
+func NewDefaultClientForHost(token, hostname string) Client {
+	return NewDefaultClientWithTokenSource(token, hostname, nil)
+}
+
+// NewDefaultClientWithTokenSource creates a client like
+// NewDefaultClientForHost that takes its tokens from tokens, renewing them
+// before they expire (nil uses token as it is)
+func NewDefaultClientWithTokenSource(token, hostname string, tokens oauth2.TokenSource) Client {
+	opts := &ClientOptions{RateLimitReserve: DefaultRateLimitReserve, Hostname: hostname, TokenSource: tokens}
+	if dir, err := DefaultCacheDir(); err == nil {
+		opts.Cache = NewHTTPCache(dir, 0)
 	}
+	return NewRetryableClient(NewClientWithOptions(token, opts), nil)
+}
+
+// RateLimiter returns the client's rate limiter
+func (c *client) RateLimiter() *RateLimiter {
+	return c.limiter
 }
 
 // GetReleases returns all releases for a repository using iterative pagination
@@ -83,6 +172,13 @@ func (c *client) ListUserRepos(ctx context.Context, username string, opts *ListO
 		opts = DefaultListOptions()
 	}
 
+	if c.graphQL {
+		repos, err := c.listReposGraphQL(ctx, username, false, opts)
+		if err == nil || !useREST(ctx, err) {
+			return repos, err
+		}
+	}
+
 	var allRepos []*gh.Repository
 
 	ghOpts := &gh.RepositoryListByUserOptions{
@@ -93,7 +189,7 @@ func (c *client) ListUserRepos(ctx context.Context, username string, opts *ListO
 		},
 	}
 
-	for {
+	for pages := 1; ; pages++ {
 		repos, resp, err := c.ghClient.Repositories.ListByUser(ctx, username, ghOpts)
 		if err != nil {
 			return nil, wrapAPIError(resp, err)
@@ -106,7 +202,7 @@ func (c *client) ListUserRepos(ctx context.Context, username string, opts *ListO
 			allRepos = append(allRepos, repo)
 		}
 
-		if resp.NextPage == 0 {
+		if resp.NextPage == 0 || (opts.MaxPages > 0 && pages >= opts.MaxPages) {
 			break
 		}
 		ghOpts.Page = resp.NextPage
@@ -121,6 +217,13 @@ func (c *client) ListOrgRepos(ctx context.Context, org string, opts *ListOptions
 		opts = DefaultListOptions()
 	}
 
+	if c.graphQL {
+		repos, err := c.listReposGraphQL(ctx, org, true, opts)
+		if err == nil || !useREST(ctx, err) {
+			return repos, err
+		}
+	}
+
 	var allRepos []*gh.Repository
 
 	ghOpts := &gh.RepositoryListByOrgOptions{
@@ -131,7 +234,7 @@ func (c *client) ListOrgRepos(ctx context.Context, org string, opts *ListOptions
 		},
 	}
 
-	for {
+	for pages := 1; ; pages++ {
 		repos, resp, err := c.ghClient.Repositories.ListByOrg(ctx, org, ghOpts)
 		if err != nil {
 			return nil, wrapAPIError(resp, err)
@@ -144,7 +247,7 @@ func (c *client) ListOrgRepos(ctx context.Context, org string, opts *ListOptions
 			allRepos = append(allRepos, repo)
 		}
 
-		if resp.NextPage == 0 {
+		if resp.NextPage == 0 || (opts.MaxPages > 0 && pages >= opts.MaxPages) {
 			break
 		}
 		ghOpts.Page = resp.NextPage
@@ -188,6 +291,18 @@ func (c *client) GetRepository(ctx context.Context, owner, repo string) (*gh.Rep
 	return repository, nil
 }
 
+// GetRepositories returns information about the named repositories
+// (owner/repo). With GraphQL they're fetched in batches; otherwise one by one.
+func (c *client) GetRepositories(ctx context.Context, fullNames []string) ([]*gh.Repository, map[string]error, error) {
+	if c.graphQL {
+		repos, failed, err := c.getReposGraphQL(ctx, fullNames)
+		if err == nil || !useREST(ctx, err) {
+			return repos, failed, err
+		}
+	}
+	return FetchRepositories(ctx, c, fullNames)
+}
+
 // GetRateLimit returns the current rate limit status
 func (c *client) GetRateLimit(ctx context.Context) (*gh.RateLimits, error) {
 	rateLimits, resp, err := c.ghClient.RateLimit.Get(ctx)
@@ -246,6 +361,9 @@ func wrapAPIError(resp *gh.Response, err error) error {
 		return gherrors.ErrUnauthorized
 	case 403:
 		// 403 without a typed rate-limit error is a permission denial
+		if ssoURL, ok := ssoRequired(resp); ok {
+			return &gherrors.SSOError{URL: ssoURL}
+		}
 		if apiMessage != "" {
 			return fmt.Errorf("%w: %s", gherrors.ErrForbidden, apiMessage)
 		}
@@ -268,3 +386,23 @@ func wrapAPIError(resp *gh.Response, err error) error {
 		return gherrors.NewAPIError(statusCode, msg, err)
 	}
 }
+
+// ssoRequired reports whether a response says the token must be authorized
+// for an organization's SAML SSO, and where to authorize it. GitHub sends
+// "X-GitHub-SSO: required; url=<url>".
+func ssoRequired(resp *gh.Response) (string, bool) {
+	if resp == nil || resp.Response == nil {
+		return "", false
+	}
+	header := resp.Header.Get("X-GitHub-SSO")
+	directive, params, _ := strings.Cut(header, ";")
+	if strings.TrimSpace(directive) != "required" {
+		return "", false
+	}
+	for _, param := range strings.Split(params, ";") {
+		if value, ok := strings.CutPrefix(strings.TrimSpace(param), "url="); ok {
+			return value, true
+		}
+	}
+	return "", true
+}
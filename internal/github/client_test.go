@@ -4,7 +4,9 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	gh "github.com/google/go-github/v68/github"
@@ -12,6 +14,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/Didstopia/githubby/internal/auth"
 	gherrors "github.com/Didstopia/githubby/internal/errors"
 )
 
@@ -23,6 +26,34 @@ func TestNewClient(t *testing.T) {
 	var _ Client = client //nolint:staticcheck // Interface compliance check
 }
 
+func TestNewClientWithOptions_EnterpriseHost(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/graphql":
+			w.WriteHeader(http.StatusBadGateway)
+		case "/api/v3/repos/acme/api":
+			fmt.Fprint(w, `{"full_name":"acme/api"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	host, err := auth.NormalizeHostname(server.URL)
+	require.NoError(t, err)
+	c := NewClientWithOptions("token", &ClientOptions{Hostname: host, GraphQL: true})
+
+	repos, failed, err := c.GetRepositories(context.Background(), []string{"acme/api"})
+	require.NoError(t, err)
+	assert.Empty(t, failed)
+	require.Len(t, repos, 1)
+	assert.Equal(t, "acme/api", repos[0].GetFullName())
+	assert.Equal(t, []string{"/api/graphql", "/api/v3/repos/acme/api"}, paths)
+}
+
 func TestDefaultListOptions(t *testing.T) {
 	opts := DefaultListOptions()
 
@@ -431,6 +462,30 @@ func TestListOrgRepos(t *testing.T) {
 		assert.Len(t, result, 1)
 	})
 
+	t.Run("stops after max pages", func(t *testing.T) {
+		httpmock.Reset()
+
+		httpmock.RegisterResponder("GET", "https://api.github.com/orgs/testorg/repos",
+			func(req *http.Request) (*http.Response, error) {
+				page := req.URL.Query().Get("page")
+				resp := httpmock.NewJsonResponderOrPanic(200, []map[string]interface{}{
+					{"id": 1, "name": "repo" + page, "full_name": "org/repo" + page},
+				})
+				r, err := resp(req)
+				if err == nil {
+					r.Header.Set("Link", `<https://api.github.com/orgs/testorg/repos?page=9>; rel="next"`)
+				}
+				return r, err
+			})
+
+		client := NewClient("test-token")
+		result, err := client.ListOrgRepos(context.Background(), "testorg", &ListOptions{PerPage: 1, MaxPages: 2})
+
+		require.NoError(t, err)
+		assert.Len(t, result, 2)
+		assert.Equal(t, 2, httpmock.GetTotalCallCount())
+	})
+
 	t.Run("not found error", func(t *testing.T) {
 		httpmock.Reset()
 
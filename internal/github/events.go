@@ -0,0 +1,146 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	gh "github.com/google/go-github/v68/github"
+)
+
+// eventsPerPage is the largest page the Events API returns
+const eventsPerPage = 100
+
+// EventOptions specifies the events to list
+type EventOptions struct {
+	// Org lists an organization's events instead of a user's
+	Org bool
+
+	// Private lists the organization's events the authenticated user can
+	// see, including those of private repositories, which the public
+	// organization events leave out. Needs a user token; GitHub App
+	// installation tokens can only list public events.
+	Private bool
+
+	// ETag of a previous listing; if nothing happened since, the request
+	// doesn't count against the rate limit and Events.NotModified is set
+	ETag string
+
+	// After is the ID of the newest event already seen; only newer events
+	// are listed
+	After string
+
+	// MaxPages limits how many pages are read (0 = all that GitHub keeps)
+	MaxPages int
+}
+
+// Events is the result of listing an owner's recent events
+type Events struct {
+	// Events newer than EventOptions.After, newest first
+	Events []*gh.Event
+
+	// ETag to pass with the next listing
+	ETag string
+
+	// NotModified is true if nothing happened since the ETag was issued
+	NotModified bool
+
+	// Complete is true if the After event was reached, so Events holds every
+	// newer event. GitHub only keeps the last 300 events (up to 90 days);
+	// when more happened since, older ones are lost.
+	Complete bool
+
+	// PollInterval is how long GitHub asks clients to wait between polls
+	PollInterval time.Duration
+}
+
+// ListOwnerEvents returns the recent events of a user or organization
+func (c *client) ListOwnerEvents(ctx context.Context, owner string, opts *EventOptions) (*Events, error) {
+	if opts == nil {
+		opts = &EventOptions{}
+	}
+
+	path := fmt.Sprintf("users/%s/events", url.PathEscape(owner))
+	switch {
+	case opts.Org && opts.Private:
+		login, err := c.authenticatedLogin(ctx)
+		if err != nil {
+			return nil, err
+		}
+		path = fmt.Sprintf("users/%s/events/orgs/%s", url.PathEscape(login), url.PathEscape(owner))
+	case opts.Org:
+		path = fmt.Sprintf("orgs/%s/events", url.PathEscape(owner))
+	}
+
+	result := &Events{}
+	for page := 1; ; page++ {
+		req, err := c.ghClient.NewRequest(http.MethodGet, fmt.Sprintf("%s?per_page=%d&page=%d", path, eventsPerPage, page), nil)
+		if err != nil {
+			return nil, err
+		}
+		// Only the first page changes when new events happen
+		if page == 1 && opts.ETag != "" {
+			req.Header.Set("If-None-Match", opts.ETag)
+		}
+
+		var events []*gh.Event
+		resp, err := c.ghClient.Do(ctx, req, &events)
+		if page == 1 && resp != nil {
+			result.ETag = resp.Header.Get("ETag")
+			if seconds, convErr := strconv.Atoi(resp.Header.Get("X-Poll-Interval")); convErr == nil {
+				result.PollInterval = time.Duration(seconds) * time.Second
+			}
+			if resp.StatusCode == http.StatusNotModified {
+				result.ETag = opts.ETag
+				result.NotModified = true
+				result.Complete = true
+				return result, nil
+			}
+		}
+		if err != nil {
+			return nil, wrapAPIError(resp, err)
+		}
+
+		for _, event := range events {
+			if opts.After != "" && !eventAfter(event.GetID(), opts.After) {
+				result.Complete = true
+				return result, nil
+			}
+			result.Events = append(result.Events, event)
+		}
+
+		if resp.NextPage == 0 || page == opts.MaxPages {
+			return result, nil
+		}
+	}
+}
+
+// authenticatedLogin returns the login of the token's user, looking it up
+// once per client
+func (c *client) authenticatedLogin(ctx context.Context) (string, error) {
+	c.loginMu.Lock()
+	defer c.loginMu.Unlock()
+	if c.login != "" {
+		return c.login, nil
+	}
+	user, resp, err := c.ghClient.Users.Get(ctx, "")
+	if err != nil {
+		return "", wrapAPIError(resp, err)
+	}
+	c.login = user.GetLogin()
+	return c.login, nil
+}
+
+// eventAfter reports whether event ID id is newer than after. Event IDs
+// increase over time; IDs that aren't numbers are only compared for equality.
+func eventAfter(id, after string) bool {
+	n, errID := strconv.ParseInt(id, 10, 64)
+	m, errAfter := strconv.ParseInt(after, 10, 64)
+	if errID != nil || errAfter != nil {
+		return id != after
+	}
+	return n > m
+}
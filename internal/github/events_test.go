@@ -0,0 +1,133 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// eventsPage returns n events with IDs counting down from first
+func eventsPage(first, n int) []map[string]any {
+	events := make([]map[string]any, n)
+	for i := range events {
+		events[i] = map[string]any{
+			"id":   fmt.Sprint(first - i),
+			"type": "PushEvent",
+			"repo": map[string]any{"name": "acme/api"},
+		}
+	}
+	return events
+}
+
+func TestListOwnerEvents(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	ctx := context.Background()
+	client := NewClient("test-token")
+	const url = "https://api.github.com/orgs/acme/events"
+
+	t.Run("lists events after the last seen one", func(t *testing.T) {
+		httpmock.Reset()
+		httpmock.RegisterResponder("GET", url, func(req *http.Request) (*http.Response, error) {
+			assert.Equal(t, "100", req.URL.Query().Get("per_page"))
+			resp, _ := httpmock.NewJsonResponse(200, eventsPage(50, 10))
+			resp.Header.Set("ETag", `W/"abc"`)
+			resp.Header.Set("X-Poll-Interval", "60")
+			return resp, nil
+		})
+
+		events, err := client.ListOwnerEvents(ctx, "acme", &EventOptions{Org: true, After: "45"})
+		require.NoError(t, err)
+		assert.Len(t, events.Events, 5)
+		assert.Equal(t, "50", events.Events[0].GetID())
+		assert.True(t, events.Complete)
+		assert.False(t, events.NotModified)
+		assert.Equal(t, `W/"abc"`, events.ETag)
+		assert.Equal(t, time.Minute, events.PollInterval)
+	})
+
+	t.Run("follows pages until the window ends", func(t *testing.T) {
+		httpmock.Reset()
+		httpmock.RegisterResponder("GET", url, func(req *http.Request) (*http.Response, error) {
+			if req.URL.Query().Get("page") == "2" {
+				return httpmock.NewJsonResponse(200, eventsPage(200, 100))
+			}
+			resp, _ := httpmock.NewJsonResponse(200, eventsPage(300, 100))
+			resp.Header.Set("Link", `<https://api.github.com/orgs/acme/events?per_page=100&page=2>; rel="next"`)
+			return resp, nil
+		})
+
+		events, err := client.ListOwnerEvents(ctx, "acme", &EventOptions{Org: true, After: "7"})
+		require.NoError(t, err)
+		assert.Len(t, events.Events, 200)
+		assert.False(t, events.Complete, "the last seen event is older than the window")
+		assert.Equal(t, 2, httpmock.GetTotalCallCount())
+	})
+
+	t.Run("not modified", func(t *testing.T) {
+		httpmock.Reset()
+		httpmock.RegisterResponder("GET", url, func(req *http.Request) (*http.Response, error) {
+			assert.Equal(t, `W/"abc"`, req.Header.Get("If-None-Match"))
+			return httpmock.NewStringResponse(http.StatusNotModified, ""), nil
+		})
+
+		events, err := client.ListOwnerEvents(ctx, "acme", &EventOptions{Org: true, ETag: `W/"abc"`, After: "50"})
+		require.NoError(t, err)
+		assert.True(t, events.NotModified)
+		assert.True(t, events.Complete)
+		assert.Empty(t, events.Events)
+		assert.Equal(t, `W/"abc"`, events.ETag)
+	})
+
+	t.Run("user events", func(t *testing.T) {
+		httpmock.Reset()
+		httpmock.RegisterResponder("GET", "https://api.github.com/users/alice/events",
+			httpmock.NewJsonResponderOrPanic(200, eventsPage(5, 5)))
+
+		events, err := client.ListOwnerEvents(ctx, "alice", nil)
+		require.NoError(t, err)
+		assert.Len(t, events.Events, 5)
+		assert.False(t, events.Complete, "without a last seen event nothing is known to be complete")
+	})
+
+	t.Run("private organization events", func(t *testing.T) {
+		httpmock.Reset()
+		httpmock.RegisterResponder("GET", "https://api.github.com/user",
+			httpmock.NewJsonResponderOrPanic(200, map[string]string{"login": "alice"}))
+		httpmock.RegisterResponder("GET", "https://api.github.com/users/alice/events/orgs/acme",
+			httpmock.NewJsonResponderOrPanic(200, eventsPage(5, 5)))
+
+		for range 2 {
+			events, err := client.ListOwnerEvents(ctx, "acme", &EventOptions{Org: true, Private: true})
+			require.NoError(t, err)
+			assert.Len(t, events.Events, 5)
+		}
+		info := httpmock.GetCallCountInfo()
+		assert.Equal(t, 1, info["GET https://api.github.com/user"], "the login is looked up once")
+		assert.Zero(t, info["GET "+url], "public events aren't listed")
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		httpmock.Reset()
+		httpmock.RegisterResponder("GET", url,
+			httpmock.NewJsonResponderOrPanic(404, map[string]string{"message": "Not Found"}))
+
+		_, err := client.ListOwnerEvents(ctx, "acme", &EventOptions{Org: true})
+		assert.Error(t, err)
+	})
+}
+
+func TestEventAfter(t *testing.T) {
+	assert.True(t, eventAfter("100", "99"))
+	assert.False(t, eventAfter("99", "99"))
+	assert.False(t, eventAfter("98", "99"))
+	assert.True(t, eventAfter("b", "a"))
+	assert.False(t, eventAfter("a", "a"))
+}
@@ -0,0 +1,359 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	gh "github.com/google/go-github/v68/github"
+
+	gherrors "github.com/Didstopia/githubby/internal/errors"
+)
+
+const (
+	// graphQLPageSize is the most repositories a GraphQL connection returns
+	graphQLPageSize = 100
+
+	// graphQLBatchSize is how many named repositories are fetched per query
+	graphQLBatchSize = 50
+)
+
+// graphQLRepoFields selects the repository fields syncs and the TUI use
+const graphQLRepoFields = `fragment repo on Repository {
+  name
+  nameWithOwner
+  owner { login }
+  description
+  url
+  sshUrl
+  isPrivate
+  isArchived
+  isFork
+  defaultBranchRef { name }
+  pushedAt
+  updatedAt
+  diskUsage
+  stargazerCount
+  primaryLanguage { name }
+}`
+
+// graphQLRepo is a repository as returned by graphQLRepoFields
+type graphQLRepo struct {
+	Name          string `json:"name"`
+	NameWithOwner string `json:"nameWithOwner"`
+	Owner         struct {
+		Login string `json:"login"`
+	} `json:"owner"`
+	Description      string `json:"description"`
+	URL              string `json:"url"`
+	SSHURL           string `json:"sshUrl"`
+	IsPrivate        bool   `json:"isPrivate"`
+	IsArchived       bool   `json:"isArchived"`
+	IsFork           bool   `json:"isFork"`
+	DefaultBranchRef *struct {
+		Name string `json:"name"`
+	} `json:"defaultBranchRef"`
+	PushedAt        *time.Time `json:"pushedAt"`
+	UpdatedAt       *time.Time `json:"updatedAt"`
+	DiskUsage       int        `json:"diskUsage"`
+	StargazerCount  int        `json:"stargazerCount"`
+	PrimaryLanguage *struct {
+		Name string `json:"name"`
+	} `json:"primaryLanguage"`
+}
+
+// toRepository converts r to the REST representation
+func (r *graphQLRepo) toRepository() *gh.Repository {
+	repo := &gh.Repository{
+		Name:            gh.Ptr(r.Name),
+		FullName:        gh.Ptr(r.NameWithOwner),
+		Owner:           &gh.User{Login: gh.Ptr(r.Owner.Login)},
+		Description:     gh.Ptr(r.Description),
+		HTMLURL:         gh.Ptr(r.URL),
+		CloneURL:        gh.Ptr(r.URL + ".git"),
+		SSHURL:          gh.Ptr(r.SSHURL),
+		Private:         gh.Ptr(r.IsPrivate),
+		Archived:        gh.Ptr(r.IsArchived),
+		Fork:            gh.Ptr(r.IsFork),
+		Size:            gh.Ptr(r.DiskUsage),
+		StargazersCount: gh.Ptr(r.StargazerCount),
+	}
+	if r.IsPrivate {
+		repo.Visibility = gh.Ptr("private")
+	} else {
+		repo.Visibility = gh.Ptr("public")
+	}
+	if r.DefaultBranchRef != nil {
+		repo.DefaultBranch = gh.Ptr(r.DefaultBranchRef.Name)
+	}
+	if r.PrimaryLanguage != nil {
+		repo.Language = gh.Ptr(r.PrimaryLanguage.Name)
+	}
+	if r.PushedAt != nil {
+		repo.PushedAt = &gh.Timestamp{Time: *r.PushedAt}
+	}
+	if r.UpdatedAt != nil {
+		repo.UpdatedAt = &gh.Timestamp{Time: *r.UpdatedAt}
+	}
+	return repo
+}
+
+// graphQLError is an error reported in a GraphQL response
+type graphQLError struct {
+	Type    string `json:"type"`
+	Path    []any  `json:"path"`
+	Message string `json:"message"`
+}
+
+// err converts e to our error types
+func (e *graphQLError) err() error {
+	switch e.Type {
+	case "NOT_FOUND":
+		return fmt.Errorf("%w: %s", gherrors.ErrNotFound, e.Message)
+	case "FORBIDDEN", "INSUFFICIENT_SCOPES":
+		return fmt.Errorf("%w: %s", gherrors.ErrForbidden, e.Message)
+	case "RATE_LIMITED":
+		return fmt.Errorf("%w: %s", gherrors.ErrRateLimited, e.Message)
+	}
+	return fmt.Errorf("GraphQL error: %s", e.Message)
+}
+
+// alias returns the top-level field the error is about, if any
+func (e *graphQLError) alias() string {
+	if len(e.Path) == 0 {
+		return ""
+	}
+	alias, _ := e.Path[0].(string)
+	return alias
+}
+
+// graphQLURL returns the GraphQL endpoint of the API the client talks to.
+// GitHub Enterprise Server serves it at /api/graphql next to /api/v3.
+func (c *client) graphQLURL() string {
+	base := *c.ghClient.BaseURL
+	if strings.HasSuffix(base.Path, "/api/v3/") {
+		base.Path = strings.TrimSuffix(base.Path, "v3/") + "graphql"
+		return base.String()
+	}
+	return base.String() + "graphql"
+}
+
+// queryGraphQL runs query and decodes its data into data. Errors about single
+// fields are returned separately; the query only fails as a whole if no
+// data was returned.
+func (c *client) queryGraphQL(ctx context.Context, query string, variables map[string]any, data any) ([]graphQLError, error) {
+	req, err := c.ghClient.NewRequest(http.MethodPost, c.graphQLURL(), map[string]any{
+		"query":     query,
+		"variables": variables,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var body struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []graphQLError  `json:"errors"`
+	}
+	resp, err := c.ghClient.Do(ctx, req, &body)
+	if err != nil {
+		return nil, wrapAPIError(resp, err)
+	}
+	if len(body.Data) == 0 || string(body.Data) == "null" {
+		if len(body.Errors) > 0 {
+			return nil, body.Errors[0].err()
+		}
+		return nil, errors.New("GraphQL response has no data")
+	}
+	if err := json.Unmarshal(body.Data, data); err != nil {
+		return nil, fmt.Errorf("failed to decode GraphQL response: %w", err)
+	}
+	return body.Errors, nil
+}
+
+// graphQLConnection is a page of repositories
+type graphQLConnection struct {
+	Nodes    []*graphQLRepo `json:"nodes"`
+	PageInfo struct {
+		HasNextPage bool   `json:"hasNextPage"`
+		EndCursor   string `json:"endCursor"`
+	} `json:"pageInfo"`
+}
+
+// listReposGraphQL lists the repositories of a user or organization with
+// GraphQL, which returns only the fields syncs need
+func (c *client) listReposGraphQL(ctx context.Context, login string, org bool, opts *ListOptions) ([]*gh.Repository, error) {
+	owner := "user"
+	args := "privacy: $privacy, ownerAffiliations: $affiliations"
+	variables := map[string]any{"login": login, "privacy": nil, "affiliations": userAffiliations(opts.Type)}
+	if org {
+		owner = "organization"
+		args = "privacy: $privacy"
+		delete(variables, "affiliations")
+	}
+	if !opts.IncludePrivate {
+		variables["privacy"] = "PUBLIC"
+	}
+
+	declarations := "$login: String!, $cursor: String, $privacy: RepositoryPrivacy"
+	if !org {
+		declarations += ", $affiliations: [RepositoryAffiliation]"
+	}
+	query := fmt.Sprintf(`query(%s) {
+  owner: %s(login: $login) {
+    repositories(first: %d, after: $cursor, %s, orderBy: {field: NAME, direction: ASC}) {
+      nodes { ...repo }
+      pageInfo { hasNextPage endCursor }
+    }
+  }
+}
+%s`, declarations, owner, graphQLPageSize, args, graphQLRepoFields)
+
+	var allRepos []*gh.Repository
+	for pages := 1; ; pages++ {
+		var data struct {
+			Owner *struct {
+				Repositories graphQLConnection `json:"repositories"`
+			} `json:"owner"`
+		}
+		fieldErrors, err := c.queryGraphQL(ctx, query, variables, &data)
+		if err != nil {
+			return nil, err
+		}
+		if data.Owner == nil {
+			if len(fieldErrors) > 0 {
+				return nil, fieldErrors[0].err()
+			}
+			return nil, gherrors.ErrNotFound
+		}
+
+		for _, node := range data.Owner.Repositories.Nodes {
+			if node == nil || (!opts.IncludePrivate && node.IsPrivate) {
+				continue
+			}
+			allRepos = append(allRepos, node.toRepository())
+		}
+
+		page := data.Owner.Repositories.PageInfo
+		if !page.HasNextPage || (opts.MaxPages > 0 && pages >= opts.MaxPages) {
+			return allRepos, nil
+		}
+		variables["cursor"] = page.EndCursor
+	}
+}
+
+// userAffiliations converts a REST repository type to GraphQL affiliations
+// that list the same repositories: the REST default (owner) is the user's
+// own repositories, member those they collaborate on, and all both.
+// Repositories of organizations the user belongs to aren't listed by REST.
+func userAffiliations(repoType string) []string {
+	switch repoType {
+	case "member":
+		return []string{"COLLABORATOR"}
+	case "all":
+		return []string{"OWNER", "COLLABORATOR"}
+	}
+	return []string{"OWNER"}
+}
+
+// getReposGraphQL fetches named repositories in batches of aliased queries
+func (c *client) getReposGraphQL(ctx context.Context, fullNames []string) ([]*gh.Repository, map[string]error, error) {
+	repos := make([]*gh.Repository, 0, len(fullNames))
+	failed := make(map[string]error)
+
+	var valid []string
+	for _, fullName := range fullNames {
+		if _, _, found := strings.Cut(fullName, "/"); !found {
+			failed[fullName] = gherrors.ErrInvalidRepository
+			continue
+		}
+		valid = append(valid, fullName)
+	}
+
+	for start := 0; start < len(valid); start += graphQLBatchSize {
+		batch := valid[start:min(start+graphQLBatchSize, len(valid))]
+
+		var declarations, fields []string
+		variables := make(map[string]any, 2*len(batch))
+		for i, fullName := range batch {
+			owner, name, _ := strings.Cut(fullName, "/")
+			declarations = append(declarations, fmt.Sprintf("$o%d: String!, $n%d: String!", i, i))
+			fields = append(fields, fmt.Sprintf("  r%d: repository(owner: $o%d, name: $n%d) { ...repo }", i, i, i))
+			variables[fmt.Sprintf("o%d", i)] = owner
+			variables[fmt.Sprintf("n%d", i)] = name
+		}
+		query := fmt.Sprintf("query(%s) {\n%s\n}\n%s", strings.Join(declarations, ", "), strings.Join(fields, "\n"), graphQLRepoFields)
+
+		var data map[string]*graphQLRepo
+		fieldErrors, err := c.queryGraphQL(ctx, query, variables, &data)
+		if err != nil {
+			return nil, nil, err
+		}
+		errs := make(map[string]error, len(fieldErrors))
+		for i := range fieldErrors {
+			errs[fieldErrors[i].alias()] = fieldErrors[i].err()
+		}
+
+		for i, fullName := range batch {
+			alias := fmt.Sprintf("r%d", i)
+			if repo := data[alias]; repo != nil {
+				repos = append(repos, repo.toRepository())
+				continue
+			}
+			err := errs[alias]
+			if err == nil {
+				err = gherrors.ErrNotFound
+			}
+			// Access denials affect every repo, as they do for REST
+			if gherrors.IsForbidden(err) {
+				return nil, nil, fmt.Errorf("failed to get repository %s: %w", fullName, err)
+			}
+			failed[fullName] = err
+		}
+	}
+
+	return repos, failed, nil
+}
+
+// FetchRepositories gets named repositories (owner/repo) one at a time with
+// GetRepository, returning the errors of the ones that couldn't be fetched
+// separately. Fails as a whole if the token is rejected or lacks access, or
+// ctx is cancelled.
+func FetchRepositories(ctx context.Context, c Client, fullNames []string) ([]*gh.Repository, map[string]error, error) {
+	repos := make([]*gh.Repository, 0, len(fullNames))
+	failed := make(map[string]error)
+
+	for _, fullName := range fullNames {
+		owner, name, found := strings.Cut(fullName, "/")
+		if !found {
+			failed[fullName] = gherrors.ErrInvalidRepository
+			continue
+		}
+
+		repo, err := c.GetRepository(ctx, owner, name)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, nil, ctx.Err()
+			}
+			// Auth failures affect every repo, so fail as a whole
+			if gherrors.IsUnauthorized(err) || gherrors.IsForbidden(err) {
+				return nil, nil, fmt.Errorf("failed to get repository %s: %w", fullName, err)
+			}
+			failed[fullName] = err
+			continue
+		}
+		repos = append(repos, repo)
+	}
+
+	return repos, failed, nil
+}
+
+// useREST reports whether a failed GraphQL request should be retried with
+// REST: anything but a rejected token or a cancelled request, such as
+// GraphQL being unavailable or its separate rate limit running out
+func useREST(ctx context.Context, err error) bool {
+	return ctx.Err() == nil && !gherrors.IsUnauthorized(err)
+}
@@ -0,0 +1,238 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	gherrors "github.com/Didstopia/githubby/internal/errors"
+)
+
+// graphQLRepoJSON returns a repository as GraphQL returns it
+func graphQLRepoJSON(fullName string, private bool) map[string]any {
+	owner, name, _ := strings.Cut(fullName, "/")
+	return map[string]any{
+		"name":             name,
+		"nameWithOwner":    fullName,
+		"owner":            map[string]any{"login": owner},
+		"url":              "https://github.com/" + fullName,
+		"sshUrl":           "git@github.com:" + fullName + ".git",
+		"isPrivate":        private,
+		"defaultBranchRef": map[string]any{"name": "main"},
+		"pushedAt":         "2026-01-02T03:04:05Z",
+		"stargazerCount":   7,
+		"primaryLanguage":  map[string]any{"name": "Go"},
+	}
+}
+
+// graphQLRequest is a decoded GraphQL request
+type graphQLRequest struct {
+	Query     string         `json:"query"`
+	Variables map[string]any `json:"variables"`
+}
+
+// newGraphQLTestClient creates a GraphQL client for a test server whose
+// /graphql endpoint is handled by graphQL and REST endpoints by rest
+func newGraphQLTestClient(t *testing.T, graphQL func(req graphQLRequest) (int, any), rest http.HandlerFunc) *client {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, r *http.Request) {
+		var req graphQLRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		status, body := graphQL(req)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		require.NoError(t, json.NewEncoder(w).Encode(body))
+	})
+	if rest != nil {
+		mux.HandleFunc("/", rest)
+	}
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	c := NewClientWithOptions("token", &ClientOptions{GraphQL: true}).(*client)
+	baseURL, err := url.Parse(server.URL + "/")
+	require.NoError(t, err)
+	c.ghClient.BaseURL = baseURL
+	return c
+}
+
+func TestListReposGraphQL(t *testing.T) {
+	var queries []graphQLRequest
+	c := newGraphQLTestClient(t, func(req graphQLRequest) (int, any) {
+		queries = append(queries, req)
+		page := map[string]any{
+			"nodes":    []any{graphQLRepoJSON("acme/api", false), graphQLRepoJSON("acme/secret", true)},
+			"pageInfo": map[string]any{"hasNextPage": true, "endCursor": "c1"},
+		}
+		if req.Variables["cursor"] == "c1" {
+			page = map[string]any{
+				"nodes":    []any{graphQLRepoJSON("acme/web", false)},
+				"pageInfo": map[string]any{"hasNextPage": false},
+			}
+		}
+		return http.StatusOK, map[string]any{"data": map[string]any{"owner": map[string]any{"repositories": page}}}
+	}, nil)
+
+	repos, err := c.ListOrgRepos(context.Background(), "acme", &ListOptions{IncludePrivate: true})
+	require.NoError(t, err)
+	require.Len(t, repos, 3)
+	assert.Equal(t, "acme/api", repos[0].GetFullName())
+	assert.Equal(t, "acme", repos[0].GetOwner().GetLogin())
+	assert.Equal(t, "https://github.com/acme/api.git", repos[0].GetCloneURL())
+	assert.Equal(t, "main", repos[0].GetDefaultBranch())
+	assert.Equal(t, "Go", repos[0].GetLanguage())
+	assert.Equal(t, 7, repos[0].GetStargazersCount())
+	assert.Equal(t, 2026, repos[0].GetPushedAt().Year())
+	assert.True(t, repos[1].GetPrivate())
+
+	require.Len(t, queries, 2)
+	assert.Contains(t, queries[0].Query, "organization(login: $login)")
+	assert.Nil(t, queries[0].Variables["privacy"])
+
+	t.Run("public only", func(t *testing.T) {
+		queries = nil
+		repos, err := c.ListUserRepos(context.Background(), "alice", nil)
+		require.NoError(t, err)
+		assert.Len(t, repos, 2, "private repositories are left out")
+		assert.Contains(t, queries[0].Query, "user(login: $login)")
+		assert.Equal(t, "PUBLIC", queries[0].Variables["privacy"])
+	})
+}
+
+func TestGetRepositoriesGraphQL(t *testing.T) {
+	var queries int
+	c := newGraphQLTestClient(t, func(req graphQLRequest) (int, any) {
+		queries++
+		data := map[string]any{}
+		var errs []any
+		for i := 0; ; i++ {
+			owner, ok := req.Variables[fmt.Sprintf("o%d", i)].(string)
+			if !ok {
+				break
+			}
+			name := req.Variables[fmt.Sprintf("n%d", i)].(string)
+			alias := fmt.Sprintf("r%d", i)
+			if name == "gone" {
+				data[alias] = nil
+				errs = append(errs, map[string]any{"type": "NOT_FOUND", "path": []any{alias}, "message": "Could not resolve to a Repository"})
+				continue
+			}
+			data[alias] = graphQLRepoJSON(owner+"/"+name, false)
+		}
+		return http.StatusOK, map[string]any{"data": data, "errors": errs}
+	}, nil)
+
+	names := []string{"acme/gone", "invalid"}
+	for i := 0; i < 60; i++ {
+		names = append(names, fmt.Sprintf("acme/repo-%d", i))
+	}
+	repos, failed, err := c.GetRepositories(context.Background(), names)
+	require.NoError(t, err)
+	assert.Len(t, repos, 60)
+	assert.Equal(t, "acme/repo-0", repos[0].GetFullName())
+	assert.Equal(t, 2, queries, "repositories are fetched in batches")
+	require.Len(t, failed, 2)
+	assert.True(t, gherrors.IsNotFound(failed["acme/gone"]))
+	assert.ErrorIs(t, failed["invalid"], gherrors.ErrInvalidRepository)
+}
+
+func TestGraphQLFallsBackToREST(t *testing.T) {
+	var restCalls int
+	c := newGraphQLTestClient(t, func(req graphQLRequest) (int, any) {
+		return http.StatusBadGateway, map[string]any{"message": "GraphQL unavailable"}
+	}, func(w http.ResponseWriter, r *http.Request) {
+		restCalls++
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"name":"api","full_name":"acme/api"}`)
+	})
+
+	repos, failed, err := c.GetRepositories(context.Background(), []string{"acme/api"})
+	require.NoError(t, err)
+	assert.Empty(t, failed)
+	require.Len(t, repos, 1)
+	assert.Equal(t, "acme/api", repos[0].GetFullName())
+	assert.Equal(t, 1, restCalls)
+
+	t.Run("not for rejected tokens", func(t *testing.T) {
+		restCalls = 0
+		c := newGraphQLTestClient(t, func(req graphQLRequest) (int, any) {
+			return http.StatusUnauthorized, map[string]any{"message": "Bad credentials"}
+		}, func(w http.ResponseWriter, r *http.Request) { restCalls++ })
+
+		_, err := c.ListOrgRepos(context.Background(), "acme", nil)
+		assert.True(t, gherrors.IsUnauthorized(err))
+		assert.Zero(t, restCalls)
+	})
+}
+
+func TestGraphQLURL(t *testing.T) {
+	c := NewClient("token").(*client)
+	assert.Equal(t, "https://api.github.com/graphql", c.graphQLURL())
+
+	enterprise, err := url.Parse("https://github.example.com/api/v3/")
+	require.NoError(t, err)
+	c.ghClient.BaseURL = enterprise
+	assert.Equal(t, "https://github.example.com/api/graphql", c.graphQLURL())
+}
+
+func TestListUserReposGraphQLMatchesREST(t *testing.T) {
+	// alice owns alice/tool, collaborates on bob/shared and belongs to acme
+	owned := []string{"alice/tool"}
+	collaborator := []string{"bob/shared"}
+	member := []string{"acme/api"}
+
+	reposFor := func(repoType string) []string {
+		switch repoType {
+		case "member":
+			return collaborator
+		case "all":
+			return append(append([]string{}, owned...), collaborator...)
+		}
+		return owned
+	}
+
+	c := newGraphQLTestClient(t, func(req graphQLRequest) (int, any) {
+		var nodes []any
+		for _, affiliation := range req.Variables["affiliations"].([]any) {
+			names := map[string][]string{"OWNER": owned, "COLLABORATOR": collaborator, "ORGANIZATION_MEMBER": member}[affiliation.(string)]
+			for _, name := range names {
+				nodes = append(nodes, graphQLRepoJSON(name, false))
+			}
+		}
+		page := map[string]any{"nodes": nodes, "pageInfo": map[string]any{"hasNextPage": false}}
+		return http.StatusOK, map[string]any{"data": map[string]any{"owner": map[string]any{"repositories": page}}}
+	}, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/users/alice/repos", r.URL.Path)
+		var repos []map[string]any
+		for _, name := range reposFor(r.URL.Query().Get("type")) {
+			repos = append(repos, map[string]any{"full_name": name})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		require.NoError(t, json.NewEncoder(w).Encode(repos))
+	})
+
+	list := func(graphQL bool, repoType string) []string {
+		c.graphQL = graphQL
+		repos, err := c.ListUserRepos(context.Background(), "alice", &ListOptions{Type: repoType, PerPage: 100})
+		require.NoError(t, err)
+		names := make([]string, 0, len(repos))
+		for _, repo := range repos {
+			names = append(names, repo.GetFullName())
+		}
+		return names
+	}
+
+	for _, repoType := range []string{"", "owner", "member", "all"} {
+		rest := list(false, repoType)
+		assert.Equal(t, reposFor(repoType), rest, "type %q", repoType)
+		assert.ElementsMatch(t, rest, list(true, repoType), "type %q", repoType)
+	}
+}
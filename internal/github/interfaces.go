@@ -27,11 +27,26 @@ type Client interface {
 	// GetRepository returns information about a single repository
 	GetRepository(ctx context.Context, owner, repo string) (*gh.Repository, error)
 
+	// GetRepositories returns information about the named repositories
+	// (owner/repo), and the errors of the ones that couldn't be fetched.
+	// Fails as a whole if the token is rejected or lacks access.
+	GetRepositories(ctx context.Context, fullNames []string) ([]*gh.Repository, map[string]error, error)
+
 	// GetRateLimit returns the current rate limit status
 	GetRateLimit(ctx context.Context) (*gh.RateLimits, error)
 
 	// GetBranchRef returns the SHA of a branch (used for fast sync check)
 	GetBranchRef(ctx context.Context, owner, repo, branch string) (string, error)
+
+	// ListOwnerEvents returns the recent events of a user or organization
+	ListOwnerEvents(ctx context.Context, owner string, opts *EventOptions) (*Events, error)
+
+	// GetTokenInfo returns the user, scopes and expiry of the token
+	GetTokenInfo(ctx context.Context) (*TokenInfo, error)
+
+	// CheckOrgAccess checks that the token can read an organization's
+	// repositories, including its SAML SSO authorization
+	CheckOrgAccess(ctx context.Context, org string) error
 }
 
 // ListOptions specifies optional parameters for list operations
@@ -44,6 +59,9 @@ type ListOptions struct {
 
 	// PerPage specifies the number of results per page (max 100)
 	PerPage int
+
+	// MaxPages stops listing after this many pages (0 = all pages)
+	MaxPages int
 }
 
 // DefaultListOptions returns default list options
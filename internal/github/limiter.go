@@ -0,0 +1,316 @@
+package github
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultRateLimitReserve is how many requests syncs leave for
+	// interactive use
+	DefaultRateLimitReserve = 100
+
+	// secondaryRateLimitPause is how long to pause after a secondary rate
+	// limit that doesn't say when to retry, as GitHub recommends
+	secondaryRateLimitPause = time.Minute
+
+	// rateLimitWaitUpdate is how often waiting requests report the wait again
+	rateLimitWaitUpdate = time.Minute
+
+	// Rate limit resources (X-RateLimit-Resource)
+	resourceCore    = "core"
+	resourceSearch  = "search"
+	resourceGraphQL = "graphql"
+)
+
+// RateLimitWait describes a pause for the rate limit
+type RateLimitWait struct {
+	// Until is when requests resume
+	Until time.Time
+
+	// Resource is the rate limit that ran out (core, search or graphql)
+	Resource string
+
+	// Secondary is true for GitHub's secondary (abuse) rate limits
+	Secondary bool
+
+	// Reserve is true if the requests left are held back for interactive use
+	Reserve bool
+}
+
+// Remaining returns how long the wait has left
+func (w RateLimitWait) Remaining() time.Duration {
+	return max(time.Until(w.Until), 0).Round(time.Second)
+}
+
+// rateBucket is the last known state of one rate limit resource
+type rateBucket struct {
+	limit     int
+	remaining int
+	reset     time.Time
+}
+
+// RateLimiter tracks a token's rate limits from the headers of its responses
+// and holds requests back until the limit resets, rather than letting them
+// fail. Requests whose context is marked with WithRateLimitReserve also stop
+// while only the reserve is left, so syncs never use up the requests of
+// interactive use.
+type RateLimiter struct {
+	mu      sync.Mutex
+	reserve int
+	buckets map[string]*rateBucket
+
+	// paused holds every request back after a secondary rate limit
+	paused time.Time
+
+	// Requests waiting, and the latest wait reported
+	waiting  int
+	current  RateLimitWait
+	notified time.Time
+	onWait   func(RateLimitWait)
+}
+
+// NewRateLimiter creates a rate limiter that keeps reserve requests for
+// interactive use (0 = none)
+func NewRateLimiter(reserve int) *RateLimiter {
+	return &RateLimiter{
+		reserve: max(reserve, 0),
+		buckets: make(map[string]*rateBucket),
+	}
+}
+
+// OnWait sets a function called when requests start waiting for the rate
+// limit, and about once a minute while they keep waiting
+func (l *RateLimiter) OnWait(fn func(RateLimitWait)) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.onWait = fn
+}
+
+// Waiting returns the current wait, if any requests are waiting
+func (l *RateLimiter) Waiting() (RateLimitWait, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.current, l.waiting > 0
+}
+
+// reserveKey marks contexts of requests that leave the reserve alone
+type reserveKey struct{}
+
+// WithRateLimitReserve returns a context whose requests wait while only the
+// rate limiter's reserve is left. Background work such as syncs uses it.
+func WithRateLimitReserve(ctx context.Context) context.Context {
+	return context.WithValue(ctx, reserveKey{}, true)
+}
+
+// keepsReserve reports whether requests with ctx leave the reserve alone
+func keepsReserve(ctx context.Context) bool {
+	keep, _ := ctx.Value(reserveKey{}).(bool)
+	return keep
+}
+
+// resourceOf returns the rate limit resource a request counts against, or
+// an empty string if it doesn't count
+func resourceOf(req *http.Request) string {
+	path := strings.TrimPrefix(req.URL.Path, "/api/v3")
+	switch {
+	case path == "/rate_limit":
+		return ""
+	case strings.HasPrefix(path, "/api/graphql"), strings.HasPrefix(path, "/graphql"):
+		return resourceGraphQL
+	case strings.HasPrefix(path, "/search/"):
+		return resourceSearch
+	}
+	return resourceCore
+}
+
+// delay returns how long a request for resource has to wait, and why
+func (l *RateLimiter) delay(resource string, keepReserve bool, now time.Time) (RateLimitWait, time.Duration) {
+	if l.paused.After(now) {
+		return RateLimitWait{Until: l.paused, Resource: resource, Secondary: true}, l.paused.Sub(now)
+	}
+
+	// The reset time has a one second resolution
+	bucket, ok := l.buckets[resource]
+	if !ok || !bucket.reset.Add(time.Second).After(now) {
+		return RateLimitWait{}, 0
+	}
+	threshold := 0
+	if keepReserve {
+		// A reserve larger than a tenth of the limit would stall small limits
+		threshold = min(l.reserve, bucket.limit/10)
+	}
+	if bucket.remaining > threshold {
+		return RateLimitWait{}, 0
+	}
+	until := bucket.reset.Add(time.Second)
+	return RateLimitWait{Until: until, Resource: resource, Reserve: bucket.remaining > 0}, until.Sub(now)
+}
+
+// Delay returns how long a request for the core API would have to wait now
+func (l *RateLimiter) Delay(ctx context.Context) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, d := l.delay(resourceCore, keepsReserve(ctx), time.Now())
+	return d
+}
+
+// Wait blocks until a request for resource may be sent or ctx is done
+func (l *RateLimiter) Wait(ctx context.Context, resource string) error {
+	if resource == "" {
+		return nil
+	}
+	keepReserve := keepsReserve(ctx)
+	counted := false
+	defer func() {
+		if counted {
+			l.mu.Lock()
+			l.waiting--
+			l.mu.Unlock()
+		}
+	}()
+
+	for {
+		l.mu.Lock()
+		wait, d := l.delay(resource, keepReserve, time.Now())
+		if d <= 0 {
+			l.mu.Unlock()
+			return nil
+		}
+		if !counted {
+			l.waiting++
+			counted = true
+		}
+		notify := l.notify(wait)
+		l.mu.Unlock()
+		if notify != nil {
+			notify(wait)
+		}
+
+		timer := time.NewTimer(min(d, rateLimitWaitUpdate))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// notify records wait as the current one and returns the function to report
+// it with, or nil if it was reported recently. Called with l.mu held.
+func (l *RateLimiter) notify(wait RateLimitWait) func(RateLimitWait) {
+	now := time.Now()
+	changed := !wait.Until.Equal(l.current.Until) || wait.Resource != l.current.Resource
+	l.current = wait
+	if !changed && now.Sub(l.notified) < rateLimitWaitUpdate {
+		return nil
+	}
+	l.notified = now
+	return l.onWait
+}
+
+// Observe updates the rate limits from the headers of resp
+func (l *RateLimiter) Observe(resp *http.Response) {
+	if resp == nil {
+		return
+	}
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	resource := resp.Header.Get("X-RateLimit-Resource")
+	if resource == "" && resp.Request != nil {
+		resource = resourceOf(resp.Request)
+	}
+	remaining, errRemaining := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining"))
+	reset, errReset := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+	if resource != "" && errRemaining == nil && errReset == nil {
+		limit, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Limit"))
+		if err != nil {
+			limit = remaining
+		}
+		l.buckets[resource] = &rateBucket{limit: limit, remaining: remaining, reset: time.Unix(reset, 0)}
+	}
+
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return
+	}
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+		l.pause(now.Add(time.Duration(seconds) * time.Second))
+		return
+	}
+	if errRemaining == nil && remaining == 0 {
+		// Primary rate limit; the bucket holds requests until the reset
+		return
+	}
+	if resp.StatusCode == http.StatusTooManyRequests || secondaryLimited(resp) {
+		l.pause(now.Add(secondaryRateLimitPause))
+	}
+}
+
+// pause holds every request back until until
+func (l *RateLimiter) pause(until time.Time) {
+	if until.After(l.paused) {
+		l.paused = until
+	}
+}
+
+// secondaryLimited reports whether a 403 response is a secondary rate limit
+// rather than a permission error. The body is left readable.
+func secondaryLimited(resp *http.Response) bool {
+	if resp.Body == nil {
+		return false
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	resp.Body = readCloser{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}
+	if err != nil {
+		return false
+	}
+	return bytes.Contains(bytes.ToLower(body), []byte("secondary rate limit"))
+}
+
+// Transport returns a RoundTripper that waits for the rate limit before
+// sending requests with base (http.DefaultTransport if nil) and observes
+// their responses
+func (l *RateLimiter) Transport(base http.RoundTripper) http.RoundTripper {
+	return &rateLimitTransport{limiter: l, base: base}
+}
+
+// rateLimitTransport holds requests back for a RateLimiter
+type rateLimitTransport struct {
+	limiter *RateLimiter
+	base    http.RoundTripper
+}
+
+// RoundTrip waits for the rate limit, then sends req
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if err := t.limiter.Wait(req.Context(), resourceOf(req)); err != nil {
+		return nil, err
+	}
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	t.limiter.Observe(resp)
+	return resp, nil
+}
+
+// RateLimiterOf returns the rate limiter of c, or nil if it has none
+func RateLimiterOf(c Client) *RateLimiter {
+	if limited, ok := c.(interface{ RateLimiter() *RateLimiter }); ok {
+		return limited.RateLimiter()
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rateLimitResponse creates a response with rate limit headers
+func rateLimitResponse(status, limit, remaining int, reset time.Time, body string) *http.Response {
+	req, _ := http.NewRequest(http.MethodGet, "https://api.github.com/repos/owner/api", nil)
+	header := http.Header{}
+	header.Set("X-RateLimit-Limit", strconv.Itoa(limit))
+	header.Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+	header.Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+	return &http.Response{StatusCode: status, Header: header, Body: io.NopCloser(strings.NewReader(body)), Request: req}
+}
+
+func TestRateLimiter(t *testing.T) {
+	background := WithRateLimitReserve(context.Background())
+	interactive := context.Background()
+	reset := time.Now().Add(time.Hour)
+
+	t.Run("waits for the reset when the limit ran out", func(t *testing.T) {
+		limiter := NewRateLimiter(0)
+		assert.Zero(t, limiter.Delay(interactive))
+
+		limiter.Observe(rateLimitResponse(http.StatusForbidden, 5000, 0, reset, `{"message":"API rate limit exceeded"}`))
+		assert.InDelta(t, time.Hour.Seconds(), limiter.Delay(interactive).Seconds(), 2)
+	})
+
+	t.Run("keeps the reserve for interactive use", func(t *testing.T) {
+		limiter := NewRateLimiter(100)
+		limiter.Observe(rateLimitResponse(http.StatusOK, 5000, 50, reset, ""))
+		assert.Positive(t, limiter.Delay(background))
+		assert.Zero(t, limiter.Delay(interactive))
+
+		// Small limits keep a tenth at most
+		limiter.Observe(rateLimitResponse(http.StatusOK, 60, 50, reset, ""))
+		assert.Zero(t, limiter.Delay(background))
+	})
+
+	t.Run("ignores limits that already reset", func(t *testing.T) {
+		limiter := NewRateLimiter(0)
+		limiter.Observe(rateLimitResponse(http.StatusOK, 5000, 0, time.Now().Add(-time.Minute), ""))
+		assert.Zero(t, limiter.Delay(interactive))
+	})
+
+	t.Run("pauses for Retry-After", func(t *testing.T) {
+		limiter := NewRateLimiter(0)
+		resp := rateLimitResponse(http.StatusTooManyRequests, 5000, 4000, reset, "")
+		resp.Header.Set("Retry-After", "30")
+		limiter.Observe(resp)
+		assert.InDelta(t, 30, limiter.Delay(interactive).Seconds(), 1)
+	})
+
+	t.Run("pauses for secondary rate limits", func(t *testing.T) {
+		limiter := NewRateLimiter(0)
+		body := `{"message":"You have exceeded a secondary rate limit."}`
+		resp := rateLimitResponse(http.StatusForbidden, 5000, 4000, reset, body)
+		limiter.Observe(resp)
+		assert.InDelta(t, secondaryRateLimitPause.Seconds(), limiter.Delay(interactive).Seconds(), 1)
+
+		read, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, body, string(read), "the body stays readable")
+	})
+
+	t.Run("permission errors don't pause", func(t *testing.T) {
+		limiter := NewRateLimiter(0)
+		limiter.Observe(rateLimitResponse(http.StatusForbidden, 5000, 4000, reset, `{"message":"Resource not accessible"}`))
+		assert.Zero(t, limiter.Delay(interactive))
+	})
+
+	t.Run("other resources have their own limits", func(t *testing.T) {
+		limiter := NewRateLimiter(0)
+		resp := rateLimitResponse(http.StatusForbidden, 30, 0, reset, "")
+		resp.Header.Set("X-RateLimit-Resource", resourceSearch)
+		limiter.Observe(resp)
+		assert.Zero(t, limiter.Delay(interactive))
+	})
+
+	t.Run("reports waits and stops when cancelled", func(t *testing.T) {
+		limiter := NewRateLimiter(0)
+		limiter.Observe(rateLimitResponse(http.StatusForbidden, 5000, 0, reset, ""))
+		waits := make(chan RateLimitWait, 1)
+		limiter.OnWait(func(wait RateLimitWait) { waits <- wait })
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error)
+		go func() { done <- limiter.Wait(ctx, resourceCore) }()
+
+		wait := <-waits
+		assert.Equal(t, resourceCore, wait.Resource)
+		assert.False(t, wait.Secondary)
+		current, waiting := limiter.Waiting()
+		assert.True(t, waiting)
+		assert.Equal(t, wait, current)
+
+		cancel()
+		assert.ErrorIs(t, <-done, context.Canceled)
+		_, waiting = limiter.Waiting()
+		assert.False(t, waiting)
+	})
+}
+
+func TestResourceOf(t *testing.T) {
+	for path, resource := range map[string]string{
+		"/repos/owner/api":         resourceCore,
+		"/search/repositories":     resourceSearch,
+		"/api/v3/search/code":      resourceSearch,
+		"/graphql":                 resourceGraphQL,
+		"/api/graphql":             resourceGraphQL,
+		"/repos/owner/search/tags": resourceCore,
+		"/rate_limit":              "",
+	} {
+		req := &http.Request{URL: &url.URL{Path: path}}
+		assert.Equal(t, resource, resourceOf(req), path)
+	}
+}
+
+func TestRetryableClient_WaitsForReset(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Limit", "5000")
+		// Resets within a second, as the reset time has a one second resolution
+		w.Header().Set("X-RateLimit-Reset", fmt.Sprint(time.Now().Unix()))
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"message":"API rate limit exceeded"}`)
+			return
+		}
+		w.Header().Set("X-RateLimit-Remaining", "4999")
+		fmt.Fprint(w, `{"full_name":"owner/api"}`)
+	}))
+	defer server.Close()
+
+	inner := NewClientWithOptions("token", nil).(*client)
+	baseURL, err := url.Parse(server.URL + "/")
+	require.NoError(t, err)
+	inner.ghClient.BaseURL = baseURL
+
+	retryable := NewRetryableClient(inner, nil)
+	require.Same(t, inner.limiter, RateLimiterOf(retryable))
+	var waits int32
+	retryable.RateLimiter().OnWait(func(RateLimitWait) { atomic.AddInt32(&waits, 1) })
+
+	repo, err := retryable.GetRepository(context.Background(), "owner", "api")
+	require.NoError(t, err)
+	assert.Equal(t, "owner/api", repo.GetFullName())
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&waits))
+}
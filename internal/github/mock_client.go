@@ -26,12 +26,25 @@ type MockClient struct {
 	// GetRepositoryFunc can be set to mock GetRepository behavior
 	GetRepositoryFunc func(ctx context.Context, owner, repo string) (*gh.Repository, error)
 
+	// GetRepositoriesFunc can be set to mock GetRepositories behavior; by
+	// default it calls GetRepository for each repository
+	GetRepositoriesFunc func(ctx context.Context, fullNames []string) ([]*gh.Repository, map[string]error, error)
+
 	// GetRateLimitFunc can be set to mock GetRateLimit behavior
 	GetRateLimitFunc func(ctx context.Context) (*gh.RateLimits, error)
 
 	// GetBranchRefFunc can be set to mock GetBranchRef behavior
 	GetBranchRefFunc func(ctx context.Context, owner, repo, branch string) (string, error)
 
+	// ListOwnerEventsFunc can be set to mock ListOwnerEvents behavior
+	ListOwnerEventsFunc func(ctx context.Context, owner string, opts *EventOptions) (*Events, error)
+
+	// GetTokenInfoFunc can be set to mock GetTokenInfo behavior
+	GetTokenInfoFunc func(ctx context.Context) (*TokenInfo, error)
+
+	// CheckOrgAccessFunc can be set to mock CheckOrgAccess behavior
+	CheckOrgAccessFunc func(ctx context.Context, org string) error
+
 	// Call tracking
 	Calls []MockCall
 }
@@ -103,6 +116,15 @@ func (m *MockClient) GetRepository(ctx context.Context, owner, repo string) (*gh
 	return nil, nil
 }
 
+// GetRepositories implements Client.GetRepositories
+func (m *MockClient) GetRepositories(ctx context.Context, fullNames []string) ([]*gh.Repository, map[string]error, error) {
+	m.Calls = append(m.Calls, MockCall{Method: "GetRepositories", Args: []interface{}{fullNames}})
+	if m.GetRepositoriesFunc != nil {
+		return m.GetRepositoriesFunc(ctx, fullNames)
+	}
+	return FetchRepositories(ctx, m, fullNames)
+}
+
 // GetRateLimit implements Client.GetRateLimit
 func (m *MockClient) GetRateLimit(ctx context.Context) (*gh.RateLimits, error) {
 	m.Calls = append(m.Calls, MockCall{Method: "GetRateLimit", Args: []interface{}{}})
@@ -121,6 +143,33 @@ func (m *MockClient) GetBranchRef(ctx context.Context, owner, repo, branch strin
 	return "mock-sha-12345", nil
 }
 
+// ListOwnerEvents implements Client.ListOwnerEvents
+func (m *MockClient) ListOwnerEvents(ctx context.Context, owner string, opts *EventOptions) (*Events, error) {
+	m.Calls = append(m.Calls, MockCall{Method: "ListOwnerEvents", Args: []interface{}{owner, opts}})
+	if m.ListOwnerEventsFunc != nil {
+		return m.ListOwnerEventsFunc(ctx, owner, opts)
+	}
+	return &Events{}, nil
+}
+
+// GetTokenInfo implements Client.GetTokenInfo
+func (m *MockClient) GetTokenInfo(ctx context.Context) (*TokenInfo, error) {
+	m.Calls = append(m.Calls, MockCall{Method: "GetTokenInfo", Args: []interface{}{}})
+	if m.GetTokenInfoFunc != nil {
+		return m.GetTokenInfoFunc(ctx)
+	}
+	return &TokenInfo{}, nil
+}
+
+// CheckOrgAccess implements Client.CheckOrgAccess
+func (m *MockClient) CheckOrgAccess(ctx context.Context, org string) error {
+	m.Calls = append(m.Calls, MockCall{Method: "CheckOrgAccess", Args: []interface{}{org}})
+	if m.CheckOrgAccessFunc != nil {
+		return m.CheckOrgAccessFunc(ctx, org)
+	}
+	return nil
+}
+
 // Reset clears all recorded calls
 func (m *MockClient) Reset() {
 	m.Calls = make([]MockCall, 0)
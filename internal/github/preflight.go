@@ -0,0 +1,188 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	gh "github.com/google/go-github/v68/github"
+
+	"github.com/Didstopia/githubby/internal/auth"
+	gherrors "github.com/Didstopia/githubby/internal/errors"
+)
+
+// PreflightSampleSize is how many repositories of each target have their
+// read access tested
+const PreflightSampleSize = 5
+
+// PreflightTarget is what a sync reads with a token
+type PreflightTarget struct {
+	// Name identifies the target in issues, e.g. the profile's name
+	Name string
+
+	// Owner is the user or organization whose repositories are synced
+	Owner string
+
+	// Org is true if Owner is an organization
+	Org bool
+
+	// IncludePrivate is true if private repositories are synced
+	IncludePrivate bool
+
+	// Repos are the repositories synced (owner/repo); empty means all of
+	// Owner's repositories
+	Repos []string
+}
+
+// PreflightIssue is something a sync will fail to read, and why
+type PreflightIssue struct {
+	// Target is the name of the target that will fail
+	Target string
+
+	// Repo is the repository (owner/repo) that will fail, empty if the
+	// whole target will
+	Repo string
+
+	// Reason says why
+	Reason string
+}
+
+// Preflight checks that a token of kind can read what targets sync: that it
+// has the scopes they need, is authorized for the SAML SSO of their
+// organizations, and can read a sample of their repositories. info is the
+// token's GetTokenInfo (nil if it isn't available, as for installation
+// tokens). Only a canceled ctx fails it; everything else is an issue.
+func Preflight(ctx context.Context, client Client, kind auth.TokenKind, info *TokenInfo, targets []PreflightTarget) ([]PreflightIssue, error) {
+	var issues []PreflightIssue
+	for _, target := range targets {
+		if err := ctx.Err(); err != nil {
+			return issues, err
+		}
+		issues = append(issues, preflightTarget(ctx, client, kind, info, target)...)
+	}
+	return issues, ctx.Err()
+}
+
+// preflightTarget checks a single target
+func preflightTarget(ctx context.Context, client Client, kind auth.TokenKind, info *TokenInfo, target PreflightTarget) []PreflightIssue {
+	var issues []PreflightIssue
+	issue := func(repo, reason string) {
+		issues = append(issues, PreflightIssue{Target: target.Name, Repo: repo, Reason: reason})
+	}
+
+	// Without the repo scope, private repositories aren't even listed
+	if info != nil && info.Scopes != nil && target.IncludePrivate && !info.HasScope("repo") {
+		issue("", fmt.Sprintf("private repositories will be skipped: the token lacks the repo scope (it has: %s)", FormatScopes(info.Scopes)))
+	}
+
+	if target.Org {
+		if err := client.CheckOrgAccess(ctx, target.Owner); err != nil {
+			issue("", fmt.Sprintf("organization %s: %s", target.Owner, DescribeAccessError(err, kind)))
+			return issues
+		}
+	}
+
+	sample, err := preflightSample(ctx, client, target)
+	if err != nil {
+		issue("", fmt.Sprintf("can't list the repositories of %s: %s", target.Owner, DescribeAccessError(err, kind)))
+		return issues
+	}
+
+	for _, repo := range sample {
+		name := repo.GetFullName()
+		owner, repoName, _ := strings.Cut(name, "/")
+		if len(target.Repos) > 0 {
+			// Selected repositories haven't been fetched yet
+			fetched, err := client.GetRepository(ctx, owner, repoName)
+			if err != nil {
+				issue(name, DescribeAccessError(err, kind))
+				continue
+			}
+			repo = fetched
+		}
+		if repo.Permissions != nil && !repo.Permissions["pull"] {
+			issue(name, "the token has no read permission")
+			continue
+		}
+		// Reading a branch needs the same access as cloning; empty
+		// repositories have none
+		if repo.GetSize() == 0 || repo.GetDefaultBranch() == "" {
+			continue
+		}
+		if _, err := client.GetBranchRef(ctx, owner, repoName, repo.GetDefaultBranch()); err != nil {
+			issue(name, "can't read its contents: "+DescribeAccessError(err, kind))
+		}
+	}
+	return issues
+}
+
+// preflightSample returns the repositories of a target to test, private ones
+// first, taken from the first page of its repositories so large owners
+// aren't listed in full. Selected repositories are returned with only their
+// names set.
+func preflightSample(ctx context.Context, client Client, target PreflightTarget) ([]*gh.Repository, error) {
+	var repos []*gh.Repository
+	if len(target.Repos) > 0 {
+		for _, name := range target.Repos {
+			repos = append(repos, &gh.Repository{FullName: gh.Ptr(name)})
+		}
+	} else {
+		opts := DefaultListOptions()
+		opts.IncludePrivate = target.IncludePrivate
+		opts.MaxPages = 1
+		var err error
+		if target.Org {
+			repos, err = client.ListOrgRepos(ctx, target.Owner, opts)
+		} else {
+			repos, err = client.ListUserRepos(ctx, target.Owner, opts)
+		}
+		if err != nil {
+			return nil, err
+		}
+		sort.SliceStable(repos, func(i, j int) bool {
+			return repos[i].GetPrivate() && !repos[j].GetPrivate()
+		})
+	}
+
+	if len(repos) > PreflightSampleSize {
+		repos = repos[:PreflightSampleSize]
+	}
+	return repos, nil
+}
+
+// DescribeAccessError says why a token of kind was denied access, with hints
+// that depend on how the token gets its permissions
+func DescribeAccessError(err error, kind auth.TokenKind) string {
+	var ssoErr *gherrors.SSOError
+	switch {
+	case errors.As(err, &ssoErr):
+		if ssoErr.URL != "" {
+			return "requires SAML SSO; authorize the token at " + ssoErr.URL
+		}
+		return "requires SAML SSO; authorize the token for the organization"
+	case gherrors.IsUnauthorized(err):
+		return "the token is invalid or expired"
+	case gherrors.IsForbidden(err) && kind == auth.TokenKindFineGrained:
+		return fmt.Sprintf("%v (check the fine-grained token's resource owner and repository permissions)", err)
+	case gherrors.IsForbidden(err) && kind == auth.TokenKindAppInstallation:
+		return fmt.Sprintf("%v (check the GitHub App's permissions and installed repositories)", err)
+	case gherrors.IsNotFound(err) && kind == auth.TokenKindFineGrained:
+		return "not found, or the fine-grained token doesn't include it"
+	case gherrors.IsNotFound(err) && kind == auth.TokenKindAppInstallation:
+		return "not found, or the GitHub App isn't installed on it"
+	case gherrors.IsNotFound(err):
+		return "not found, or the token can't see it (private repositories need the repo scope)"
+	default:
+		return err.Error()
+	}
+}
+
+// FormatScopes lists OAuth scopes for messages
+func FormatScopes(scopes []string) string {
+	if len(scopes) == 0 {
+		return "no scopes"
+	}
+	return strings.Join(scopes, ", ")
+}
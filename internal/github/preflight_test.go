@@ -0,0 +1,91 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	gh "github.com/google/go-github/v68/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Didstopia/githubby/internal/auth"
+	gherrors "github.com/Didstopia/githubby/internal/errors"
+)
+
+// preflightRepo returns a listed repository with contents
+func preflightRepo(name string, private bool) *gh.Repository {
+	return &gh.Repository{
+		FullName:      gh.Ptr(name),
+		Private:       gh.Ptr(private),
+		Size:          gh.Ptr(10),
+		DefaultBranch: gh.Ptr("main"),
+	}
+}
+
+func TestPreflight(t *testing.T) {
+	ssoURL := "https://github.com/orgs/acme/sso?authorization_request=abc"
+	client := NewMockClient()
+	client.CheckOrgAccessFunc = func(ctx context.Context, org string) error {
+		if org == "acme" {
+			return &gherrors.SSOError{URL: ssoURL}
+		}
+		return nil
+	}
+	client.ListUserReposFunc = func(ctx context.Context, username string, opts *ListOptions) ([]*gh.Repository, error) {
+		return []*gh.Repository{preflightRepo("alice/public", false), preflightRepo("alice/secret", true)}, nil
+	}
+	client.GetRepositoryFunc = func(ctx context.Context, owner, repo string) (*gh.Repository, error) {
+		if repo == "gone" {
+			return nil, gherrors.ErrNotFound
+		}
+		return preflightRepo(owner+"/"+repo, false), nil
+	}
+	client.GetBranchRefFunc = func(ctx context.Context, owner, repo, branch string) (string, error) {
+		if repo == "secret" {
+			return "", fmt.Errorf("%w: Resource not accessible by personal access token", gherrors.ErrForbidden)
+		}
+		return "abc123", nil
+	}
+
+	targets := []PreflightTarget{
+		{Name: "personal", Owner: "alice", IncludePrivate: true},
+		{Name: "work", Owner: "acme", Org: true},
+		{Name: "picked", Owner: "bob", Repos: []string{"bob/tools", "bob/gone"}},
+	}
+
+	t.Run("classic token", func(t *testing.T) {
+		info := &TokenInfo{Login: "alice", Scopes: []string{"public_repo"}}
+		issues, err := Preflight(context.Background(), client, auth.TokenKindClassic, info, targets)
+		require.NoError(t, err)
+		assert.Equal(t, []PreflightIssue{
+			{Target: "personal", Reason: "private repositories will be skipped: the token lacks the repo scope (it has: public_repo)"},
+			{Target: "personal", Repo: "alice/secret", Reason: "can't read its contents: forbidden: insufficient permissions: Resource not accessible by personal access token"},
+			{Target: "work", Reason: "organization acme: requires SAML SSO; authorize the token at " + ssoURL},
+			{Target: "picked", Repo: "bob/gone", Reason: "not found, or the token can't see it (private repositories need the repo scope)"},
+		}, issues)
+	})
+
+	t.Run("fine-grained token", func(t *testing.T) {
+		issues, err := Preflight(context.Background(), client, auth.TokenKindFineGrained, &TokenInfo{Login: "alice"}, targets[2:])
+		require.NoError(t, err)
+		require.Len(t, issues, 1)
+		assert.Equal(t, "not found, or the fine-grained token doesn't include it", issues[0].Reason)
+	})
+
+	t.Run("samples private repositories first", func(t *testing.T) {
+		var repos []*gh.Repository
+		for i := range PreflightSampleSize + 3 {
+			repos = append(repos, preflightRepo(fmt.Sprintf("big/repo%d", i), i == PreflightSampleSize+2))
+		}
+		sample, err := preflightSample(context.Background(), &MockClient{
+			ListOrgReposFunc: func(ctx context.Context, org string, opts *ListOptions) ([]*gh.Repository, error) {
+				assert.Equal(t, 1, opts.MaxPages, "only the first page is listed")
+				return repos, nil
+			},
+		}, PreflightTarget{Owner: "big", Org: true, IncludePrivate: true})
+		require.NoError(t, err)
+		require.Len(t, sample, PreflightSampleSize)
+		assert.True(t, sample[0].GetPrivate())
+	})
+}
@@ -5,6 +5,8 @@ import (
 	"math"
 	"time"
 
+	gh "github.com/google/go-github/v68/github"
+
 	gherrors "github.com/Didstopia/githubby/internal/errors"
 )
 
@@ -33,10 +35,13 @@ func DefaultRetryConfig() *RetryConfig {
 	}
 }
 
-// RetryableClient wraps a Client with retry logic
+// RetryableClient wraps a Client and retries calls that hit the rate limit.
+// If the wrapped client has a RateLimiter, retries wait until the limit
+// resets; otherwise they back off exponentially.
 type RetryableClient struct {
-	client Client
-	config *RetryConfig
+	client  Client
+	config  *RetryConfig
+	limiter *RateLimiter
 }
 
 // NewRetryableClient creates a new client with retry support
@@ -45,18 +50,29 @@ func NewRetryableClient(c Client, config *RetryConfig) *RetryableClient {
 		config = DefaultRetryConfig()
 	}
 	return &RetryableClient{
-		client: c,
-		config: config,
+		client:  c,
+		config:  config,
+		limiter: RateLimiterOf(c),
 	}
 }
 
+// RateLimiter returns the rate limiter of the wrapped client, if any
+func (r *RetryableClient) RateLimiter() *RateLimiter {
+	return r.limiter
+}
+
 // withRetry executes a function with exponential backoff retry logic
 func (r *RetryableClient) withRetry(ctx context.Context, operation func() error) error {
 	var lastErr error
 	delay := r.config.InitialDelay
 
 	for attempt := 0; attempt <= r.config.MaxRetries; attempt++ {
-		if attempt > 0 {
+		if attempt > 0 && r.limiter != nil && r.limiter.Delay(ctx) > 0 {
+			// The rate limiter knows when the limit resets
+			if err := r.limiter.Wait(ctx, resourceCore); err != nil {
+				return err
+			}
+		} else if attempt > 0 {
 			// Wait before retrying
 			select {
 			case <-ctx.Done():
@@ -93,3 +109,114 @@ func calculateBackoff(attempt int, config *RetryConfig) time.Duration {
 	}
 	return time.Duration(delay)
 }
+
+// GetReleases returns all releases for a repository
+func (r *RetryableClient) GetReleases(ctx context.Context, owner, repo string) ([]*gh.RepositoryRelease, error) {
+	var releases []*gh.RepositoryRelease
+	err := r.withRetry(ctx, func() (err error) {
+		releases, err = r.client.GetReleases(ctx, owner, repo)
+		return err
+	})
+	return releases, err
+}
+
+// RemoveRelease deletes a release and its associated tag
+func (r *RetryableClient) RemoveRelease(ctx context.Context, owner, repo string, release *gh.RepositoryRelease) error {
+	return r.withRetry(ctx, func() error {
+		return r.client.RemoveRelease(ctx, owner, repo, release)
+	})
+}
+
+// ListUserRepos returns all repositories for a user
+func (r *RetryableClient) ListUserRepos(ctx context.Context, username string, opts *ListOptions) ([]*gh.Repository, error) {
+	var repos []*gh.Repository
+	err := r.withRetry(ctx, func() (err error) {
+		repos, err = r.client.ListUserRepos(ctx, username, opts)
+		return err
+	})
+	return repos, err
+}
+
+// ListOrgRepos returns all repositories for an organization
+func (r *RetryableClient) ListOrgRepos(ctx context.Context, org string, opts *ListOptions) ([]*gh.Repository, error) {
+	var repos []*gh.Repository
+	err := r.withRetry(ctx, func() (err error) {
+		repos, err = r.client.ListOrgRepos(ctx, org, opts)
+		return err
+	})
+	return repos, err
+}
+
+// ListUserOrgs returns all organizations the authenticated user belongs to
+func (r *RetryableClient) ListUserOrgs(ctx context.Context) ([]*gh.Organization, error) {
+	var orgs []*gh.Organization
+	err := r.withRetry(ctx, func() (err error) {
+		orgs, err = r.client.ListUserOrgs(ctx)
+		return err
+	})
+	return orgs, err
+}
+
+// GetRepository returns information about a single repository
+func (r *RetryableClient) GetRepository(ctx context.Context, owner, repo string) (*gh.Repository, error) {
+	var repository *gh.Repository
+	err := r.withRetry(ctx, func() (err error) {
+		repository, err = r.client.GetRepository(ctx, owner, repo)
+		return err
+	})
+	return repository, err
+}
+
+// GetRepositories returns information about the named repositories
+func (r *RetryableClient) GetRepositories(ctx context.Context, fullNames []string) ([]*gh.Repository, map[string]error, error) {
+	var repos []*gh.Repository
+	var failed map[string]error
+	err := r.withRetry(ctx, func() (err error) {
+		repos, failed, err = r.client.GetRepositories(ctx, fullNames)
+		return err
+	})
+	return repos, failed, err
+}
+
+// GetRateLimit returns the current rate limit status. It's never retried:
+// checking the rate limit doesn't count against it.
+func (r *RetryableClient) GetRateLimit(ctx context.Context) (*gh.RateLimits, error) {
+	return r.client.GetRateLimit(ctx)
+}
+
+// GetBranchRef returns the SHA of a branch
+func (r *RetryableClient) GetBranchRef(ctx context.Context, owner, repo, branch string) (string, error) {
+	var sha string
+	err := r.withRetry(ctx, func() (err error) {
+		sha, err = r.client.GetBranchRef(ctx, owner, repo, branch)
+		return err
+	})
+	return sha, err
+}
+
+// ListOwnerEvents returns the recent events of a user or organization
+func (r *RetryableClient) ListOwnerEvents(ctx context.Context, owner string, opts *EventOptions) (*Events, error) {
+	var events *Events
+	err := r.withRetry(ctx, func() (err error) {
+		events, err = r.client.ListOwnerEvents(ctx, owner, opts)
+		return err
+	})
+	return events, err
+}
+
+// GetTokenInfo returns the user, scopes and expiry of the token
+func (r *RetryableClient) GetTokenInfo(ctx context.Context) (*TokenInfo, error) {
+	var info *TokenInfo
+	err := r.withRetry(ctx, func() (err error) {
+		info, err = r.client.GetTokenInfo(ctx)
+		return err
+	})
+	return info, err
+}
+
+// CheckOrgAccess checks that the token can read an organization's repositories
+func (r *RetryableClient) CheckOrgAccess(ctx context.Context, org string) error {
+	return r.withRetry(ctx, func() error {
+		return r.client.CheckOrgAccess(ctx, org)
+	})
+}
@@ -0,0 +1,96 @@
+package github
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	gh "github.com/google/go-github/v68/github"
+)
+
+// tokenExpirationLayout is the format of the token expiration header
+const tokenExpirationLayout = "2006-01-02 15:04:05 MST"
+
+// impliedScopes lists the OAuth scopes that grant others
+var impliedScopes = map[string][]string{
+	"repo":            {"repo:status", "repo_deployment", "public_repo", "repo:invite", "security_events"},
+	"admin:org":       {"write:org", "read:org"},
+	"write:org":       {"read:org"},
+	"user":            {"read:user", "user:email", "user:follow"},
+	"admin:repo_hook": {"write:repo_hook", "read:repo_hook"},
+	"write:repo_hook": {"read:repo_hook"},
+}
+
+// TokenInfo describes the token a client authenticates with
+type TokenInfo struct {
+	// Login is the user the token belongs to
+	Login string
+
+	// Scopes are the token's OAuth scopes. Nil if GitHub didn't report any,
+	// as for fine-grained tokens, which have permissions instead.
+	Scopes []string
+
+	// Expires is when the token expires (zero if it doesn't, or GitHub
+	// didn't say)
+	Expires time.Time
+}
+
+// HasScope reports whether the token has scope, directly or through a
+// scope that grants it (e.g. repo grants public_repo)
+func (i *TokenInfo) HasScope(scope string) bool {
+	for _, have := range i.Scopes {
+		if have == scope {
+			return true
+		}
+		for _, implied := range impliedScopes[have] {
+			if implied == scope {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// GetTokenInfo returns the authenticated user, and the scopes and expiry of
+// the token from the response headers
+func (c *client) GetTokenInfo(ctx context.Context) (*TokenInfo, error) {
+	user, resp, err := c.ghClient.Users.Get(ctx, "")
+	if err != nil {
+		return nil, wrapAPIError(resp, err)
+	}
+	return tokenInfo(user, resp.Header), nil
+}
+
+// tokenInfo reads the token's details from a response to a request it made
+func tokenInfo(user *gh.User, header http.Header) *TokenInfo {
+	info := &TokenInfo{Login: user.GetLogin()}
+
+	// Classic tokens without scopes send an empty header; others send none
+	if values, ok := header[http.CanonicalHeaderKey("X-OAuth-Scopes")]; ok {
+		info.Scopes = []string{}
+		for _, value := range values {
+			for _, scope := range strings.Split(value, ",") {
+				if scope = strings.TrimSpace(scope); scope != "" {
+					info.Scopes = append(info.Scopes, scope)
+				}
+			}
+		}
+	}
+
+	if expires := header.Get("GitHub-Authentication-Token-Expiration"); expires != "" {
+		if t, err := time.Parse(tokenExpirationLayout, expires); err == nil {
+			info.Expires = t
+		}
+	}
+	return info
+}
+
+// CheckOrgAccess checks that the token can list an organization's
+// repositories, failing with an SSOError if the organization requires SAML
+// SSO the token isn't authorized for
+func (c *client) CheckOrgAccess(ctx context.Context, org string) error {
+	opts := &gh.RepositoryListByOrgOptions{ListOptions: gh.ListOptions{PerPage: 1}}
+	_, resp, err := c.ghClient.Repositories.ListByOrg(ctx, org, opts)
+	return wrapAPIError(resp, err)
+}
@@ -0,0 +1,92 @@
+package github
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	gherrors "github.com/Didstopia/githubby/internal/errors"
+)
+
+func TestGetTokenInfo(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	respond := func(header http.Header) {
+		httpmock.Reset()
+		httpmock.RegisterResponder("GET", "https://api.github.com/user", func(req *http.Request) (*http.Response, error) {
+			resp := httpmock.NewStringResponse(200, `{"login":"alice"}`)
+			for name, values := range header {
+				resp.Header[name] = values
+			}
+			return resp, nil
+		})
+	}
+
+	t.Run("classic token", func(t *testing.T) {
+		respond(http.Header{
+			"X-Oauth-Scopes":                         {"repo, read:org"},
+			"Github-Authentication-Token-Expiration": {"2026-12-31 23:59:59 UTC"},
+		})
+		info, err := NewClient("ghp_token").GetTokenInfo(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "alice", info.Login)
+		assert.Equal(t, []string{"repo", "read:org"}, info.Scopes)
+		assert.Equal(t, time.Date(2026, 12, 31, 23, 59, 59, 0, time.UTC), info.Expires.UTC())
+	})
+
+	t.Run("classic token without scopes", func(t *testing.T) {
+		respond(http.Header{"X-Oauth-Scopes": {""}})
+		info, err := NewClient("ghp_token").GetTokenInfo(context.Background())
+		require.NoError(t, err)
+		assert.NotNil(t, info.Scopes)
+		assert.Empty(t, info.Scopes)
+		assert.True(t, info.Expires.IsZero())
+	})
+
+	t.Run("fine-grained token", func(t *testing.T) {
+		respond(http.Header{})
+		info, err := NewClient("github_pat_token").GetTokenInfo(context.Background())
+		require.NoError(t, err)
+		assert.Nil(t, info.Scopes)
+	})
+}
+
+func TestTokenInfo_HasScope(t *testing.T) {
+	info := &TokenInfo{Scopes: []string{"repo", "admin:org"}}
+	assert.True(t, info.HasScope("repo"))
+	assert.True(t, info.HasScope("public_repo"), "granted by repo")
+	assert.True(t, info.HasScope("read:org"), "granted by admin:org")
+	assert.False(t, info.HasScope("workflow"))
+
+	info = &TokenInfo{Scopes: []string{"public_repo"}}
+	assert.False(t, info.HasScope("repo"))
+}
+
+func TestCheckOrgAccess_SSO(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	ssoURL := "https://github.com/orgs/acme/sso?authorization_request=abc"
+	httpmock.RegisterResponder("GET", "https://api.github.com/orgs/acme/repos", func(req *http.Request) (*http.Response, error) {
+		resp := httpmock.NewStringResponse(403, `{"message":"Resource protected by organization SAML enforcement."}`)
+		resp.Header.Set("X-GitHub-SSO", "required; url="+ssoURL)
+		return resp, nil
+	})
+	httpmock.RegisterResponder("GET", "https://api.github.com/orgs/open/repos",
+		httpmock.NewStringResponder(200, `[]`))
+
+	client := NewClient("ghp_token")
+	err := client.CheckOrgAccess(context.Background(), "acme")
+	var ssoErr *gherrors.SSOError
+	require.ErrorAs(t, err, &ssoErr)
+	assert.Equal(t, ssoURL, ssoErr.URL)
+	assert.True(t, gherrors.IsForbidden(err))
+
+	assert.NoError(t, client.CheckOrgAccess(context.Background(), "open"))
+}
@@ -0,0 +1,251 @@
+// Package lock provides cross-process advisory file locks.
+//
+// Locks are backed by the operating system (flock on Unix, LockFileEx on
+// Windows), so they are released automatically when the holding process
+// exits or crashes. The lock file records who holds the lock so contended
+// callers can report it, and a clean release empties the file; a lock file
+// that still has holder info when it is acquired was left behind by a
+// process that died while holding it.
+package lock
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ErrLocked is returned when a lock is held by another process
+var ErrLocked = errors.New("lock is held by another process")
+
+// errWouldBlock is returned by the platform lock implementation when the
+// lock is already held
+var errWouldBlock = errors.New("lock would block")
+
+// errUnsupported is returned by the platform lock implementation when the
+// filesystem doesn't support advisory locks (e.g., some network mounts)
+var errUnsupported = errors.New("file locking not supported")
+
+// pollInterval is how often Acquire retries a contended lock
+const pollInterval = 500 * time.Millisecond
+
+// Holder identifies the process holding a lock
+type Holder struct {
+	PID       int       `json:"pid"`
+	Hostname  string    `json:"hostname"`
+	StartedAt time.Time `json:"started_at"`
+}
+
+// String returns a human-readable description of the holder
+func (h *Holder) String() string {
+	if h == nil {
+		return "another process"
+	}
+	return fmt.Sprintf("PID %d on %s (since %s)", h.PID, h.Hostname, h.StartedAt.Local().Format("2006-01-02 15:04:05"))
+}
+
+// currentHolder describes the current process
+func currentHolder() *Holder {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	return &Holder{
+		PID:       os.Getpid(),
+		Hostname:  hostname,
+		StartedAt: time.Now(),
+	}
+}
+
+// LockedError is returned when a lock is held by another process
+type LockedError struct {
+	Path   string
+	Holder *Holder
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("%s is locked by %s", e.Path, e.Holder)
+}
+
+func (e *LockedError) Unwrap() error {
+	return ErrLocked
+}
+
+// HolderOf returns the lock holder from a LockedError, or nil
+func HolderOf(err error) *Holder {
+	var lockedErr *LockedError
+	if errors.As(err, &lockedErr) {
+		return lockedErr.Holder
+	}
+	return nil
+}
+
+// Lock is a held cross-process lock
+type Lock struct {
+	path string
+	file *os.File
+
+	// Stale is the holder recorded by a previous process that exited without
+	// releasing the lock, or nil if the lock was free
+	Stale *Holder
+}
+
+// TryAcquire takes the lock at path without waiting.
+// Returns a *LockedError if another process holds it.
+func TryAcquire(path string) (*Lock, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create lock directory: %w", err)
+	}
+
+	for {
+		f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open lock file: %w", err)
+		}
+
+		if err := lockFile(f); err != nil {
+			holder := readHolder(f)
+			f.Close()
+			if errors.Is(err, errWouldBlock) {
+				return nil, &LockedError{Path: path, Holder: holder}
+			}
+			if errors.Is(err, errUnsupported) {
+				// Fall back to running unlocked rather than refusing to work
+				return &Lock{path: path}, nil
+			}
+			return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+		}
+
+		// The previous holder may have removed the file between our open and
+		// lock; if so we locked an orphaned inode and must try again
+		if !sameFile(f, path) {
+			_ = unlockFile(f)
+			f.Close()
+			continue
+		}
+
+		l := &Lock{path: path, file: f, Stale: readHolder(f)}
+		if err := writeHolder(f, currentHolder()); err != nil {
+			_ = l.Release()
+			return nil, fmt.Errorf("failed to write lock file: %w", err)
+		}
+		return l, nil
+	}
+}
+
+// Acquire takes the lock at path, waiting until it is free or ctx is done.
+// onWait (optional) is called once with the current holder if the lock is
+// contended.
+func Acquire(ctx context.Context, path string, onWait func(holder *Holder)) (*Lock, error) {
+	notified := false
+	for {
+		l, err := TryAcquire(path)
+		if err == nil {
+			return l, nil
+		}
+		if !errors.Is(err, ErrLocked) {
+			return nil, err
+		}
+
+		if !notified && onWait != nil {
+			onWait(HolderOf(err))
+			notified = true
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("gave up waiting for lock: %w", err)
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// Release releases the lock and removes the lock file
+func (l *Lock) Release() error {
+	if l == nil || l.file == nil {
+		return nil
+	}
+
+	// Empty the file first so it isn't mistaken for a stale lock if the
+	// removal below fails (e.g., Windows with another process waiting on it)
+	_ = l.file.Truncate(0)
+	_ = os.Remove(l.path)
+
+	err := unlockFile(l.file)
+	l.file.Close()
+	l.file = nil
+	return err
+}
+
+// Path returns the path of the lock file
+func (l *Lock) Path() string {
+	return l.path
+}
+
+// sameFile reports whether f is still the file at path
+func sameFile(f *os.File, path string) bool {
+	openInfo, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	pathInfo, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(openInfo, pathInfo)
+}
+
+// readHolder reads holder info from a lock file. Returns nil if the file is
+// empty or unreadable.
+func readHolder(f *os.File) *Holder {
+	data, err := io.ReadAll(io.NewSectionReader(f, 0, 64*1024))
+	if err != nil || len(data) == 0 {
+		return nil
+	}
+	var holder Holder
+	if err := json.Unmarshal(data, &holder); err != nil {
+		return nil
+	}
+	return &holder
+}
+
+// writeHolder replaces the contents of a lock file with holder info
+func writeHolder(f *os.File, holder *Holder) error {
+	data, err := json.Marshal(holder)
+	if err != nil {
+		return err
+	}
+	if err := f.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := f.WriteAt(data, 0); err != nil {
+		return err
+	}
+	return f.Sync()
+}
+
+// Policy controls what a caller does when a lock is held by another process
+type Policy string
+
+const (
+	// PolicyWait waits until the lock is released
+	PolicyWait Policy = "wait"
+	// PolicySkip skips the locked work and carries on
+	PolicySkip Policy = "skip"
+	// PolicyFail fails with an error naming the holder
+	PolicyFail Policy = "fail"
+)
+
+// ParsePolicy parses a lock policy name
+func ParsePolicy(s string) (Policy, error) {
+	switch p := Policy(s); p {
+	case PolicyWait, PolicySkip, PolicyFail:
+		return p, nil
+	default:
+		return "", fmt.Errorf("invalid lock policy %q (expected wait, skip or fail)", s)
+	}
+}
@@ -0,0 +1,128 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTryAcquire(t *testing.T) {
+	t.Run("contended lock reports holder", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "test.lock")
+
+		l, err := TryAcquire(path)
+		require.NoError(t, err)
+		defer l.Release()
+		assert.Nil(t, l.Stale)
+
+		// Locks belong to the open file, so a second open conflicts even
+		// within the same process
+		_, err = TryAcquire(path)
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, ErrLocked))
+
+		holder := HolderOf(err)
+		require.NotNil(t, holder)
+		assert.Equal(t, os.Getpid(), holder.PID)
+		assert.NotEmpty(t, holder.Hostname)
+	})
+
+	t.Run("release frees the lock", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "test.lock")
+
+		l, err := TryAcquire(path)
+		require.NoError(t, err)
+		require.NoError(t, l.Release())
+		assert.NoFileExists(t, path)
+
+		l, err = TryAcquire(path)
+		require.NoError(t, err)
+		assert.Nil(t, l.Stale)
+		require.NoError(t, l.Release())
+	})
+
+	t.Run("detects stale lock", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "test.lock")
+		require.NoError(t, os.WriteFile(path, []byte(`{"pid":999999999,"hostname":"old-host","started_at":"2024-01-01T00:00:00Z"}`), 0600))
+
+		l, err := TryAcquire(path)
+		require.NoError(t, err)
+		defer l.Release()
+
+		require.NotNil(t, l.Stale)
+		assert.Equal(t, 999999999, l.Stale.PID)
+		assert.Equal(t, "old-host", l.Stale.Hostname)
+	})
+}
+
+func TestAcquire(t *testing.T) {
+	t.Run("waits for release", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "test.lock")
+
+		held, err := TryAcquire(path)
+		require.NoError(t, err)
+
+		var waitedOn *Holder
+		go func() {
+			time.Sleep(100 * time.Millisecond)
+			_ = held.Release()
+		}()
+
+		l, err := Acquire(context.Background(), path, func(holder *Holder) {
+			waitedOn = holder
+		})
+		require.NoError(t, err)
+		defer l.Release()
+
+		require.NotNil(t, waitedOn)
+		assert.Equal(t, os.Getpid(), waitedOn.PID)
+	})
+
+	t.Run("gives up when context is done", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "test.lock")
+
+		held, err := TryAcquire(path)
+		require.NoError(t, err)
+		defer held.Release()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		_, err = Acquire(ctx, path, nil)
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, ErrLocked))
+		assert.Contains(t, err.Error(), "gave up waiting")
+	})
+}
+
+func TestParsePolicy(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Policy
+		wantErr bool
+	}{
+		{input: "wait", want: PolicyWait},
+		{input: "skip", want: PolicySkip},
+		{input: "fail", want: PolicyFail},
+		{input: "", wantErr: true},
+		{input: "retry", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParsePolicy(tt.input)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
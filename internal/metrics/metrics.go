@@ -0,0 +1,376 @@
+// Package metrics collects sync metrics for scheduled and daemon modes and
+// serves them, with health checks, over HTTP
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Repository outcomes counted by githubby_repos_synced_total
+const (
+	StatusCloned   = "cloned"
+	StatusUpdated  = "updated"
+	StatusUpToDate = "up_to_date"
+	StatusSkipped  = "skipped"
+	StatusFailed   = "failed"
+	StatusArchived = "archived"
+)
+
+// statuses lists the repository outcomes in output order
+var statuses = []string{StatusCloned, StatusUpdated, StatusUpToDate, StatusSkipped, StatusFailed, StatusArchived}
+
+// Run describes a finished sync of one profile
+type Run struct {
+	// Profile is the name of the synced profile
+	Profile string
+
+	// Started and Finished bound the run
+	Started  time.Time
+	Finished time.Time
+
+	// Repos counts repositories by outcome (the Status constants)
+	Repos map[string]int
+
+	// BytesFetched is the data downloaded by the run
+	BytesFetched int64
+
+	// Err is the error the run failed with, if any
+	Err error
+}
+
+// profileMetrics holds the metrics of one profile
+type profileMetrics struct {
+	repos         map[string]float64
+	successes     float64
+	failures      float64
+	durationSum   float64
+	lastDuration  float64
+	lastRun       time.Time
+	lastSuccess   time.Time
+	lastError     string
+	bytesFetched  float64
+	lastRunFailed bool
+}
+
+// Metrics collects sync metrics. It's safe for concurrent use.
+type Metrics struct {
+	mu                 sync.Mutex
+	profiles           map[string]*profileMetrics
+	schedulerUp        bool
+	rateLimitRemaining int
+	rateLimitKnown     bool
+}
+
+// New creates an empty metrics collector
+func New() *Metrics {
+	return &Metrics{profiles: make(map[string]*profileMetrics)}
+}
+
+// SetSchedulerUp records whether the scheduler is running
+func (m *Metrics) SetSchedulerUp(up bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.schedulerUp = up
+}
+
+// SetRateLimitRemaining records the GitHub API requests left in the current window
+func (m *Metrics) SetRateLimitRemaining(remaining int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.rateLimitRemaining = remaining
+	m.rateLimitKnown = true
+}
+
+// RecordRun records a finished sync
+func (m *Metrics) RecordRun(run Run) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p, ok := m.profiles[run.Profile]
+	if !ok {
+		p = &profileMetrics{repos: make(map[string]float64)}
+		m.profiles[run.Profile] = p
+	}
+
+	for status, n := range run.Repos {
+		p.repos[status] += float64(n)
+	}
+	duration := run.Finished.Sub(run.Started).Seconds()
+	p.durationSum += duration
+	p.lastDuration = duration
+	p.lastRun = run.Finished
+	p.bytesFetched += float64(run.BytesFetched)
+
+	if run.Err != nil {
+		p.failures++
+		p.lastRunFailed = true
+		p.lastError = run.Err.Error()
+		return
+	}
+	p.successes++
+	p.lastRunFailed = false
+	p.lastError = ""
+	p.lastSuccess = run.Finished
+}
+
+// Retain drops the metrics of profiles not in names, e.g. after profiles
+// were deleted or renamed, so they no longer affect readiness
+func (m *Metrics) Retain(names []string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	keep := make(map[string]bool, len(names))
+	for _, name := range names {
+		keep[name] = true
+	}
+	for name := range m.profiles {
+		if !keep[name] {
+			delete(m.profiles, name)
+		}
+	}
+}
+
+// Handler serves /metrics, /healthz and /readyz
+func (m *Metrics) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", m.serveMetrics)
+	mux.HandleFunc("/healthz", m.serveHealth)
+	mux.HandleFunc("/readyz", m.serveReady)
+	return mux
+}
+
+// Start listens on addr and serves Handler in the background until ctx is
+// cancelled. Returns the address it listens on.
+func (m *Metrics) Start(ctx context.Context, addr string) (net.Addr, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	server := &http.Server{
+		Handler:           m.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("[metrics] Server error: %v", err)
+		}
+	}()
+
+	log.Printf("[metrics] Serving /metrics, /healthz and /readyz on %s", listener.Addr())
+	return listener.Addr(), nil
+}
+
+// healthStatus is the JSON body of /healthz and /readyz
+type healthStatus struct {
+	Status    string                   `json:"status"`
+	Scheduler string                   `json:"scheduler"`
+	Profiles  map[string]profileStatus `json:"profiles,omitempty"`
+}
+
+// profileStatus is a profile's last run in healthStatus
+type profileStatus struct {
+	LastRun     time.Time  `json:"last_run"`
+	LastSuccess *time.Time `json:"last_success,omitempty"`
+	Success     bool       `json:"success"`
+	Error       string     `json:"error,omitempty"`
+}
+
+// serveHealth reports whether the scheduler is running
+func (m *Metrics) serveHealth(w http.ResponseWriter, r *http.Request) {
+	status := m.health()
+	code := http.StatusOK
+	if status.Scheduler != "running" {
+		status.Status = "unhealthy"
+		code = http.StatusServiceUnavailable
+	}
+	writeJSON(w, code, status)
+}
+
+// serveReady reports whether the scheduler is running, at least one sync
+// has finished and the last sync of every profile succeeded
+func (m *Metrics) serveReady(w http.ResponseWriter, r *http.Request) {
+	status := m.health()
+	code := http.StatusOK
+
+	switch {
+	case status.Scheduler != "running":
+		status.Status = "unhealthy"
+	case len(status.Profiles) == 0:
+		status.Status = "waiting for the first sync"
+	default:
+		for _, p := range status.Profiles {
+			if !p.Success {
+				status.Status = "last sync failed"
+			}
+		}
+	}
+	if status.Status != "ok" {
+		code = http.StatusServiceUnavailable
+	}
+
+	writeJSON(w, code, status)
+}
+
+// health returns the current health, with status "ok"
+func (m *Metrics) health() healthStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	status := healthStatus{Status: "ok", Scheduler: "stopped"}
+	if m.schedulerUp {
+		status.Scheduler = "running"
+	}
+
+	if len(m.profiles) > 0 {
+		status.Profiles = make(map[string]profileStatus, len(m.profiles))
+	}
+	for name, p := range m.profiles {
+		ps := profileStatus{LastRun: p.lastRun, Success: !p.lastRunFailed, Error: p.lastError}
+		if !p.lastSuccess.IsZero() {
+			lastSuccess := p.lastSuccess
+			ps.LastSuccess = &lastSuccess
+		}
+		status.Profiles[name] = ps
+	}
+	return status
+}
+
+// writeJSON writes v as the response body
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// serveMetrics writes the metrics in the Prometheus text format
+func (m *Metrics) serveMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.WriteText(w)
+}
+
+// WriteText writes the metrics in the Prometheus text exposition format
+func (m *Metrics) WriteText(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	names := make([]string, 0, len(m.profiles))
+	for name := range m.profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	up := 0.0
+	if m.schedulerUp {
+		up = 1
+	}
+	writeHeader(w, "githubby_scheduler_up", "gauge", "Whether the scheduler is running.")
+	writeSample(w, "githubby_scheduler_up", nil, up)
+
+	if m.rateLimitKnown {
+		writeHeader(w, "githubby_rate_limit_remaining", "gauge", "GitHub API requests left in the current rate limit window.")
+		writeSample(w, "githubby_rate_limit_remaining", nil, float64(m.rateLimitRemaining))
+	}
+
+	if len(names) == 0 {
+		return
+	}
+
+	writeHeader(w, "githubby_repos_synced_total", "counter", "Repositories processed by syncs, by outcome.")
+	for _, name := range names {
+		for _, status := range statuses {
+			writeSample(w, "githubby_repos_synced_total", []string{"profile", name, "status", status}, m.profiles[name].repos[status])
+		}
+	}
+
+	writeHeader(w, "githubby_sync_runs_total", "counter", "Finished syncs, by result.")
+	for _, name := range names {
+		writeSample(w, "githubby_sync_runs_total", []string{"profile", name, "result", "success"}, m.profiles[name].successes)
+		writeSample(w, "githubby_sync_runs_total", []string{"profile", name, "result", "failure"}, m.profiles[name].failures)
+	}
+
+	writeHeader(w, "githubby_sync_duration_seconds", "summary", "Time taken by syncs.")
+	for _, name := range names {
+		p := m.profiles[name]
+		writeSample(w, "githubby_sync_duration_seconds_sum", []string{"profile", name}, p.durationSum)
+		writeSample(w, "githubby_sync_duration_seconds_count", []string{"profile", name}, p.successes+p.failures)
+	}
+
+	writeHeader(w, "githubby_sync_last_duration_seconds", "gauge", "Time taken by the last sync.")
+	for _, name := range names {
+		writeSample(w, "githubby_sync_last_duration_seconds", []string{"profile", name}, m.profiles[name].lastDuration)
+	}
+
+	writeHeader(w, "githubby_last_run_timestamp_seconds", "gauge", "Unix time the last sync finished.")
+	for _, name := range names {
+		writeSample(w, "githubby_last_run_timestamp_seconds", []string{"profile", name}, unixSeconds(m.profiles[name].lastRun))
+	}
+
+	writeHeader(w, "githubby_last_success_timestamp_seconds", "gauge", "Unix time the last successful sync finished (0 if none has).")
+	for _, name := range names {
+		writeSample(w, "githubby_last_success_timestamp_seconds", []string{"profile", name}, unixSeconds(m.profiles[name].lastSuccess))
+	}
+
+	writeHeader(w, "githubby_fetched_bytes_total", "counter", "Data added to local repositories by syncs.")
+	for _, name := range names {
+		writeSample(w, "githubby_fetched_bytes_total", []string{"profile", name}, m.profiles[name].bytesFetched)
+	}
+}
+
+// unixSeconds returns t as Unix seconds, or 0 for the zero time
+func unixSeconds(t time.Time) float64 {
+	if t.IsZero() {
+		return 0
+	}
+	return float64(t.UnixNano()) / float64(time.Second)
+}
+
+// writeHeader writes the HELP and TYPE lines of a metric
+func writeHeader(w io.Writer, name, metricType, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, metricType)
+}
+
+// writeSample writes a sample line. labels alternates label names and values.
+func writeSample(w io.Writer, name string, labels []string, value float64) {
+	var b strings.Builder
+	b.WriteString(name)
+	if len(labels) > 0 {
+		b.WriteByte('{')
+		for i := 0; i+1 < len(labels); i += 2 {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			fmt.Fprintf(&b, "%s=\"%s\"", labels[i], escapeLabel(labels[i+1]))
+		}
+		b.WriteByte('}')
+	}
+	fmt.Fprintf(w, "%s %s\n", b.String(), strconv.FormatFloat(value, 'f', -1, 64))
+}
+
+// labelEscaper escapes label values as the text format requires
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// escapeLabel escapes a label value
+func escapeLabel(value string) string {
+	return labelEscaper.Replace(value)
+}
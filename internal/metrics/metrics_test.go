@@ -0,0 +1,163 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// get requests path from handler and returns the status code and body
+func get(t *testing.T, handler http.Handler, path string) (int, string) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	return rec.Code, rec.Body.String()
+}
+
+func TestMetrics_WriteText(t *testing.T) {
+	m := New()
+	m.SetSchedulerUp(true)
+	m.SetRateLimitRemaining(4990)
+
+	started := time.Unix(1700000000, 0)
+	m.RecordRun(Run{
+		Profile:      "work",
+		Started:      started,
+		Finished:     started.Add(90 * time.Second),
+		Repos:        map[string]int{StatusCloned: 2, StatusUpdated: 1, StatusFailed: 1},
+		BytesFetched: 2048,
+	})
+	m.RecordRun(Run{
+		Profile:  "work",
+		Started:  started.Add(time.Hour),
+		Finished: started.Add(time.Hour + 30*time.Second),
+		Repos:    map[string]int{StatusUpToDate: 4},
+		Err:      errors.New("boom"),
+	})
+
+	var b strings.Builder
+	m.WriteText(&b)
+	out := b.String()
+
+	for _, line := range []string{
+		"# TYPE githubby_scheduler_up gauge",
+		"githubby_scheduler_up 1",
+		"githubby_rate_limit_remaining 4990",
+		"# TYPE githubby_repos_synced_total counter",
+		`githubby_repos_synced_total{profile="work",status="cloned"} 2`,
+		`githubby_repos_synced_total{profile="work",status="up_to_date"} 4`,
+		`githubby_repos_synced_total{profile="work",status="archived"} 0`,
+		`githubby_sync_runs_total{profile="work",result="success"} 1`,
+		`githubby_sync_runs_total{profile="work",result="failure"} 1`,
+		`githubby_sync_duration_seconds_sum{profile="work"} 120`,
+		`githubby_sync_duration_seconds_count{profile="work"} 2`,
+		`githubby_sync_last_duration_seconds{profile="work"} 30`,
+		`githubby_last_run_timestamp_seconds{profile="work"} 1700003630`,
+		`githubby_last_success_timestamp_seconds{profile="work"} 1700000090`,
+		`githubby_fetched_bytes_total{profile="work"} 2048`,
+	} {
+		assert.Contains(t, out, line+"\n")
+	}
+}
+
+func TestMetrics_WriteText_Empty(t *testing.T) {
+	var b strings.Builder
+	New().WriteText(&b)
+	assert.Equal(t, "# HELP githubby_scheduler_up Whether the scheduler is running.\n"+
+		"# TYPE githubby_scheduler_up gauge\n"+
+		"githubby_scheduler_up 0\n", b.String())
+}
+
+func TestEscapeLabel(t *testing.T) {
+	assert.Equal(t, `my \"profile\"\\n\n`, escapeLabel("my \"profile\"\\n\n"))
+}
+
+func TestMetrics_Health(t *testing.T) {
+	m := New()
+	handler := m.Handler()
+
+	code, _ := get(t, handler, "/healthz")
+	assert.Equal(t, http.StatusServiceUnavailable, code, "scheduler not running yet")
+
+	m.SetSchedulerUp(true)
+	code, _ = get(t, handler, "/healthz")
+	assert.Equal(t, http.StatusOK, code)
+
+	code, body := get(t, handler, "/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Contains(t, body, "waiting for the first sync")
+
+	now := time.Now()
+	m.RecordRun(Run{Profile: "work", Started: now, Finished: now})
+	m.RecordRun(Run{Profile: "home", Started: now, Finished: now, Err: errors.New("not authenticated")})
+
+	code, body = get(t, handler, "/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+
+	var status healthStatus
+	require.NoError(t, json.Unmarshal([]byte(body), &status))
+	assert.Equal(t, "last sync failed", status.Status)
+	assert.True(t, status.Profiles["work"].Success)
+	assert.NotNil(t, status.Profiles["work"].LastSuccess)
+	assert.False(t, status.Profiles["home"].Success)
+	assert.Nil(t, status.Profiles["home"].LastSuccess)
+	assert.Equal(t, "not authenticated", status.Profiles["home"].Error)
+
+	// A later successful run makes it ready again
+	m.RecordRun(Run{Profile: "home", Started: now, Finished: now})
+	code, _ = get(t, handler, "/readyz")
+	assert.Equal(t, http.StatusOK, code)
+
+	// Health doesn't depend on sync results
+	m.RecordRun(Run{Profile: "home", Started: now, Finished: now, Err: errors.New("boom")})
+	code, _ = get(t, handler, "/healthz")
+	assert.Equal(t, http.StatusOK, code)
+}
+
+func TestMetrics_Retain(t *testing.T) {
+	m := New()
+	m.SetSchedulerUp(true)
+	now := time.Now()
+	m.RecordRun(Run{Profile: "work", Started: now, Finished: now})
+	m.RecordRun(Run{Profile: "deleted", Started: now, Finished: now, Err: errors.New("boom")})
+
+	m.Retain([]string{"work", "new"})
+
+	code, body := get(t, m.Handler(), "/readyz")
+	assert.Equal(t, http.StatusOK, code)
+	assert.NotContains(t, body, "deleted")
+}
+
+func TestMetrics_Start(t *testing.T) {
+	m := New()
+	m.SetSchedulerUp(true)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	addr, err := m.Start(ctx, "127.0.0.1:0")
+	require.NoError(t, err)
+
+	resp, err := http.Get("http://" + addr.String() + "/metrics")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, resp.Header.Get("Content-Type"), "text/plain")
+	assert.Contains(t, string(body), "githubby_scheduler_up 1")
+
+	// The address is in use until the server stops
+	_, err = m.Start(ctx, addr.String())
+	assert.ErrorContains(t, err, "failed to listen")
+}
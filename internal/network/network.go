@@ -0,0 +1,309 @@
+// Package network applies the proxy and TLS settings of the config file to
+// every HTTP request githubby makes and to the git commands it runs
+package network
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/mitchellh/go-homedir"
+
+	"github.com/Didstopia/githubby/pkg/util"
+)
+
+// Config is the network section of the config file:
+//
+//	network:
+//	  proxy: http://proxy.mycompany.com:3128
+//	  no_proxy: [github.mycompany.com, .internal, 10.0.0.0/8]
+//	  ca_bundle: ~/certs/mycompany-root.pem
+type Config struct {
+	// Proxy is the URL of the proxy requests go through (empty = the
+	// HTTPS_PROXY and HTTP_PROXY environment variables)
+	Proxy string `yaml:"proxy,omitempty"`
+
+	// NoProxy lists hosts reached without the proxy: hostnames (which
+	// include their subdomains), .domain suffixes, host:port pairs, IP
+	// addresses, CIDR ranges or "*" for all
+	NoProxy []string `yaml:"no_proxy,omitempty"`
+
+	// CABundle is a PEM file of root certificates trusted in addition to
+	// the system's, such as the one of a TLS-inspecting proxy
+	CABundle string `yaml:"ca_bundle,omitempty"`
+
+	// InsecureSkipVerify disables certificate verification. Only meant for
+	// test labs: anyone on the network path can read the token.
+	InsecureSkipVerify bool `yaml:"insecure_skip_verify,omitempty"`
+}
+
+// IsZero reports whether no network settings are set
+func (c Config) IsZero() bool {
+	return c.Proxy == "" && len(c.NoProxy) == 0 && c.CABundle == "" && !c.InsecureSkipVerify
+}
+
+// Validate checks the proxy URL, no-proxy entries and CA bundle
+func (c Config) Validate() error {
+	_, err := c.Transport()
+	return err
+}
+
+// ProxyURL returns the parsed proxy URL, or nil if none is set. URLs
+// without a scheme, such as proxy.mycompany.com:3128, use http://.
+func (c Config) ProxyURL() (*url.URL, error) {
+	if c.Proxy == "" {
+		return nil, nil
+	}
+	raw := c.Proxy
+	if !strings.Contains(raw, "://") {
+		raw = "http://" + raw
+	}
+	proxy, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL %q: %w", c.Proxy, err)
+	}
+	switch proxy.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("invalid proxy URL %q: unsupported scheme %s (expected http, https or socks5)", c.Proxy, proxy.Scheme)
+	}
+	if proxy.Host == "" {
+		return nil, fmt.Errorf("invalid proxy URL %q: no host", c.Proxy)
+	}
+	return proxy, nil
+}
+
+// CABundlePath returns the path of the CA bundle with a leading ~ expanded
+func (c Config) CABundlePath() string {
+	if c.CABundle == "" {
+		return ""
+	}
+	if path, err := homedir.Expand(c.CABundle); err == nil {
+		return path
+	}
+	return c.CABundle
+}
+
+// Transport returns an HTTP transport that applies the settings on top of
+// Go's default transport
+func (c Config) Transport() (*http.Transport, error) {
+	transport := defaultTransport.Clone()
+
+	proxy, err := c.ProxyURL()
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range c.NoProxy {
+		if strings.TrimSpace(entry) == "" {
+			return nil, fmt.Errorf("invalid no_proxy entry: empty")
+		}
+	}
+	if proxy != nil || len(c.NoProxy) > 0 {
+		transport.Proxy = func(req *http.Request) (*url.URL, error) {
+			if c.bypassProxy(req.URL.Host) {
+				return nil, nil
+			}
+			if proxy == nil {
+				return http.ProxyFromEnvironment(req)
+			}
+			return proxy, nil
+		}
+	}
+
+	if c.CABundle != "" || c.InsecureSkipVerify {
+		tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12, InsecureSkipVerify: c.InsecureSkipVerify}
+		if c.CABundle != "" {
+			pool, err := c.certPool()
+			if err != nil {
+				return nil, err
+			}
+			tlsConfig.RootCAs = pool
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+	return transport, nil
+}
+
+// certPool returns the system's root certificates plus the CA bundle's
+func (c Config) certPool() (*x509.CertPool, error) {
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	data, err := os.ReadFile(c.CABundlePath())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+	}
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no PEM certificates found in CA bundle %s", c.CABundlePath())
+	}
+	return pool, nil
+}
+
+// systemCertFiles are where the system's root certificates are found as PEM
+// on Linux, the BSDs and macOS, in the order Go looks for them; SSL_CERT_FILE
+// takes precedence
+var systemCertFiles = []string{
+	"/etc/ssl/certs/ca-certificates.crt",
+	"/etc/pki/tls/certs/ca-bundle.crt",
+	"/etc/ssl/ca-bundle.pem",
+	"/etc/pki/tls/cacert.pem",
+	"/etc/pki/ca-trust/extracted/pem/tls-ca-bundle.pem",
+	"/etc/ssl/cert.pem",
+	"/usr/local/etc/ssl/cert.pem",
+	"/usr/local/share/certs/ca-root-nss.crt",
+}
+
+// systemCertsPEM returns the system's root certificates as PEM, or nil if
+// they aren't kept in a file (as on Windows)
+func systemCertsPEM() []byte {
+	files := systemCertFiles
+	if file := os.Getenv("SSL_CERT_FILE"); file != "" {
+		files = []string{file}
+	}
+	for _, file := range files {
+		if data, err := os.ReadFile(file); err == nil && len(data) > 0 {
+			return data
+		}
+	}
+	return nil
+}
+
+// gitCAInfo returns the file git trusts for TLS: the system's root
+// certificates followed by the CA bundle's, written to the user's cache
+// directory, so git trusts the bundle in addition to the system's
+// certificates like API requests do. git's http.sslCAInfo replaces its
+// default certificates, so the bundle is used alone if the system's
+// certificates or the cache directory can't be found. Empty without a CA
+// bundle.
+func (c Config) gitCAInfo() string {
+	if c.CABundle == "" {
+		return ""
+	}
+	bundle, err := os.ReadFile(c.CABundlePath())
+	system := systemCertsPEM()
+	if err != nil || system == nil {
+		return c.CABundlePath()
+	}
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return c.CABundlePath()
+	}
+
+	combined := append(append(bytes.TrimRight(system, "\n"), '\n'), bundle...)
+	sum := sha256.Sum256(combined)
+	path := filepath.Join(cacheDir, "githubby", "ca-"+hex.EncodeToString(sum[:8])+".pem")
+	if existing, err := os.ReadFile(path); err == nil && bytes.Equal(existing, combined) {
+		return path
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return c.CABundlePath()
+	}
+	if err := util.WriteFileAtomic(path, combined, 0600); err != nil {
+		return c.CABundlePath()
+	}
+	return path
+}
+
+// bypassProxy reports whether requests to hostport are sent without the
+// proxy. Like Go's default, loopback addresses never use it.
+func (c Config) bypassProxy(hostport string) bool {
+	host, port, err := net.SplitHostPort(hostport)
+	if err != nil {
+		host, port = hostport, ""
+	}
+	host = strings.ToLower(strings.Trim(host, "[]"))
+	ip := net.ParseIP(host)
+	if host == "localhost" || (ip != nil && ip.IsLoopback()) {
+		return true
+	}
+
+	for _, entry := range c.NoProxy {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "*" {
+			return true
+		}
+		if _, ipNet, err := net.ParseCIDR(entry); err == nil {
+			if ip != nil && ipNet.Contains(ip) {
+				return true
+			}
+			continue
+		}
+		entryHost, entryPort, err := net.SplitHostPort(entry)
+		if err != nil {
+			entryHost, entryPort = entry, ""
+		}
+		if entryPort != "" && entryPort != port {
+			continue
+		}
+		entryHost = strings.TrimPrefix(strings.TrimPrefix(entryHost, "*"), ".")
+		if entryIP := net.ParseIP(strings.Trim(entryHost, "[]")); entryIP != nil {
+			if ip != nil && entryIP.Equal(ip) {
+				return true
+			}
+			continue
+		}
+		if host == entryHost || strings.HasSuffix(host, "."+entryHost) {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultTransport is Go's default transport, before Configure replaces it
+var defaultTransport = http.DefaultTransport.(*http.Transport)
+
+var (
+	mu      sync.RWMutex
+	current Config
+
+	// currentGitCAInfo is current's gitCAInfo
+	currentGitCAInfo string
+)
+
+// Configure applies the settings to every HTTP client that uses Go's
+// default transport, which includes the GitHub API client, the device flow,
+// the update checker and notifications, and to git commands (see Current)
+func Configure(c Config) error {
+	transport, err := c.Transport()
+	if err != nil {
+		return err
+	}
+
+	gitCAInfo := c.gitCAInfo()
+
+	mu.Lock()
+	defer mu.Unlock()
+	current, currentGitCAInfo = c, gitCAInfo
+	if c.IsZero() {
+		http.DefaultTransport = defaultTransport
+	} else {
+		http.DefaultTransport = transport
+	}
+	return nil
+}
+
+// Current returns the settings applied with Configure
+func Current() Config {
+	mu.RLock()
+	defer mu.RUnlock()
+	return current
+}
+
+// GitCAInfo returns the file of root certificates git trusts with the
+// settings applied with Configure (empty = git's default certificates)
+func GitCAInfo() string {
+	mu.RLock()
+	defer mu.RUnlock()
+	return currentGitCAInfo
+}
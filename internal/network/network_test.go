@@ -0,0 +1,172 @@
+package network
+
+import (
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_ProxyURL(t *testing.T) {
+	proxy, err := Config{}.ProxyURL()
+	require.NoError(t, err)
+	assert.Nil(t, proxy)
+
+	proxy, err = Config{Proxy: "proxy.example.com:3128"}.ProxyURL()
+	require.NoError(t, err)
+	assert.Equal(t, "http://proxy.example.com:3128", proxy.String(), "URLs without a scheme use http")
+
+	proxy, err = Config{Proxy: "socks5://127.0.0.1:1080"}.ProxyURL()
+	require.NoError(t, err)
+	assert.Equal(t, "socks5", proxy.Scheme)
+
+	_, err = Config{Proxy: "ftp://proxy.example.com"}.ProxyURL()
+	assert.ErrorContains(t, err, "unsupported scheme ftp")
+	_, err = Config{Proxy: "http://"}.ProxyURL()
+	assert.ErrorContains(t, err, "no host")
+}
+
+func TestConfig_bypassProxy(t *testing.T) {
+	c := Config{NoProxy: []string{"github.example.com", ".internal", "*.corp", "api.example.org:8443", "10.0.0.0/8", "192.168.1.5"}}
+	tests := []struct {
+		host string
+		want bool
+	}{
+		{"github.example.com", true},
+		{"GITHUB.example.com:443", true},
+		{"api.github.example.com", true},
+		{"notgithub.example.com", false},
+		{"git.internal", true},
+		{"build.corp", true},
+		{"api.example.org:8443", true},
+		{"api.example.org:443", false},
+		{"10.1.2.3:8080", true},
+		{"192.168.1.5", true},
+		{"192.168.1.6", false},
+		{"github.com", false},
+		{"localhost:8080", true},
+		{"127.0.0.1:8080", true},
+		{"[::1]:8080", true},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, c.bypassProxy(tt.host), tt.host)
+	}
+	assert.True(t, Config{NoProxy: []string{"*"}}.bypassProxy("github.com"))
+}
+
+func TestConfig_Transport_Proxy(t *testing.T) {
+	// The proxy answers every request with the URL it was asked for
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "proxied "+r.URL.String())
+	}))
+	defer proxy.Close()
+
+	transport, err := Config{Proxy: proxy.URL, NoProxy: []string{"direct.example.com"}}.Transport()
+	require.NoError(t, err)
+
+	proxyFor := func(rawURL string) string {
+		req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+		require.NoError(t, err)
+		proxyURL, err := transport.Proxy(req)
+		require.NoError(t, err)
+		if proxyURL == nil {
+			return ""
+		}
+		return proxyURL.String()
+	}
+	assert.Equal(t, proxy.URL, proxyFor("https://api.github.com/user"))
+	assert.Empty(t, proxyFor("https://direct.example.com/api/v3"))
+
+	resp, err := (&http.Client{Transport: transport}).Get("http://github.example.com/owner/repo")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, "proxied http://github.example.com/owner/repo", string(body))
+}
+
+func TestConfig_Transport_CABundle(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	get := func(c Config) error {
+		transport, err := c.Transport()
+		if err != nil {
+			return err
+		}
+		resp, err := (&http.Client{Transport: transport}).Get(server.URL)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+	assert.Error(t, get(Config{}), "the test server's certificate isn't trusted by default")
+
+	bundle := filepath.Join(t.TempDir(), "ca.pem")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	require.NoError(t, os.WriteFile(bundle, certPEM, 0600))
+	assert.NoError(t, get(Config{CABundle: bundle}))
+	assert.NoError(t, get(Config{InsecureSkipVerify: true}))
+
+	empty := filepath.Join(t.TempDir(), "empty.pem")
+	require.NoError(t, os.WriteFile(empty, []byte("not a certificate"), 0600))
+	assert.ErrorContains(t, Config{CABundle: empty}.Validate(), "no PEM certificates")
+	assert.ErrorContains(t, Config{CABundle: filepath.Join(t.TempDir(), "missing.pem")}.Validate(), "failed to read CA bundle")
+}
+
+func TestConfig_gitCAInfo(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	certPEM := func() []byte {
+		server := httptest.NewTLSServer(http.NotFoundHandler())
+		defer server.Close()
+		return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	}
+	dir := t.TempDir()
+	system, bundle := filepath.Join(dir, "system.pem"), filepath.Join(dir, "ca.pem")
+	systemPEM, bundlePEM := certPEM(), certPEM()
+	require.NoError(t, os.WriteFile(system, systemPEM, 0600))
+	require.NoError(t, os.WriteFile(bundle, bundlePEM, 0600))
+	t.Setenv("SSL_CERT_FILE", system)
+
+	assert.Empty(t, Config{}.gitCAInfo())
+
+	// git trusts the bundle in addition to the system's certificates
+	path := Config{CABundle: bundle}.gitCAInfo()
+	require.NotEqual(t, bundle, path)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, string(systemPEM)+string(bundlePEM), string(data))
+	assert.Equal(t, path, Config{CABundle: bundle}.gitCAInfo(), "the combined file is reused")
+
+	// Without system certificates in a file, the bundle is used alone
+	t.Setenv("SSL_CERT_FILE", filepath.Join(dir, "missing.pem"))
+	assert.Equal(t, bundle, Config{CABundle: bundle}.gitCAInfo())
+}
+
+func TestConfigure(t *testing.T) {
+	t.Cleanup(func() { _ = Configure(Config{}) })
+
+	require.NoError(t, Configure(Config{Proxy: "http://proxy.example.com:3128"}))
+	assert.Equal(t, "http://proxy.example.com:3128", Current().Proxy)
+	transport, ok := http.DefaultTransport.(*http.Transport)
+	require.True(t, ok)
+	req, _ := http.NewRequest(http.MethodGet, "https://api.github.com/", nil)
+	proxyURL, err := transport.Proxy(req)
+	require.NoError(t, err)
+	assert.Equal(t, "http://proxy.example.com:3128", proxyURL.String(), "the default transport goes through the proxy")
+
+	assert.Error(t, Configure(Config{Proxy: "ftp://proxy.example.com"}))
+	assert.Equal(t, "http://proxy.example.com:3128", Current().Proxy, "invalid settings aren't applied")
+
+	require.NoError(t, Configure(Config{}))
+	assert.Same(t, defaultTransport, http.DefaultTransport)
+	assert.True(t, Current().IsZero())
+}
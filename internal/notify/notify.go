@@ -0,0 +1,329 @@
+// Package notify sends notifications about finished syncs to webhooks,
+// Slack, Discord and email
+package notify
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// Target types
+const (
+	// TypeWebhook POSTs the event as JSON to a URL
+	TypeWebhook = "webhook"
+	// TypeSlack posts a message to a Slack incoming webhook
+	TypeSlack = "slack"
+	// TypeDiscord posts a message to a Discord webhook
+	TypeDiscord = "discord"
+	// TypeEmail sends an email through an SMTP server
+	TypeEmail = "email"
+)
+
+// Trigger selects the syncs a target is notified about
+type Trigger string
+
+const (
+	// OnFailure notifies about syncs that failed or had failed repositories (the default)
+	OnFailure Trigger = "on-failure"
+	// OnChange notifies about syncs that cloned or updated repositories, or failed
+	OnChange Trigger = "on-change"
+	// Always notifies about every sync
+	Always Trigger = "always"
+)
+
+// ParseTrigger parses a trigger name; empty means OnFailure
+func ParseTrigger(s string) (Trigger, error) {
+	switch Trigger(s) {
+	case "":
+		return OnFailure, nil
+	case OnFailure, OnChange, Always:
+		return Trigger(s), nil
+	default:
+		return "", fmt.Errorf("invalid notification trigger %q (expected %s, %s or %s)", s, OnFailure, OnChange, Always)
+	}
+}
+
+// Matches returns true if the trigger fires for event. Test events match
+// every trigger.
+func (t Trigger) Matches(event *Event) bool {
+	if event.Test {
+		return true
+	}
+	switch t {
+	case Always:
+		return true
+	case OnChange:
+		return event.HasChanges() || event.HasFailures()
+	default:
+		return event.HasFailures()
+	}
+}
+
+// DefaultSMTPPort is the SMTP submission port used when none is set
+const DefaultSMTPPort = 587
+
+// SMTPPasswordEnv is the environment variable read for the SMTP password
+// when the config doesn't set one
+const SMTPPasswordEnv = "GITHUBBY_SMTP_PASSWORD"
+
+// SMTPConfig is the SMTP server email notifications are sent through.
+// The connection is upgraded with STARTTLS when the server supports it.
+type SMTPConfig struct {
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port,omitempty"` // 0 = DefaultSMTPPort
+	Username string `yaml:"username,omitempty"`
+	Password string `yaml:"password,omitempty"` // falls back to $GITHUBBY_SMTP_PASSWORD
+	From     string `yaml:"from"`
+}
+
+// Validate checks that the SMTP settings are usable
+func (c *SMTPConfig) Validate() error {
+	if strings.TrimSpace(c.Host) == "" {
+		return fmt.Errorf("SMTP host is required")
+	}
+	if c.Port < 0 || c.Port > 65535 {
+		return fmt.Errorf("invalid SMTP port %d", c.Port)
+	}
+	if !strings.Contains(c.From, "@") {
+		return fmt.Errorf("invalid SMTP from address %q", c.From)
+	}
+	return nil
+}
+
+// addr returns the host:port to connect to
+func (c *SMTPConfig) addr() string {
+	port := c.Port
+	if port == 0 {
+		port = DefaultSMTPPort
+	}
+	return fmt.Sprintf("%s:%d", c.Host, port)
+}
+
+// password returns the configured password, or the one from the environment
+func (c *SMTPConfig) password() string {
+	if c.Password != "" {
+		return c.Password
+	}
+	return os.Getenv(SMTPPasswordEnv)
+}
+
+// Target is where notifications are sent
+type Target struct {
+	// Type is one of the Type constants
+	Type string `yaml:"type"`
+
+	// URL is the webhook to post to (webhook, slack and discord)
+	URL string `yaml:"url,omitempty"`
+
+	// Headers are added to webhook requests, e.g. for authentication
+	Headers map[string]string `yaml:"headers,omitempty"`
+
+	// To lists the email recipients (email)
+	To []string `yaml:"to,omitempty"`
+
+	// SMTP overrides the global SMTP server (email)
+	SMTP *SMTPConfig `yaml:"smtp,omitempty"`
+
+	// On is the Trigger for this target (default on-failure)
+	On string `yaml:"on,omitempty"`
+}
+
+// Validate checks that the target has everything its type needs
+func (t *Target) Validate() error {
+	switch t.Type {
+	case TypeWebhook, TypeSlack, TypeDiscord:
+		u, err := url.Parse(t.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("%s notification needs an http(s) URL", t.Type)
+		}
+	case TypeEmail:
+		if len(t.To) == 0 {
+			return fmt.Errorf("email notification needs at least one recipient")
+		}
+		for _, to := range t.To {
+			if !strings.Contains(to, "@") {
+				return fmt.Errorf("invalid email recipient %q", to)
+			}
+		}
+		if t.SMTP != nil {
+			if err := t.SMTP.Validate(); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("invalid notification type %q (expected %s, %s, %s or %s)", t.Type, TypeWebhook, TypeSlack, TypeDiscord, TypeEmail)
+	}
+
+	if _, err := ParseTrigger(t.On); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Trigger returns when the target is notified
+func (t *Target) Trigger() Trigger {
+	trigger, err := ParseTrigger(t.On)
+	if err != nil {
+		return OnFailure
+	}
+	return trigger
+}
+
+// String describes the target without its URL path or query, which often
+// contain secrets
+func (t Target) String() string {
+	if t.Type == TypeEmail {
+		return fmt.Sprintf("%s to %s", t.Type, strings.Join(t.To, ", "))
+	}
+	if u, err := url.Parse(t.URL); err == nil && u.Host != "" {
+		return fmt.Sprintf("%s %s", t.Type, u.Host)
+	}
+	return t.Type
+}
+
+// Fingerprint returns a short hash of all of the target's settings, for
+// telling targets apart without showing their secrets
+func (t Target) Fingerprint() string {
+	data, _ := json.Marshal(t)
+	return fmt.Sprintf("%x", sha256.Sum256(data))[:8]
+}
+
+// Clone returns a deep copy of the target
+func (t Target) Clone() Target {
+	clone := t
+	clone.Headers = maps.Clone(t.Headers)
+	clone.To = append([]string(nil), t.To...)
+	if t.SMTP != nil {
+		smtp := *t.SMTP
+		clone.SMTP = &smtp
+	}
+	return clone
+}
+
+// CloneTargets returns a deep copy of targets
+func CloneTargets(targets []Target) []Target {
+	if targets == nil {
+		return nil
+	}
+	clones := make([]Target, len(targets))
+	for i, t := range targets {
+		clones[i] = t.Clone()
+	}
+	return clones
+}
+
+// ParseTarget parses a TYPE=DESTINATION target, e.g.
+// "slack=https://hooks.slack.com/services/..." or "email=ops@example.com".
+// Email targets take a comma-separated list of recipients.
+func ParseTarget(spec string, on Trigger) (Target, error) {
+	kind, dest, found := strings.Cut(spec, "=")
+	if !found || dest == "" {
+		return Target{}, fmt.Errorf("invalid notification %q (expected TYPE=URL or email=ADDRESS)", spec)
+	}
+
+	target := Target{Type: strings.ToLower(strings.TrimSpace(kind))}
+	if on != OnFailure {
+		target.On = string(on)
+	}
+	if target.Type == TypeEmail {
+		for _, to := range strings.Split(dest, ",") {
+			target.To = append(target.To, strings.TrimSpace(to))
+		}
+	} else {
+		target.URL = dest
+	}
+
+	if err := target.Validate(); err != nil {
+		return Target{}, err
+	}
+	return target, nil
+}
+
+// Config is the global notification settings
+type Config struct {
+	// Targets are notified about every sync
+	Targets []Target `yaml:"targets,omitempty"`
+
+	// SMTP is the server used by email targets without their own
+	SMTP *SMTPConfig `yaml:"smtp,omitempty"`
+}
+
+// Validate checks the targets and SMTP settings
+func (c *Config) Validate() error {
+	for i := range c.Targets {
+		if err := c.Targets[i].Validate(); err != nil {
+			return fmt.Errorf("notification #%d: %w", i+1, err)
+		}
+	}
+	if c.SMTP != nil {
+		if err := c.SMTP.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Status is the outcome of a sync
+type Status string
+
+const (
+	// StatusSuccess means every repository synced
+	StatusSuccess Status = "success"
+	// StatusPartial means the sync finished but some repositories failed
+	StatusPartial Status = "partial"
+	// StatusFailure means the sync failed as a whole
+	StatusFailure Status = "failure"
+)
+
+// RepoResult is the outcome of a single repository in an Event
+type RepoResult struct {
+	FullName string `json:"full_name"`
+	Status   string `json:"status"` // "cloned", "updated" or "failed"
+	Error    string `json:"error,omitempty"`
+}
+
+// Event describes a finished sync. It's the JSON body of generic webhooks.
+type Event struct {
+	Profile     string       `json:"profile"`
+	Status      Status       `json:"status"`
+	Error       string       `json:"error,omitempty"`
+	StartedAt   time.Time    `json:"started_at"`
+	CompletedAt time.Time    `json:"completed_at"`
+	TotalRepos  int          `json:"total_repos"`
+	Cloned      int          `json:"cloned"`
+	Updated     int          `json:"updated"`
+	UpToDate    int          `json:"up_to_date"`
+	Skipped     int          `json:"skipped"`
+	Failed      int          `json:"failed"`
+	Archived    int          `json:"archived"`
+	Results     []RepoResult `json:"results,omitempty"` // cloned, updated and failed repositories
+	Test        bool         `json:"test,omitempty"`
+}
+
+// HasFailures returns true if the sync or any repository failed
+func (e *Event) HasFailures() bool {
+	return e.Status != StatusSuccess
+}
+
+// HasChanges returns true if the sync cloned or updated repositories
+func (e *Event) HasChanges() bool {
+	return e.Cloned+e.Updated > 0
+}
+
+// TestEvent returns the event sent by "githubby notify test"
+func TestEvent(profile string) *Event {
+	now := time.Now()
+	return &Event{
+		Profile:     profile,
+		Status:      StatusSuccess,
+		StartedAt:   now,
+		CompletedAt: now,
+		Test:        true,
+	}
+}
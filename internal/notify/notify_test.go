@@ -0,0 +1,129 @@
+package notify
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTrigger(t *testing.T) {
+	trigger, err := ParseTrigger("")
+	require.NoError(t, err)
+	assert.Equal(t, OnFailure, trigger)
+
+	trigger, err = ParseTrigger("on-change")
+	require.NoError(t, err)
+	assert.Equal(t, OnChange, trigger)
+
+	_, err = ParseTrigger("sometimes")
+	assert.ErrorContains(t, err, "invalid notification trigger")
+}
+
+func TestTrigger_Matches(t *testing.T) {
+	success := &Event{Status: StatusSuccess}
+	changed := &Event{Status: StatusSuccess, Cloned: 1}
+	partial := &Event{Status: StatusPartial, Failed: 1}
+	failure := &Event{Status: StatusFailure}
+	test := TestEvent("test")
+
+	tests := []struct {
+		trigger Trigger
+		event   *Event
+		want    bool
+	}{
+		{OnFailure, success, false},
+		{OnFailure, changed, false},
+		{OnFailure, partial, true},
+		{OnFailure, failure, true},
+		{OnFailure, test, true},
+		{OnChange, success, false},
+		{OnChange, changed, true},
+		{OnChange, failure, true},
+		{Always, success, true},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, tt.trigger.Matches(tt.event), "%s with %+v", tt.trigger, tt.event)
+	}
+}
+
+func TestTarget_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		target  Target
+		wantErr string
+	}{
+		{name: "webhook", target: Target{Type: TypeWebhook, URL: "https://example.com/hook"}},
+		{name: "slack with trigger", target: Target{Type: TypeSlack, URL: "https://hooks.slack.com/services/x", On: "always"}},
+		{name: "email", target: Target{Type: TypeEmail, To: []string{"ops@example.com"}}},
+		{name: "unknown type", target: Target{Type: "pager"}, wantErr: "invalid notification type"},
+		{name: "missing URL", target: Target{Type: TypeDiscord}, wantErr: "needs an http(s) URL"},
+		{name: "non-http URL", target: Target{Type: TypeWebhook, URL: "ftp://example.com"}, wantErr: "needs an http(s) URL"},
+		{name: "no recipients", target: Target{Type: TypeEmail}, wantErr: "at least one recipient"},
+		{name: "bad recipient", target: Target{Type: TypeEmail, To: []string{"ops"}}, wantErr: "invalid email recipient"},
+		{
+			name:    "bad SMTP",
+			target:  Target{Type: TypeEmail, To: []string{"ops@example.com"}, SMTP: &SMTPConfig{From: "githubby@example.com"}},
+			wantErr: "SMTP host is required",
+		},
+		{name: "bad trigger", target: Target{Type: TypeWebhook, URL: "https://example.com", On: "never"}, wantErr: "invalid notification trigger"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.target.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseTarget(t *testing.T) {
+	target, err := ParseTarget("slack=https://hooks.slack.com/services/T0/B0/secret", OnFailure)
+	require.NoError(t, err)
+	assert.Equal(t, Target{Type: TypeSlack, URL: "https://hooks.slack.com/services/T0/B0/secret"}, target)
+
+	target, err = ParseTarget("email=ops@example.com, dev@example.com", Always)
+	require.NoError(t, err)
+	assert.Equal(t, Target{Type: TypeEmail, To: []string{"ops@example.com", "dev@example.com"}, On: "always"}, target)
+
+	_, err = ParseTarget("https://example.com", OnFailure)
+	assert.ErrorContains(t, err, "expected TYPE=URL")
+
+	_, err = ParseTarget("pager=https://example.com", OnFailure)
+	assert.ErrorContains(t, err, "invalid notification type")
+}
+
+func TestTarget_String(t *testing.T) {
+	slack := Target{Type: TypeSlack, URL: "https://hooks.slack.com/services/T0/B0/secret"}
+	assert.Equal(t, "slack hooks.slack.com", slack.String())
+	assert.Equal(t, "email to ops@example.com", Target{Type: TypeEmail, To: []string{"ops@example.com"}}.String())
+
+	// Fingerprints tell targets on the same host apart
+	other := slack
+	other.URL = "https://hooks.slack.com/services/T0/B0/other"
+	assert.NotEqual(t, slack.Fingerprint(), other.Fingerprint())
+}
+
+func TestCloneTargets(t *testing.T) {
+	targets := []Target{{
+		Type:    TypeEmail,
+		To:      []string{"ops@example.com"},
+		Headers: map[string]string{"X-Token": "a"},
+		SMTP:    &SMTPConfig{Host: "smtp.example.com"},
+	}}
+
+	clones := CloneTargets(targets)
+	clones[0].To[0] = "dev@example.com"
+	clones[0].Headers["X-Token"] = "b"
+	clones[0].SMTP.Host = "mail.example.com"
+
+	assert.Equal(t, "ops@example.com", targets[0].To[0])
+	assert.Equal(t, "a", targets[0].Headers["X-Token"])
+	assert.Equal(t, "smtp.example.com", targets[0].SMTP.Host)
+	assert.Nil(t, CloneTargets(nil))
+}
@@ -0,0 +1,248 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/smtp"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// maxListedFailures is how many failed repositories messages list by name
+const maxListedFailures = 10
+
+// sendMail sends an email; replaced in tests
+var sendMail = smtp.SendMail
+
+// Sender delivers events to targets
+type Sender struct {
+	// Client makes the webhook requests
+	Client *http.Client
+
+	// SMTP is the server used by email targets without their own
+	SMTP *SMTPConfig
+}
+
+// NewSender creates a sender using the global SMTP server, if any
+func NewSender(smtpConfig *SMTPConfig) *Sender {
+	return &Sender{
+		Client: &http.Client{Timeout: 30 * time.Second},
+		SMTP:   smtpConfig,
+	}
+}
+
+// Notify sends event to the targets whose trigger matches it. A failing
+// target doesn't stop the others; their errors are joined.
+func (s *Sender) Notify(ctx context.Context, targets []Target, event *Event) error {
+	var errs []error
+	for _, target := range targets {
+		if !target.Trigger().Matches(event) {
+			continue
+		}
+		if err := s.Send(ctx, target, event); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", target, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Send sends event to target regardless of its trigger
+func (s *Sender) Send(ctx context.Context, target Target, event *Event) error {
+	switch target.Type {
+	case TypeWebhook:
+		return s.post(ctx, target, event)
+	case TypeSlack:
+		return s.post(ctx, target, slackMessage(event))
+	case TypeDiscord:
+		return s.post(ctx, target, discordMessage(event))
+	case TypeEmail:
+		return s.email(target, event)
+	default:
+		return fmt.Errorf("invalid notification type %q", target.Type)
+	}
+}
+
+// post sends payload as JSON to the target's URL
+func (s *Sender) post(ctx context.Context, target Target, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode notification: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("invalid notification URL")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "githubby")
+	for name, value := range target.Headers {
+		req.Header.Set(name, value)
+	}
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		// Don't repeat the URL, which often contains a secret
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		if msg := strings.TrimSpace(string(detail)); msg != "" {
+			return fmt.Errorf("unexpected response %s: %s", resp.Status, msg)
+		}
+		return fmt.Errorf("unexpected response %s", resp.Status)
+	}
+	return nil
+}
+
+// email sends event as a plain text email
+func (s *Sender) email(target Target, event *Event) error {
+	server := target.SMTP
+	if server == nil {
+		server = s.SMTP
+	}
+	if server == nil {
+		return fmt.Errorf("no SMTP server configured for email notifications")
+	}
+
+	var auth smtp.Auth
+	if server.Username != "" {
+		auth = smtp.PlainAuth("", server.Username, server.password(), server.Host)
+	}
+
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", server.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(target.To, ", "))
+	// The subject contains the profile name, so it's encoded rather than
+	// written raw into the headers
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", "[githubby] "+Title(event)))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(Summary(event), "\n", "\r\n"))
+	msg.WriteString("\r\n")
+
+	if err := sendMail(server.addr(), auth, server.From, target.To, []byte(msg.String())); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	return nil
+}
+
+// Title returns a one-line description of event
+func Title(event *Event) string {
+	switch {
+	case event.Test:
+		return "Test notification from githubby"
+	case event.Status == StatusFailure:
+		return fmt.Sprintf("Sync of %q failed", event.Profile)
+	case event.Status == StatusPartial:
+		return fmt.Sprintf("Sync of %q finished with %d failed %s", event.Profile, event.Failed, plural(event.Failed, "repository", "repositories"))
+	default:
+		return fmt.Sprintf("Sync of %q succeeded", event.Profile)
+	}
+}
+
+// Summary returns the details of event as plain text
+func Summary(event *Event) string {
+	if event.Test {
+		return "Notifications for githubby syncs are set up correctly."
+	}
+
+	var lines []string
+	// Syncs that failed before listing any repositories have nothing to count
+	if event.TotalRepos > 0 || event.Archived > 0 || event.Status != StatusFailure {
+		lines = append(lines, fmt.Sprintf("Cloned %d, updated %d, up to date %d, skipped %d, failed %d, archived %d (%s)",
+			event.Cloned, event.Updated, event.UpToDate, event.Skipped, event.Failed, event.Archived,
+			event.CompletedAt.Sub(event.StartedAt).Round(time.Second)))
+	}
+	if event.Error != "" {
+		lines = append(lines, "Error: "+event.Error)
+	}
+	var b strings.Builder
+	b.WriteString(strings.Join(lines, "\n"))
+
+	listed := 0
+	for _, result := range event.Results {
+		if result.Status != "failed" {
+			continue
+		}
+		if listed == maxListedFailures {
+			fmt.Fprintf(&b, "\n...and %d more", event.Failed-listed)
+			break
+		}
+		fmt.Fprintf(&b, "\n- %s: %s", result.FullName, result.Error)
+		listed++
+	}
+	return b.String()
+}
+
+// plural returns singular if n is 1, plural otherwise
+func plural(n int, singular, plural string) string {
+	if n == 1 {
+		return singular
+	}
+	return plural
+}
+
+// slackMessage formats event for a Slack incoming webhook
+func slackMessage(event *Event) map[string]any {
+	return map[string]any{
+		"text": fmt.Sprintf("%s *%s*\n%s", statusEmoji(event), Title(event), Summary(event)),
+	}
+}
+
+// Discord embed colors
+const (
+	discordGreen  = 0x2ea043
+	discordYellow = 0xd29922
+	discordRed    = 0xcf222e
+)
+
+// discordMessage formats event for a Discord webhook
+func discordMessage(event *Event) map[string]any {
+	color := discordGreen
+	switch event.Status {
+	case StatusPartial:
+		color = discordYellow
+	case StatusFailure:
+		color = discordRed
+	}
+
+	return map[string]any{
+		"username": "githubby",
+		"embeds": []map[string]any{{
+			"title":       Title(event),
+			"description": Summary(event),
+			"color":       color,
+			"timestamp":   event.CompletedAt.Format(time.RFC3339),
+		}},
+	}
+}
+
+// statusEmoji returns a Slack emoji for the event's status
+func statusEmoji(event *Event) string {
+	switch event.Status {
+	case StatusPartial:
+		return ":warning:"
+	case StatusFailure:
+		return ":x:"
+	default:
+		return ":white_check_mark:"
+	}
+}
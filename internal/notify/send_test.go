@@ -0,0 +1,230 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// receiver is a local webhook endpoint that records the requests it gets
+type receiver struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+// newReceiver starts a receiver that answers with status
+func newReceiver(t *testing.T, status int) *receiver {
+	t.Helper()
+	r := &receiver{}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		r.mu.Lock()
+		r.requests = append(r.requests, req)
+		r.bodies = append(r.bodies, body)
+		r.mu.Unlock()
+		w.WriteHeader(status)
+		if status >= 400 {
+			_, _ = w.Write([]byte("invalid_token"))
+		}
+	}))
+	t.Cleanup(r.Close)
+	return r
+}
+
+// body decodes the nth request body
+func (r *receiver) body(t *testing.T, n int) map[string]any {
+	t.Helper()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	require.Greater(t, len(r.bodies), n)
+	var payload map[string]any
+	require.NoError(t, json.Unmarshal(r.bodies[n], &payload))
+	return payload
+}
+
+// count returns the number of requests received
+func (r *receiver) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.requests)
+}
+
+// partialEvent returns an event for a sync with a failed repository
+func partialEvent() *Event {
+	started := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	return &Event{
+		Profile:     "work",
+		Status:      StatusPartial,
+		StartedAt:   started,
+		CompletedAt: started.Add(90 * time.Second),
+		TotalRepos:  3,
+		Cloned:      1,
+		UpToDate:    1,
+		Failed:      1,
+		Results: []RepoResult{
+			{FullName: "acme/api", Status: "cloned"},
+			{FullName: "acme/web", Status: "failed", Error: "exit status 128"},
+		},
+	}
+}
+
+func TestSender_Webhook(t *testing.T) {
+	r := newReceiver(t, http.StatusOK)
+	target := Target{Type: TypeWebhook, URL: r.URL + "/hook", Headers: map[string]string{"Authorization": "Bearer abc"}}
+
+	require.NoError(t, NewSender(nil).Send(context.Background(), target, partialEvent()))
+
+	require.Equal(t, 1, r.count())
+	assert.Equal(t, http.MethodPost, r.requests[0].Method)
+	assert.Equal(t, "application/json", r.requests[0].Header.Get("Content-Type"))
+	assert.Equal(t, "Bearer abc", r.requests[0].Header.Get("Authorization"))
+
+	payload := r.body(t, 0)
+	assert.Equal(t, "work", payload["profile"])
+	assert.Equal(t, "partial", payload["status"])
+	assert.Equal(t, float64(1), payload["failed"])
+	assert.Equal(t, "2026-01-02T03:04:05Z", payload["started_at"])
+	assert.Len(t, payload["results"], 2)
+}
+
+func TestSender_Slack(t *testing.T) {
+	r := newReceiver(t, http.StatusOK)
+	require.NoError(t, NewSender(nil).Send(context.Background(), Target{Type: TypeSlack, URL: r.URL}, partialEvent()))
+
+	text := r.body(t, 0)["text"].(string)
+	assert.Contains(t, text, `:warning: *Sync of "work" finished with 1 failed repository*`)
+	assert.Contains(t, text, "Cloned 1, updated 0, up to date 1, skipped 0, failed 1, archived 0 (1m30s)")
+	assert.Contains(t, text, "- acme/web: exit status 128")
+	assert.NotContains(t, text, "acme/api")
+}
+
+func TestSender_Discord(t *testing.T) {
+	r := newReceiver(t, http.StatusNoContent)
+	event := partialEvent()
+	event.Status = StatusFailure
+	event.Error = "not authenticated"
+	require.NoError(t, NewSender(nil).Send(context.Background(), Target{Type: TypeDiscord, URL: r.URL}, event))
+
+	embeds := r.body(t, 0)["embeds"].([]any)
+	require.Len(t, embeds, 1)
+	embed := embeds[0].(map[string]any)
+	assert.Equal(t, `Sync of "work" failed`, embed["title"])
+	assert.Contains(t, embed["description"], "Error: not authenticated")
+	assert.Equal(t, float64(discordRed), embed["color"])
+
+	// Failures before any repository was listed have no counts
+	event = &Event{Profile: "work", Status: StatusFailure, Error: "not authenticated"}
+	assert.Equal(t, "Error: not authenticated", Summary(event))
+}
+
+func TestSender_ErrorResponse(t *testing.T) {
+	r := newReceiver(t, http.StatusForbidden)
+	err := NewSender(nil).Send(context.Background(), Target{Type: TypeSlack, URL: r.URL + "/services/secret"}, partialEvent())
+	assert.ErrorContains(t, err, "unexpected response 403 Forbidden: invalid_token")
+
+	// Connection errors don't repeat the secret URL
+	r.Close()
+	err = NewSender(nil).Send(context.Background(), Target{Type: TypeSlack, URL: r.URL + "/services/secret"}, partialEvent())
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), "secret")
+}
+
+func TestSender_Email(t *testing.T) {
+	var gotAddr, gotFrom string
+	var gotTo []string
+	var gotMsg string
+	original := sendMail
+	sendMail = func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+		gotAddr, gotFrom, gotTo, gotMsg = addr, from, to, string(msg)
+		return nil
+	}
+	t.Cleanup(func() { sendMail = original })
+
+	target := Target{Type: TypeEmail, To: []string{"ops@example.com"}}
+
+	err := NewSender(nil).Send(context.Background(), target, partialEvent())
+	assert.ErrorContains(t, err, "no SMTP server configured")
+
+	sender := NewSender(&SMTPConfig{Host: "smtp.example.com", From: "githubby@example.com"})
+	require.NoError(t, sender.Send(context.Background(), target, partialEvent()))
+	assert.Equal(t, "smtp.example.com:587", gotAddr)
+	assert.Equal(t, "githubby@example.com", gotFrom)
+	assert.Equal(t, []string{"ops@example.com"}, gotTo)
+	assert.Contains(t, gotMsg, "To: ops@example.com\r\n")
+	assert.Contains(t, gotMsg, "Subject: [githubby] Sync of \"work\" finished with 1 failed repository\r\n")
+	assert.Contains(t, gotMsg, "\r\n- acme/web: exit status 128")
+
+	// A target's own SMTP server takes precedence
+	target.SMTP = &SMTPConfig{Host: "mail.example.com", Port: 2525, From: "backup@example.com"}
+	require.NoError(t, sender.Send(context.Background(), target, partialEvent()))
+	assert.Equal(t, "mail.example.com:2525", gotAddr)
+	assert.Equal(t, "backup@example.com", gotFrom)
+
+	// Profile names can't add headers, and non-ASCII names are encoded
+	event := partialEvent()
+	event.Profile = "work\r\nBcc: attacker@example.com"
+	require.NoError(t, sender.Send(context.Background(), target, event))
+	assert.NotContains(t, gotMsg, "\r\nBcc:")
+
+	event.Profile = "työ"
+	require.NoError(t, sender.Send(context.Background(), target, event))
+	assert.Contains(t, gotMsg, "Subject: =?utf-8?q?")
+	msg, err := mail.ReadMessage(strings.NewReader(gotMsg))
+	require.NoError(t, err)
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	require.NoError(t, err)
+	assert.Equal(t, "[githubby] Sync of \"työ\" finished with 1 failed repository", subject)
+}
+
+func TestSender_Notify(t *testing.T) {
+	failures := newReceiver(t, http.StatusOK)
+	always := newReceiver(t, http.StatusOK)
+	broken := newReceiver(t, http.StatusInternalServerError)
+
+	targets := []Target{
+		{Type: TypeWebhook, URL: failures.URL},
+		{Type: TypeWebhook, URL: always.URL, On: string(Always)},
+		{Type: TypeWebhook, URL: broken.URL, On: string(OnChange)},
+	}
+	sender := NewSender(nil)
+
+	success := &Event{Profile: "work", Status: StatusSuccess}
+	require.NoError(t, sender.Notify(context.Background(), targets, success))
+	assert.Equal(t, 0, failures.count())
+	assert.Equal(t, 1, always.count())
+	assert.Equal(t, 0, broken.count())
+
+	// The broken target's error doesn't stop the others
+	err := sender.Notify(context.Background(), targets, partialEvent())
+	require.Error(t, err)
+	assert.True(t, strings.HasPrefix(err.Error(), "webhook 127.0.0.1"), err.Error())
+	assert.Equal(t, 1, failures.count())
+	assert.Equal(t, 2, always.count())
+	assert.Equal(t, 1, broken.count())
+}
+
+func TestSummary_ListsLimitedFailures(t *testing.T) {
+	event := &Event{Profile: "work", Status: StatusPartial}
+	for i := 0; i < maxListedFailures+2; i++ {
+		event.Results = append(event.Results, RepoResult{FullName: "acme/repo", Status: "failed", Error: "boom"})
+		event.Failed++
+	}
+
+	summary := Summary(event)
+	assert.Equal(t, maxListedFailures, strings.Count(summary, "- acme/repo"))
+	assert.Contains(t, summary, "...and 2 more")
+}
@@ -0,0 +1,334 @@
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// OverlapPolicy controls what happens when a job is due while its previous
+// run is still going
+type OverlapPolicy string
+
+const (
+	// OverlapSkip drops the run that's due (the default)
+	OverlapSkip OverlapPolicy = "skip"
+	// OverlapQueue runs the job once more as soon as the current run
+	// finishes; further runs that fall due in the meantime are merged into it
+	OverlapQueue OverlapPolicy = "queue"
+)
+
+// ParseOverlapPolicy parses an overlap policy name. An empty name is OverlapSkip.
+func ParseOverlapPolicy(s string) (OverlapPolicy, error) {
+	switch p := OverlapPolicy(s); p {
+	case "":
+		return OverlapSkip, nil
+	case OverlapSkip, OverlapQueue:
+		return p, nil
+	default:
+		return "", fmt.Errorf("invalid overlap policy %q (expected skip or queue)", s)
+	}
+}
+
+// Job is a recurring task run by a Daemon
+type Job struct {
+	// ID identifies the job across SetJobs calls
+	ID string
+
+	// Name is shown in log messages
+	Name string
+
+	// Spec is the job's cron expression
+	Spec string
+
+	// Host groups jobs for the per-host concurrency limit
+	Host string
+
+	// Overlap controls what happens when the job is due while still running
+	Overlap OverlapPolicy
+
+	// Run performs the job
+	Run SyncFunc
+}
+
+// DaemonOptions configures a Daemon
+type DaemonOptions struct {
+	// MaxConcurrent limits how many jobs run at once (0 = no limit)
+	MaxConcurrent int
+
+	// MaxPerHost limits how many jobs with the same Host run at once (0 = no limit)
+	MaxPerHost int
+
+	// RunOnStart runs jobs as soon as they're added, rather than waiting
+	// for their first scheduled time
+	RunOnStart bool
+}
+
+// Daemon runs many jobs, each on its own cron schedule, on a shared cron.
+// Jobs waiting for a free slot count as running for their overlap policy.
+type Daemon struct {
+	opts DaemonOptions
+	cron *cron.Cron
+
+	mu      sync.Mutex
+	ctx     context.Context
+	jobs    map[string]*daemonJob
+	global  chan struct{}
+	perHost map[string]chan struct{}
+	wg      sync.WaitGroup
+}
+
+// daemonJob is a job with its scheduling state
+type daemonJob struct {
+	Job
+	entryID cron.EntryID
+	running bool
+	queued  bool
+	removed bool
+}
+
+// NewDaemon creates a new Daemon without any jobs
+func NewDaemon(opts DaemonOptions) *Daemon {
+	d := &Daemon{
+		opts:    opts,
+		cron:    cron.New(cron.WithParser(specParser)),
+		jobs:    make(map[string]*daemonJob),
+		perHost: make(map[string]chan struct{}),
+	}
+	if opts.MaxConcurrent > 0 {
+		d.global = make(chan struct{}, opts.MaxConcurrent)
+	}
+	return d
+}
+
+// SetJobs replaces the daemon's jobs. Jobs are matched by ID: new jobs are
+// scheduled, missing jobs are unscheduled (a run in progress is finished)
+// and changed jobs are rescheduled. Nothing changes if any spec is invalid.
+func (d *Daemon) SetJobs(jobs []Job) error {
+	seen := make(map[string]bool)
+	for _, job := range jobs {
+		if seen[job.ID] {
+			return fmt.Errorf("job %q is defined more than once", job.ID)
+		}
+		seen[job.ID] = true
+		if err := ValidateSpec(job.Spec); err != nil {
+			return fmt.Errorf("job %q: %w", job.Name, err)
+		}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for id, j := range d.jobs {
+		if !seen[id] {
+			d.cron.Remove(j.entryID)
+			j.removed = true
+			delete(d.jobs, id)
+			log.Printf("[daemon] Removed %s", j.Name)
+		}
+	}
+
+	var added []string
+	for _, job := range jobs {
+		if job.Overlap == "" {
+			job.Overlap = OverlapSkip
+		}
+
+		j, exists := d.jobs[job.ID]
+		if exists && j.Spec == job.Spec {
+			j.Job = job
+			continue
+		}
+
+		if exists {
+			d.cron.Remove(j.entryID)
+			log.Printf("[daemon] Rescheduled %s: %s", job.Name, job.Spec)
+		} else {
+			j = &daemonJob{}
+			d.jobs[job.ID] = j
+			added = append(added, job.ID)
+			log.Printf("[daemon] Scheduled %s: %s", job.Name, job.Spec)
+		}
+		j.Job = job
+
+		id := job.ID
+		entryID, err := d.cron.AddFunc(job.Spec, func() { d.Trigger(id) })
+		if err != nil {
+			// Unreachable: the spec was validated above
+			return fmt.Errorf("failed to schedule %q: %w", job.Name, err)
+		}
+		j.entryID = entryID
+	}
+
+	// Jobs added while the daemon is running start right away
+	if d.ctx != nil && d.opts.RunOnStart {
+		for _, id := range added {
+			d.triggerLocked(id)
+		}
+	}
+
+	return nil
+}
+
+// Trigger runs a job now, subject to its overlap policy and the concurrency
+// limits. Unknown jobs and jobs triggered before Run are ignored.
+func (d *Daemon) Trigger(id string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.triggerLocked(id)
+}
+
+// triggerLocked implements Trigger (must be called with d.mu held)
+func (d *Daemon) triggerLocked(id string) {
+	j, ok := d.jobs[id]
+	if !ok || d.ctx == nil || d.ctx.Err() != nil {
+		return
+	}
+
+	if j.running {
+		if j.Overlap == OverlapQueue {
+			if !j.queued {
+				log.Printf("[daemon] %s is still running; queued another run", j.Name)
+			}
+			j.queued = true
+		} else {
+			log.Printf("[daemon] %s is still running; skipped this run", j.Name)
+		}
+		return
+	}
+
+	j.running = true
+	d.wg.Add(1)
+	go d.run(j)
+}
+
+// run executes a job, then any run queued while it was going
+func (d *Daemon) run(j *daemonJob) {
+	defer d.wg.Done()
+
+	for {
+		d.mu.Lock()
+		job := j.Job
+		d.mu.Unlock()
+
+		d.execute(job)
+
+		d.mu.Lock()
+		if !j.queued || j.removed || d.ctx.Err() != nil {
+			j.running = false
+			j.queued = false
+			d.mu.Unlock()
+			return
+		}
+		j.queued = false
+		d.mu.Unlock()
+	}
+}
+
+// execute runs a job once it gets a slot under the concurrency limits
+func (d *Daemon) execute(job Job) {
+	release, err := d.acquire(job.Host)
+	if err != nil {
+		return
+	}
+	defer release()
+
+	log.Printf("[daemon] Running %s...", job.Name)
+	start := time.Now()
+	if err := job.Run(d.ctx); err != nil {
+		log.Printf("[daemon] %s failed after %s: %v", job.Name, time.Since(start).Round(time.Second), err)
+		return
+	}
+	log.Printf("[daemon] %s finished in %s", job.Name, time.Since(start).Round(time.Second))
+}
+
+// acquire waits for a per-host slot, then a global one. Taking the host slot
+// first keeps jobs queued for a busy host from holding global slots that
+// jobs for other hosts could use.
+func (d *Daemon) acquire(host string) (release func(), err error) {
+	hostSlots := d.hostSlots(host)
+	if hostSlots != nil {
+		select {
+		case hostSlots <- struct{}{}:
+		case <-d.ctx.Done():
+			return nil, d.ctx.Err()
+		}
+	}
+
+	if d.global != nil {
+		select {
+		case d.global <- struct{}{}:
+		case <-d.ctx.Done():
+			if hostSlots != nil {
+				<-hostSlots
+			}
+			return nil, d.ctx.Err()
+		}
+	}
+
+	return func() {
+		if d.global != nil {
+			<-d.global
+		}
+		if hostSlots != nil {
+			<-hostSlots
+		}
+	}, nil
+}
+
+// hostSlots returns the semaphore for host, or nil if hosts aren't limited
+func (d *Daemon) hostSlots(host string) chan struct{} {
+	if d.opts.MaxPerHost <= 0 {
+		return nil
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	slots, ok := d.perHost[host]
+	if !ok {
+		slots = make(chan struct{}, d.opts.MaxPerHost)
+		d.perHost[host] = slots
+	}
+	return slots
+}
+
+// Run starts the daemon and blocks until ctx is cancelled, then waits for
+// running jobs to return. Jobs should stop promptly when their context is
+// cancelled.
+func (d *Daemon) Run(ctx context.Context) error {
+	d.mu.Lock()
+	if d.ctx != nil {
+		d.mu.Unlock()
+		return fmt.Errorf("daemon is already running")
+	}
+	d.ctx = ctx
+	if d.opts.RunOnStart {
+		for id := range d.jobs {
+			d.triggerLocked(id)
+		}
+	}
+	d.mu.Unlock()
+
+	d.cron.Start()
+	log.Printf("[daemon] Daemon started with %d job(s)", d.JobCount())
+
+	<-ctx.Done()
+
+	stopCtx := d.cron.Stop()
+	<-stopCtx.Done()
+	d.wg.Wait()
+
+	log.Printf("[daemon] Daemon stopped")
+	return nil
+}
+
+// JobCount returns the number of scheduled jobs
+func (d *Daemon) JobCount() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.jobs)
+}
@@ -0,0 +1,196 @@
+package schedule
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startDaemon runs d in the background until the test ends
+func startDaemon(t *testing.T, d *Daemon) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		_ = d.Run(ctx)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	// Wait for Run to take over so triggers aren't ignored
+	require.Eventually(t, func() bool {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		return d.ctx != nil
+	}, time.Second, time.Millisecond)
+}
+
+// blockingJob returns a job that counts its runs and blocks until release is closed
+func blockingJob(id, host string, runs *atomic.Int32, release <-chan struct{}) Job {
+	return Job{
+		ID:   id,
+		Name: id,
+		Spec: "@every 1h",
+		Host: host,
+		Run: func(ctx context.Context) error {
+			runs.Add(1)
+			select {
+			case <-release:
+			case <-ctx.Done():
+			}
+			return nil
+		},
+	}
+}
+
+func TestParseOverlapPolicy(t *testing.T) {
+	p, err := ParseOverlapPolicy("")
+	require.NoError(t, err)
+	assert.Equal(t, OverlapSkip, p)
+
+	p, err = ParseOverlapPolicy("queue")
+	require.NoError(t, err)
+	assert.Equal(t, OverlapQueue, p)
+
+	_, err = ParseOverlapPolicy("parallel")
+	assert.ErrorContains(t, err, "invalid overlap policy")
+}
+
+func TestDaemon_SetJobs(t *testing.T) {
+	t.Run("invalid spec changes nothing", func(t *testing.T) {
+		d := NewDaemon(DaemonOptions{})
+		require.NoError(t, d.SetJobs([]Job{{ID: "a", Name: "a", Spec: "@hourly"}}))
+
+		err := d.SetJobs([]Job{{ID: "b", Name: "b", Spec: "sometimes"}})
+		assert.ErrorContains(t, err, "invalid cron schedule")
+		assert.Equal(t, 1, d.JobCount())
+	})
+
+	t.Run("duplicate IDs are rejected", func(t *testing.T) {
+		d := NewDaemon(DaemonOptions{})
+		err := d.SetJobs([]Job{{ID: "a", Spec: "@hourly"}, {ID: "a", Spec: "@daily"}})
+		assert.ErrorContains(t, err, "more than once")
+	})
+
+	t.Run("removed jobs are unscheduled", func(t *testing.T) {
+		d := NewDaemon(DaemonOptions{})
+		require.NoError(t, d.SetJobs([]Job{{ID: "a", Spec: "@hourly"}, {ID: "b", Spec: "@daily"}}))
+		require.NoError(t, d.SetJobs([]Job{{ID: "b", Spec: "@weekly"}}))
+		assert.Equal(t, 1, d.JobCount())
+		assert.Len(t, d.cron.Entries(), 1)
+	})
+}
+
+func TestDaemon_RunOnStart(t *testing.T) {
+	var runs atomic.Int32
+	release := make(chan struct{})
+	defer close(release)
+
+	d := NewDaemon(DaemonOptions{RunOnStart: true})
+	require.NoError(t, d.SetJobs([]Job{blockingJob("a", "h", &runs, release)}))
+	startDaemon(t, d)
+
+	assert.Eventually(t, func() bool { return runs.Load() == 1 }, time.Second, 10*time.Millisecond)
+
+	// Jobs added later start right away too
+	require.NoError(t, d.SetJobs([]Job{
+		blockingJob("a", "h", &runs, release),
+		blockingJob("b", "h", &runs, release),
+	}))
+	assert.Eventually(t, func() bool { return runs.Load() == 2 }, time.Second, 10*time.Millisecond)
+}
+
+func TestDaemon_Overlap(t *testing.T) {
+	tests := []struct {
+		name     string
+		overlap  OverlapPolicy
+		wantRuns int32
+	}{
+		{name: "skip", overlap: OverlapSkip, wantRuns: 1},
+		{name: "queue merges pending runs", overlap: OverlapQueue, wantRuns: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var runs atomic.Int32
+			release := make(chan struct{})
+
+			d := NewDaemon(DaemonOptions{})
+			job := blockingJob("a", "h", &runs, release)
+			job.Overlap = tt.overlap
+			require.NoError(t, d.SetJobs([]Job{job}))
+			startDaemon(t, d)
+
+			d.Trigger("a")
+			require.Eventually(t, func() bool { return runs.Load() == 1 }, time.Second, 10*time.Millisecond)
+
+			// Due twice more while the first run is still going
+			d.Trigger("a")
+			d.Trigger("a")
+			close(release)
+
+			time.Sleep(100 * time.Millisecond)
+			assert.Equal(t, tt.wantRuns, runs.Load())
+		})
+	}
+}
+
+func TestDaemon_ConcurrencyLimits(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     DaemonOptions
+		hosts    []string
+		wantRuns int32
+	}{
+		{name: "global limit", opts: DaemonOptions{MaxConcurrent: 1}, hosts: []string{"a", "b"}, wantRuns: 1},
+		{name: "per-host limit", opts: DaemonOptions{MaxPerHost: 1}, hosts: []string{"a", "a", "b"}, wantRuns: 2},
+		{name: "no limits", opts: DaemonOptions{}, hosts: []string{"a", "a", "b"}, wantRuns: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var runs atomic.Int32
+			release := make(chan struct{})
+
+			d := NewDaemon(tt.opts)
+			var jobs []Job
+			for i, host := range tt.hosts {
+				jobs = append(jobs, blockingJob(string(rune('a'+i))+"-job", host, &runs, release))
+			}
+			require.NoError(t, d.SetJobs(jobs))
+			startDaemon(t, d)
+
+			for _, job := range jobs {
+				d.Trigger(job.ID)
+			}
+
+			// Only the jobs that got a slot have started
+			time.Sleep(100 * time.Millisecond)
+			assert.Equal(t, tt.wantRuns, runs.Load())
+
+			// The rest run once slots free up
+			close(release)
+			assert.Eventually(t, func() bool { return runs.Load() == int32(len(jobs)) }, time.Second, 10*time.Millisecond)
+		})
+	}
+}
+
+func TestDaemon_TriggerBeforeRun(t *testing.T) {
+	var runs atomic.Int32
+	d := NewDaemon(DaemonOptions{})
+	require.NoError(t, d.SetJobs([]Job{{ID: "a", Spec: "@hourly", Run: func(ctx context.Context) error {
+		runs.Add(1)
+		return nil
+	}}}))
+
+	d.Trigger("a")
+	d.Trigger("missing")
+	assert.Equal(t, int32(0), runs.Load())
+}
@@ -18,10 +18,12 @@ type Scheduler struct {
 	syncFn SyncFunc
 }
 
+// specParser parses standard 5-field cron expressions and descriptors
+var specParser = cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
 // ValidateSpec validates a cron expression
 func ValidateSpec(spec string) error {
-	parser := cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
-	_, err := parser.Parse(spec)
+	_, err := specParser.Parse(spec)
 	if err != nil {
 		return fmt.Errorf("invalid cron schedule %q: %w", spec, err)
 	}
@@ -57,7 +59,7 @@ func (s *Scheduler) Run(ctx context.Context) error {
 
 	// Set up cron with SkipIfStillRunning to prevent overlapping runs
 	c := cron.New(
-		cron.WithParser(cron.NewParser(cron.Minute|cron.Hour|cron.Dom|cron.Month|cron.Dow|cron.Descriptor)),
+		cron.WithParser(specParser),
 		cron.WithChain(cron.SkipIfStillRunning(cron.DefaultLogger)),
 	)
 
@@ -0,0 +1,333 @@
+package state
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gopkg.in/yaml.v3"
+
+	"github.com/Didstopia/githubby/internal/notify"
+)
+
+// BundleVersion is the profile bundle format version written by this build
+const BundleVersion = 1
+
+// Bundle is a portable export of sync profiles and, optionally, their recent
+// sync history, used to move profiles between machines
+type Bundle struct {
+	Version     int            `yaml:"version"`
+	ExportedAt  time.Time      `yaml:"exported_at"`
+	Profiles    []*SyncProfile `yaml:"profiles"`
+	SyncHistory []*SyncRecord  `yaml:"sync_history,omitempty"`
+}
+
+// ExportBundle bundles the given profiles with up to historyPerProfile of
+// their most recent sync records
+func (s *State) ExportBundle(profiles []*SyncProfile, historyPerProfile int) *Bundle {
+	bundle := &Bundle{
+		Version:    BundleVersion,
+		ExportedAt: time.Now(),
+		Profiles:   make([]*SyncProfile, 0, len(profiles)),
+	}
+
+	for _, p := range profiles {
+		bundle.Profiles = append(bundle.Profiles, p.Clone())
+
+		if historyPerProfile <= 0 {
+			continue
+		}
+		var records []*SyncRecord
+		for _, r := range s.SyncHistory {
+			if r.ProfileID == p.ID {
+				records = append(records, r)
+			}
+		}
+		if len(records) > historyPerProfile {
+			records = records[len(records)-historyPerProfile:]
+		}
+		bundle.SyncHistory = append(bundle.SyncHistory, records...)
+	}
+
+	return bundle
+}
+
+// StripSecrets removes the secrets of the bundled profiles' notifications,
+// so the bundle can be shared: webhook, Slack and Discord targets are
+// dropped, as their URLs and headers hold the credentials, and SMTP
+// passwords are cleared from email targets (which then read
+// $GITHUBBY_SMTP_PASSWORD). Returns the number of targets changed or dropped.
+func (b *Bundle) StripSecrets() int {
+	stripped := 0
+	for _, p := range b.Profiles {
+		var kept []notify.Target
+		for _, target := range p.Notifications {
+			if target.Type != notify.TypeEmail {
+				stripped++
+				continue
+			}
+			if target.SMTP != nil && target.SMTP.Password != "" {
+				target.SMTP.Password = ""
+				stripped++
+			}
+			kept = append(kept, target)
+		}
+		p.Notifications = kept
+	}
+	return stripped
+}
+
+// LoadBundle reads a profile bundle from path
+func LoadBundle(path string) (*Bundle, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bundle: %w", err)
+	}
+
+	var bundle Bundle
+	if err := yaml.Unmarshal(data, &bundle); err != nil {
+		return nil, fmt.Errorf("failed to parse bundle %s: %w", path, err)
+	}
+	if bundle.Version > BundleVersion {
+		return nil, fmt.Errorf("bundle version %d is not supported by this githubby (up to %d); upgrade githubby to import it",
+			bundle.Version, BundleVersion)
+	}
+
+	return &bundle, nil
+}
+
+// ConflictPolicy controls what an import does with a profile whose name
+// already exists
+type ConflictPolicy string
+
+const (
+	// ConflictFail aborts the import without changing anything
+	ConflictFail ConflictPolicy = "fail"
+	// ConflictSkip keeps the existing profile
+	ConflictSkip ConflictPolicy = "skip"
+	// ConflictOverwrite replaces the existing profile's settings, keeping its ID and history
+	ConflictOverwrite ConflictPolicy = "overwrite"
+	// ConflictRename imports the profile under a new, unused name
+	ConflictRename ConflictPolicy = "rename"
+)
+
+// ParseConflictPolicy parses a conflict policy name
+func ParseConflictPolicy(s string) (ConflictPolicy, error) {
+	switch p := ConflictPolicy(s); p {
+	case ConflictFail, ConflictSkip, ConflictOverwrite, ConflictRename:
+		return p, nil
+	default:
+		return "", fmt.Errorf("invalid conflict policy %q (expected fail, skip, overwrite or rename)", s)
+	}
+}
+
+// PathMapping rewrites target directories that start with From to start
+// with To instead
+type PathMapping struct {
+	From string
+	To   string
+}
+
+// ParsePathMapping parses a FROM=TO path mapping
+func ParsePathMapping(s string) (PathMapping, error) {
+	from, to, found := strings.Cut(s, "=")
+	if !found || from == "" || to == "" {
+		return PathMapping{}, fmt.Errorf("invalid path mapping %q (expected FROM=TO)", s)
+	}
+	return PathMapping{From: strings.TrimRight(from, `/\`), To: strings.TrimRight(to, `/\`)}, nil
+}
+
+// MapPath rewrites path with the longest matching mapping. Paths only match
+// on whole directory names, so /repos maps /repos/a but not /repos-old.
+func MapPath(path string, mappings []PathMapping) string {
+	var best *PathMapping
+	for i, m := range mappings {
+		if !hasPathPrefix(path, m.From) {
+			continue
+		}
+		if best == nil || len(m.From) > len(best.From) {
+			best = &mappings[i]
+		}
+	}
+	if best == nil {
+		return path
+	}
+
+	rest := path[len(best.From):]
+	// Moving from Windows to Unix paths also needs the separators rewritten
+	if strings.Contains(best.To, "/") && !strings.Contains(best.To, `\`) {
+		rest = strings.ReplaceAll(rest, `\`, "/")
+	}
+	return best.To + rest
+}
+
+// hasPathPrefix reports whether path is prefix or inside it
+func hasPathPrefix(path, prefix string) bool {
+	if !strings.HasPrefix(path, prefix) {
+		return false
+	}
+	rest := path[len(prefix):]
+	return rest == "" || rest[0] == '/' || rest[0] == '\\'
+}
+
+// ImportOptions configures ImportBundle
+type ImportOptions struct {
+	// OnConflict controls what happens to profiles whose name already exists
+	OnConflict ConflictPolicy
+
+	// PathMappings rewrite the imported profiles' target directories
+	PathMappings []PathMapping
+}
+
+// ImportResult describes what ImportBundle changed
+type ImportResult struct {
+	// Added lists the imported profiles
+	Added []*SyncProfile
+
+	// Overwritten lists existing profiles whose settings were replaced
+	Overwritten []*SyncProfile
+
+	// Skipped lists the names of profiles that already existed and were kept
+	Skipped []string
+
+	// Renamed maps the bundle names of renamed profiles to their new names
+	Renamed map[string]string
+
+	// HistoryImported is the number of sync records imported
+	HistoryImported int
+}
+
+// ImportBundle merges a bundle into the state. Profiles are matched by name
+// and conflicts are handled according to opts.OnConflict. Nothing is changed
+// if any imported profile is invalid or, with ConflictFail, conflicts.
+func (s *State) ImportBundle(bundle *Bundle, opts ImportOptions) (*ImportResult, error) {
+	policy := opts.OnConflict
+	if policy == "" {
+		policy = ConflictFail
+	}
+
+	// Validate everything up front so a bad bundle doesn't half-apply
+	imported := make([]*SyncProfile, 0, len(bundle.Profiles))
+	seen := make(map[string]bool)
+	var conflicts []string
+	for i, p := range bundle.Profiles {
+		if p == nil {
+			return nil, fmt.Errorf("profile #%d in the bundle is empty", i+1)
+		}
+		if seen[p.Name] {
+			return nil, fmt.Errorf("profile %q is in the bundle more than once", p.Name)
+		}
+		seen[p.Name] = true
+
+		profile := p.Clone()
+		profile.TargetDir = MapPath(profile.TargetDir, opts.PathMappings)
+		if profile.SSHKey != "" {
+			profile.SSHKey = MapPath(profile.SSHKey, opts.PathMappings)
+		}
+		if profile.SSHKnownHosts != "" {
+			profile.SSHKnownHosts = MapPath(profile.SSHKnownHosts, opts.PathMappings)
+		}
+		if err := profile.Validate(); err != nil {
+			return nil, fmt.Errorf("profile %q: %w", p.Name, err)
+		}
+		if s.GetProfileByName(profile.Name) != nil {
+			conflicts = append(conflicts, profile.Name)
+		}
+		imported = append(imported, profile)
+	}
+	if policy == ConflictFail && len(conflicts) > 0 {
+		return nil, fmt.Errorf("profiles already exist: %s (choose how to handle them with --on-conflict)",
+			strings.Join(conflicts, ", "))
+	}
+
+	result := &ImportResult{Renamed: make(map[string]string)}
+	localIDs := make(map[string]string) // bundle profile ID -> local profile ID
+
+	for i, profile := range imported {
+		bundleID := bundle.Profiles[i].ID
+
+		if existing := s.GetProfileByName(profile.Name); existing != nil {
+			switch policy {
+			case ConflictSkip:
+				result.Skipped = append(result.Skipped, profile.Name)
+				continue
+			case ConflictOverwrite:
+				profile.ID = existing.ID
+				profile.CreatedAt = existing.CreatedAt
+				if existing.LastSyncAt.After(profile.LastSyncAt) {
+					profile.LastSyncAt = existing.LastSyncAt
+				}
+				s.UpdateProfile(profile)
+				localIDs[bundleID] = profile.ID
+				result.Overwritten = append(result.Overwritten, profile)
+				continue
+			case ConflictRename:
+				newName := s.unusedProfileName(profile.Name)
+				result.Renamed[profile.Name] = newName
+				profile.Name = newName
+			}
+		}
+
+		// Keep the exported ID so history links up, unless it's taken here
+		if profile.ID == "" || s.GetProfile(profile.ID) != nil {
+			profile.ID = uuid.New().String()
+		}
+		s.AddProfile(profile)
+		localIDs[bundleID] = profile.ID
+		result.Added = append(result.Added, profile)
+	}
+
+	result.HistoryImported = s.importSyncHistory(bundle.SyncHistory, localIDs)
+	return result, nil
+}
+
+// importSyncHistory adds the records of imported profiles to the history,
+// skipping records that were imported before. Returns the number added.
+func (s *State) importSyncHistory(records []*SyncRecord, localIDs map[string]string) int {
+	count := 0
+	for _, r := range records {
+		localID, ok := localIDs[r.ProfileID]
+		if !ok || s.hasSyncRecord(localID, r.StartedAt) {
+			continue
+		}
+
+		record := *r
+		record.ProfileID = localID
+		if profile := s.GetProfile(localID); profile != nil {
+			record.ProfileName = profile.Name
+		}
+		s.SyncHistory = append(s.SyncHistory, &record)
+		count++
+	}
+
+	if count > 0 {
+		sort.SliceStable(s.SyncHistory, func(i, j int) bool {
+			return s.SyncHistory[i].StartedAt.Before(s.SyncHistory[j].StartedAt)
+		})
+		s.trimSyncHistory()
+	}
+	return count
+}
+
+// hasSyncRecord reports whether a record for profileID started at startedAt exists
+func (s *State) hasSyncRecord(profileID string, startedAt time.Time) bool {
+	for _, r := range s.SyncHistory {
+		if r.ProfileID == profileID && r.StartedAt.Equal(startedAt) {
+			return true
+		}
+	}
+	return false
+}
+
+// unusedProfileName returns name with the lowest " (N)" suffix not in use
+func (s *State) unusedProfileName(name string) string {
+	for n := 2; ; n++ {
+		candidate := fmt.Sprintf("%s (%d)", name, n)
+		if s.GetProfileByName(candidate) == nil {
+			return candidate
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	"github.com/Didstopia/githubby/internal/notify"
+)
+
+// newBundleSource returns a state with one profile and three sync records
+func newBundleSource() (*State, *SyncProfile) {
+	s := NewState()
+	profile := NewProfile("laptop", ProfileTypeUser, "alice", "/Users/alice/repos", true)
+	profile.SyncAllRepos = true
+	s.AddProfile(profile)
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		record := NewSyncRecord(profile.ID, profile.Name)
+		record.StartedAt = start.Add(time.Duration(i) * time.Hour)
+		record.CompletedAt = record.StartedAt.Add(time.Minute)
+		s.AddSyncRecord(record)
+	}
+	return s, profile
+}
+
+func TestState_ExportBundle(t *testing.T) {
+	s, profile := newBundleSource()
+
+	bundle := s.ExportBundle([]*SyncProfile{profile}, 2)
+	assert.Equal(t, BundleVersion, bundle.Version)
+	require.Len(t, bundle.Profiles, 1)
+	require.Len(t, bundle.SyncHistory, 2)
+	// The most recent records are kept
+	assert.Equal(t, s.SyncHistory[2].StartedAt, bundle.SyncHistory[1].StartedAt)
+
+	assert.Empty(t, s.ExportBundle([]*SyncProfile{profile}, 0).SyncHistory)
+}
+
+func TestBundle_StripSecrets(t *testing.T) {
+	s, profile := newBundleSource()
+	profile.Notifications = []notify.Target{
+		{Type: notify.TypeSlack, URL: "https://hooks.slack.com/services/T0/B0/secret"},
+		{Type: notify.TypeWebhook, URL: "https://example.com/hook", Headers: map[string]string{"Authorization": "Bearer secret"}},
+		{Type: notify.TypeEmail, To: []string{"ops@example.com"}, SMTP: &notify.SMTPConfig{Host: "smtp.example.com", Password: "secret"}},
+		{Type: notify.TypeEmail, To: []string{"dev@example.com"}},
+	}
+
+	bundle := s.ExportBundle([]*SyncProfile{profile}, 0)
+	assert.Equal(t, 3, bundle.StripSecrets())
+	require.Len(t, bundle.Profiles[0].Notifications, 2)
+	assert.Equal(t, notify.TypeEmail, bundle.Profiles[0].Notifications[0].Type)
+	assert.Empty(t, bundle.Profiles[0].Notifications[0].SMTP.Password)
+	assert.Equal(t, "smtp.example.com", bundle.Profiles[0].Notifications[0].SMTP.Host)
+
+	// The profile itself keeps its secrets
+	assert.Equal(t, "secret", profile.Notifications[2].SMTP.Password)
+	assert.Len(t, profile.Notifications, 4)
+}
+
+func TestMapPath(t *testing.T) {
+	mappings := []PathMapping{
+		{From: "/Users/alice", To: "/home/alice"},
+		{From: "/Users/alice/repos", To: "/repos"},
+		{From: `C:\Users\bob`, To: "/bob"},
+	}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "/Users/alice/repos", want: "/repos"},
+		{path: "/Users/alice/repos/work", want: "/repos/work"},
+		{path: "/Users/alice/other", want: "/home/alice/other"},
+		{path: "/Users/alice-old/repos", want: "/Users/alice-old/repos"},
+		{path: `C:\Users\bob\repos`, want: "/bob/repos"},
+		{path: "/srv/repos", want: "/srv/repos"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			assert.Equal(t, tt.want, MapPath(tt.path, mappings))
+		})
+	}
+}
+
+func TestParsePathMapping(t *testing.T) {
+	m, err := ParsePathMapping("/Users/alice/repos/=/repos")
+	require.NoError(t, err)
+	assert.Equal(t, PathMapping{From: "/Users/alice/repos", To: "/repos"}, m)
+
+	_, err = ParsePathMapping("/Users/alice/repos")
+	assert.Error(t, err)
+	_, err = ParsePathMapping("=/repos")
+	assert.Error(t, err)
+}
+
+func TestState_ImportBundle(t *testing.T) {
+	source, profile := newBundleSource()
+	bundle := source.ExportBundle([]*SyncProfile{profile}, 10)
+	mappings := []PathMapping{{From: "/Users/alice/repos", To: "/repos"}}
+
+	t.Run("into empty state", func(t *testing.T) {
+		s := NewState()
+		result, err := s.ImportBundle(bundle, ImportOptions{PathMappings: mappings})
+		require.NoError(t, err)
+
+		require.Len(t, result.Added, 1)
+		assert.Equal(t, 3, result.HistoryImported)
+
+		imported := s.GetProfileByName("laptop")
+		require.NotNil(t, imported)
+		assert.Equal(t, profile.ID, imported.ID, "exported IDs are kept")
+		assert.Equal(t, "/repos", imported.TargetDir)
+		assert.NotNil(t, s.GetLatestSyncForProfile(imported.ID))
+
+		// The bundle itself is not modified
+		assert.Equal(t, "/Users/alice/repos", bundle.Profiles[0].TargetDir)
+	})
+
+	t.Run("conflicts fail by default", func(t *testing.T) {
+		s := NewState()
+		s.AddProfile(NewProfile("laptop", ProfileTypeOrg, "acme", "/srv", false))
+
+		_, err := s.ImportBundle(bundle, ImportOptions{})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "laptop")
+		assert.Len(t, s.Profiles, 1)
+		assert.Empty(t, s.SyncHistory)
+	})
+
+	t.Run("skip keeps existing profile", func(t *testing.T) {
+		s := NewState()
+		existing := NewProfile("laptop", ProfileTypeOrg, "acme", "/srv", false)
+		s.AddProfile(existing)
+
+		result, err := s.ImportBundle(bundle, ImportOptions{OnConflict: ConflictSkip})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"laptop"}, result.Skipped)
+		assert.Equal(t, "acme", s.GetProfileByName("laptop").Source)
+		assert.Zero(t, result.HistoryImported)
+	})
+
+	t.Run("overwrite keeps local ID", func(t *testing.T) {
+		s := NewState()
+		existing := NewProfile("laptop", ProfileTypeOrg, "acme", "/srv", false)
+		s.AddProfile(existing)
+
+		result, err := s.ImportBundle(bundle, ImportOptions{OnConflict: ConflictOverwrite, PathMappings: mappings})
+		require.NoError(t, err)
+		require.Len(t, result.Overwritten, 1)
+
+		updated := s.GetProfile(existing.ID)
+		require.NotNil(t, updated)
+		assert.Equal(t, "alice", updated.Source)
+		assert.Equal(t, "/repos", updated.TargetDir)
+		assert.Equal(t, 3, result.HistoryImported)
+		assert.Equal(t, existing.ID, s.SyncHistory[0].ProfileID)
+	})
+
+	t.Run("rename imports under new name", func(t *testing.T) {
+		s := NewState()
+		s.AddProfile(NewProfile("laptop", ProfileTypeOrg, "acme", "/srv", false))
+
+		result, err := s.ImportBundle(bundle, ImportOptions{OnConflict: ConflictRename})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"laptop": "laptop (2)"}, result.Renamed)
+
+		renamed := s.GetProfileByName("laptop (2)")
+		require.NotNil(t, renamed)
+		assert.Equal(t, "laptop (2)", s.GetLatestSyncForProfile(renamed.ID).ProfileName)
+	})
+
+	t.Run("importing twice does not duplicate history", func(t *testing.T) {
+		s := NewState()
+		_, err := s.ImportBundle(bundle, ImportOptions{})
+		require.NoError(t, err)
+
+		result, err := s.ImportBundle(bundle, ImportOptions{OnConflict: ConflictOverwrite})
+		require.NoError(t, err)
+		assert.Zero(t, result.HistoryImported)
+		assert.Len(t, s.SyncHistory, 3)
+	})
+
+	t.Run("invalid profile aborts import", func(t *testing.T) {
+		broken := source.ExportBundle([]*SyncProfile{profile}, 0)
+		broken.Profiles[0].TargetDir = ""
+
+		s := NewState()
+		_, err := s.ImportBundle(broken, ImportOptions{})
+		assert.Error(t, err)
+		assert.Empty(t, s.Profiles)
+	})
+}
+
+func TestStorage_ImportBundle(t *testing.T) {
+	source, profile := newBundleSource()
+	data, err := yaml.Marshal(source.ExportBundle([]*SyncProfile{profile}, 1))
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	bundlePath := filepath.Join(dir, "profiles.bundle.yaml")
+	require.NoError(t, os.WriteFile(bundlePath, data, 0600))
+
+	bundle, err := LoadBundle(bundlePath)
+	require.NoError(t, err)
+
+	storage := NewStorageWithPath(filepath.Join(dir, "state.yaml"))
+	require.NoError(t, storage.Load())
+
+	result, err := storage.ImportBundle(bundle, ImportOptions{}, true)
+	require.NoError(t, err)
+	assert.Len(t, result.Added, 1)
+	assert.Empty(t, storage.GetProfiles(), "dry run must not change the state")
+
+	_, err = storage.ImportBundle(bundle, ImportOptions{}, false)
+	require.NoError(t, err)
+	assert.Len(t, storage.GetProfiles(), 1)
+	assert.Len(t, storage.GetSyncHistory(), 1)
+}
@@ -0,0 +1,84 @@
+package state
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultFullSyncInterval is how often incremental profiles list every
+// repository unless they set their own interval
+const DefaultFullSyncInterval = 24 * time.Hour
+
+// EventCursor records how far an incremental profile has synced its
+// source's events
+type EventCursor struct {
+	// LastEventID is the newest event synced
+	LastEventID string `yaml:"last_event_id,omitempty"`
+
+	// ETag of the last events listing
+	ETag string `yaml:"etag,omitempty"`
+
+	// LastFullSync is when every repository was last listed and synced
+	LastFullSync time.Time `yaml:"last_full_sync,omitempty"`
+
+	// Retry lists the repositories that failed and are synced again next time
+	Retry []string `yaml:"retry,omitempty"`
+
+	// Scope identifies the profile settings the cursor was recorded with
+	Scope string `yaml:"scope"`
+}
+
+// FullSyncEvery returns how often an incremental profile lists every repository
+func (p *SyncProfile) FullSyncEvery() time.Duration {
+	if p.FullSyncInterval <= 0 {
+		return DefaultFullSyncInterval
+	}
+	return p.FullSyncInterval
+}
+
+// syncScope fingerprints the settings that decide which repositories a
+// profile syncs, from which instance and account, and where. An event cursor recorded under different settings
+// doesn't say anything about what's on disk.
+func (p *SyncProfile) syncScope() string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{
+		p.Hostname(),
+		p.Account,
+		p.Type,
+		strings.ToLower(p.Source),
+		p.TargetDir,
+		strconv.FormatBool(p.IncludePrivate),
+		strings.Join(p.IncludeFilter, ","),
+		strings.Join(p.ExcludeFilter, ","),
+	}, "\x00")))
+	return hex.EncodeToString(sum[:8])
+}
+
+// GetEventCursor returns a copy of the profile's event cursor, or nil if it
+// has none or its settings changed since the cursor was recorded
+func (s *State) GetEventCursor(profile *SyncProfile) *EventCursor {
+	cursor, ok := s.EventCursors[profile.ID]
+	if !ok || cursor.Scope != profile.syncScope() {
+		return nil
+	}
+	clone := *cursor
+	clone.Retry = append([]string(nil), cursor.Retry...)
+	return &clone
+}
+
+// SetEventCursor records the profile's event cursor; nil removes it
+func (s *State) SetEventCursor(profile *SyncProfile, cursor *EventCursor) {
+	if cursor == nil {
+		delete(s.EventCursors, profile.ID)
+		return
+	}
+	if s.EventCursors == nil {
+		s.EventCursors = make(map[string]*EventCursor)
+	}
+	clone := *cursor
+	clone.Retry = append([]string(nil), cursor.Retry...)
+	clone.Scope = profile.syncScope()
+	s.EventCursors[profile.ID] = &clone
+}
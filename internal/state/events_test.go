@@ -0,0 +1,75 @@
+package state
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStorage_EventCursor(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.yaml")
+	storage := NewStorageWithPath(path)
+	require.NoError(t, storage.Load())
+
+	profile := NewProfile("work", "org", "acme", "/tmp/a", false)
+	profile.SyncAllRepos = true
+	require.NoError(t, storage.AddProfile(profile))
+	assert.Nil(t, storage.GetEventCursor(profile))
+
+	cursor := &EventCursor{
+		LastEventID:  "42",
+		ETag:         `W/"abc"`,
+		LastFullSync: time.Now().Truncate(time.Second),
+		Retry:        []string{"acme/api"},
+	}
+	require.NoError(t, storage.SetEventCursor(profile, cursor))
+
+	reloaded := NewStorageWithPath(path)
+	require.NoError(t, reloaded.Load())
+	got := reloaded.GetEventCursor(profile)
+	require.NotNil(t, got)
+	assert.Equal(t, "42", got.LastEventID)
+	assert.Equal(t, `W/"abc"`, got.ETag)
+	assert.True(t, cursor.LastFullSync.Equal(got.LastFullSync))
+	assert.Equal(t, []string{"acme/api"}, got.Retry)
+
+	t.Run("settings that change what's synced invalidate it", func(t *testing.T) {
+		changed := profile.Clone()
+		changed.Concurrency = 8
+		changed.Host = "GitHub.com"
+		assert.NotNil(t, storage.GetEventCursor(changed), "the same host spelled differently")
+
+		for _, change := range []func(p *SyncProfile){
+			func(p *SyncProfile) { p.TargetDir = "/tmp/b" },
+			func(p *SyncProfile) { p.IncludePrivate = true },
+			func(p *SyncProfile) { p.ExcludeFilter = []string{"archive-*"} },
+			func(p *SyncProfile) { p.Source = "other" },
+			func(p *SyncProfile) { p.Host = "github.mycompany.com" },
+			func(p *SyncProfile) { p.Account = "work" },
+		} {
+			changed := profile.Clone()
+			change(changed)
+			assert.Nil(t, storage.GetEventCursor(changed))
+		}
+	})
+
+	t.Run("deleting the profile removes it", func(t *testing.T) {
+		require.NoError(t, storage.DeleteProfile(profile.ID))
+		assert.Nil(t, storage.GetEventCursor(profile))
+	})
+}
+
+func TestSyncProfile_FullSyncEvery(t *testing.T) {
+	profile := NewProfile("work", "org", "acme", "/tmp/a", false)
+	profile.SyncAllRepos = true
+	assert.Equal(t, DefaultFullSyncInterval, profile.FullSyncEvery())
+
+	profile.FullSyncInterval = 6 * time.Hour
+	assert.Equal(t, 6*time.Hour, profile.FullSyncEvery())
+
+	profile.FullSyncInterval = -time.Hour
+	assert.ErrorContains(t, profile.Validate(), "full sync interval")
+}
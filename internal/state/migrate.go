@@ -0,0 +1,111 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CurrentVersion is the state file schema version written by this build
+const CurrentVersion = 1
+
+// ErrUnsupportedVersion is returned when the state file was written by a newer
+// version of githubby than this one
+var ErrUnsupportedVersion = errors.New("unsupported state file version")
+
+// migration upgrades a raw state document from version From to From+1.
+// Migrations work on the decoded YAML document rather than on State, so they
+// can rename or restructure fields the current State type no longer has.
+type migration struct {
+	From        int
+	Description string
+	Migrate     func(doc map[string]any) error
+}
+
+// migrations lists every schema upgrade in order. To change the schema, bump
+// CurrentVersion and append a migration from the previous version.
+var migrations = []migration{
+	{
+		From:        0,
+		Description: "state files written before versioning had no version field",
+		Migrate:     func(doc map[string]any) error { return nil },
+	},
+}
+
+// decodeState parses a state file, upgrading older schema versions to
+// CurrentVersion. Returns the version the file was written with.
+func decodeState(data []byte) (*State, int, error) {
+	doc := make(map[string]any)
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, 0, err
+	}
+
+	version, err := documentVersion(doc)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if version != CurrentVersion {
+		if err := migrateDocument(doc, version, CurrentVersion, migrations); err != nil {
+			return nil, version, err
+		}
+		if data, err = yaml.Marshal(doc); err != nil {
+			return nil, version, fmt.Errorf("failed to serialize migrated state: %w", err)
+		}
+	}
+
+	var state State
+	if err := yaml.Unmarshal(data, &state); err != nil {
+		return nil, version, err
+	}
+	state.Version = CurrentVersion
+
+	return &state, version, nil
+}
+
+// documentVersion returns the schema version of a raw state document.
+// A missing version field means version 0.
+func documentVersion(doc map[string]any) (int, error) {
+	raw, ok := doc["version"]
+	if !ok || raw == nil {
+		return 0, nil
+	}
+	version, ok := raw.(int)
+	if !ok || version < 0 {
+		return 0, fmt.Errorf("invalid state file version: %v", raw)
+	}
+	return version, nil
+}
+
+// migrateDocument applies the migrations needed to take doc from version from
+// to version to, one step at a time
+func migrateDocument(doc map[string]any, from, to int, steps []migration) error {
+	if from > to {
+		return fmt.Errorf("%w: state file is version %d but this githubby only supports up to version %d; upgrade githubby to use it",
+			ErrUnsupportedVersion, from, to)
+	}
+
+	for version := from; version < to; version++ {
+		step := findMigration(steps, version)
+		if step == nil {
+			return fmt.Errorf("no state migration from version %d to %d", version, version+1)
+		}
+		if err := step.Migrate(doc); err != nil {
+			return fmt.Errorf("state migration from version %d failed (%s): %w", version, step.Description, err)
+		}
+		doc["version"] = version + 1
+	}
+
+	return nil
+}
+
+// findMigration returns the migration starting at version, or nil
+func findMigration(steps []migration, version int) *migration {
+	for i := range steps {
+		if steps[i].From == version {
+			return &steps[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package state
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeState(t *testing.T) {
+	t.Run("current version", func(t *testing.T) {
+		state, version, err := decodeState([]byte("version: 1\nprofiles:\n  - id: abc\n    name: test\n"))
+		require.NoError(t, err)
+		assert.Equal(t, 1, version)
+		assert.Equal(t, CurrentVersion, state.Version)
+		require.Len(t, state.Profiles, 1)
+		assert.Equal(t, "test", state.Profiles[0].Name)
+	})
+
+	t.Run("missing version is migrated", func(t *testing.T) {
+		state, version, err := decodeState([]byte("onboarding_complete: true\n"))
+		require.NoError(t, err)
+		assert.Equal(t, 0, version)
+		assert.Equal(t, CurrentVersion, state.Version)
+		assert.True(t, state.OnboardingComplete)
+	})
+
+	t.Run("future version is rejected", func(t *testing.T) {
+		_, _, err := decodeState([]byte("version: 99\n"))
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, ErrUnsupportedVersion))
+		assert.Contains(t, err.Error(), "upgrade githubby")
+	})
+
+	t.Run("invalid version", func(t *testing.T) {
+		_, _, err := decodeState([]byte("version: latest\n"))
+		assert.Error(t, err)
+	})
+}
+
+func TestMigrateDocument(t *testing.T) {
+	steps := []migration{
+		{From: 1, Description: "rename target", Migrate: func(doc map[string]any) error {
+			doc["target_dir"] = doc["target"]
+			delete(doc, "target")
+			return nil
+		}},
+		{From: 2, Description: "add default", Migrate: func(doc map[string]any) error {
+			doc["layout"] = "owner/repo"
+			return nil
+		}},
+	}
+
+	t.Run("applies each step in order", func(t *testing.T) {
+		doc := map[string]any{"version": 1, "target": "/repos"}
+		require.NoError(t, migrateDocument(doc, 1, 3, steps))
+
+		assert.Equal(t, 3, doc["version"])
+		assert.Equal(t, "/repos", doc["target_dir"])
+		assert.NotContains(t, doc, "target")
+		assert.Equal(t, "owner/repo", doc["layout"])
+	})
+
+	t.Run("missing step", func(t *testing.T) {
+		err := migrateDocument(map[string]any{}, 0, 3, steps)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "no state migration from version 0")
+	})
+
+	t.Run("failing step", func(t *testing.T) {
+		failing := []migration{{From: 0, Description: "broken", Migrate: func(doc map[string]any) error {
+			return errors.New("boom")
+		}}}
+		err := migrateDocument(map[string]any{}, 0, 1, failing)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "broken")
+	})
+
+	t.Run("newer than supported", func(t *testing.T) {
+		err := migrateDocument(map[string]any{}, 4, 3, steps)
+		assert.True(t, errors.Is(err, ErrUnsupportedVersion))
+	})
+}
@@ -0,0 +1,270 @@
+package state
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Didstopia/githubby/internal/auth"
+	"github.com/Didstopia/githubby/internal/notify"
+	"github.com/Didstopia/githubby/internal/schedule"
+	"github.com/Didstopia/githubby/internal/sync"
+)
+
+// Profile types
+const (
+	ProfileTypeUser = "user"
+	ProfileTypeOrg  = "org"
+)
+
+// Clone modes
+const (
+	// CloneModeFull clones working copies with full history (the default)
+	CloneModeFull = "full"
+	// CloneModeShallow clones working copies with only the latest commit of each branch
+	CloneModeShallow = "shallow"
+	// CloneModeMirror clones bare mirrors of all refs
+	CloneModeMirror = "mirror"
+)
+
+// Transports
+const (
+	// TransportHTTPS clones and fetches over HTTPS with the account's token
+	// (the default)
+	TransportHTTPS = "https"
+	// TransportSSH clones and fetches over SSH with the user's SSH keys
+	TransportSSH = "ssh"
+)
+
+// DefaultConcurrency is the number of repositories a profile syncs in
+// parallel unless it sets its own concurrency
+const DefaultConcurrency = 4
+
+// MaxConcurrency is the highest concurrency a profile can set, which is
+// the most the syncer runs in parallel
+const MaxConcurrency = sync.MaxConcurrency
+
+// ShallowDepth is the number of commits per branch kept by shallow clones
+const ShallowDepth = 1
+
+// Validate checks that a profile has everything a sync needs.
+// The TUI wizard and the profile CLI commands both validate through here.
+func (p *SyncProfile) Validate() error {
+	if err := ValidateProfileName(p.Name); err != nil {
+		return err
+	}
+
+	switch p.Type {
+	case ProfileTypeUser, ProfileTypeOrg:
+	default:
+		return fmt.Errorf("invalid profile type %q (expected %q or %q)", p.Type, ProfileTypeUser, ProfileTypeOrg)
+	}
+
+	if strings.TrimSpace(p.Source) == "" {
+		return fmt.Errorf("please enter a %s to sync from", p.Type)
+	}
+
+	if strings.TrimSpace(p.TargetDir) == "" {
+		return fmt.Errorf("please enter a target directory")
+	}
+
+	if _, err := auth.NormalizeHostname(p.Host); err != nil {
+		return err
+	}
+
+	if err := auth.ValidateAccountName(p.Account); err != nil {
+		return err
+	}
+
+	for _, repo := range p.SelectedRepos {
+		owner, name, found := strings.Cut(repo, "/")
+		if !found || owner == "" || name == "" || strings.Contains(name, "/") {
+			return fmt.Errorf("invalid repository %q (expected owner/repo)", repo)
+		}
+	}
+
+	for _, pattern := range append(append([]string{}, p.IncludeFilter...), p.ExcludeFilter...) {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid filter pattern %q: %w", pattern, err)
+		}
+	}
+
+	if p.Concurrency < 0 || p.Concurrency > MaxConcurrency {
+		return fmt.Errorf("concurrency must be 0 (default of %d) or between 1 and %d", DefaultConcurrency, MaxConcurrency)
+	}
+	if p.RepoTimeout < 0 {
+		return fmt.Errorf("repository timeout must not be negative")
+	}
+	if err := ValidateCloneMode(p.CloneMode); err != nil {
+		return err
+	}
+	if err := ValidateTransport(p.Transport); err != nil {
+		return err
+	}
+	if !p.UseSSH() && (p.SSHKey != "" || p.SSHKnownHosts != "") {
+		return fmt.Errorf("an SSH key or known_hosts file needs the %s transport", TransportSSH)
+	}
+	if p.Schedule != "" {
+		if err := schedule.ValidateSpec(p.Schedule); err != nil {
+			return err
+		}
+	}
+	if _, err := schedule.ParseOverlapPolicy(p.Overlap); err != nil {
+		return err
+	}
+	if p.FullSyncInterval < 0 {
+		return fmt.Errorf("full sync interval must not be negative")
+	}
+	for i := range p.Notifications {
+		if err := p.Notifications[i].Validate(); err != nil {
+			return fmt.Errorf("notification #%d: %w", i+1, err)
+		}
+	}
+
+	return nil
+}
+
+// ValidateCloneMode checks that mode is a known clone mode (empty means full)
+func ValidateCloneMode(mode string) error {
+	switch mode {
+	case "", CloneModeFull, CloneModeShallow, CloneModeMirror:
+		return nil
+	default:
+		return fmt.Errorf("invalid clone mode %q (expected %s, %s or %s)", mode, CloneModeFull, CloneModeShallow, CloneModeMirror)
+	}
+}
+
+// ValidateTransport checks that transport is a known transport (empty means
+// https)
+func ValidateTransport(transport string) error {
+	switch transport {
+	case "", TransportHTTPS, TransportSSH:
+		return nil
+	default:
+		return fmt.Errorf("invalid transport %q (expected %s or %s)", transport, TransportHTTPS, TransportSSH)
+	}
+}
+
+// SyncConcurrency returns the number of repositories to sync in parallel
+func (p *SyncProfile) SyncConcurrency() int {
+	if p.Concurrency <= 0 {
+		return DefaultConcurrency
+	}
+	return min(p.Concurrency, MaxConcurrency)
+}
+
+// Mirror returns true if new clones are bare mirrors
+func (p *SyncProfile) Mirror() bool {
+	return p.CloneMode == CloneModeMirror
+}
+
+// CloneDepth returns the history depth of new clones (0 = full history)
+func (p *SyncProfile) CloneDepth() int {
+	if p.CloneMode == CloneModeShallow {
+		return ShallowDepth
+	}
+	return 0
+}
+
+// UseSSH returns true if repositories are cloned and fetched over SSH
+func (p *SyncProfile) UseSSH() bool {
+	return p.Transport == TransportSSH
+}
+
+// Hostname returns the normalized hostname of the GitHub instance the
+// profile syncs from
+func (p *SyncProfile) Hostname() string {
+	hostname, err := auth.NormalizeHostname(p.Host)
+	if err != nil {
+		// Validate rejects these, so this is a profile edited by hand
+		return p.Host
+	}
+	return hostname
+}
+
+// OverlapPolicy returns what the daemon does when the profile is due while
+// it's still syncing
+func (p *SyncProfile) OverlapPolicy() schedule.OverlapPolicy {
+	policy, err := schedule.ParseOverlapPolicy(p.Overlap)
+	if err != nil {
+		return schedule.OverlapSkip
+	}
+	return policy
+}
+
+// ValidateProfileName checks that a profile name is usable
+func ValidateProfileName(name string) error {
+	if strings.TrimSpace(name) == "" {
+		return fmt.Errorf("please enter a profile name")
+	}
+	if name != strings.TrimSpace(name) {
+		return fmt.Errorf("profile name %q must not start or end with spaces", name)
+	}
+	if strings.ContainsAny(name, "\r\n") {
+		return fmt.Errorf("profile name %q must not contain line breaks", name)
+	}
+	return nil
+}
+
+// Clone returns a deep copy of the profile
+func (p *SyncProfile) Clone() *SyncProfile {
+	clone := *p
+	clone.SelectedRepos = append([]string(nil), p.SelectedRepos...)
+	clone.IncludeFilter = append([]string(nil), p.IncludeFilter...)
+	clone.ExcludeFilter = append([]string(nil), p.ExcludeFilter...)
+	clone.Notifications = notify.CloneTargets(p.Notifications)
+	return &clone
+}
+
+// Duplicate returns a copy of the profile under a new name and ID, without
+// its sync history
+func (p *SyncProfile) Duplicate(name string) *SyncProfile {
+	duplicate := p.Clone()
+	duplicate.ID = uuid.New().String()
+	duplicate.Name = name
+	duplicate.CreatedAt = time.Now()
+	duplicate.LastSyncAt = time.Time{}
+	return duplicate
+}
+
+// ExpandPath expands a leading ~ to the user's home directory
+func ExpandPath(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") && !strings.HasPrefix(path, `~\`) {
+		return path
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(homeDir, path[1:])
+}
+
+// CheckProfileName returns an error if another profile (with a different ID)
+// already uses name
+func (s *State) CheckProfileName(name, id string) error {
+	if existing := s.GetProfileByName(name); existing != nil && existing.ID != id {
+		return fmt.Errorf("a profile named %q already exists", name)
+	}
+	return nil
+}
+
+// RenameProfile renames a profile and the sync history recorded under its
+// old name
+func (s *State) RenameProfile(id, name string) bool {
+	profile := s.GetProfile(id)
+	if profile == nil {
+		return false
+	}
+
+	profile.Name = name
+	for _, r := range s.SyncHistory {
+		if r.ProfileID == id {
+			r.ProfileName = name
+		}
+	}
+	return true
+}
@@ -0,0 +1,161 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Didstopia/githubby/internal/notify"
+	"github.com/Didstopia/githubby/internal/schedule"
+)
+
+func TestSyncProfile_Validate(t *testing.T) {
+	valid := func() *SyncProfile {
+		p := NewProfile("test", ProfileTypeUser, "alice", "/tmp/repos", false)
+		p.SyncAllRepos = true
+		return p
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(p *SyncProfile)
+		wantErr string
+	}{
+		{name: "valid", modify: func(p *SyncProfile) {}},
+		{name: "selected repos", modify: func(p *SyncProfile) {
+			p.SyncAllRepos = false
+			p.SelectedRepos = []string{"alice/repo"}
+		}},
+		{name: "empty name", modify: func(p *SyncProfile) { p.Name = " " }, wantErr: "profile name"},
+		{name: "padded name", modify: func(p *SyncProfile) { p.Name = "test " }, wantErr: "spaces"},
+		{name: "multiline name", modify: func(p *SyncProfile) { p.Name = "test\r\nBcc: x@example.com" }, wantErr: "line breaks"},
+		{name: "invalid type", modify: func(p *SyncProfile) { p.Type = "team" }, wantErr: "invalid profile type"},
+		{name: "missing source", modify: func(p *SyncProfile) { p.Source = "" }, wantErr: "user to sync from"},
+		{name: "missing target", modify: func(p *SyncProfile) { p.TargetDir = "" }, wantErr: "target directory"},
+		{name: "no repos selected", modify: func(p *SyncProfile) { p.SyncAllRepos = false }},
+		{name: "invalid repo", modify: func(p *SyncProfile) {
+			p.SyncAllRepos = false
+			p.SelectedRepos = []string{"repo"}
+		}, wantErr: "owner/repo"},
+		{name: "invalid pattern", modify: func(p *SyncProfile) { p.ExcludeFilter = []string{"["} }, wantErr: "invalid filter pattern"},
+		{name: "enterprise host", modify: func(p *SyncProfile) { p.Host = "github.mycompany.com" }},
+		{name: "invalid host", modify: func(p *SyncProfile) { p.Host = "github.mycompany.com/org" }, wantErr: "invalid GitHub hostname"},
+		{name: "named account", modify: func(p *SyncProfile) { p.Account = "work" }},
+		{name: "invalid account", modify: func(p *SyncProfile) { p.Account = "work@github.com" }, wantErr: "invalid account name"},
+		{name: "sync settings", modify: func(p *SyncProfile) {
+			p.Concurrency = MaxConcurrency
+			p.RepoTimeout = 10 * time.Minute
+			p.SkipLFS = true
+			p.CloneMode = CloneModeMirror
+			p.Schedule = "@every 6h"
+		}},
+		{name: "concurrency too high", modify: func(p *SyncProfile) { p.Concurrency = MaxConcurrency + 1 }, wantErr: "concurrency"},
+		{name: "negative timeout", modify: func(p *SyncProfile) { p.RepoTimeout = -time.Second }, wantErr: "timeout"},
+		{name: "invalid clone mode", modify: func(p *SyncProfile) { p.CloneMode = "bare" }, wantErr: "invalid clone mode"},
+		{name: "ssh transport", modify: func(p *SyncProfile) {
+			p.Transport = TransportSSH
+			p.SSHKey = "/home/user/.ssh/deploy_key"
+			p.SSHKnownHosts = "/home/user/.ssh/known_hosts"
+		}},
+		{name: "invalid transport", modify: func(p *SyncProfile) { p.Transport = "git" }, wantErr: "invalid transport"},
+		{name: "ssh key without ssh", modify: func(p *SyncProfile) { p.SSHKey = "/home/user/.ssh/deploy_key" }, wantErr: "needs the ssh transport"},
+		{name: "invalid schedule", modify: func(p *SyncProfile) { p.Schedule = "every day" }, wantErr: "invalid cron schedule"},
+		{name: "invalid overlap", modify: func(p *SyncProfile) { p.Overlap = "parallel" }, wantErr: "invalid overlap policy"},
+		{name: "notifications", modify: func(p *SyncProfile) {
+			p.Notifications = []notify.Target{{Type: notify.TypeSlack, URL: "https://hooks.slack.com/services/x", On: "on-change"}}
+		}},
+		{name: "invalid notification", modify: func(p *SyncProfile) {
+			p.Notifications = []notify.Target{{Type: notify.TypeWebhook}}
+		}, wantErr: "notification #1: webhook notification needs an http(s) URL"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := valid()
+			tt.modify(p)
+			err := p.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestSyncProfile_SyncSettings(t *testing.T) {
+	p := NewProfile("test", ProfileTypeUser, "alice", "/tmp/repos", false)
+	assert.Equal(t, DefaultConcurrency, p.SyncConcurrency())
+	assert.False(t, p.Mirror())
+	assert.Zero(t, p.CloneDepth())
+	assert.Equal(t, schedule.OverlapSkip, p.OverlapPolicy())
+	assert.Equal(t, "github.com", p.Hostname())
+
+	p.Host = "https://GitHub.MyCompany.com/"
+	assert.Equal(t, "github.mycompany.com", p.Hostname())
+
+	p.Concurrency = 2
+	p.CloneMode = CloneModeShallow
+	assert.Equal(t, 2, p.SyncConcurrency())
+	assert.Equal(t, ShallowDepth, p.CloneDepth())
+
+	p.CloneMode = CloneModeMirror
+	assert.True(t, p.Mirror())
+	assert.Zero(t, p.CloneDepth())
+
+	p.Overlap = string(schedule.OverlapQueue)
+	assert.Equal(t, schedule.OverlapQueue, p.OverlapPolicy())
+}
+
+func TestSyncProfile_Duplicate(t *testing.T) {
+	original := NewProfile("test", ProfileTypeOrg, "acme", "/tmp/repos", true)
+	original.SelectedRepos = []string{"acme/one"}
+	original.LastSyncAt = original.CreatedAt
+
+	duplicate := original.Duplicate("copy")
+
+	assert.NotEqual(t, original.ID, duplicate.ID)
+	assert.Equal(t, "copy", duplicate.Name)
+	assert.Equal(t, original.Source, duplicate.Source)
+	assert.True(t, duplicate.LastSyncAt.IsZero())
+
+	// The copy must not share slices with the original
+	duplicate.SelectedRepos[0] = "acme/two"
+	assert.Equal(t, "acme/one", original.SelectedRepos[0])
+}
+
+func TestExpandPath(t *testing.T) {
+	home, err := os.UserHomeDir()
+	require.NoError(t, err)
+
+	assert.Equal(t, home, ExpandPath("~"))
+	assert.Equal(t, filepath.Join(home, "repos"), ExpandPath("~/repos"))
+	assert.Equal(t, "/tmp/repos", ExpandPath("/tmp/repos"))
+	assert.Equal(t, "~other/repos", ExpandPath("~other/repos"))
+}
+
+func TestStorage_RenameProfile(t *testing.T) {
+	storage := NewStorageWithPath(filepath.Join(t.TempDir(), "state.yaml"))
+	require.NoError(t, storage.Load())
+
+	first := NewProfile("first", ProfileTypeUser, "alice", "/tmp/a", false)
+	second := NewProfile("second", ProfileTypeUser, "alice", "/tmp/b", false)
+	require.NoError(t, storage.AddProfile(first))
+	require.NoError(t, storage.AddProfile(second))
+
+	record := NewSyncRecord(first.ID, first.Name)
+	require.NoError(t, storage.AddSyncRecord(record))
+
+	err := storage.RenameProfile(first.ID, "second")
+	assert.Error(t, err, "names must stay unique")
+
+	require.NoError(t, storage.RenameProfile(first.ID, "renamed"))
+	assert.NotNil(t, storage.GetProfileByName("renamed"))
+	assert.Nil(t, storage.GetProfileByName("first"))
+	assert.Equal(t, "renamed", storage.GetLatestSyncForProfile(first.ID).ProfileName)
+}
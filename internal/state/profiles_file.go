@@ -0,0 +1,320 @@
+package state
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/Didstopia/githubby/internal/auth"
+	"github.com/Didstopia/githubby/internal/notify"
+)
+
+// DefaultProfilesFileName is the conventional name of a declarative profiles file
+const DefaultProfilesFileName = "githubby.profiles.yaml"
+
+// ProfilesFileVersion is the profiles file format version understood by this build
+const ProfilesFileVersion = 1
+
+// ProfilesFile is a declarative, version-controllable list of sync profiles.
+// Applying it reconciles the profiles in the state file with it, matching
+// profiles by name so their IDs and sync history are kept.
+type ProfilesFile struct {
+	Version  int            `yaml:"version,omitempty"`
+	Profiles []*ProfileSpec `yaml:"profiles"`
+}
+
+// ProfileSpec declares a sync profile. Profiles without selected repos sync
+// every repository of their source.
+type ProfileSpec struct {
+	Name           string   `yaml:"name"`
+	Type           string   `yaml:"type"`
+	Source         string   `yaml:"source"`
+	TargetDir      string   `yaml:"target_dir"`
+	Host           string   `yaml:"host,omitempty"`
+	Account        string   `yaml:"account,omitempty"`
+	IncludePrivate bool     `yaml:"include_private,omitempty"`
+	SelectedRepos  []string `yaml:"selected_repos,omitempty"`
+	IncludeFilter  []string `yaml:"include_filter,omitempty"`
+	ExcludeFilter  []string `yaml:"exclude_filter,omitempty"`
+
+	Concurrency int           `yaml:"concurrency,omitempty"`
+	RepoTimeout time.Duration `yaml:"repo_timeout,omitempty"`
+	SkipLFS     bool          `yaml:"skip_lfs,omitempty"`
+	CloneMode   string        `yaml:"clone_mode,omitempty"`
+	Schedule    string        `yaml:"schedule,omitempty"`
+	Overlap     string        `yaml:"overlap,omitempty"`
+
+	Transport     string `yaml:"transport,omitempty"`
+	SSHKey        string `yaml:"ssh_key,omitempty"`
+	SSHKnownHosts string `yaml:"ssh_known_hosts,omitempty"`
+
+	Incremental      bool          `yaml:"incremental,omitempty"`
+	FullSyncInterval time.Duration `yaml:"full_sync_interval,omitempty"`
+
+	Notifications []notify.Target `yaml:"notifications,omitempty"`
+}
+
+// LoadProfilesFile reads and validates a profiles file
+func LoadProfilesFile(path string) (*ProfilesFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read profiles file: %w", err)
+	}
+
+	file, err := ParseProfilesFile(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return file, nil
+}
+
+// ParseProfilesFile parses and validates profiles file contents.
+// Unknown fields are rejected so typos don't silently drop settings.
+func ParseProfilesFile(data []byte) (*ProfilesFile, error) {
+	var file ProfilesFile
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&file); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse profiles file: %w", err)
+	}
+
+	if file.Version > ProfilesFileVersion {
+		return nil, fmt.Errorf("profiles file version %d is not supported by this githubby (up to %d); upgrade githubby to use it",
+			file.Version, ProfilesFileVersion)
+	}
+
+	seen := make(map[string]bool)
+	for i, spec := range file.Profiles {
+		if spec == nil {
+			return nil, fmt.Errorf("profile #%d is empty", i+1)
+		}
+		if seen[spec.Name] {
+			return nil, fmt.Errorf("profile %q is declared more than once", spec.Name)
+		}
+		seen[spec.Name] = true
+
+		if err := spec.ToProfile().Validate(); err != nil {
+			if spec.Name == "" {
+				return nil, fmt.Errorf("profile #%d: %w", i+1, err)
+			}
+			return nil, fmt.Errorf("profile %q: %w", spec.Name, err)
+		}
+	}
+
+	return &file, nil
+}
+
+// ToProfile returns a new, unsaved profile with the declared settings
+func (spec *ProfileSpec) ToProfile() *SyncProfile {
+	profile := NewProfile("", "", "", "", false)
+	spec.applyTo(profile)
+	return profile
+}
+
+// applyTo sets the declared fields on profile, leaving its ID, creation and
+// sync times alone
+func (spec *ProfileSpec) applyTo(profile *SyncProfile) {
+	profile.Name = spec.Name
+	profile.Type = spec.Type
+	profile.Source = spec.Source
+	profile.TargetDir = ExpandPath(spec.TargetDir)
+	profile.Host = spec.Host
+	profile.Account = spec.Account
+	profile.IncludePrivate = spec.IncludePrivate
+	profile.SyncAllRepos = len(spec.SelectedRepos) == 0
+	profile.SelectedRepos = append([]string(nil), spec.SelectedRepos...)
+	profile.IncludeFilter = append([]string(nil), spec.IncludeFilter...)
+	profile.ExcludeFilter = append([]string(nil), spec.ExcludeFilter...)
+	profile.Concurrency = spec.Concurrency
+	profile.RepoTimeout = spec.RepoTimeout
+	profile.SkipLFS = spec.SkipLFS
+	profile.CloneMode = spec.CloneMode
+	profile.Transport = spec.Transport
+	profile.SSHKey = ExpandPath(spec.SSHKey)
+	profile.SSHKnownHosts = ExpandPath(spec.SSHKnownHosts)
+	profile.Schedule = spec.Schedule
+	profile.Overlap = spec.Overlap
+	profile.Incremental = spec.Incremental
+	profile.FullSyncInterval = spec.FullSyncInterval
+	profile.Notifications = notify.CloneTargets(spec.Notifications)
+}
+
+// FieldChange describes a changed profile setting
+type FieldChange struct {
+	Field string
+	Old   string
+	New   string
+}
+
+// ProfileChange describes the changes applied to an existing profile
+type ProfileChange struct {
+	Name   string
+	Fields []FieldChange
+}
+
+// ProfilesPlan is the difference between a profiles file and the state
+type ProfilesPlan struct {
+	// Added profiles are declared in the file but don't exist yet
+	Added []*SyncProfile
+
+	// Changed profiles exist but differ from the file
+	Changed []ProfileChange
+
+	// Removed profiles exist but aren't declared in the file (only with prune)
+	Removed []*SyncProfile
+
+	// Unchanged profiles already match the file
+	Unchanged []string
+
+	// Unmanaged profiles aren't declared in the file and are kept (without prune)
+	Unmanaged []string
+}
+
+// Empty returns true if applying the plan changes nothing
+func (p *ProfilesPlan) Empty() bool {
+	return len(p.Added) == 0 && len(p.Changed) == 0 && len(p.Removed) == 0
+}
+
+// profileFields lists the profile settings compared when planning changes
+var profileFields = []struct {
+	name  string
+	value func(p *SyncProfile) string
+}{
+	{"type", func(p *SyncProfile) string { return p.Type }},
+	{"source", func(p *SyncProfile) string { return p.Source }},
+	{"target_dir", func(p *SyncProfile) string { return p.TargetDir }},
+	{"host", func(p *SyncProfile) string { return p.Hostname() }},
+	{"account", func(p *SyncProfile) string { return orDefault(p.Account, auth.DefaultAccountName) }},
+	{"include_private", func(p *SyncProfile) string { return strconv.FormatBool(p.IncludePrivate) }},
+	{"sync_all_repos", func(p *SyncProfile) string { return strconv.FormatBool(p.SyncAllRepos || len(p.SelectedRepos) == 0) }},
+	{"selected_repos", func(p *SyncProfile) string { return formatList(p.SelectedRepos) }},
+	{"include_filter", func(p *SyncProfile) string { return formatList(p.IncludeFilter) }},
+	{"exclude_filter", func(p *SyncProfile) string { return formatList(p.ExcludeFilter) }},
+	{"concurrency", func(p *SyncProfile) string { return strconv.Itoa(p.SyncConcurrency()) }},
+	{"repo_timeout", func(p *SyncProfile) string { return orDefault(formatTimeout(p.RepoTimeout), "none") }},
+	{"skip_lfs", func(p *SyncProfile) string { return strconv.FormatBool(p.SkipLFS) }},
+	{"clone_mode", func(p *SyncProfile) string { return orDefault(p.CloneMode, CloneModeFull) }},
+	{"transport", func(p *SyncProfile) string { return orDefault(p.Transport, TransportHTTPS) }},
+	{"ssh_key", func(p *SyncProfile) string { return orDefault(p.SSHKey, "default") }},
+	{"ssh_known_hosts", func(p *SyncProfile) string { return orDefault(p.SSHKnownHosts, "default") }},
+	{"schedule", func(p *SyncProfile) string { return orDefault(p.Schedule, "none") }},
+	{"overlap", func(p *SyncProfile) string { return string(p.OverlapPolicy()) }},
+	{"incremental", func(p *SyncProfile) string { return strconv.FormatBool(p.Incremental) }},
+	{"full_sync_interval", func(p *SyncProfile) string { return p.FullSyncEvery().String() }},
+	{"notifications", func(p *SyncProfile) string { return formatNotifications(p.Notifications) }},
+}
+
+// formatList formats a list setting for display in a diff
+func formatList(values []string) string {
+	return "[" + strings.Join(values, ", ") + "]"
+}
+
+// formatNotifications formats notification targets for display in a diff.
+// Targets are shown with a fingerprint instead of their URLs, which usually
+// contain secrets.
+func formatNotifications(targets []notify.Target) string {
+	values := make([]string, len(targets))
+	for i, t := range targets {
+		values[i] = fmt.Sprintf("%s %s #%s", t, t.Trigger(), t.Fingerprint())
+	}
+	return formatList(values)
+}
+
+// formatTimeout formats a timeout setting, returning "" if it's unset
+func formatTimeout(d time.Duration) string {
+	if d == 0 {
+		return ""
+	}
+	return d.String()
+}
+
+// orDefault returns value, or fallback if value is empty
+func orDefault(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+// diffProfiles returns the settings that differ between two profiles
+func diffProfiles(old, new *SyncProfile) []FieldChange {
+	var changes []FieldChange
+	for _, field := range profileFields {
+		oldValue, newValue := field.value(old), field.value(new)
+		if oldValue != newValue {
+			changes = append(changes, FieldChange{Field: field.name, Old: oldValue, New: newValue})
+		}
+	}
+	return changes
+}
+
+// PlanProfiles works out how applying file would change the state. With
+// prune, profiles not declared in the file are removed.
+func (s *State) PlanProfiles(file *ProfilesFile, prune bool) *ProfilesPlan {
+	plan, _ := s.reconcileProfiles(file, prune)
+	return plan
+}
+
+// ApplyProfiles reconciles the state's profiles with file. Existing profiles
+// are matched by name and updated in place, keeping their IDs and history.
+func (s *State) ApplyProfiles(file *ProfilesFile, prune bool) *ProfilesPlan {
+	plan, apply := s.reconcileProfiles(file, prune)
+	apply()
+	return plan
+}
+
+// reconcileProfiles builds the plan for file and a function that applies it
+func (s *State) reconcileProfiles(file *ProfilesFile, prune bool) (*ProfilesPlan, func()) {
+	plan := &ProfilesPlan{}
+	var updates []*SyncProfile
+
+	declared := make(map[string]bool)
+	for _, spec := range file.Profiles {
+		declared[spec.Name] = true
+
+		existing := s.GetProfileByName(spec.Name)
+		if existing == nil {
+			plan.Added = append(plan.Added, spec.ToProfile())
+			continue
+		}
+
+		updated := existing.Clone()
+		spec.applyTo(updated)
+		if changes := diffProfiles(existing, updated); len(changes) > 0 {
+			plan.Changed = append(plan.Changed, ProfileChange{Name: spec.Name, Fields: changes})
+			updates = append(updates, updated)
+		} else {
+			plan.Unchanged = append(plan.Unchanged, spec.Name)
+		}
+	}
+
+	for _, p := range s.Profiles {
+		if declared[p.Name] {
+			continue
+		}
+		if prune {
+			plan.Removed = append(plan.Removed, p)
+		} else {
+			plan.Unmanaged = append(plan.Unmanaged, p.Name)
+		}
+	}
+
+	return plan, func() {
+		for _, p := range plan.Added {
+			s.AddProfile(p)
+		}
+		for _, p := range updates {
+			s.UpdateProfile(p)
+		}
+		for _, p := range plan.Removed {
+			s.DeleteProfile(p.ID)
+		}
+	}
+}
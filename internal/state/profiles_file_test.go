@@ -0,0 +1,220 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Didstopia/githubby/internal/notify"
+)
+
+const testProfilesFile = `
+version: 1
+profiles:
+  - name: personal
+    type: user
+    source: alice
+    target_dir: /backup/alice
+    include_private: true
+    incremental: true
+    full_sync_interval: 6h
+  - name: tools
+    type: org
+    source: acme
+    target_dir: /backup/tools
+    selected_repos: [acme/one, acme/two]
+    exclude_filter: ["archive-*"]
+    concurrency: 8
+    repo_timeout: 30m
+    skip_lfs: true
+    clone_mode: mirror
+    schedule: "@daily"
+    notifications:
+      - type: slack
+        url: https://hooks.slack.com/services/T0/B0/secret
+`
+
+func TestParseProfilesFile(t *testing.T) {
+	t.Run("valid file", func(t *testing.T) {
+		file, err := ParseProfilesFile([]byte(testProfilesFile))
+		require.NoError(t, err)
+		require.Len(t, file.Profiles, 2)
+
+		tools := file.Profiles[1].ToProfile()
+		assert.False(t, tools.SyncAllRepos)
+		assert.Equal(t, []string{"acme/one", "acme/two"}, tools.SelectedRepos)
+		assert.Equal(t, 8, tools.Concurrency)
+		assert.Equal(t, 30*time.Minute, tools.RepoTimeout)
+		assert.True(t, tools.SkipLFS)
+		assert.True(t, tools.Mirror())
+		assert.Equal(t, "@daily", tools.Schedule)
+		require.Len(t, tools.Notifications, 1)
+		assert.Equal(t, notify.TypeSlack, tools.Notifications[0].Type)
+		alice := file.Profiles[0].ToProfile()
+		assert.True(t, alice.SyncAllRepos)
+		assert.True(t, alice.Incremental)
+		assert.Equal(t, 6*time.Hour, alice.FullSyncEvery())
+	})
+
+	t.Run("empty file", func(t *testing.T) {
+		file, err := ParseProfilesFile(nil)
+		require.NoError(t, err)
+		assert.Empty(t, file.Profiles)
+	})
+
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{
+			name:    "unknown field",
+			data:    "profiles:\n  - name: a\n    type: user\n    source: alice\n    target: /tmp\n",
+			wantErr: "field target not found",
+		},
+		{
+			name:    "duplicate name",
+			data:    "profiles:\n  - {name: a, type: user, source: alice, target_dir: /a}\n  - {name: a, type: user, source: bob, target_dir: /b}\n",
+			wantErr: "declared more than once",
+		},
+		{
+			name:    "invalid profile",
+			data:    "profiles:\n  - {name: a, type: team, source: alice, target_dir: /a}\n",
+			wantErr: `profile "a": invalid profile type`,
+		},
+		{
+			name:    "unnamed profile",
+			data:    "profiles:\n  - {type: user, source: alice, target_dir: /a}\n",
+			wantErr: "profile #1",
+		},
+		{
+			name:    "invalid schedule",
+			data:    "profiles:\n  - {name: a, type: user, source: alice, target_dir: /a, schedule: sometimes}\n",
+			wantErr: "invalid cron schedule",
+		},
+		{
+			name:    "future version",
+			data:    "version: 2\nprofiles: []\n",
+			wantErr: "upgrade githubby",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseProfilesFile([]byte(tt.data))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestState_ApplyProfiles(t *testing.T) {
+	file, err := ParseProfilesFile([]byte(testProfilesFile))
+	require.NoError(t, err)
+
+	newState := func() (*State, *SyncProfile) {
+		s := NewState()
+		personal := NewProfile("personal", ProfileTypeUser, "alice", "/old/alice", true)
+		personal.SyncAllRepos = true
+		personal.Incremental = true
+		personal.FullSyncInterval = 6 * time.Hour
+		s.AddProfile(personal)
+		s.AddProfile(NewProfile("manual", ProfileTypeUser, "bob", "/backup/bob", false))
+		s.AddSyncRecord(NewSyncRecord(personal.ID, personal.Name))
+		return s, personal
+	}
+
+	t.Run("plan does not modify state", func(t *testing.T) {
+		s, personal := newState()
+		plan := s.PlanProfiles(file, false)
+
+		require.Len(t, plan.Added, 1)
+		assert.Equal(t, "tools", plan.Added[0].Name)
+		require.Len(t, plan.Changed, 1)
+		assert.Equal(t, []FieldChange{{Field: "target_dir", Old: "/old/alice", New: "/backup/alice"}}, plan.Changed[0].Fields)
+		assert.Equal(t, []string{"manual"}, plan.Unmanaged)
+		assert.Empty(t, plan.Removed)
+
+		assert.Len(t, s.Profiles, 2)
+		assert.Equal(t, "/old/alice", personal.TargetDir)
+	})
+
+	t.Run("apply keeps IDs and history", func(t *testing.T) {
+		s, personal := newState()
+		s.ApplyProfiles(file, false)
+
+		updated := s.GetProfileByName("personal")
+		require.NotNil(t, updated)
+		assert.Equal(t, personal.ID, updated.ID)
+		assert.Equal(t, "/backup/alice", updated.TargetDir)
+		assert.NotNil(t, s.GetLatestSyncForProfile(personal.ID))
+		assert.NotNil(t, s.GetProfileByName("tools"))
+		assert.NotNil(t, s.GetProfileByName("manual"))
+
+		// Applying again changes nothing
+		plan := s.ApplyProfiles(file, false)
+		assert.True(t, plan.Empty())
+		assert.ElementsMatch(t, []string{"personal", "tools"}, plan.Unchanged)
+	})
+
+	t.Run("sync settings are diffed", func(t *testing.T) {
+		s, _ := newState()
+		s.ApplyProfiles(file, false)
+
+		tools := s.GetProfileByName("tools")
+		tools.Concurrency = 2
+		tools.CloneMode = ""
+		tools.Notifications[0].URL = "https://hooks.slack.com/services/T0/B0/old"
+
+		plan := s.PlanProfiles(file, false)
+		require.Len(t, plan.Changed, 1)
+		fields := plan.Changed[0].Fields
+		require.Len(t, fields, 3)
+		assert.Equal(t, FieldChange{Field: "concurrency", Old: "2", New: "8"}, fields[0])
+		assert.Equal(t, FieldChange{Field: "clone_mode", Old: "full", New: "mirror"}, fields[1])
+
+		// Changed webhook URLs are detected without showing them
+		assert.Equal(t, "notifications", fields[2].Field)
+		assert.NotEqual(t, fields[2].Old, fields[2].New)
+		assert.Contains(t, fields[2].New, "slack hooks.slack.com on-failure #")
+		assert.NotContains(t, fields[2].New, "secret")
+	})
+
+	t.Run("prune removes undeclared profiles", func(t *testing.T) {
+		s, _ := newState()
+		plan := s.ApplyProfiles(file, true)
+
+		require.Len(t, plan.Removed, 1)
+		assert.Equal(t, "manual", plan.Removed[0].Name)
+		assert.Nil(t, s.GetProfileByName("manual"))
+		assert.Len(t, s.Profiles, 2)
+	})
+}
+
+func TestStorage_ApplyProfiles(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, DefaultProfilesFileName)
+	require.NoError(t, os.WriteFile(path, []byte(testProfilesFile), 0644))
+
+	file, err := LoadProfilesFile(path)
+	require.NoError(t, err)
+
+	storage := NewStorageWithPath(filepath.Join(dir, "state.yaml"))
+	require.NoError(t, storage.Load())
+
+	plan, err := storage.ApplyProfiles(file, false, true)
+	require.NoError(t, err)
+	assert.Len(t, plan.Added, 2)
+	assert.Empty(t, storage.GetProfiles(), "dry run must not save")
+
+	_, err = storage.ApplyProfiles(file, false, false)
+	require.NoError(t, err)
+
+	reloaded := NewStorageWithPath(filepath.Join(dir, "state.yaml"))
+	require.NoError(t, reloaded.Load())
+	assert.Len(t, reloaded.GetProfiles(), 2)
+}
@@ -2,11 +2,18 @@
 package state
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
+	"gopkg.in/yaml.v3"
+
+	"github.com/Didstopia/githubby/internal/notify"
 )
 
+// MaxSyncHistory is the number of sync records kept in the state
+const MaxSyncHistory = 100
+
 // State represents the persisted application state
 type State struct {
 	Version            int            `yaml:"version"`
@@ -16,6 +23,9 @@ type State struct {
 	Profiles           []*SyncProfile `yaml:"profiles"`
 	SyncHistory        []*SyncRecord  `yaml:"sync_history"`
 	RepoCache          []*CachedRepo  `yaml:"repo_cache,omitempty"`
+
+	// EventCursors track incremental syncs, by profile ID
+	EventCursors map[string]*EventCursor `yaml:"event_cursors,omitempty"`
 }
 
 // SyncProfile represents a saved sync configuration
@@ -25,13 +35,38 @@ type SyncProfile struct {
 	Type           string    `yaml:"type"`    // "user" or "org"
 	Source         string    `yaml:"source"`  // username or org name
 	TargetDir      string    `yaml:"target_dir"`
+	Host           string    `yaml:"host,omitempty"`    // GitHub Enterprise Server hostname (empty = github.com)
+	Account        string    `yaml:"account,omitempty"` // named account to sync with (empty = the host's default account)
 	IncludePrivate bool      `yaml:"include_private"`
 	SyncAllRepos   bool      `yaml:"sync_all_repos,omitempty"`  // true = fetch all from API, false = use SelectedRepos
-	SelectedRepos  []string  `yaml:"selected_repos,omitempty"` // specific repos (only used when SyncAllRepos is false)
+	SelectedRepos  []string  `yaml:"selected_repos,omitempty"` // specific repos (only used when SyncAllRepos is false; empty = all)
 	IncludeFilter  []string  `yaml:"include_filter,omitempty"` // glob patterns
 	ExcludeFilter  []string  `yaml:"exclude_filter,omitempty"` // glob patterns
 	CreatedAt      time.Time `yaml:"created_at"`
 	LastSyncAt     time.Time `yaml:"last_sync_at,omitempty"`
+
+	// Sync settings; zero values use the defaults
+	Concurrency int           `yaml:"concurrency,omitempty"`  // parallel repo syncs (0 = DefaultConcurrency)
+	RepoTimeout time.Duration `yaml:"repo_timeout,omitempty"` // per-repo clone/update limit (0 = none)
+	SkipLFS     bool          `yaml:"skip_lfs,omitempty"`     // leave LFS files as pointer files
+	CloneMode   string        `yaml:"clone_mode,omitempty"`   // "full" (default), "shallow" or "mirror"
+	Schedule    string        `yaml:"schedule,omitempty"`     // cron expression for recurring syncs
+	Overlap     string        `yaml:"overlap,omitempty"`      // "skip" (default) or "queue" a run that's due while syncing
+
+	// Transport is "https" (default) or "ssh". SSH syncs can use their own
+	// private key, such as a deploy key, and known_hosts file (empty = ssh's
+	// defaults).
+	Transport     string `yaml:"transport,omitempty"`
+	SSHKey        string `yaml:"ssh_key,omitempty"`
+	SSHKnownHosts string `yaml:"ssh_known_hosts,omitempty"`
+
+	// Incremental syncs only the repositories with new events, listing every
+	// repository once per FullSyncInterval (0 = DefaultFullSyncInterval)
+	Incremental      bool          `yaml:"incremental,omitempty"`
+	FullSyncInterval time.Duration `yaml:"full_sync_interval,omitempty"`
+
+	// Notifications are sent after syncs of this profile, in addition to the global ones
+	Notifications []notify.Target `yaml:"notifications,omitempty"`
 }
 
 // SyncRecord represents a completed sync operation
@@ -69,7 +104,7 @@ type CachedRepo struct {
 // NewState creates a new empty state
 func NewState() *State {
 	return &State{
-		Version:     1,
+		Version:     CurrentVersion,
 		Profiles:    make([]*SyncProfile, 0),
 		SyncHistory: make([]*SyncRecord, 0),
 		RepoCache:   make([]*CachedRepo, 0),
@@ -155,6 +190,7 @@ func (s *State) DeleteProfile(id string) bool {
 			}
 		}
 		s.SyncHistory = filtered
+		delete(s.EventCursors, id)
 	}
 
 	return found
@@ -163,9 +199,13 @@ func (s *State) DeleteProfile(id string) bool {
 // AddSyncRecord adds a sync record to history
 func (s *State) AddSyncRecord(record *SyncRecord) {
 	s.SyncHistory = append(s.SyncHistory, record)
-	// Keep only last 100 records
-	if len(s.SyncHistory) > 100 {
-		s.SyncHistory = s.SyncHistory[len(s.SyncHistory)-100:]
+	s.trimSyncHistory()
+}
+
+// trimSyncHistory keeps only the last MaxSyncHistory records
+func (s *State) trimSyncHistory() {
+	if len(s.SyncHistory) > MaxSyncHistory {
+		s.SyncHistory = s.SyncHistory[len(s.SyncHistory)-MaxSyncHistory:]
 	}
 }
 
@@ -270,3 +310,16 @@ func (r *SyncRecord) AddResult(fullName, status, errorMsg string) {
 		Error:    errorMsg,
 	})
 }
+
+// copy returns a deep copy of the state
+func (s *State) copy() (*State, error) {
+	data, err := yaml.Marshal(s)
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy state: %w", err)
+	}
+	var c State
+	if err := yaml.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("failed to copy state: %w", err)
+	}
+	return &c, nil
+}
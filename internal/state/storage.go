@@ -1,12 +1,19 @@
 package state
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"sync"
+	"time"
 
 	"gopkg.in/yaml.v3"
+
+	"github.com/Didstopia/githubby/internal/lock"
+	"github.com/Didstopia/githubby/pkg/util"
 )
 
 const (
@@ -14,13 +21,49 @@ const (
 	StateFileName = "state.yaml"
 	// ConfigDirName is the name of the config directory
 	ConfigDirName = ".githubby"
+
+	// MaxBackups is how many previous versions of the state file are kept
+	// next to it as state.yaml.bak.1 (newest) to state.yaml.bak.N (oldest)
+	MaxBackups = 5
+
+	// lockTimeout bounds how long a save waits for another process to
+	// finish writing the state file
+	lockTimeout = 30 * time.Second
 )
 
-// Storage handles state persistence
+// Storage handles state persistence.
+// Changes are made under a cross-process lock on the state file: the latest
+// state is re-read from disk, modified and written back, so concurrent
+// githubby processes (e.g., a scheduled container and the TUI) don't
+// overwrite each other's changes.
 type Storage struct {
 	mu       sync.RWMutex
 	filePath string
 	state    *State
+
+	// loaded identifies the version of the file the state was last read
+	// from or written to, so Reload can tell when another process changed it
+	loaded fileStamp
+}
+
+// fileStamp identifies a version of a file by its modification time and size
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// equal reports whether two stamps identify the same version of a file
+func (f fileStamp) equal(other fileStamp) bool {
+	return f.modTime.Equal(other.modTime) && f.size == other.size
+}
+
+// statFile returns the state file's current stamp (zero if it doesn't exist)
+func (s *Storage) statFile() fileStamp {
+	info, err := os.Stat(s.filePath)
+	if err != nil {
+		return fileStamp{}
+	}
+	return fileStamp{modTime: info.ModTime(), size: info.Size()}
 }
 
 // NewStorage creates a new storage instance with the default path
@@ -52,35 +95,112 @@ func (s *Storage) Load() error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	stamp := s.statFile()
+	state, err := s.readFile()
+	if err != nil {
+		return err
+	}
+	if state == nil {
+		// No state file yet, use default empty state
+		state = NewState()
+	}
+
+	s.state = state
+	s.loaded = stamp
+	return nil
+}
+
+// Reload re-reads the state file if it changed on disk since it was last
+// loaded or saved, e.g. by the TUI or "githubby profile". Returns true if
+// the state was reloaded.
+func (s *Storage) Reload() (bool, error) {
+	s.mu.RLock()
+	unchanged := s.statFile().equal(s.loaded)
+	s.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	if err := s.Load(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Path returns the path of the state file
+func (s *Storage) Path() string {
+	return s.filePath
+}
+
+// readFile reads and parses the state file. Returns nil if it doesn't exist.
+func (s *Storage) readFile() (*State, error) {
 	data, err := os.ReadFile(s.filePath)
 	if err != nil {
 		if os.IsNotExist(err) {
-			// No state file yet, use default empty state
-			s.state = NewState()
-			return nil
+			return nil, nil
 		}
-		return fmt.Errorf("failed to read state file: %w", err)
-	}
-
-	var state State
-	if err := yaml.Unmarshal(data, &state); err != nil {
-		return fmt.Errorf("failed to parse state file: %w", err)
+		return nil, fmt.Errorf("failed to read state file: %w", err)
 	}
 
-	// Migrate if needed
-	if state.Version < 1 {
-		state.Version = 1
+	state, _, err := decodeState(data)
+	if err != nil {
+		if errors.Is(err, ErrUnsupportedVersion) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to parse state file %s (previous versions are kept as %s.bak.1 to .bak.%d): %w",
+			s.filePath, filepath.Base(s.filePath), MaxBackups, err)
 	}
 
-	s.state = &state
-	return nil
+	return state, nil
 }
 
-// Save writes the state to disk atomically
+// Save writes the in-memory state to disk atomically
 func (s *Storage) Save() error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	l, err := s.lockFile()
+	if err != nil {
+		return err
+	}
+	defer l.Release()
+
+	return s.saveInternal()
+}
+
+// lockFile takes the cross-process lock on the state file
+func (s *Storage) lockFile() (*lock.Lock, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), lockTimeout)
+	defer cancel()
+
+	l, err := lock.Acquire(ctx, s.filePath+".lock", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock state file: %w", err)
+	}
+	return l, nil
+}
+
+// update applies fn to the latest state on disk and saves the result,
+// all under the cross-process lock (must be called with s.mu held)
+func (s *Storage) update(fn func(state *State) error) error {
+	l, err := s.lockFile()
+	if err != nil {
+		return err
+	}
+	defer l.Release()
+
+	// Pick up changes made by other processes since we last loaded
+	latest, err := s.readFile()
+	if err != nil {
+		return err
+	}
+	if latest != nil {
+		s.state = latest
+	}
+
+	if err := fn(s.state); err != nil {
+		return err
+	}
 	return s.saveInternal()
 }
 
@@ -92,26 +212,84 @@ func (s *Storage) saveInternal() error {
 		return fmt.Errorf("failed to create config directory: %w", err)
 	}
 
+	s.state.Version = CurrentVersion
 	data, err := yaml.Marshal(s.state)
 	if err != nil {
 		return fmt.Errorf("failed to serialize state: %w", err)
 	}
 
-	// Write atomically using temp file
-	tempFile := s.filePath + ".tmp"
-	if err := os.WriteFile(tempFile, data, 0600); err != nil {
-		return fmt.Errorf("failed to write temp state file: %w", err)
+	// Keep the state being replaced as the newest backup when its settings
+	// change, so syncs don't push out the backups from before an edit
+	previous, err := os.ReadFile(s.filePath)
+	if err == nil && !bytes.Equal(previous, data) && s.settingsChanged(previous) {
+		if err := s.rotateBackups(previous); err != nil {
+			return err
+		}
+	}
+
+	if err := util.WriteFileAtomic(s.filePath, data, 0600); err != nil {
+		return err
 	}
+	s.loaded = s.statFile()
+	return nil
+}
 
-	if err := os.Rename(tempFile, s.filePath); err != nil {
-		// Clean up temp file on failure
-		_ = os.Remove(tempFile)
-		return fmt.Errorf("failed to rename temp state file: %w", err)
+// settingsChanged reports whether previous differs from the current state in
+// its schema version or settings. Sync history, caches, event cursors and last
+// sync times change on every run and don't count.
+func (s *Storage) settingsChanged(previous []byte) bool {
+	state, version, err := decodeState(previous)
+	if err != nil {
+		return true
+	}
+	before, err := yaml.Marshal(settingsOf(state, version))
+	if err != nil {
+		return true
+	}
+	after, err := yaml.Marshal(settingsOf(s.state, CurrentVersion))
+	if err != nil {
+		return true
 	}
+	return !bytes.Equal(before, after)
+}
 
+// settingsOf returns the parts of a state that backups protect
+func settingsOf(state *State, version int) *State {
+	settings := &State{
+		Version:            version,
+		OnboardingComplete: state.OnboardingComplete,
+		DefaultTargetDir:   state.DefaultTargetDir,
+		DefaultUsername:    state.DefaultUsername,
+		Profiles:           make([]*SyncProfile, len(state.Profiles)),
+	}
+	for i, profile := range state.Profiles {
+		settings.Profiles[i] = profile.Clone()
+		settings.Profiles[i].LastSyncAt = time.Time{}
+	}
+	return settings
+}
+
+// rotateBackups shifts existing backups down by one, dropping the oldest,
+// and stores data as the newest backup
+func (s *Storage) rotateBackups(data []byte) error {
+	_ = os.Remove(s.backupPath(MaxBackups))
+	for i := MaxBackups - 1; i >= 1; i-- {
+		if err := os.Rename(s.backupPath(i), s.backupPath(i+1)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to rotate state backups: %w", err)
+		}
+	}
+
+	if err := util.WriteFileAtomic(s.backupPath(1), data, 0600); err != nil {
+		return fmt.Errorf("failed to back up state file: %w", err)
+	}
 	return nil
 }
 
+// backupPath returns the path of the nth most recent backup (1 = newest)
+func (s *Storage) backupPath(n int) string {
+	return fmt.Sprintf("%s.bak.%d", s.filePath, n)
+}
+
 // State returns the current state (read-only copy)
 func (s *Storage) State() *State {
 	s.mu.RLock()
@@ -124,8 +302,10 @@ func (s *Storage) AddProfile(profile *SyncProfile) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	s.state.AddProfile(profile)
-	return s.saveInternal()
+	return s.update(func(state *State) error {
+		state.AddProfile(profile)
+		return nil
+	})
 }
 
 // UpdateProfile updates a profile and saves
@@ -133,10 +313,12 @@ func (s *Storage) UpdateProfile(profile *SyncProfile) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if !s.state.UpdateProfile(profile) {
-		return fmt.Errorf("profile not found: %s", profile.ID)
-	}
-	return s.saveInternal()
+	return s.update(func(state *State) error {
+		if !state.UpdateProfile(profile) {
+			return fmt.Errorf("profile not found: %s", profile.ID)
+		}
+		return nil
+	})
 }
 
 // DeleteProfile deletes a profile and saves
@@ -144,10 +326,92 @@ func (s *Storage) DeleteProfile(id string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if !s.state.DeleteProfile(id) {
-		return fmt.Errorf("profile not found: %s", id)
+	return s.update(func(state *State) error {
+		if !state.DeleteProfile(id) {
+			return fmt.Errorf("profile not found: %s", id)
+		}
+		return nil
+	})
+}
+
+// RenameProfile renames a profile and saves
+func (s *Storage) RenameProfile(id, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.update(func(state *State) error {
+		if err := ValidateProfileName(name); err != nil {
+			return err
+		}
+		if err := state.CheckProfileName(name, id); err != nil {
+			return err
+		}
+		if !state.RenameProfile(id, name) {
+			return fmt.Errorf("profile not found: %s", id)
+		}
+		return nil
+	})
+}
+
+// ApplyProfiles reconciles the stored profiles with a profiles file and
+// saves. With dryRun the plan is returned without changing anything.
+func (s *Storage) ApplyProfiles(file *ProfilesFile, prune, dryRun bool) (*ProfilesPlan, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if dryRun {
+		return s.state.PlanProfiles(file, prune), nil
 	}
-	return s.saveInternal()
+
+	var plan *ProfilesPlan
+	err := s.update(func(state *State) error {
+		plan = state.ApplyProfiles(file, prune)
+		return nil
+	})
+	return plan, err
+}
+
+// ExportBundle bundles the given profiles with up to historyPerProfile of
+// their most recent sync records
+func (s *Storage) ExportBundle(profiles []*SyncProfile, historyPerProfile int) *Bundle {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.state.ExportBundle(profiles, historyPerProfile)
+}
+
+// ImportBundle merges a profile bundle into the state and saves. With dryRun
+// the import runs against a copy of the state and nothing is saved.
+func (s *Storage) ImportBundle(bundle *Bundle, opts ImportOptions, dryRun bool) (*ImportResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if dryRun {
+		preview, err := s.state.copy()
+		if err != nil {
+			return nil, err
+		}
+		return preview.ImportBundle(bundle, opts)
+	}
+
+	var result *ImportResult
+	err := s.update(func(state *State) error {
+		var err error
+		result, err = state.ImportBundle(bundle, opts)
+		return err
+	})
+	return result, err
+}
+
+// ValidateProfile checks that a profile is complete and that no other
+// profile uses its name
+func (s *Storage) ValidateProfile(profile *SyncProfile) error {
+	if err := profile.Validate(); err != nil {
+		return err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.state.CheckProfileName(profile.Name, profile.ID)
 }
 
 // GetProfile returns a profile by ID
@@ -179,8 +443,10 @@ func (s *Storage) AddSyncRecord(record *SyncRecord) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	s.state.AddSyncRecord(record)
-	return s.saveInternal()
+	return s.update(func(state *State) error {
+		state.AddSyncRecord(record)
+		return nil
+	})
 }
 
 // GetLatestSyncForProfile returns the most recent sync for a profile
@@ -202,13 +468,38 @@ func (s *Storage) UpdateProfileLastSync(profileID string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	profile := s.state.GetProfile(profileID)
-	if profile == nil {
-		return fmt.Errorf("profile not found: %s", profileID)
-	}
+	return s.update(func(state *State) error {
+		profile := state.GetProfile(profileID)
+		if profile == nil {
+			return fmt.Errorf("profile not found: %s", profileID)
+		}
 
-	profile.LastSyncAt = s.state.GetLatestSyncForProfile(profileID).CompletedAt
-	return s.saveInternal()
+		// Profiles synced without a recorded history entry use the current time
+		profile.LastSyncAt = time.Now()
+		if latest := state.GetLatestSyncForProfile(profileID); latest != nil {
+			profile.LastSyncAt = latest.CompletedAt
+		}
+		return nil
+	})
+}
+
+// GetEventCursor returns the profile's event cursor, or nil if it has none
+// that matches its current settings
+func (s *Storage) GetEventCursor(profile *SyncProfile) *EventCursor {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.state.GetEventCursor(profile)
+}
+
+// SetEventCursor records the profile's event cursor and saves; nil removes it
+func (s *Storage) SetEventCursor(profile *SyncProfile, cursor *EventCursor) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.update(func(state *State) error {
+		state.SetEventCursor(profile, cursor)
+		return nil
+	})
 }
 
 // UpdateRepoCache updates the repo cache and saves
@@ -216,8 +507,10 @@ func (s *Storage) UpdateRepoCache(repo *CachedRepo) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	s.state.UpdateRepoCache(repo)
-	return s.saveInternal()
+	return s.update(func(state *State) error {
+		state.UpdateRepoCache(repo)
+		return nil
+	})
 }
 
 // GetCachedRepo returns a cached repo by full name
@@ -266,8 +559,10 @@ func (s *Storage) SetOnboardingComplete(complete bool) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	s.state.OnboardingComplete = complete
-	return s.saveInternal()
+	return s.update(func(state *State) error {
+		state.OnboardingComplete = complete
+		return nil
+	})
 }
 
 // GetDefaultTargetDir returns the default target directory for syncing
@@ -289,7 +584,9 @@ func (s *Storage) SetDefaults(targetDir, username string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	s.state.DefaultTargetDir = targetDir
-	s.state.DefaultUsername = username
-	return s.saveInternal()
+	return s.update(func(state *State) error {
+		state.DefaultTargetDir = targetDir
+		state.DefaultUsername = username
+		return nil
+	})
 }
@@ -0,0 +1,168 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStorage_ConcurrentWriters(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.yaml")
+
+	// Two storages on the same file stand in for two githubby processes
+	first := NewStorageWithPath(path)
+	require.NoError(t, first.Load())
+	second := NewStorageWithPath(path)
+	require.NoError(t, second.Load())
+
+	require.NoError(t, first.AddProfile(NewProfile("first", "user", "alice", "/tmp/a", false)))
+	require.NoError(t, second.AddProfile(NewProfile("second", "org", "acme", "/tmp/b", false)))
+
+	// The second writer must not have dropped the first writer's profile
+	reloaded := NewStorageWithPath(path)
+	require.NoError(t, reloaded.Load())
+	assert.NotNil(t, reloaded.GetProfileByName("first"))
+	assert.NotNil(t, reloaded.GetProfileByName("second"))
+	assert.NoFileExists(t, path+".lock")
+}
+
+func TestStorage_UpdateProfileLastSync(t *testing.T) {
+	storage := NewStorageWithPath(filepath.Join(t.TempDir(), "state.yaml"))
+	require.NoError(t, storage.Load())
+
+	profile := NewProfile("test", "user", "alice", "/tmp/a", false)
+	require.NoError(t, storage.AddProfile(profile))
+
+	// No sync history yet falls back to the current time
+	require.NoError(t, storage.UpdateProfileLastSync(profile.ID))
+	assert.False(t, storage.GetProfile(profile.ID).LastSyncAt.IsZero())
+}
+
+func TestStorage_Backups(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.yaml")
+	storage := NewStorageWithPath(path)
+	require.NoError(t, storage.Load())
+
+	for i := 0; i < MaxBackups+2; i++ {
+		require.NoError(t, storage.AddProfile(NewProfile(fmt.Sprintf("profile-%d", i), "user", "alice", "/tmp/a", false)))
+	}
+
+	// Only the last MaxBackups states are kept, newest first
+	for i := 1; i <= MaxBackups; i++ {
+		assert.FileExists(t, fmt.Sprintf("%s.bak.%d", path, i))
+	}
+	assert.NoFileExists(t, fmt.Sprintf("%s.bak.%d", path, MaxBackups+1))
+	assert.NoFileExists(t, path+".tmp")
+
+	newest, err := os.ReadFile(path + ".bak.1")
+	require.NoError(t, err)
+	backup, _, err := decodeState(newest)
+	require.NoError(t, err)
+	assert.Len(t, backup.Profiles, MaxBackups+1)
+
+	// Saving unchanged state doesn't push out older backups
+	oldest, err := os.ReadFile(fmt.Sprintf("%s.bak.%d", path, MaxBackups))
+	require.NoError(t, err)
+	require.NoError(t, storage.Save())
+	unchanged, err := os.ReadFile(fmt.Sprintf("%s.bak.%d", path, MaxBackups))
+	require.NoError(t, err)
+	assert.Equal(t, oldest, unchanged)
+}
+
+func TestStorage_BackupsKeptAcrossSyncs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.yaml")
+	storage := NewStorageWithPath(path)
+	require.NoError(t, storage.Load())
+
+	profile := NewProfile("home", "user", "alice", "/tmp/a", false)
+	require.NoError(t, storage.AddProfile(profile))
+	require.NoError(t, storage.AddProfile(NewProfile("work", "org", "acme", "/tmp/b", false)))
+	backup, err := os.ReadFile(path + ".bak.1")
+	require.NoError(t, err)
+
+	// Sync records and last sync times don't rotate the backups
+	for i := 0; i < MaxBackups+1; i++ {
+		record := NewSyncRecord(profile.ID, profile.Name)
+		record.Complete()
+		require.NoError(t, storage.AddSyncRecord(record))
+		require.NoError(t, storage.UpdateProfileLastSync(profile.ID))
+	}
+
+	unchanged, err := os.ReadFile(path + ".bak.1")
+	require.NoError(t, err)
+	assert.Equal(t, backup, unchanged)
+	assert.NoFileExists(t, path+".bak.2")
+
+	// A settings change does
+	require.NoError(t, storage.DeleteProfile(profile.ID))
+	assert.FileExists(t, path+".bak.2")
+}
+
+func TestStorage_LegacyProfileWithoutRepos(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.yaml")
+	// Older versions saved "all repos" profiles without sync_all_repos
+	legacy := "profiles:\n  - id: abc\n    name: home\n    type: user\n    source: alice\n    target_dir: /tmp/a\n"
+	require.NoError(t, os.WriteFile(path, []byte(legacy), 0600))
+
+	storage := NewStorageWithPath(path)
+	require.NoError(t, storage.Load())
+	profile := storage.GetProfile("abc")
+	require.NotNil(t, profile)
+	assert.False(t, profile.SyncAllRepos)
+	assert.Empty(t, profile.SelectedRepos)
+
+	// It can still be validated and edited
+	require.NoError(t, storage.ValidateProfile(profile))
+	profile.TargetDir = "/tmp/b"
+	require.NoError(t, storage.UpdateProfile(profile))
+	assert.Equal(t, "/tmp/b", storage.GetProfile("abc").TargetDir)
+}
+
+func TestStorage_FutureVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("version: 99\nprofiles: []\n"), 0600))
+
+	storage := NewStorageWithPath(path)
+	err := storage.Load()
+	assert.True(t, errors.Is(err, ErrUnsupportedVersion))
+
+	// Changes must not overwrite a file from a newer githubby
+	err = storage.AddProfile(NewProfile("test", "user", "alice", "/tmp/a", false))
+	assert.True(t, errors.Is(err, ErrUnsupportedVersion))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "version: 99")
+}
+
+func TestStorage_Reload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.yaml")
+	daemon := NewStorageWithPath(path)
+	require.NoError(t, daemon.Load())
+	assert.Equal(t, path, daemon.Path())
+
+	reloaded, err := daemon.Reload()
+	require.NoError(t, err)
+	assert.False(t, reloaded, "nothing changed yet")
+
+	// Another process adds a profile
+	other := NewStorageWithPath(path)
+	require.NoError(t, other.Load())
+	require.NoError(t, other.AddProfile(NewProfile("work", "org", "acme", "/tmp/a", false)))
+
+	reloaded, err = daemon.Reload()
+	require.NoError(t, err)
+	assert.True(t, reloaded)
+	assert.NotNil(t, daemon.GetProfileByName("work"))
+
+	// Our own saves don't need a reload
+	require.NoError(t, daemon.AddProfile(NewProfile("home", "user", "alice", "/tmp/b", false)))
+	reloaded, err = daemon.Reload()
+	require.NoError(t, err)
+	assert.False(t, reloaded)
+}
@@ -0,0 +1,209 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	gh "github.com/google/go-github/v68/github"
+
+	gherrors "github.com/Didstopia/githubby/internal/errors"
+	"github.com/Didstopia/githubby/internal/github"
+)
+
+// changeEvents are the event types that change what's in a repository
+var changeEvents = map[string]bool{
+	"PushEvent":   true,
+	"CreateEvent": true,
+	"DeleteEvent": true,
+	// Private repositories made public are new to profiles without private repos
+	"PublicEvent": true,
+}
+
+// EventCursor records how far an owner's events have been synced
+type EventCursor struct {
+	// LastEventID is the newest event synced
+	LastEventID string
+
+	// ETag of the last events listing
+	ETag string
+
+	// LastFullSync is when every repository was last listed and synced
+	LastFullSync time.Time
+
+	// Retry lists the repositories that failed and are synced again next time
+	Retry []string
+}
+
+// Incremental describes how SyncIncremental synced
+type Incremental struct {
+	// Cursor to pass to the next incremental sync
+	Cursor EventCursor
+
+	// FullReason explains why every repository was listed and synced; empty
+	// if only the changed ones were
+	FullReason string
+
+	// Changed lists the repositories synced because of new events (or to
+	// retry a failure), for incremental syncs
+	Changed []string
+
+	// NotModified is true if nothing happened since the last sync
+	NotModified bool
+}
+
+// SyncIncremental syncs the repositories of a user or organization that had
+// push, create or delete events since cursor, plus the ones that failed last
+// time. Polling events costs a single request, which doesn't count against
+// the rate limit when nothing happened.
+//
+// Every repository is listed and synced instead when there's no cursor, the
+// last full sync is older than fullInterval, the events can't be listed, or
+// more events happened than GitHub keeps. Full syncs also catch what events
+// don't report, such as renamed and archived repositories.
+func (s *Syncer) SyncIncremental(ctx context.Context, owner string, org bool, cursor EventCursor, fullInterval time.Duration) (*Result, *Incremental, error) {
+	started := time.Now()
+	run := &Incremental{Cursor: cursor}
+
+	switch {
+	case cursor.LastEventID == "" || cursor.LastFullSync.IsZero():
+		run.FullReason = "no previous incremental sync"
+	case started.Sub(cursor.LastFullSync) >= fullInterval:
+		run.FullReason = fmt.Sprintf("last full sync was over %s ago", fullInterval)
+	}
+
+	// Private repositories' events are only listed for the organization's members
+	opts := &github.EventOptions{Org: org, Private: org && s.opts.IncludePrivate, After: cursor.LastEventID}
+	if run.FullReason == "" {
+		opts.ETag = cursor.ETag
+	} else {
+		// Only the newest event is needed to start from
+		opts.MaxPages = 1
+	}
+
+	events, err := s.ghClient.ListOwnerEvents(ctx, owner, opts)
+	switch {
+	case err != nil && ctx.Err() != nil:
+		return nil, run, ctx.Err()
+	case err != nil && gherrors.IsUnauthorized(err):
+		return nil, run, fmt.Errorf("failed to list events: %w", err)
+	case err != nil:
+		run.FullReason = fmt.Sprintf("events unavailable: %v", err)
+		events = &github.Events{}
+	case run.FullReason == "" && events.NotModified && len(cursor.Retry) == 0:
+		run.NotModified = true
+		return NewResult(), run, nil
+	case run.FullReason == "" && !events.Complete:
+		run.FullReason = "more events happened than GitHub keeps"
+	}
+
+	next := EventCursor{
+		LastEventID:  cursor.LastEventID,
+		ETag:         events.ETag,
+		LastFullSync: cursor.LastFullSync,
+	}
+	if len(events.Events) > 0 {
+		next.LastEventID = events.Events[0].GetID()
+	}
+
+	if run.FullReason != "" {
+		var result *Result
+		if org {
+			result, err = s.SyncOrgRepos(ctx, owner)
+		} else {
+			result, err = s.SyncUserRepos(ctx, owner)
+		}
+		if err != nil {
+			return result, run, err
+		}
+		if next.LastEventID != "" {
+			next.LastFullSync = started
+		}
+		next.Retry = failedRepos(result)
+		run.Cursor = next
+		return result, run, nil
+	}
+
+	run.Changed = changedRepos(events.Events, owner, cursor.Retry)
+	if len(run.Changed) == 0 {
+		run.Cursor = next
+		return NewResult(), run, nil
+	}
+
+	repos, failed, err := s.getRepos(ctx, run.Changed)
+	if err != nil {
+		return nil, run, err
+	}
+	repos, failed = s.dropUnlisted(repos, failed)
+
+	result, err := s.syncSelection(ctx, repos, failed)
+	if err != nil {
+		return result, run, err
+	}
+	next.Retry = failedRepos(result)
+	run.Cursor = next
+	return result, run, nil
+}
+
+// changedRepos returns the owner's repositories with change events, and the
+// ones to retry, sorted by name
+func changedRepos(events []*gh.Event, owner string, retry []string) []string {
+	seen := make(map[string]bool)
+	var names []string
+	add := func(name string) {
+		if !seen[strings.ToLower(name)] {
+			seen[strings.ToLower(name)] = true
+			names = append(names, name)
+		}
+	}
+
+	for _, event := range events {
+		if !changeEvents[event.GetType()] {
+			continue
+		}
+		name := event.GetRepo().GetName()
+		// User events include pushes to other owners' repositories
+		repoOwner, _, found := strings.Cut(name, "/")
+		if !found || !strings.EqualFold(repoOwner, owner) {
+			continue
+		}
+		add(name)
+	}
+	for _, name := range retry {
+		add(name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// dropUnlisted removes the repositories a full listing wouldn't include:
+// private ones unless they're included, and ones deleted since their events,
+// which are left for the next full sync to report as archived
+func (s *Syncer) dropUnlisted(repos []*gh.Repository, failed map[string]error) ([]*gh.Repository, map[string]error) {
+	listed := repos[:0]
+	for _, repo := range repos {
+		if repo.GetPrivate() && !s.opts.IncludePrivate {
+			continue
+		}
+		listed = append(listed, repo)
+	}
+	for name, err := range failed {
+		if gherrors.IsNotFound(err) {
+			delete(failed, name)
+		}
+	}
+	return listed, failed
+}
+
+// failedRepos returns the names of the repositories that failed, sorted
+func failedRepos(result *Result) []string {
+	var names []string
+	for name := range result.Failed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
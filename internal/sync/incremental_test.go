@@ -0,0 +1,187 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	gh "github.com/google/go-github/v68/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	gherrors "github.com/Didstopia/githubby/internal/errors"
+	"github.com/Didstopia/githubby/internal/git"
+	"github.com/Didstopia/githubby/internal/github"
+)
+
+// testEvent creates an event of type on repo
+func testEvent(id int, eventType, repo string) *gh.Event {
+	return &gh.Event{
+		ID:   strPtr(fmt.Sprint(id)),
+		Type: strPtr(eventType),
+		Repo: &gh.Repository{Name: strPtr(repo)},
+	}
+}
+
+func TestSyncIncremental(t *testing.T) {
+	gitInstance, err := git.New()
+	if err != nil {
+		t.Skip("git is not installed")
+	}
+
+	ctx := context.Background()
+	recent := EventCursor{LastEventID: "10", ETag: `W/"old"`, LastFullSync: time.Now().Add(-time.Hour)}
+
+	newClient := func(events *github.Events, eventsErr error) *github.MockClient {
+		client := github.NewMockClient()
+		client.ListOwnerEventsFunc = func(ctx context.Context, owner string, opts *github.EventOptions) (*github.Events, error) {
+			return events, eventsErr
+		}
+		client.GetRepositoryFunc = func(ctx context.Context, owner, repo string) (*gh.Repository, error) {
+			switch repo {
+			case "deleted":
+				return nil, fmt.Errorf("failed: %w", gherrors.ErrNotFound)
+			case "broken":
+				return nil, errors.New("server error")
+			}
+			return createMockRepo(repo, owner+"/"+repo, repo == "secret"), nil
+		}
+		client.ListOrgReposFunc = func(ctx context.Context, org string, opts *github.ListOptions) ([]*gh.Repository, error) {
+			return []*gh.Repository{createMockRepo("api", "owner/api", false), createMockRepo("web", "owner/web", false)}, nil
+		}
+		return client
+	}
+
+	t.Run("syncs only repositories with new events", func(t *testing.T) {
+		client := newClient(&github.Events{
+			ETag:     `W/"new"`,
+			Complete: true,
+			Events: []*gh.Event{
+				testEvent(14, "PushEvent", "owner/api"),
+				testEvent(13, "WatchEvent", "owner/web"),
+				testEvent(12, "CreateEvent", "owner/api"),
+				testEvent(11, "PushEvent", "someone/else"),
+			},
+		}, nil)
+		syncer := New(client, gitInstance, &Options{Target: t.TempDir(), DryRun: true})
+
+		result, run, err := syncer.SyncIncremental(ctx, "owner", true, recent, 24*time.Hour)
+		require.NoError(t, err)
+		assert.Empty(t, run.FullReason)
+		assert.Equal(t, []string{"owner/api"}, run.Changed)
+		assert.Equal(t, []string{"owner/api"}, result.Cloned)
+		assert.Equal(t, 0, client.CallCount("ListOrgRepos"))
+		assert.Equal(t, "14", run.Cursor.LastEventID)
+		assert.Equal(t, `W/"new"`, run.Cursor.ETag)
+		assert.Equal(t, recent.LastFullSync, run.Cursor.LastFullSync)
+
+		opts := client.Calls[0].Args[1].(*github.EventOptions)
+		assert.Equal(t, "10", opts.After)
+		assert.Equal(t, `W/"old"`, opts.ETag)
+	})
+
+	t.Run("nothing changed", func(t *testing.T) {
+		client := newClient(&github.Events{ETag: `W/"old"`, NotModified: true, Complete: true}, nil)
+		syncer := New(client, gitInstance, &Options{Target: t.TempDir(), DryRun: true})
+
+		result, run, err := syncer.SyncIncremental(ctx, "owner", true, recent, 24*time.Hour)
+		require.NoError(t, err)
+		assert.True(t, run.NotModified)
+		assert.Equal(t, recent, run.Cursor)
+		assert.Empty(t, result.Cloned)
+		assert.Equal(t, 1, len(client.Calls), "only the events are polled")
+	})
+
+	t.Run("failures are retried and deleted or private repositories dropped", func(t *testing.T) {
+		client := newClient(&github.Events{
+			Complete: true,
+			Events: []*gh.Event{
+				testEvent(13, "PushEvent", "owner/broken"),
+				testEvent(12, "DeleteEvent", "owner/deleted"),
+				testEvent(11, "PushEvent", "owner/secret"),
+			},
+		}, nil)
+		syncer := New(client, gitInstance, &Options{Target: t.TempDir(), DryRun: true})
+
+		cursor := recent
+		cursor.Retry = []string{"owner/web"}
+		result, run, err := syncer.SyncIncremental(ctx, "owner", true, cursor, 24*time.Hour)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"owner/broken", "owner/deleted", "owner/secret", "owner/web"}, run.Changed)
+		assert.Equal(t, []string{"owner/web"}, result.Cloned)
+		assert.Contains(t, result.Failed, "owner/broken")
+		assert.Len(t, result.Failed, 1)
+		assert.Equal(t, []string{"owner/broken"}, run.Cursor.Retry)
+	})
+
+	fullSyncs := []struct {
+		name   string
+		cursor EventCursor
+		events *github.Events
+		err    error
+		reason string
+	}{
+		{
+			name:   "first sync",
+			events: &github.Events{Events: []*gh.Event{testEvent(20, "PushEvent", "owner/api")}},
+			reason: "no previous incremental sync",
+		},
+		{
+			name:   "full sync interval passed",
+			cursor: EventCursor{LastEventID: "10", LastFullSync: time.Now().Add(-48 * time.Hour)},
+			events: &github.Events{Events: []*gh.Event{testEvent(20, "PushEvent", "owner/api")}},
+			reason: "last full sync was over 24h0m0s ago",
+		},
+		{
+			name:   "event window exceeded",
+			cursor: recent,
+			events: &github.Events{Events: []*gh.Event{testEvent(20, "PushEvent", "owner/api")}},
+			reason: "more events happened than GitHub keeps",
+		},
+		{
+			name:   "events unavailable",
+			cursor: recent,
+			err:    gherrors.ErrForbidden,
+			reason: "events unavailable",
+		},
+	}
+	for _, tt := range fullSyncs {
+		t.Run("full sync when "+tt.name, func(t *testing.T) {
+			client := newClient(tt.events, tt.err)
+			syncer := New(client, gitInstance, &Options{Target: t.TempDir(), DryRun: true})
+
+			result, run, err := syncer.SyncIncremental(ctx, "owner", true, tt.cursor, 24*time.Hour)
+			require.NoError(t, err)
+			assert.Contains(t, run.FullReason, tt.reason)
+			assert.Equal(t, 1, client.CallCount("ListOrgRepos"))
+			assert.Len(t, result.Cloned, 2)
+			if tt.err == nil {
+				assert.Equal(t, "20", run.Cursor.LastEventID)
+				assert.WithinDuration(t, time.Now(), run.Cursor.LastFullSync, time.Minute)
+			}
+		})
+	}
+
+	t.Run("rejected tokens fail the sync", func(t *testing.T) {
+		client := newClient(nil, gherrors.ErrUnauthorized)
+		syncer := New(client, gitInstance, &Options{Target: t.TempDir(), DryRun: true})
+
+		_, run, err := syncer.SyncIncremental(ctx, "owner", true, recent, 24*time.Hour)
+		assert.True(t, gherrors.IsUnauthorized(err))
+		assert.Equal(t, recent, run.Cursor)
+	})
+
+	t.Run("lists private organization events with private repos", func(t *testing.T) {
+		for _, includePrivate := range []bool{false, true} {
+			client := newClient(&github.Events{NotModified: true, Complete: true}, nil)
+			syncer := New(client, gitInstance, &Options{Target: t.TempDir(), DryRun: true, IncludePrivate: includePrivate})
+
+			_, _, err := syncer.SyncIncremental(ctx, "owner", true, recent, 24*time.Hour)
+			require.NoError(t, err)
+			opts := client.Calls[0].Args[1].(*github.EventOptions)
+			assert.Equal(t, includePrivate, opts.Private)
+		}
+	})
+}
@@ -0,0 +1,17 @@
+//go:build windows
+
+package sync
+
+import "os"
+
+// processAlive reports whether a process with the given PID exists.
+// On Windows, os.FindProcess opens a handle to the process and fails if
+// no such process is running.
+func processAlive(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	_ = p.Release()
+	return true
+}
@@ -0,0 +1,234 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Didstopia/githubby/internal/git"
+	"github.com/Didstopia/githubby/internal/lock"
+	"github.com/Didstopia/githubby/pkg/util"
+)
+
+// StagingDirName is the directory inside a sync target where new clones are
+// staged. A clone is only renamed into its final location once the clone and
+// any LFS pull have finished, so an interrupted sync never leaves a
+// half-finished repository where a complete one is expected.
+const StagingDirName = ".githubby-staging"
+
+// staleGitLockAge is how old a git lock file must be before recovery treats it
+// as left behind by a crashed git process rather than one still running.
+const staleGitLockAge = 10 * time.Minute
+
+// gitLockFiles are the lock files git creates inside .git that block further
+// fetches when a git process dies without cleaning them up.
+var gitLockFiles = []string{
+	"index.lock",
+	"HEAD.lock",
+	"config.lock",
+	"packed-refs.lock",
+	"shallow.lock",
+}
+
+// RecoveryReport describes what Recover cleaned up in a target directory
+type RecoveryReport struct {
+	// RemovedStaging lists orphaned staging directories that were removed
+	RemovedStaging []string
+
+	// RemovedBroken lists half-finished clones (owner/repo) that were removed
+	// so they are cloned again
+	RemovedBroken []string
+
+	// Repaired lists repositories (owner/repo) whose stale git lock files were removed
+	Repaired []string
+}
+
+// Empty returns true if recovery did not change anything
+func (r *RecoveryReport) Empty() bool {
+	return len(r.RemovedStaging) == 0 && len(r.RemovedBroken) == 0 && len(r.Repaired) == 0
+}
+
+// stagingPath returns the staging location for a clone of owner/name.
+// The current PID is part of the name so Recover can tell clones that are
+// still in progress apart from ones orphaned by a crashed process.
+func stagingPath(target, owner, name string) string {
+	return filepath.Join(target, StagingDirName, fmt.Sprintf("%d-%s-%s", os.Getpid(), owner, name))
+}
+
+// stagingOwnerPID extracts the PID prefix from a staging directory name
+func stagingOwnerPID(name string) (int, bool) {
+	prefix, _, found := strings.Cut(name, "-")
+	if !found {
+		return 0, false
+	}
+	pid, err := strconv.Atoi(prefix)
+	if err != nil || pid <= 0 {
+		return 0, false
+	}
+	return pid, true
+}
+
+// Recover sweeps a target directory for leftovers of interrupted syncs.
+// Staging directories whose owning process is gone are removed, clones that
+// never finished are removed so the next sync clones them again, and stale
+// git lock files are deleted from otherwise healthy repositories. Only
+// clones githubby made are removed: repositories without commits or an
+// upstream that the user created, e.g. with git init, are left alone.
+func Recover(ctx context.Context, g *git.Git, target string) (*RecoveryReport, error) {
+	report := &RecoveryReport{
+		RemovedStaging: make([]string, 0),
+		RemovedBroken:  make([]string, 0),
+		Repaired:       make([]string, 0),
+	}
+
+	// Nothing to recover if the target doesn't exist yet
+	if _, err := os.Stat(target); os.IsNotExist(err) {
+		return report, nil
+	}
+
+	if err := recoverStaging(target, report); err != nil {
+		return report, err
+	}
+
+	err := filepath.WalkDir(target, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil // Skip errors
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if !d.IsDir() {
+			return nil
+		}
+		if d.Name() == StagingDirName && filepath.Dir(path) == target {
+			return fs.SkipDir
+		}
+		if d.Name() != ".git" {
+			// Mirror clones are bare repositories without a .git directory
+			if path != target && g.IsBareRepo(path) {
+				if relPath, relErr := filepath.Rel(target, path); relErr == nil && removeStaleGitLocks(path) {
+					report.Repaired = append(report.Repaired, filepath.ToSlash(relPath))
+				}
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		repoPath := filepath.Dir(path)
+		relPath, relErr := filepath.Rel(target, repoPath)
+		if relErr != nil {
+			return fs.SkipDir
+		}
+		repoName := filepath.ToSlash(relPath)
+
+		if !g.IsCompleteRepo(ctx, repoPath) {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if !isSyncedClone(ctx, g, repoPath, repoName) {
+				return fs.SkipDir
+			}
+			if rmErr := os.RemoveAll(repoPath); rmErr == nil {
+				report.RemovedBroken = append(report.RemovedBroken, repoName)
+			}
+			return fs.SkipDir
+		}
+
+		if removeStaleGitLocks(path) {
+			report.Repaired = append(report.Repaired, repoName)
+		}
+
+		return fs.SkipDir // Don't recurse into .git
+	})
+	if err != nil {
+		return report, err
+	}
+
+	return report, nil
+}
+
+// isSyncedClone reports whether the repository at repoPath (repoName
+// relative to the target) is a clone githubby made: its origin is the
+// repository of the same owner/repo on the GitHub host
+func isSyncedClone(ctx context.Context, g *git.Git, repoPath, repoName string) bool {
+	url, err := g.GetRemoteURL(ctx, repoPath)
+	if err != nil {
+		return false
+	}
+	owner, name, err := util.ParseRepositoryURLForHost(url, g.Host)
+	return err == nil && strings.EqualFold(owner+"/"+name, repoName)
+}
+
+// recoverStaging removes staging directories left behind by processes that
+// are no longer running
+func recoverStaging(target string, report *RecoveryReport) error {
+	stagingRoot := filepath.Join(target, StagingDirName)
+
+	entries, err := os.ReadDir(stagingRoot)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read staging directory: %w", err)
+	}
+
+	for _, entry := range entries {
+		// Leave clones owned by this process or another live githubby alone
+		if pid, ok := stagingOwnerPID(entry.Name()); ok && (pid == os.Getpid() || processAlive(pid)) {
+			continue
+		}
+
+		if err := os.RemoveAll(filepath.Join(stagingRoot, entry.Name())); err != nil {
+			return fmt.Errorf("failed to remove orphaned staging directory %s: %w", entry.Name(), err)
+		}
+		report.RemovedStaging = append(report.RemovedStaging, entry.Name())
+	}
+
+	// Best-effort: only succeeds once the staging root is empty
+	_ = os.Remove(stagingRoot)
+
+	return nil
+}
+
+// removeStaleGitLocks deletes lock files in gitDir that are older than
+// staleGitLockAge. Returns true if any lock file was removed.
+func removeStaleGitLocks(gitDir string) bool {
+	removed := false
+	for _, name := range gitLockFiles {
+		lockPath := filepath.Join(gitDir, name)
+		info, err := os.Stat(lockPath)
+		if err != nil || time.Since(info.ModTime()) < staleGitLockAge {
+			continue
+		}
+		if err := os.Remove(lockPath); err == nil {
+			removed = true
+		}
+	}
+	return removed
+}
+
+// TargetLockFileName is the lock file held in a target directory while it
+// is being synced, so two githubby processes never sync the same target at once
+const TargetLockFileName = ".githubby.lock"
+
+// LockTarget takes the cross-process lock for a target directory.
+// With lock.PolicyWait it waits until the lock is free or ctx is done,
+// calling onWait (optional) once with the current holder; otherwise it
+// returns a *lock.LockedError immediately if the target is locked.
+func LockTarget(ctx context.Context, target string, policy lock.Policy, onWait func(holder *lock.Holder)) (*lock.Lock, error) {
+	if err := os.MkdirAll(target, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create target directory: %w", err)
+	}
+
+	path := filepath.Join(target, TargetLockFileName)
+	if policy == lock.PolicyWait {
+		return lock.Acquire(ctx, path, onWait)
+	}
+	return lock.TryAcquire(path)
+}
@@ -0,0 +1,238 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	gh "github.com/google/go-github/v68/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Didstopia/githubby/internal/git"
+	"github.com/Didstopia/githubby/internal/github"
+	"github.com/Didstopia/githubby/internal/lock"
+)
+
+// runGit runs a git command for test setup
+func runGit(t *testing.T, g *git.Git, args ...string) {
+	t.Helper()
+	cmd := exec.Command(g.GitPath, args...)
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=Test", "GIT_AUTHOR_EMAIL=test@test.com",
+		"GIT_COMMITTER_NAME=Test", "GIT_COMMITTER_EMAIL=test@test.com",
+	)
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, "git %v: %s", args, out)
+}
+
+// createCommittedRepo creates a non-bare repo with a single commit
+func createCommittedRepo(t *testing.T, g *git.Git, dir string) {
+	t.Helper()
+	runGit(t, g, "init", "-q", dir)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "README.md"), []byte("test"), 0644))
+	runGit(t, g, "-C", dir, "add", ".")
+	runGit(t, g, "-C", dir, "commit", "-q", "-m", "initial")
+}
+
+func TestStagingOwnerPID(t *testing.T) {
+	tests := []struct {
+		name    string
+		entry   string
+		wantPID int
+		wantOK  bool
+	}{
+		{name: "valid entry", entry: "1234-owner-repo", wantPID: 1234, wantOK: true},
+		{name: "no separator", entry: "1234", wantOK: false},
+		{name: "non-numeric prefix", entry: "abc-owner-repo", wantOK: false},
+		{name: "zero pid", entry: "0-owner-repo", wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pid, ok := stagingOwnerPID(tt.entry)
+			assert.Equal(t, tt.wantOK, ok)
+			if tt.wantOK {
+				assert.Equal(t, tt.wantPID, pid)
+			}
+		})
+	}
+}
+
+func TestRecover(t *testing.T) {
+	gitInstance, err := git.New()
+	if err != nil {
+		t.Skip("git is not installed")
+	}
+
+	t.Run("missing target is a no-op", func(t *testing.T) {
+		report, err := Recover(context.Background(), gitInstance, filepath.Join(t.TempDir(), "missing"))
+		require.NoError(t, err)
+		assert.True(t, report.Empty())
+	})
+
+	t.Run("removes orphaned staging dirs and keeps live ones", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		stagingRoot := filepath.Join(tmpDir, StagingDirName)
+
+		// PIDs above the Linux maximum are never alive
+		orphaned := filepath.Join(stagingRoot, "999999999-owner-repo")
+		ours := filepath.Join(stagingRoot, fmt.Sprintf("%d-owner-other", os.Getpid()))
+		require.NoError(t, os.MkdirAll(filepath.Join(orphaned, ".git"), 0755))
+		require.NoError(t, os.MkdirAll(filepath.Join(ours, ".git"), 0755))
+
+		report, err := Recover(context.Background(), gitInstance, tmpDir)
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"999999999-owner-repo"}, report.RemovedStaging)
+		assert.NoDirExists(t, orphaned)
+		assert.DirExists(t, ours)
+		// Staging clones are never treated as broken repos
+		assert.Empty(t, report.RemovedBroken)
+	})
+
+	t.Run("removes empty staging root", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		stagingRoot := filepath.Join(tmpDir, StagingDirName)
+		require.NoError(t, os.MkdirAll(filepath.Join(stagingRoot, "999999999-owner-repo"), 0755))
+
+		_, err := Recover(context.Background(), gitInstance, tmpDir)
+		require.NoError(t, err)
+		assert.NoDirExists(t, stagingRoot)
+	})
+
+	t.Run("removes half-finished clone", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		repoPath := filepath.Join(tmpDir, "owner", "broken")
+		runGit(t, gitInstance, "init", "-q", repoPath)
+		runGit(t, gitInstance, "-C", repoPath, "remote", "add", "origin", "https://github.com/owner/broken.git")
+
+		report, err := Recover(context.Background(), gitInstance, tmpDir)
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"owner/broken"}, report.RemovedBroken)
+		assert.NoDirExists(t, repoPath)
+	})
+
+	t.Run("keeps local repos without commits", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		initialized := filepath.Join(tmpDir, "owner", "scratch")
+		runGit(t, gitInstance, "init", "-q", initialized)
+		otherHost := filepath.Join(tmpDir, "owner", "mirror")
+		runGit(t, gitInstance, "init", "-q", otherHost)
+		runGit(t, gitInstance, "-C", otherHost, "remote", "add", "origin", "https://gitlab.com/owner/mirror.git")
+		otherPath := filepath.Join(tmpDir, "backup", "broken")
+		runGit(t, gitInstance, "init", "-q", otherPath)
+		runGit(t, gitInstance, "-C", otherPath, "remote", "add", "origin", "https://github.com/owner/broken.git")
+
+		report, err := Recover(context.Background(), gitInstance, tmpDir)
+		require.NoError(t, err)
+
+		assert.Empty(t, report.RemovedBroken)
+		assert.DirExists(t, initialized)
+		assert.DirExists(t, otherHost)
+		assert.DirExists(t, otherPath, "clones are only removed at their owner/repo path")
+	})
+
+	t.Run("keeps complete repo and removes stale locks", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		repoPath := filepath.Join(tmpDir, "owner", "healthy")
+		createCommittedRepo(t, gitInstance, repoPath)
+
+		staleLock := filepath.Join(repoPath, ".git", "index.lock")
+		require.NoError(t, os.WriteFile(staleLock, nil, 0644))
+		old := time.Now().Add(-time.Hour)
+		require.NoError(t, os.Chtimes(staleLock, old, old))
+
+		freshLock := filepath.Join(repoPath, ".git", "shallow.lock")
+		require.NoError(t, os.WriteFile(freshLock, nil, 0644))
+
+		report, err := Recover(context.Background(), gitInstance, tmpDir)
+		require.NoError(t, err)
+
+		assert.Empty(t, report.RemovedBroken)
+		assert.Equal(t, []string{"owner/healthy"}, report.Repaired)
+		assert.DirExists(t, repoPath)
+		assert.NoFileExists(t, staleLock)
+		assert.FileExists(t, freshLock)
+	})
+}
+
+func TestCloneRepo_Staging(t *testing.T) {
+	gitInstance, err := git.NewQuiet()
+	if err != nil {
+		t.Skip("git is not installed")
+	}
+
+	// A local bare repo stands in for the remote
+	srcDir := filepath.Join(t.TempDir(), "src")
+	createCommittedRepo(t, gitInstance, srcDir)
+	bareDir := filepath.Join(t.TempDir(), "remote.git")
+	runGit(t, gitInstance, "clone", "-q", "--bare", srcDir, bareDir)
+
+	newRepo := func(cloneURL string) *gh.Repository {
+		repo := createMockRepo("repo", "owner/repo", false)
+		repo.CloneURL = gh.Ptr(cloneURL)
+		return repo
+	}
+
+	t.Run("successful clone is moved into place", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		syncer := New(github.NewMockClient(), gitInstance, &Options{Target: tmpDir})
+
+		localPath := filepath.Join(tmpDir, "owner", "repo")
+		require.NoError(t, syncer.cloneRepo(context.Background(), newRepo(bareDir), localPath))
+
+		assert.True(t, gitInstance.IsCompleteRepo(context.Background(), localPath))
+		assert.FileExists(t, filepath.Join(localPath, "README.md"))
+		assert.NoDirExists(t, filepath.Join(tmpDir, StagingDirName))
+		assert.Equal(t, gitInstance.ObjectStoreSize(localPath), syncer.fetchedBytes())
+		assert.Positive(t, syncer.fetchedBytes())
+	})
+
+	t.Run("replaces empty destination directory", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		syncer := New(github.NewMockClient(), gitInstance, &Options{Target: tmpDir})
+
+		localPath := filepath.Join(tmpDir, "owner", "repo")
+		require.NoError(t, os.MkdirAll(localPath, 0755))
+		require.NoError(t, syncer.cloneRepo(context.Background(), newRepo(bareDir), localPath))
+
+		assert.FileExists(t, filepath.Join(localPath, "README.md"))
+	})
+
+	t.Run("failed clone leaves nothing behind", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		syncer := New(github.NewMockClient(), gitInstance, &Options{Target: tmpDir})
+
+		localPath := filepath.Join(tmpDir, "owner", "repo")
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err := syncer.cloneRepo(ctx, newRepo(filepath.Join(t.TempDir(), "missing.git")), localPath)
+		assert.Error(t, err)
+		assert.NoDirExists(t, localPath)
+		assert.NoDirExists(t, filepath.Join(tmpDir, StagingDirName))
+	})
+}
+
+func TestLockTarget(t *testing.T) {
+	tmpDir := filepath.Join(t.TempDir(), "target")
+
+	held, err := LockTarget(context.Background(), tmpDir, lock.PolicyFail, nil)
+	require.NoError(t, err)
+	assert.FileExists(t, filepath.Join(tmpDir, TargetLockFileName))
+
+	_, err = LockTarget(context.Background(), tmpDir, lock.PolicySkip, nil)
+	assert.ErrorIs(t, err, lock.ErrLocked)
+
+	require.NoError(t, held.Release())
+
+	l, err := LockTarget(context.Background(), tmpDir, lock.PolicyFail, nil)
+	require.NoError(t, err)
+	require.NoError(t, l.Release())
+}
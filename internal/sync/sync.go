@@ -3,17 +3,20 @@ package sync
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	gh "github.com/google/go-github/v68/github"
 
 	"github.com/Didstopia/githubby/internal/git"
 	"github.com/Didstopia/githubby/internal/github"
+	"github.com/Didstopia/githubby/pkg/util"
 )
 
 // ProgressStatus represents the status of a sync operation
@@ -72,6 +75,29 @@ type Options struct {
 	// target directory. This is useful when syncing single repos (e.g., TUI worker)
 	// where archive detection should be done once at the end, not per-repo.
 	SkipArchiveDetection bool
+
+	// SkipRecovery skips the Recover() sweep of the target directory before
+	// syncing. Callers that sync one repo at a time (e.g., TUI worker) should
+	// run Recover once per target themselves and set this.
+	SkipRecovery bool
+
+	// RepoTimeout limits how long cloning or updating a single repository
+	// may take (0 = no limit)
+	RepoTimeout time.Duration
+
+	// SkipLFS skips downloading Git LFS objects, leaving LFS files as pointer files
+	SkipLFS bool
+
+	// Mirror clones new repositories as bare mirrors of all refs
+	Mirror bool
+
+	// Depth makes new clones shallow, keeping the given number of commits per
+	// branch (0 = full history). Shallow clones stay at this depth when updated.
+	Depth int
+
+	// SSH clones and fetches over SSH instead of HTTPS. Existing clones are
+	// switched over when they're next updated.
+	SSH bool
 }
 
 // Result represents the result of a sync operation
@@ -93,6 +119,10 @@ type Result struct {
 
 	// Archived repositories (exist locally but not on remote - preserved for backup)
 	Archived []string
+
+	// BytesFetched is how much the clones and fetches added to the local
+	// object stores, an estimate of the data downloaded
+	BytesFetched int64
 }
 
 // NewResult creates a new sync result
@@ -107,12 +137,18 @@ func NewResult() *Result {
 	}
 }
 
+// MaxConcurrency is the most repositories a Syncer syncs in parallel
+const MaxConcurrency = 8
+
 // Syncer handles repository synchronization
 type Syncer struct {
 	ghClient github.Client
 	git      *git.Git
 	lfs      *git.LFS
 	opts     *Options
+
+	// fetched counts the bytes added to object stores by all syncs
+	fetched *atomic.Int64
 }
 
 // New creates a new Syncer
@@ -122,6 +158,7 @@ func New(ghClient github.Client, g *git.Git, opts *Options) *Syncer {
 		git:      g,
 		lfs:      git.NewLFS(g),
 		opts:     opts,
+		fetched:  new(atomic.Int64),
 	}
 }
 
@@ -163,12 +200,107 @@ func (s *Syncer) SyncRepo(ctx context.Context, owner, repo string) (*Result, err
 	return s.syncRepos(ctx, []*gh.Repository{repository})
 }
 
+// SyncRepos syncs the named repositories (owner/repo). Repositories that
+// can't be fetched are reported as failed. Archive detection is skipped,
+// since the target may also hold repositories outside the selection.
+func (s *Syncer) SyncRepos(ctx context.Context, fullNames []string) (*Result, error) {
+	repos, failed, err := s.getRepos(ctx, fullNames)
+	if err != nil {
+		return nil, err
+	}
+	return s.syncSelection(ctx, repos, failed)
+}
+
+// syncSelection syncs some of a target's repositories, reporting the ones
+// that couldn't be fetched as failed
+func (s *Syncer) syncSelection(ctx context.Context, repos []*gh.Repository, failed map[string]error) (*Result, error) {
+	opts := *s.opts
+	opts.SkipArchiveDetection = true
+	selection := *s
+	selection.opts = &opts
+
+	result, err := selection.syncRepos(ctx, repos)
+	if result != nil {
+		for fullName, failErr := range failed {
+			result.Failed[fullName] = failErr
+		}
+	}
+	return result, err
+}
+
+// getRepos fetches the named repositories (owner/repo), returning the errors
+// of the ones that couldn't be fetched separately. Fails as a whole if the
+// token is rejected or ctx is cancelled.
+func (s *Syncer) getRepos(ctx context.Context, fullNames []string) ([]*gh.Repository, map[string]error, error) {
+	repos, failed, err := s.ghClient.GetRepositories(ctx, fullNames)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, nil, ctx.Err()
+		}
+		return nil, nil, err
+	}
+
+	for fullName, failErr := range failed {
+		failed[fullName] = fmt.Errorf("failed to get repository: %w", failErr)
+	}
+	return repos, failed, nil
+}
+
 // SyncRepoWithData syncs a single repository using pre-fetched data.
 // This avoids redundant API calls when the repository data is already available.
 func (s *Syncer) SyncRepoWithData(ctx context.Context, repo *gh.Repository) (*Result, error) {
 	return s.syncRepos(ctx, []*gh.Repository{repo})
 }
 
+// MoveLocal moves the local copy of a renamed or transferred repository from
+// one full name (owner/repo) to another, so it's updated instead of cloned
+// again. Returns false if there's no local copy to move or the new location
+// is already taken. Dry runs only report whether the copy would be moved.
+func (s *Syncer) MoveLocal(from, to string) (bool, error) {
+	fromPath, err := s.localPathOf(from)
+	if err != nil {
+		return false, err
+	}
+	toPath, err := s.localPathOf(to)
+	if err != nil {
+		return false, err
+	}
+
+	if !s.isLocalRepo(fromPath) {
+		return false, nil
+	}
+	if _, err := os.Stat(toPath); err == nil {
+		return false, nil
+	}
+	if s.opts.DryRun {
+		return true, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(toPath), 0755); err != nil {
+		return false, fmt.Errorf("failed to create parent directory: %w", err)
+	}
+	if err := os.Rename(fromPath, toPath); err != nil {
+		return false, fmt.Errorf("failed to move %s to %s: %w", from, to, err)
+	}
+	// Remove the old owner's directory if this was its last repository
+	_ = os.Remove(filepath.Dir(fromPath))
+	return true, nil
+}
+
+// localPathOf returns where a repository (owner/repo) is synced to
+func (s *Syncer) localPathOf(fullName string) (string, error) {
+	owner, name, found := strings.Cut(fullName, "/")
+	if !found || !validPathName(owner) || !validPathName(name) {
+		return "", fmt.Errorf("invalid repository name %q (expected owner/repo)", fullName)
+	}
+	return filepath.Join(s.opts.Target, owner, name), nil
+}
+
+// validPathName returns true if name is usable as a single path element
+func validPathName(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.ContainsAny(name, `/\`)
+}
+
 // syncResult holds the result of syncing a single repo
 type syncResult struct {
 	repoName string
@@ -179,6 +311,9 @@ type syncResult struct {
 func (s *Syncer) syncRepos(ctx context.Context, repos []*gh.Repository) (*Result, error) {
 	result := NewResult()
 
+	fetchedBefore := s.fetchedBytes()
+	defer func() { result.BytesFetched = s.fetchedBytes() - fetchedBefore }()
+
 	// Ensure target directory exists
 	if !s.opts.DryRun {
 		if err := os.MkdirAll(s.opts.Target, 0755); err != nil {
@@ -186,13 +321,18 @@ func (s *Syncer) syncRepos(ctx context.Context, repos []*gh.Repository) (*Result
 		}
 	}
 
-	// Default concurrency to 1 (sequential), max 8 to avoid rate limits
+	// Clean up leftovers from interrupted syncs before deciding clone vs. update
+	if !s.opts.DryRun && !s.opts.SkipRecovery {
+		s.recover(ctx)
+	}
+
+	// Default concurrency to 1 (sequential), capped to avoid rate limits
 	concurrency := s.opts.Concurrency
 	if concurrency <= 0 {
 		concurrency = 1
 	}
-	if concurrency > 8 {
-		concurrency = 8
+	if concurrency > MaxConcurrency {
+		concurrency = MaxConcurrency
 	}
 
 	// For sequential processing, use the simple loop
@@ -302,7 +442,7 @@ func (s *Syncer) syncWorker(ctx context.Context, jobs <-chan *gh.Repository, res
 		}
 
 		if s.opts.DryRun {
-			if s.git.IsGitRepo(localPath) {
+			if s.isLocalRepo(localPath) {
 				if s.opts.Verbose {
 					fmt.Printf("[DRY RUN] Would update: %s\n", repoName)
 				}
@@ -322,9 +462,13 @@ func (s *Syncer) syncWorker(ctx context.Context, jobs <-chan *gh.Repository, res
 		s.reportProgress(repoName, ProgressInProgress, "")
 
 		// Sync the repository
-		if s.git.IsGitRepo(localPath) {
+		if s.isLocalRepo(localPath) {
 			// Pull existing repo
-			status, err := s.pullRepo(ctx, repo, localPath)
+			var status ProgressStatus
+			err := s.withRepoTimeout(ctx, func(ctx context.Context) (err error) {
+				status, err = s.pullRepo(ctx, repo, localPath)
+				return err
+			})
 			if err != nil {
 				s.reportProgress(repoName, ProgressFailed, err.Error())
 				if s.opts.Verbose {
@@ -344,7 +488,10 @@ func (s *Syncer) syncWorker(ctx context.Context, jobs <-chan *gh.Repository, res
 			}
 		} else {
 			// Clone new repo
-			if err := s.cloneRepo(ctx, repo, localPath); err != nil {
+			err := s.withRepoTimeout(ctx, func(ctx context.Context) error {
+				return s.cloneRepo(ctx, repo, localPath)
+			})
+			if err != nil {
 				s.reportProgress(repoName, ProgressFailed, err.Error())
 				if s.opts.Verbose {
 					fmt.Printf("Failed to clone %s: %v\n", repoName, err)
@@ -382,7 +529,7 @@ func (s *Syncer) syncSingleRepo(ctx context.Context, repo *gh.Repository, result
 	}
 
 	if s.opts.DryRun {
-		if s.git.IsGitRepo(localPath) {
+		if s.isLocalRepo(localPath) {
 			if s.opts.Verbose {
 				fmt.Printf("[DRY RUN] Would update: %s\n", repoName)
 			}
@@ -402,9 +549,13 @@ func (s *Syncer) syncSingleRepo(ctx context.Context, repo *gh.Repository, result
 	s.reportProgress(repoName, ProgressInProgress, "")
 
 	// Sync the repository
-	if s.git.IsGitRepo(localPath) {
+	if s.isLocalRepo(localPath) {
 		// Pull existing repo
-		status, err := s.pullRepo(ctx, repo, localPath)
+		var status ProgressStatus
+		err := s.withRepoTimeout(ctx, func(ctx context.Context) (err error) {
+			status, err = s.pullRepo(ctx, repo, localPath)
+			return err
+		})
 		if err != nil {
 			result.Failed[repoName] = err
 			s.reportProgress(repoName, ProgressFailed, err.Error())
@@ -428,7 +579,10 @@ func (s *Syncer) syncSingleRepo(ctx context.Context, repo *gh.Repository, result
 		}
 	} else {
 		// Clone new repo
-		if err := s.cloneRepo(ctx, repo, localPath); err != nil {
+		err := s.withRepoTimeout(ctx, func(ctx context.Context) error {
+			return s.cloneRepo(ctx, repo, localPath)
+		})
+		if err != nil {
 			result.Failed[repoName] = err
 			s.reportProgress(repoName, ProgressFailed, err.Error())
 			if s.opts.Verbose {
@@ -451,8 +605,7 @@ func (s *Syncer) detectArchived(remoteRepos []*gh.Repository) []string {
 	remoteSet := make(map[string]bool)
 	for _, repo := range remoteRepos {
 		// Use owner/repo path format
-		path := filepath.Join(repo.GetOwner().GetLogin(), repo.GetName())
-		remoteSet[path] = true
+		remoteSet[repo.GetOwner().GetLogin()+"/"+repo.GetName()] = true
 	}
 
 	// Scan local target directory for git repos
@@ -468,6 +621,11 @@ func (s *Syncer) detectArchived(remoteRepos []*gh.Repository) []string {
 			return nil // Skip errors
 		}
 
+		// Clones in the staging area aren't in place yet
+		if d.Name() == StagingDirName && d.IsDir() && filepath.Dir(path) == s.opts.Target {
+			return fs.SkipDir
+		}
+
 		// Look for .git directories
 		if d.Name() == ".git" && d.IsDir() {
 			repoPath := filepath.Dir(path)
@@ -479,19 +637,101 @@ func (s *Syncer) detectArchived(remoteRepos []*gh.Repository) []string {
 			// Check if this repo exists on remote
 			// Normalize path separators for cross-platform
 			normalizedPath := filepath.ToSlash(relPath)
-			if !remoteSet[normalizedPath] {
+			if !remoteSet[normalizedPath] && !s.clonesRemote(repoPath, remoteSet) {
 				archived = append(archived, normalizedPath)
 			}
 
 			return fs.SkipDir // Don't recurse into .git
 		}
 
+		// Mirror clones are bare repositories without a .git directory
+		if d.IsDir() && path != s.opts.Target && s.git.IsBareRepo(path) {
+			relPath, err := filepath.Rel(s.opts.Target, path)
+			if err == nil && !remoteSet[filepath.ToSlash(relPath)] && !s.clonesRemote(path, remoteSet) {
+				archived = append(archived, filepath.ToSlash(relPath))
+			}
+			return fs.SkipDir
+		}
+
 		return nil
 	})
 
 	return archived
 }
 
+// clonesRemote reports whether the origin remote of the clone at repoPath,
+// over HTTPS or SSH, is one of the remote repositories (owner/repo), such as
+// a clone kept under another directory
+func (s *Syncer) clonesRemote(repoPath string, remoteSet map[string]bool) bool {
+	url, err := s.git.GetRemoteURL(context.Background(), repoPath)
+	if err != nil {
+		return false
+	}
+	owner, name, err := util.ParseRepositoryURLForHost(url, s.git.Host)
+	return err == nil && remoteSet[owner+"/"+name]
+}
+
+// recover runs Recover on the target directory and reports what it cleaned up
+func (s *Syncer) recover(ctx context.Context) {
+	report, err := Recover(ctx, s.git, s.opts.Target)
+	if err != nil {
+		if s.opts.Verbose {
+			fmt.Printf("Warning: Failed to recover target directory: %v\n", err)
+		}
+		return
+	}
+	if !s.opts.Verbose || report.Empty() {
+		return
+	}
+	for _, name := range report.RemovedStaging {
+		fmt.Printf("Removed orphaned staging directory: %s\n", name)
+	}
+	for _, repoName := range report.RemovedBroken {
+		fmt.Printf("Removed incomplete clone (will be cloned again): %s\n", repoName)
+	}
+	for _, repoName := range report.Repaired {
+		fmt.Printf("Removed stale git lock files: %s\n", repoName)
+	}
+}
+
+// isLocalRepo reports whether localPath holds a clone, either a working copy
+// or a bare mirror
+func (s *Syncer) isLocalRepo(localPath string) bool {
+	return s.git.IsGitRepo(localPath) || s.git.IsBareRepo(localPath)
+}
+
+// withRepoTimeout runs fn with ctx limited to RepoTimeout, if set
+func (s *Syncer) withRepoTimeout(ctx context.Context, fn func(ctx context.Context) error) error {
+	if s.opts.RepoTimeout <= 0 {
+		return fn(ctx)
+	}
+
+	repoCtx, cancel := context.WithTimeout(ctx, s.opts.RepoTimeout)
+	defer cancel()
+
+	err := fn(repoCtx)
+	if err != nil && ctx.Err() == nil && errors.Is(repoCtx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("timed out after %s: %w", s.opts.RepoTimeout, err)
+	}
+	return err
+}
+
+// addFetched records bytes added to an object store. Stores can also shrink
+// (e.g., after an automatic git gc), which isn't counted.
+func (s *Syncer) addFetched(n int64) {
+	if s.fetched != nil && n > 0 {
+		s.fetched.Add(n)
+	}
+}
+
+// fetchedBytes returns the bytes added to object stores so far
+func (s *Syncer) fetchedBytes() int64 {
+	if s.fetched == nil {
+		return 0
+	}
+	return s.fetched.Load()
+}
+
 // reportProgress calls the progress callback if set
 func (s *Syncer) reportProgress(repoName string, status ProgressStatus, message string) {
 	if s.opts.OnProgress != nil {
@@ -506,17 +746,41 @@ func (s *Syncer) cloneRepo(ctx context.Context, repo *gh.Repository, localPath s
 		return fmt.Errorf("failed to create parent directory: %w", err)
 	}
 
-	// Clone the repository using HTTPS (token auth is handled by Git instance)
-	cloneURL := repo.GetCloneURL()
+	// Clone into the staging area first; the repo is only moved to localPath
+	// once everything has finished, so an interrupted clone is never mistaken
+	// for a complete one
+	stagePath := stagingPath(s.opts.Target, repo.GetOwner().GetLogin(), repo.GetName())
+	if err := os.MkdirAll(filepath.Dir(stagePath), 0755); err != nil {
+		return fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	// Leftover from an earlier attempt by a process that reused our PID
+	_ = os.RemoveAll(stagePath)
+	defer func() {
+		// No-op after a successful rename; removes the partial clone otherwise
+		_ = os.RemoveAll(stagePath)
+		_ = os.Remove(filepath.Dir(stagePath))
+	}()
+
+	// Clone the repository over HTTPS (token auth is handled by Git instance)
+	// or SSH
+	cloneURL, err := s.remoteURL(repo)
+	if err != nil {
+		return err
+	}
+	cloneOpts := git.CloneOptions{
+		Mirror:  s.opts.Mirror,
+		Depth:   s.opts.Depth,
+		SkipLFS: s.opts.SkipLFS,
+	}
 
 	// Retry clone on transient failures (e.g., Dropbox file locking on Windows)
 	if err := withGitRetry(ctx, DefaultGitRetryConfig(),
 		func() error {
-			return s.git.Clone(ctx, cloneURL, localPath)
+			return s.git.CloneWithOptions(ctx, cloneURL, stagePath, cloneOpts)
 		},
 		func() error {
 			// Remove partial clone directory before retrying
-			return os.RemoveAll(localPath)
+			return os.RemoveAll(stagePath)
 		},
 		isTransientGitError,
 	); err != nil {
@@ -524,7 +788,7 @@ func (s *Syncer) cloneRepo(ctx context.Context, repo *gh.Repository, localPath s
 	}
 
 	// Handle LFS if needed (non-fatal - repo still usable without LFS objects)
-	if s.lfs.RepoUsesLFS(localPath) {
+	if !s.opts.SkipLFS && s.lfs.RepoUsesLFS(stagePath) {
 		if s.opts.Verbose {
 			fmt.Printf("Repository uses LFS, pulling LFS objects...\n")
 		}
@@ -534,9 +798,7 @@ func (s *Syncer) cloneRepo(ctx context.Context, repo *gh.Repository, localPath s
 				fmt.Printf("Warning: LFS not available (%v). Large files will be pointer files.\n", err)
 			}
 			// Continue without LFS - repo is still cloned, just without LFS objects
-			return nil
-		}
-		if err := s.lfs.Pull(ctx, localPath); err != nil {
+		} else if err := s.lfs.Pull(ctx, stagePath); err != nil {
 			// LFS pull failed - warn but don't fail
 			if s.opts.Verbose {
 				fmt.Printf("Warning: Failed to pull LFS objects: %v\n", err)
@@ -544,12 +806,80 @@ func (s *Syncer) cloneRepo(ctx context.Context, repo *gh.Repository, localPath s
 		}
 	}
 
+	// Don't move a clone into place if we were cancelled while finishing up
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if err := moveIntoPlace(stagePath, localPath); err != nil {
+		return err
+	}
+	s.addFetched(s.git.ObjectStoreSize(localPath))
+	return nil
+}
+
+// remoteURL returns the URL to clone repo from: its SSH URL with the SSH
+// option, its HTTPS clone URL otherwise
+func (s *Syncer) remoteURL(repo *gh.Repository) (string, error) {
+	if !s.opts.SSH {
+		return repo.GetCloneURL(), nil
+	}
+	if repo.GetSSHURL() == "" {
+		return "", fmt.Errorf("%w: no SSH URL for %s", git.ErrCloneFailed, repo.GetFullName())
+	}
+	return repo.GetSSHURL(), nil
+}
+
+// useTransport points the origin remote of a clone at repo's URL when it
+// uses the other transport, so switching between HTTPS and SSH applies to
+// existing clones too. Remotes that aren't on the GitHub host are left
+// alone.
+func (s *Syncer) useTransport(ctx context.Context, repo *gh.Repository, localPath string) error {
+	current, err := s.git.GetRemoteURL(ctx, localPath)
+	if err != nil || git.IsSSHURL(current) == s.opts.SSH {
+		return nil
+	}
+	if _, _, err := util.ParseRepositoryURLForHost(current, s.git.Host); err != nil {
+		return nil
+	}
+
+	url, err := s.remoteURL(repo)
+	if err != nil || url == "" {
+		return err
+	}
+	if s.opts.Verbose {
+		fmt.Printf("%s: switching remote to %s\n", repo.GetFullName(), url)
+	}
+	return s.git.SetRemoteURL(ctx, localPath, url)
+}
+
+// moveIntoPlace renames a finished clone from the staging area to its final
+// location. An empty directory at the destination (e.g., created by hand) is
+// replaced, matching what git clone itself allows.
+func moveIntoPlace(stagePath, localPath string) error {
+	if entries, err := os.ReadDir(localPath); err == nil {
+		if len(entries) > 0 {
+			return fmt.Errorf("%w: destination path %s already exists and is not empty", git.ErrCloneFailed, localPath)
+		}
+		if err := os.Remove(localPath); err != nil {
+			return fmt.Errorf("failed to replace empty directory %s: %w", localPath, err)
+		}
+	}
+
+	if err := os.Rename(stagePath, localPath); err != nil {
+		return fmt.Errorf("failed to move clone into place: %w", err)
+	}
 	return nil
 }
 
 // pullRepo pulls updates for an existing repository.
 // Returns ProgressUpToDate if already current, ProgressUpdated if pulled, or error.
 func (s *Syncer) pullRepo(ctx context.Context, repo *gh.Repository, localPath string) (ProgressStatus, error) {
+	// Switch the remote first, so up-to-date clones change transport too
+	if err := s.useTransport(ctx, repo, localPath); err != nil {
+		return ProgressFailed, err
+	}
+
 	// Fast check: compare repo's pushed_at timestamp with our last fetch time
 	// This works for ALL branches, not just the default branch
 	if repo.PushedAt != nil && !repo.PushedAt.IsZero() {
@@ -591,9 +921,18 @@ func (s *Syncer) pullRepo(ctx context.Context, repo *gh.Repository, localPath st
 	// Using fetch instead of pull so we update all remote-tracking branches
 	// without modifying the working directory
 	// Retry on transient failures (e.g., Dropbox file locking on Windows)
+	// Shallow clones are kept shallow; full clones are never truncated
+	var fetchOpts git.FetchOptions
+	if s.opts.Depth > 0 && s.git.IsShallowRepo(localPath) {
+		fetchOpts.Depth = s.opts.Depth
+	}
+
+	sizeBefore := s.git.ObjectStoreSize(localPath)
+	defer func() { s.addFetched(s.git.ObjectStoreSize(localPath) - sizeBefore) }()
+
 	if err := withGitRetry(ctx, DefaultGitRetryConfig(),
 		func() error {
-			return s.git.FetchAll(ctx, localPath)
+			return s.git.FetchAllWithOptions(ctx, localPath, fetchOpts)
 		},
 		nil, // No cleanup needed for fetch
 		isTransientGitError,
@@ -602,7 +941,7 @@ func (s *Syncer) pullRepo(ctx context.Context, repo *gh.Repository, localPath st
 	}
 
 	// Handle LFS if needed (non-fatal - repo still usable without LFS objects)
-	if s.lfs.RepoUsesLFS(localPath) {
+	if !s.opts.SkipLFS && s.lfs.RepoUsesLFS(localPath) {
 		if s.opts.Verbose {
 			fmt.Printf("Repository uses LFS, pulling LFS objects...\n")
 		}
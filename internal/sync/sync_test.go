@@ -2,6 +2,7 @@ package sync
 
 import (
 	"context"
+	"errors"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -12,6 +13,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	gherrors "github.com/Didstopia/githubby/internal/errors"
 	"github.com/Didstopia/githubby/internal/git"
 	"github.com/Didstopia/githubby/internal/github"
 )
@@ -319,6 +321,48 @@ func TestSyncRepo(t *testing.T) {
 	})
 }
 
+func TestSyncRepos_Selected(t *testing.T) {
+	gitInstance, err := git.New()
+	if err != nil {
+		t.Skip("git is not installed")
+	}
+
+	t.Run("fetches each named repo and reports failures", func(t *testing.T) {
+		mockClient := github.NewMockClient()
+		mockClient.GetRepositoryFunc = func(ctx context.Context, o, r string) (*gh.Repository, error) {
+			if r == "missing" {
+				return nil, errors.New("not found")
+			}
+			return createMockRepo(r, o+"/"+r, false), nil
+		}
+
+		tmpDir := t.TempDir()
+		// A local repo outside the selection must not be reported as archived
+		require.NoError(t, os.MkdirAll(filepath.Join(tmpDir, "owner", "other", ".git"), 0755))
+
+		syncer := New(mockClient, gitInstance, &Options{Target: tmpDir, DryRun: true})
+		result, err := syncer.SyncRepos(context.Background(), []string{"owner/one", "owner/missing", "invalid"})
+
+		require.NoError(t, err)
+		assert.Equal(t, 2, mockClient.CallCount("GetRepository"))
+		assert.Contains(t, result.Failed, "owner/missing")
+		assert.Contains(t, result.Failed, "invalid")
+		assert.Len(t, result.Cloned, 1)
+		assert.Empty(t, result.Archived)
+	})
+
+	t.Run("auth errors fail the sync", func(t *testing.T) {
+		mockClient := github.NewMockClient()
+		mockClient.GetRepositoryFunc = func(ctx context.Context, o, r string) (*gh.Repository, error) {
+			return nil, gherrors.ErrUnauthorized
+		}
+
+		syncer := New(mockClient, gitInstance, &Options{Target: t.TempDir(), DryRun: true})
+		_, err := syncer.SyncRepos(context.Background(), []string{"owner/one"})
+		assert.True(t, gherrors.IsUnauthorized(err))
+	})
+}
+
 func TestSyncRepos_DryRun(t *testing.T) {
 	gitInstance, err := git.New()
 	if err != nil {
@@ -464,6 +508,86 @@ func TestSyncRepos_ExistingRepo(t *testing.T) {
 	assert.Empty(t, result.Cloned)
 }
 
+func TestSyncer_MoveLocal(t *testing.T) {
+	gitInstance, err := git.New()
+	if err != nil {
+		t.Skip("git is not installed")
+	}
+
+	tmpDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(tmpDir, "owner", "old-name", ".git"), 0755))
+	syncer := New(github.NewMockClient(), gitInstance, &Options{Target: tmpDir})
+
+	// Nothing to move
+	moved, err := syncer.MoveLocal("owner/missing", "owner/new-name")
+	require.NoError(t, err)
+	assert.False(t, moved)
+
+	// Transferred to another owner
+	moved, err = syncer.MoveLocal("owner/old-name", "neworg/new-name")
+	require.NoError(t, err)
+	assert.True(t, moved)
+	assert.DirExists(t, filepath.Join(tmpDir, "neworg", "new-name", ".git"))
+	assert.NoDirExists(t, filepath.Join(tmpDir, "owner"), "empty owner directory should be removed")
+
+	// An existing copy at the new name is left alone
+	require.NoError(t, os.MkdirAll(filepath.Join(tmpDir, "owner", "old-name", ".git"), 0755))
+	moved, err = syncer.MoveLocal("owner/old-name", "neworg/new-name")
+	require.NoError(t, err)
+	assert.False(t, moved)
+	assert.DirExists(t, filepath.Join(tmpDir, "owner", "old-name"))
+
+	_, err = syncer.MoveLocal("owner/old-name", "../escape")
+	assert.ErrorContains(t, err, "invalid repository name")
+}
+
+func TestSyncer_useTransport(t *testing.T) {
+	gitInstance, err := git.New()
+	if err != nil {
+		t.Skip("git is not installed")
+	}
+	ctx := context.Background()
+	repoDir := t.TempDir()
+	require.NoError(t, exec.Command(gitInstance.GitPath, "init", repoDir).Run())
+	require.NoError(t, exec.Command(gitInstance.GitPath, "-C", repoDir, "remote", "add", "origin", "https://github.com/owner/repo.git").Run())
+
+	repo := createMockRepo("repo", "owner/repo", false)
+	repo.SSHURL = strPtr("git@github.com:owner/repo.git")
+	remote := func() string {
+		url, err := gitInstance.GetRemoteURL(ctx, repoDir)
+		require.NoError(t, err)
+		return url
+	}
+
+	// Switching to SSH points existing clones at the SSH URL, and back
+	syncer := New(github.NewMockClient(), gitInstance, &Options{SSH: true})
+	require.NoError(t, syncer.useTransport(ctx, repo, repoDir))
+	assert.Equal(t, "git@github.com:owner/repo.git", remote())
+	assert.True(t, syncer.clonesRemote(repoDir, map[string]bool{"owner/repo": true}), "SSH clones are recognized")
+
+	syncer.opts.SSH = false
+	require.NoError(t, syncer.useTransport(ctx, repo, repoDir))
+	assert.Equal(t, "https://github.com/owner/repo.git", remote())
+
+	// Clones that are up to date switch too, without fetching
+	require.NoError(t, os.WriteFile(filepath.Join(repoDir, ".git", "FETCH_HEAD"), nil, 0644))
+	repo.PushedAt = &gh.Timestamp{Time: time.Now().Add(-time.Hour)}
+	syncer.opts.SSH = true
+	status, err := syncer.pullRepo(ctx, repo, repoDir)
+	require.NoError(t, err)
+	assert.Equal(t, ProgressUpToDate, status)
+	assert.Equal(t, "git@github.com:owner/repo.git", remote())
+	syncer.opts.SSH = false
+	require.NoError(t, syncer.useTransport(ctx, repo, repoDir))
+
+	// Remotes on other hosts are left alone
+	require.NoError(t, gitInstance.SetRemoteURL(ctx, repoDir, "https://gitlab.com/owner/repo.git"))
+	syncer.opts.SSH = true
+	require.NoError(t, syncer.useTransport(ctx, repo, repoDir))
+	assert.Equal(t, "https://gitlab.com/owner/repo.git", remote())
+	assert.False(t, syncer.clonesRemote(repoDir, map[string]bool{"owner/repo": true}))
+}
+
 func TestOptions_Defaults(t *testing.T) {
 	opts := &Options{}
 
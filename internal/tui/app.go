@@ -9,7 +9,9 @@ import (
 	"github.com/charmbracelet/bubbles/key"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"golang.org/x/oauth2"
 
+	"github.com/Didstopia/githubby/internal/auth"
 	"github.com/Didstopia/githubby/internal/github"
 	"github.com/Didstopia/githubby/internal/state"
 	"github.com/Didstopia/githubby/internal/update"
@@ -26,6 +28,7 @@ const (
 	ScreenClean
 	ScreenSettings
 	ScreenConfirmDelete
+	ScreenProfileSettings
 	// Legacy screens (kept for compatibility during transition)
 	ScreenRepos
 )
@@ -82,6 +85,14 @@ type App struct {
 	isAuthenticated bool
 	username        string
 	token           string
+	tokens          oauth2.TokenSource
+
+	// Account the auth state belongs to, the accounts that can be switched
+	// to, the last one switched to, and why that switch failed
+	account    auth.Account
+	accounts   []auth.Account
+	switchedTo int
+	accountErr error
 
 	// Selected profile(s) for sync
 	selectedProfile  *state.SyncProfile
@@ -91,6 +102,9 @@ type App struct {
 	deleteProfileID   string
 	deleteProfileName string
 
+	// Profile whose settings are being edited
+	editProfileID string
+
 	// Terminal dimensions
 	width  int
 	height int
@@ -145,6 +159,22 @@ func WithAuth(authenticated bool, username, token string) AppOption {
 	}
 }
 
+// WithTokenSource sets the source that renews the authentication token
+// before it expires (nil if it doesn't)
+func WithTokenSource(tokens oauth2.TokenSource) AppOption {
+	return func(a *App) {
+		a.tokens = tokens
+	}
+}
+
+// WithAccount sets the account the authentication state belongs to
+// (github.com's default account if not set)
+func WithAccount(account auth.Account) AppOption {
+	return func(a *App) {
+		a.account = account
+	}
+}
+
 // WithGitHubClient sets the GitHub client
 func WithGitHubClient(client github.Client) AppOption {
 	return func(a *App) {
@@ -183,11 +213,13 @@ func NewApp(opts ...AppOption) *App {
 		keys:            DefaultKeyMap(),
 		width:           80,
 		height:          24,
+		account:         auth.Account{Hostname: auth.DefaultHostname},
 	}
 
 	for _, opt := range opts {
 		opt(app)
 	}
+	app.loadAccounts()
 
 	return app
 }
@@ -278,6 +310,62 @@ func (a *App) Token() string {
 	return a.token
 }
 
+// TokenSource returns the source that renews the authentication token before
+// it expires, nil if it doesn't expire
+func (a *App) TokenSource() oauth2.TokenSource {
+	return a.tokens
+}
+
+// Account returns the account the app is authenticated with
+func (a *App) Account() auth.Account {
+	return a.account
+}
+
+// Accounts returns the accounts that can be switched to, including the
+// current one
+func (a *App) Accounts() []auth.Account {
+	return a.accounts
+}
+
+// loadAccounts lists the logged in accounts, adding the current one if it
+// isn't listed (such as a token from GITHUB_TOKEN)
+func (a *App) loadAccounts() {
+	a.accounts, _ = auth.NewStorageWithConfig("", false).ListAccounts()
+	if a.accountIndex(a.account) < 0 {
+		a.accounts = append([]auth.Account{a.account}, a.accounts...)
+	}
+	a.switchedTo = a.accountIndex(a.account)
+}
+
+// accountIndex returns the index of account in the accounts list, or -1
+func (a *App) accountIndex(account auth.Account) int {
+	for i, listed := range a.accounts {
+		if listed.Name == account.Name && listed.Hostname == account.Hostname {
+			return i
+		}
+	}
+	return -1
+}
+
+// switchAccountCmd checks the token of account and reports the result with
+// an AccountSwitchedMsg
+func (a *App) switchAccountCmd(account auth.Account) tea.Cmd {
+	return func() tea.Msg {
+		result, err := auth.GetAccountToken(a.ctx, "", account.Name, account.Hostname)
+		if err != nil {
+			return AccountSwitchedMsg{Account: account, Error: err}
+		}
+		if result.Token == "" {
+			return AccountSwitchedMsg{Account: account, Error: fmt.Errorf("not logged in")}
+		}
+		user, err := auth.ValidateToken(a.ctx, result.Token, account.Hostname)
+		if err != nil {
+			return AccountSwitchedMsg{Account: account, Error: fmt.Errorf("token is invalid or expired")}
+		}
+		return AccountSwitchedMsg{Account: account, Token: result.Token, Tokens: result.Tokens, Username: user.Login}
+	}
+}
+
 // IsAuthenticated returns whether the user is authenticated
 func (a *App) IsAuthenticated() bool {
 	return a.isAuthenticated
@@ -308,6 +396,11 @@ func (a *App) DeleteProfileInfo() (string, string) {
 	return a.deleteProfileID, a.deleteProfileName
 }
 
+// EditProfileID returns the ID of the profile whose settings are being edited
+func (a *App) EditProfileID() string {
+	return a.editProfileID
+}
+
 // Width returns the terminal width
 func (a *App) Width() int {
 	return a.width
@@ -549,9 +642,12 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			a.isAuthenticated = true
 			a.username = msg.Username
 			a.token = msg.Token
+			a.tokens = msg.Tokens
+			a.account = auth.Account{Hostname: auth.DefaultHostname, Login: msg.Username}
+			a.loadAccounts()
 			// Create GitHub client with the new token
 			if msg.Token != "" {
-				a.ghClient = github.NewClient(msg.Token)
+				a.ghClient = github.NewDefaultClientWithTokenSource(msg.Token, auth.DefaultHostname, msg.Tokens)
 			}
 			// Mark onboarding as complete
 			if a.storage != nil {
@@ -561,6 +657,37 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return a, a.ResetToScreen(ScreenDashboard)
 		}
 
+	case SwitchAccountMsg:
+		// Move on to the next account, keeping the current one until its
+		// token checks out
+		if len(a.accounts) > 1 {
+			next := a.accounts[(a.switchedTo+1)%len(a.accounts)]
+			return a, a.switchAccountCmd(next)
+		}
+		return a, nil
+
+	case AccountSwitchedMsg:
+		// The next switch moves on from here, even if this one failed
+		a.switchedTo = max(a.accountIndex(msg.Account), 0)
+		if msg.Error != nil {
+			a.accountErr = fmt.Errorf("%s: %w", accountLabel(msg.Account), msg.Error)
+			return a, nil
+		}
+		a.accountErr = nil
+		a.isAuthenticated = true
+		a.account = msg.Account
+		a.username = msg.Username
+		a.token = msg.Token
+		a.tokens = msg.Tokens
+		a.ghClient = github.NewDefaultClientWithTokenSource(msg.Token, msg.Account.Hostname, msg.Tokens)
+		// Screens created with the previous account's client start over
+		for screen := range a.screens {
+			if screen != a.currentScreen {
+				delete(a.screens, screen)
+			}
+		}
+		return a, RefreshDashboardCmd()
+
 	case RefreshDashboardMsg:
 		// If we're on dashboard, trigger a refresh
 		if a.currentScreen == ScreenDashboard {
@@ -623,6 +750,12 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		a.deleteProfileName = msg.ProfileName
 		return a, a.PushScreen(ScreenConfirmDelete)
 
+	case EditProfileSettingsMsg:
+		// Show the settings screen for the profile
+		delete(a.screens, ScreenProfileSettings)
+		a.editProfileID = msg.ProfileID
+		return a, a.PushScreen(ScreenProfileSettings)
+
 	case DeleteProfileConfirmedMsg:
 		// Actually delete the profile
 		if a.storage != nil {
@@ -804,8 +937,14 @@ func (a *App) renderHeader() string {
 
 	// Status section
 	var status string
-	if a.isAuthenticated {
+	if a.accountErr != nil {
+		status = a.styles.Error.Render("●") + " " + a.styles.HeaderStatus.Render(a.accountErr.Error())
+	} else if a.isAuthenticated {
 		status = a.styles.Success.Render("●") + " " + a.styles.HeaderStatus.Render(a.username)
+		// Show which account is in use once there's more than one
+		if len(a.accounts) > 1 {
+			status += a.styles.Muted.Render(" (" + accountLabel(a.account) + ")")
+		}
 	} else {
 		status = a.styles.Error.Render("●") + " " + a.styles.HeaderStatus.Render("Not authenticated")
 	}
@@ -829,6 +968,15 @@ func (a *App) renderHeader() string {
 	return a.styles.Header.Width(a.width - 4).Render(headerContent)
 }
 
+// accountLabel names an account in the header: its name, and its host if it
+// isn't github.com
+func accountLabel(account auth.Account) string {
+	if auth.IsEnterprise(account.Hostname) {
+		return account.DisplayName() + "@" + account.Hostname
+	}
+	return account.DisplayName()
+}
+
 // renderFooter renders the app footer with help and version
 func (a *App) renderFooter() string {
 	var helpItems []string
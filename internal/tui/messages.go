@@ -5,7 +5,9 @@ import (
 
 	tea "github.com/charmbracelet/bubbletea"
 	gh "github.com/google/go-github/v68/github"
+	"golang.org/x/oauth2"
 
+	"github.com/Didstopia/githubby/internal/auth"
 	"github.com/Didstopia/githubby/internal/state"
 	"github.com/Didstopia/githubby/internal/sync"
 )
@@ -38,6 +40,7 @@ type ErrorMsg struct {
 // AuthCompleteMsg signals authentication completed
 type AuthCompleteMsg struct {
 	Token    string
+	Tokens   oauth2.TokenSource // renews Token if it expires, nil if it doesn't
 	Username string
 	Error    error
 }
@@ -126,6 +129,23 @@ type DeleteProfileConfirmedMsg struct {
 // DeleteProfileCancelledMsg signals user cancelled profile deletion
 type DeleteProfileCancelledMsg struct{}
 
+// EditProfileSettingsMsg signals user wants to edit a profile's sync settings
+type EditProfileSettingsMsg struct {
+	ProfileID string
+}
+
+// SwitchAccountMsg signals user wants to switch to the next logged in account
+type SwitchAccountMsg struct{}
+
+// AccountSwitchedMsg signals the token of the account switched to was checked
+type AccountSwitchedMsg struct {
+	Account  auth.Account
+	Token    string
+	Tokens   oauth2.TokenSource // renews Token if it expires, nil if it doesn't
+	Username string
+	Error    error
+}
+
 // UpdateAvailableMsg signals that an update is available
 type UpdateAvailableMsg struct {
 	CurrentVersion string
@@ -224,10 +224,15 @@ func (d *DashboardV2) ShortHelp() []key.Binding {
 	// Add contextual shortcuts if a profile is selected
 	if item, ok := d.actionList.SelectedItem().(DashboardItem); ok && item.profileID != "" {
 		bindings = append(bindings,
+			key.NewBinding(key.WithKeys("e"), key.WithHelp("e", "settings")),
 			key.NewBinding(key.WithKeys("d"), key.WithHelp("d", "delete")),
 		)
 	}
 
+	if len(d.app.Accounts()) > 1 {
+		bindings = append(bindings, key.NewBinding(key.WithKeys("a"), key.WithHelp("a", "switch account")))
+	}
+
 	return bindings
 }
 
@@ -275,6 +280,20 @@ func (d *DashboardV2) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				return tui.NewSyncRequestedMsg{}
 			}
 
+		case msg.String() == "a":
+			d.exitPending = false
+			return d, func() tea.Msg {
+				return tui.SwitchAccountMsg{}
+			}
+
+		case msg.String() == "e":
+			d.exitPending = false
+			if item, ok := d.actionList.SelectedItem().(DashboardItem); ok && item.profileID != "" {
+				return d, func() tea.Msg {
+					return tui.EditProfileSettingsMsg{ProfileID: item.profileID}
+				}
+			}
+
 		case msg.String() == "d":
 			d.exitPending = false
 			// Check if a profile is selected
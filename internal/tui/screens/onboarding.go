@@ -15,6 +15,7 @@ import (
 	"github.com/charmbracelet/huh"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/cli/oauth/device"
+	"golang.org/x/oauth2"
 
 	"github.com/Didstopia/githubby/internal/auth"
 	"github.com/Didstopia/githubby/internal/state"
@@ -66,7 +67,8 @@ type Onboarding struct {
 
 	// User info after auth
 	username string
-	token    string // stored token for AuthCompleteMsg
+	token    string             // stored token for AuthCompleteMsg
+	tokens   oauth2.TokenSource // renews token if it expires
 
 	// Channels for async operations
 	oauthCodeChan     chan oauthCodeResult
@@ -312,6 +314,12 @@ func (o *Onboarding) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				o.oauthError = err
 				o.oauthStatus = "Failed to store token"
 			} else {
+				_ = storage.SaveAccount(auth.Account{Hostname: auth.DefaultHostname, Login: msg.username})
+				// Without its refresh token, an expiring token works until
+				// it expires
+				if err := storage.SetAccountRefresh("", "", msg.refresh); err == nil {
+					o.tokens, _ = storage.StoredTokenSource(o.ctx, "", "")
+				}
 				o.username = msg.username
 				o.token = msg.token // Store for AuthCompleteMsg
 				o.step = StepSyncConfig
@@ -331,6 +339,9 @@ func (o *Onboarding) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			if err := storage.SetToken("", o.tokenInput); err != nil {
 				o.err = err
 			} else {
+				_ = storage.SaveAccount(auth.Account{Hostname: auth.DefaultHostname, Login: msg.username})
+				// Personal access tokens aren't renewed
+				_ = storage.SetAccountRefresh("", "", nil)
 				o.username = msg.username
 				o.token = o.tokenInput // Store for AuthCompleteMsg
 				o.step = StepSyncConfig
@@ -395,6 +406,7 @@ func (o *Onboarding) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					return o, func() tea.Msg {
 						return tui.AuthCompleteMsg{
 							Token:    o.token,
+							Tokens:   o.tokens,
 							Username: o.username,
 						}
 					}
@@ -588,6 +600,7 @@ type oauthCodeMsg struct {
 
 type oauthCompleteMsg struct {
 	token    string
+	refresh  *auth.RefreshCredentials
 	username string
 	err      error
 }
@@ -605,6 +618,7 @@ type oauthCodeResult struct {
 
 type oauthCompleteResult struct {
 	token    string
+	refresh  *auth.RefreshCredentials
 	username string
 	err      error
 }
@@ -682,7 +696,7 @@ func (o *Onboarding) waitForOAuthComplete() tea.Cmd {
 			return
 		}
 
-		o.oauthCompleteChan <- oauthCompleteResult{token: result.Token, username: user.Login}
+		o.oauthCompleteChan <- oauthCompleteResult{token: result.Token, refresh: result.RefreshCredentials(), username: user.Login}
 	}()
 
 	// Return batch with spinner tick to keep UI responsive
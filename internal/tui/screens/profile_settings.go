@@ -0,0 +1,349 @@
+package screens
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/huh"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/Didstopia/githubby/internal/auth"
+	"github.com/Didstopia/githubby/internal/schedule"
+	"github.com/Didstopia/githubby/internal/state"
+	"github.com/Didstopia/githubby/internal/tui"
+)
+
+// ProfileSettingsScreen edits a profile's sync settings: GitHub host,
+// account, concurrency, per-repo timeout, Git LFS, clone mode, transport,
+// schedule, overlap policy and incremental syncs
+type ProfileSettingsScreen struct {
+	ctx    context.Context
+	app    *tui.App
+	styles *tui.Styles
+	keys   tui.KeyMap
+
+	// Profile being edited (a copy, saved on completion)
+	profile *state.SyncProfile
+
+	// Form values
+	host        string
+	account     string
+	concurrency string
+	repoTimeout string
+	downloadLFS bool
+	cloneMode   string
+	transport   string
+	sshKey      string
+	knownHosts  string
+	schedule    string
+	overlap     string
+	incremental bool
+	form        *huh.Form
+
+	// Dimensions
+	width  int
+	height int
+
+	// State
+	err error
+}
+
+// NewProfileSettings creates a new profile settings screen
+func NewProfileSettings(ctx context.Context, app *tui.App) *ProfileSettingsScreen {
+	return &ProfileSettingsScreen{
+		ctx:    ctx,
+		app:    app,
+		styles: tui.GetStyles(),
+		keys:   tui.GetKeyMap(),
+		width:  80,
+		height: 24,
+	}
+}
+
+// Title returns the screen title
+func (p *ProfileSettingsScreen) Title() string {
+	if p.profile != nil {
+		return fmt.Sprintf("Settings: %s", p.profile.Name)
+	}
+	return "Profile Settings"
+}
+
+// ShortHelp returns key bindings for the footer
+func (p *ProfileSettingsScreen) ShortHelp() []key.Binding {
+	return []key.Binding{
+		key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "next")),
+		key.NewBinding(key.WithKeys("shift+tab"), key.WithHelp("shift+tab", "previous")),
+		p.keys.Back,
+	}
+}
+
+// Init initializes the screen
+func (p *ProfileSettingsScreen) Init() tea.Cmd {
+	if p.app.Storage() != nil {
+		if profile := p.app.Storage().GetProfile(p.app.EditProfileID()); profile != nil {
+			p.profile = profile.Clone()
+		}
+	}
+	if p.profile == nil {
+		p.err = fmt.Errorf("profile not found")
+		return nil
+	}
+
+	p.host = p.profile.Host
+	p.account = p.profile.Account
+	p.concurrency = strconv.Itoa(p.profile.Concurrency)
+	p.repoTimeout = ""
+	if p.profile.RepoTimeout > 0 {
+		p.repoTimeout = p.profile.RepoTimeout.String()
+	}
+	p.downloadLFS = !p.profile.SkipLFS
+	p.cloneMode = p.profile.CloneMode
+	if p.cloneMode == "" {
+		p.cloneMode = state.CloneModeFull
+	}
+	p.transport = state.TransportHTTPS
+	if p.profile.UseSSH() {
+		p.transport = state.TransportSSH
+	}
+	p.sshKey = p.profile.SSHKey
+	p.knownHosts = p.profile.SSHKnownHosts
+	p.schedule = p.profile.Schedule
+	p.overlap = string(p.profile.OverlapPolicy())
+	p.incremental = p.profile.Incremental
+
+	p.initForm()
+	return p.form.Init()
+}
+
+// initForm builds the settings form
+func (p *ProfileSettingsScreen) initForm() {
+	concurrencyOptions := []huh.Option[string]{
+		huh.NewOption(fmt.Sprintf("Default (%d)", state.DefaultConcurrency), "0"),
+	}
+	for n := 1; n <= state.MaxConcurrency; n++ {
+		concurrencyOptions = append(concurrencyOptions, huh.NewOption(strconv.Itoa(n), strconv.Itoa(n)))
+	}
+
+	p.form = huh.NewForm(
+		huh.NewGroup(
+			huh.NewInput().
+				Title("GitHub host").
+				Description("GitHub Enterprise Server hostname, e.g. github.mycompany.com; leave empty for github.com").
+				Value(&p.host).
+				Validate(validateHost),
+			huh.NewSelect[string]().
+				Title("Account").
+				Description("Account whose token the profile syncs with; log in to more with \"githubby login --account <name>\"").
+				Options(p.accountOptions()...).
+				Value(&p.account),
+			huh.NewSelect[string]().
+				Title("Parallel repository syncs").
+				Description("Large organizations sync faster with more; keep it low for slow connections").
+				Options(concurrencyOptions...).
+				Value(&p.concurrency),
+			huh.NewInput().
+				Title("Per-repository timeout").
+				Description("e.g. 10m or 1h; leave empty for no limit").
+				Value(&p.repoTimeout).
+				Validate(validateRepoTimeout),
+			huh.NewConfirm().
+				Title("Download Git LFS objects?").
+				Affirmative("Yes").
+				Negative("No, keep pointer files").
+				Value(&p.downloadLFS),
+			huh.NewSelect[string]().
+				Title("Clone mode").
+				Description("Applies to new clones; existing clones keep their layout").
+				Options(
+					huh.NewOption("Full - working copy with full history", state.CloneModeFull),
+					huh.NewOption("Shallow - latest commit of each branch only", state.CloneModeShallow),
+					huh.NewOption("Mirror - bare copy of all refs, for backups", state.CloneModeMirror),
+				).
+				Value(&p.cloneMode),
+			huh.NewSelect[string]().
+				Title("Transport").
+				Description("How repositories are cloned and fetched; existing clones are switched over").
+				Options(
+					huh.NewOption("HTTPS - authenticated with the account's token", state.TransportHTTPS),
+					huh.NewOption("SSH - authenticated with an SSH key", state.TransportSSH),
+				).
+				Value(&p.transport),
+			huh.NewInput().
+				Title("SSH key").
+				Description("Private key for the SSH transport, such as a deploy key; leave empty for ssh's default keys").
+				Value(&p.sshKey),
+			huh.NewInput().
+				Title("SSH known_hosts file").
+				Description("Host keys the SSH transport checks against; leave empty for ssh's default").
+				Value(&p.knownHosts),
+			huh.NewInput().
+				Title("Schedule").
+				Description("Cron expression used by \"githubby sync --profile\" and \"githubby daemon\", e.g. @every 6h; leave empty for none").
+				Value(&p.schedule).
+				Validate(validateSchedule),
+			huh.NewSelect[string]().
+				Title("When a scheduled sync is due while the last one is still running").
+				Options(
+					huh.NewOption("Skip it", string(schedule.OverlapSkip)),
+					huh.NewOption("Run it once the current sync finishes", string(schedule.OverlapQueue)),
+				).
+				Value(&p.overlap),
+			huh.NewConfirm().
+				Title("Only sync repositories with new events?").
+				Description("Scheduled syncs poll the owner's events and list every repository once a day; applies when syncing all repositories").
+				Affirmative("Yes").
+				Negative("No, list every repository").
+				Value(&p.incremental),
+		),
+	).WithTheme(huh.ThemeCharm())
+}
+
+// accountOptions lists the accounts a profile can sync with: the default
+// account and every named account logged in to, on any host
+func (p *ProfileSettingsScreen) accountOptions() []huh.Option[string] {
+	options := []huh.Option[string]{huh.NewOption("Default account", "")}
+	seen := map[string]bool{"": true}
+	names := make([]string, 0, len(p.app.Accounts())+1)
+	for _, account := range p.app.Accounts() {
+		names = append(names, account.Name)
+	}
+	// Keep an account the profile uses even if it was logged out of
+	names = append(names, p.profile.Account)
+	for _, name := range names {
+		if !seen[name] {
+			seen[name] = true
+			options = append(options, huh.NewOption(name, name))
+		}
+	}
+	return options
+}
+
+// validateRepoTimeout checks the timeout entered in the form
+func validateRepoTimeout(value string) error {
+	if strings.TrimSpace(value) == "" {
+		return nil
+	}
+	d, err := time.ParseDuration(strings.TrimSpace(value))
+	if err != nil || d < 0 {
+		return fmt.Errorf("enter a duration such as 10m or 1h")
+	}
+	return nil
+}
+
+// validateHost checks the GitHub hostname entered in the form
+func validateHost(value string) error {
+	_, err := auth.NormalizeHostname(value)
+	return err
+}
+
+// validateSchedule checks the cron schedule entered in the form
+func validateSchedule(value string) error {
+	if strings.TrimSpace(value) == "" {
+		return nil
+	}
+	return schedule.ValidateSpec(strings.TrimSpace(value))
+}
+
+// Update handles messages
+func (p *ProfileSettingsScreen) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		p.width = msg.Width
+		p.height = msg.Height
+
+	case tea.KeyMsg:
+		if key.Matches(msg, p.keys.Back) || msg.Type == tea.KeyCtrlC {
+			return p, tui.PopScreenCmd()
+		}
+	}
+
+	if p.form == nil {
+		return p, nil
+	}
+
+	form, cmd := p.form.Update(msg)
+	if f, ok := form.(*huh.Form); ok {
+		p.form = f
+		if f.State == huh.StateCompleted {
+			if err := p.save(); err != nil {
+				// Let the user fix the settings and try again
+				p.err = err
+				p.initForm()
+				return p, p.form.Init()
+			}
+			return p, tea.Batch(tui.PopScreenCmd(), tui.RefreshDashboardCmd())
+		}
+	}
+
+	return p, cmd
+}
+
+// save applies the form values to the profile and saves it
+func (p *ProfileSettingsScreen) save() error {
+	p.profile.Host = ""
+	if host, err := auth.NormalizeHostname(p.host); err == nil && auth.IsEnterprise(host) {
+		p.profile.Host = host
+	}
+	p.profile.Account = p.account
+	p.profile.Concurrency, _ = strconv.Atoi(p.concurrency)
+	p.profile.RepoTimeout = 0
+	if value := strings.TrimSpace(p.repoTimeout); value != "" {
+		p.profile.RepoTimeout, _ = time.ParseDuration(value)
+	}
+	p.profile.SkipLFS = !p.downloadLFS
+	p.profile.CloneMode = p.cloneMode
+	if p.cloneMode == state.CloneModeFull {
+		p.profile.CloneMode = ""
+	}
+	p.profile.Transport = p.transport
+	if p.transport == state.TransportHTTPS {
+		p.profile.Transport = ""
+	}
+	p.profile.SSHKey = state.ExpandPath(strings.TrimSpace(p.sshKey))
+	p.profile.SSHKnownHosts = state.ExpandPath(strings.TrimSpace(p.knownHosts))
+	p.profile.Schedule = strings.TrimSpace(p.schedule)
+	p.profile.Overlap = p.overlap
+	if p.overlap == string(schedule.OverlapSkip) {
+		p.profile.Overlap = ""
+	}
+	p.profile.Incremental = p.incremental
+
+	if p.app.Storage() == nil {
+		return fmt.Errorf("no state storage")
+	}
+	if err := p.app.Storage().ValidateProfile(p.profile); err != nil {
+		return err
+	}
+	if err := p.app.Storage().UpdateProfile(p.profile); err != nil {
+		return fmt.Errorf("failed to save profile: %w", err)
+	}
+	return nil
+}
+
+// View renders the settings screen
+func (p *ProfileSettingsScreen) View() string {
+	if p.profile == nil {
+		var content strings.Builder
+		if p.err != nil {
+			content.WriteString(p.styles.Error.Render("Error: " + p.err.Error()))
+			content.WriteString("\n\n")
+		}
+		content.WriteString("Press " + p.styles.HelpKey.Render("Esc") + " to go back")
+		return p.styles.Content.Render(content.String())
+	}
+
+	title := p.styles.FormTitle.Render(fmt.Sprintf("Sync Settings: %s", p.profile.Name))
+	source := p.styles.Muted.Render(fmt.Sprintf("%s/%s -> %s", p.profile.Type, p.profile.Source, p.profile.TargetDir))
+
+	parts := []string{title, source, "", p.form.View()}
+	if p.err != nil {
+		parts = append(parts, "", p.styles.Error.Render("Error: "+p.err.Error()))
+	}
+
+	return p.styles.Content.Render(lipgloss.JoinVertical(lipgloss.Left, parts...))
+}
@@ -2,7 +2,9 @@ package screens
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"os"
 	"sort"
 	"strings"
 	"time"
@@ -13,9 +15,11 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 	gh "github.com/google/go-github/v68/github"
 
+	"github.com/Didstopia/githubby/internal/auth"
 	gherrors "github.com/Didstopia/githubby/internal/errors"
 	"github.com/Didstopia/githubby/internal/git"
 	"github.com/Didstopia/githubby/internal/github"
+	"github.com/Didstopia/githubby/internal/lock"
 	"github.com/Didstopia/githubby/internal/state"
 	"github.com/Didstopia/githubby/internal/sync"
 	"github.com/Didstopia/githubby/internal/tui"
@@ -422,7 +426,9 @@ func (s *SyncProgressScreen) View() string {
 	// Current operation
 	if s.syncing {
 		content.WriteString(s.spinner.View())
-		if s.collecting {
+		if wait, waiting := s.rateLimitWait(); waiting {
+			fmt.Fprintf(&content, " %s", s.styles.Warning.Render(formatRateLimitWait(wait)))
+		} else if s.collecting {
 			// Show collecting phase with count
 			if s.collectingCount > 0 {
 				fmt.Fprintf(&content, " Collecting repositories... (%d found)", s.collectingCount)
@@ -464,10 +470,20 @@ func (s *SyncProgressScreen) View() string {
 			actualTotal = done
 		}
 		if s.err != nil {
-			if gherrors.IsUnauthorized(s.err) || gherrors.IsForbidden(s.err) {
+			if isAuthError(s.err) {
 				content.WriteString(s.styles.Error.Render("Authentication failed: " + s.err.Error()))
 				content.WriteString("\n\n")
-				content.WriteString(s.styles.Warning.Render("To fix this, run: githubby logout && githubby login"))
+				var refreshErr *auth.RefreshError
+				switch {
+				case errors.As(s.err, &refreshErr):
+					// The error says how to log in again
+				case gherrors.IsSSORequired(s.err):
+					content.WriteString(s.styles.Warning.Render("To fix this, authorize the token for the organization's SAML SSO"))
+				case gherrors.IsForbidden(s.err):
+					content.WriteString(s.styles.Warning.Render("To see what the token can't access, run: githubby auth status --check"))
+				default:
+					content.WriteString(s.styles.Warning.Render("To fix this, run: githubby logout && githubby login"))
+				}
 			} else {
 				content.WriteString(s.styles.Error.Render("Sync completed with errors: " + s.err.Error()))
 			}
@@ -548,8 +564,8 @@ func (s *SyncProgressScreen) View() string {
 // startSync starts the sync operation in a background goroutine
 func (s *SyncProgressScreen) startSync() tea.Cmd {
 	// Initialize channel with larger buffer to prevent worker blocking
-	// Buffer size accounts for 4 concurrent workers + main loop sending completions
-	s.syncProgressChan = make(chan profileSyncProgressUpdate, 16)
+	// Buffer size accounts for up to 8 concurrent workers + main loop sending completions
+	s.syncProgressChan = make(chan profileSyncProgressUpdate, 2*sync.MaxConcurrency)
 
 	// Start sync in background goroutine
 	go s.runSyncInBackground()
@@ -565,6 +581,9 @@ func (s *SyncProgressScreen) startSync() tea.Cmd {
 func (s *SyncProgressScreen) runSyncInBackground() {
 	defer close(s.syncProgressChan)
 
+	// Leave some of the rate limit for browsing while syncing
+	ctx := github.WithRateLimitReserve(s.ctx)
+
 	if len(s.profiles) == 0 {
 		s.syncProgressChan <- profileSyncProgressUpdate{status: "complete", err: fmt.Errorf("no profiles")}
 		return
@@ -582,18 +601,67 @@ func (s *SyncProgressScreen) runSyncInBackground() {
 		s.syncProgressChan <- profileSyncProgressUpdate{status: "complete", err: fmt.Errorf("git not available: %w", err)}
 		return
 	}
+	gitOps.TokenSource = s.app.TokenSource()
 
 	var cloned, updated, skipped, failed, archived int
 
+	// Profiles of another account or a GitHub Enterprise Server use a client
+	// and token of their own, keyed by account name and hostname, and by
+	// owner for GitHub App tokens, which are per installation
+	type hostClient struct {
+		client github.Client
+		git    *git.Git
+	}
+	type accountKey struct{ name, host, owner string }
+	current := s.app.Account()
+	gitOps.Host = current.Hostname
+	hostClients := map[accountKey]hostClient{{current.Name, current.Hostname, ""}: {client, gitOps}}
+	clientFor := func(profile *state.SyncProfile) (hostClient, error) {
+		host := profile.Hostname()
+		key := accountKey{profile.Account, host, ""}
+		if profile.Account == "" && os.Getenv(auth.EnvGitHubAppID) != "" {
+			key.owner = profile.Source
+		}
+		if hc, ok := hostClients[key]; ok {
+			return hc, nil
+		}
+		result, err := auth.GetAccountToken(auth.WithOwner(ctx, profile.Source), "", profile.Account, host)
+		if err != nil {
+			return hostClient{}, err
+		}
+		if result.Token == "" {
+			login := "githubby login"
+			if auth.IsEnterprise(host) {
+				login += " --hostname " + host
+			}
+			if profile.Account != "" {
+				login += " --account " + profile.Account
+				return hostClient{}, fmt.Errorf("not logged in to account %q on %s; run: %s", profile.Account, host, login)
+			}
+			return hostClient{}, fmt.Errorf("not logged in to %s; run: %s", host, login)
+		}
+		hostGit, err := git.NewQuietWithToken(result.Token)
+		if err != nil {
+			return hostClient{}, fmt.Errorf("git not available: %w", err)
+		}
+		hostGit.Host = host
+		hostGit.TokenSource = result.Tokens
+		hc := hostClient{github.NewDefaultClientWithTokenSource(result.Token, host, result.Tokens), hostGit}
+		hostClients[key] = hc
+		return hc, nil
+	}
+
 	// First, collect all repos to sync to get accurate total
 	type repoToSync struct {
 		owner         string
 		repo          string
 		defaultBranch string
 		cloneURL      string
+		sshURL        string
 		isPrivate     bool
 		pushedAt      *gh.Timestamp
 		profile       *state.SyncProfile
+		host          hostClient
 	}
 	var allRepos []repoToSync
 
@@ -607,12 +675,26 @@ func (s *SyncProgressScreen) runSyncInBackground() {
 	for _, profile := range s.profiles {
 		// Check for context cancellation before processing each profile
 		select {
-		case <-s.ctx.Done():
-			s.syncProgressChan <- profileSyncProgressUpdate{status: "complete", err: s.ctx.Err()}
+		case <-ctx.Done():
+			s.syncProgressChan <- profileSyncProgressUpdate{status: "complete", err: ctx.Err()}
 			return
 		default:
 		}
 
+		host, err := clientFor(profile)
+		if err != nil {
+			s.syncProgressChan <- profileSyncProgressUpdate{
+				repoName: fmt.Sprintf("%s/%s", profile.Type, profile.Source),
+				status:   "failed",
+				current:  len(allRepos),
+				total:    0,
+				err:      err,
+			}
+			failed++
+			continue
+		}
+		client := host.client
+
 		// Check if this is an "all repos" profile
 		if profile.SyncAllRepos || len(profile.SelectedRepos) == 0 {
 			// Fetch repos from API first
@@ -624,19 +706,19 @@ func (s *SyncProgressScreen) runSyncInBackground() {
 			var err error
 
 			if profile.Type == "org" {
-				repos, err = client.ListOrgRepos(s.ctx, profile.Source, listOpts)
+				repos, err = client.ListOrgRepos(ctx, profile.Source, listOpts)
 			} else {
-				repos, err = client.ListUserRepos(s.ctx, profile.Source, listOpts)
+				repos, err = client.ListUserRepos(ctx, profile.Source, listOpts)
 			}
 
 			if err != nil {
 				// Check if it's a context cancellation
-				if s.ctx.Err() != nil {
-					s.syncProgressChan <- profileSyncProgressUpdate{status: "complete", err: s.ctx.Err()}
+				if ctx.Err() != nil {
+					s.syncProgressChan <- profileSyncProgressUpdate{status: "complete", err: ctx.Err()}
 					return
 				}
 				// Check for auth errors - abort immediately, don't continue with other profiles
-				if gherrors.IsUnauthorized(err) || gherrors.IsForbidden(err) {
+				if isAuthError(err) {
 					s.syncProgressChan <- profileSyncProgressUpdate{
 						status: "complete",
 						err:    fmt.Errorf("authentication failed: %w", err),
@@ -660,9 +742,11 @@ func (s *SyncProgressScreen) runSyncInBackground() {
 					repo:          r.GetName(),
 					defaultBranch: r.GetDefaultBranch(),
 					cloneURL:      r.GetCloneURL(),
+					sshURL:        r.GetSSHURL(),
 					isPrivate:     r.GetPrivate(),
 					pushedAt:      r.PushedAt,
 					profile:       profile,
+					host:          host,
 				})
 			}
 			// Send collecting progress update after each profile's repos are fetched
@@ -672,58 +756,60 @@ func (s *SyncProgressScreen) runSyncInBackground() {
 				total:   0,
 			}
 		} else {
-			// Use specific repos from profile - fetch repo data upfront
-			for _, repoFullName := range profile.SelectedRepos {
-				// Check for context cancellation periodically
-				select {
-				case <-s.ctx.Done():
-					s.syncProgressChan <- profileSyncProgressUpdate{status: "complete", err: s.ctx.Err()}
+			// Use specific repos from profile - fetch repo data upfront in
+			// batches rather than one request per repo
+			repos, failedRepos, err := client.GetRepositories(ctx, profile.SelectedRepos)
+			if err != nil {
+				if ctx.Err() != nil {
+					s.syncProgressChan <- profileSyncProgressUpdate{status: "complete", err: ctx.Err()}
 					return
-				default:
 				}
-
-				parts := strings.SplitN(repoFullName, "/", 2)
-				if len(parts) == 2 {
-					owner, repoName := parts[0], parts[1]
-					// Fetch repo data to get defaultBranch, cloneURL, etc.
-					repoData, err := client.GetRepository(s.ctx, owner, repoName)
-					if err != nil {
-						// Check for auth errors - abort immediately
-						if gherrors.IsUnauthorized(err) || gherrors.IsForbidden(err) {
-							s.syncProgressChan <- profileSyncProgressUpdate{
-								status: "complete",
-								err:    fmt.Errorf("authentication failed: %w", err),
-							}
-							return
-						}
-						// If we can't fetch repo data, still add it but without the optimization data
-						// The sync will fall back to fetching it again
-						allRepos = append(allRepos, repoToSync{
-							owner:   owner,
-							repo:    repoName,
-							profile: profile,
-						})
-					} else {
-						allRepos = append(allRepos, repoToSync{
-							owner:         owner,
-							repo:          repoName,
-							defaultBranch: repoData.GetDefaultBranch(),
-							cloneURL:      repoData.GetCloneURL(),
-							isPrivate:     repoData.GetPrivate(),
-							pushedAt:      repoData.PushedAt,
-							profile:       profile,
-						})
-					}
-					// Send collecting progress update periodically (every 10 repos)
-					if len(allRepos)%10 == 0 {
-						s.syncProgressChan <- profileSyncProgressUpdate{
-							status:  "collecting",
-							current: len(allRepos),
-							total:   0,
-						}
+				// Check for auth errors - abort immediately
+				if isAuthError(err) {
+					s.syncProgressChan <- profileSyncProgressUpdate{
+						status: "complete",
+						err:    fmt.Errorf("authentication failed: %w", err),
 					}
+					return
+				}
+				failedRepos = make(map[string]error)
+				for _, repoFullName := range profile.SelectedRepos {
+					failedRepos[repoFullName] = err
 				}
 			}
+			for _, r := range repos {
+				allRepos = append(allRepos, repoToSync{
+					owner:         r.GetOwner().GetLogin(),
+					repo:          r.GetName(),
+					defaultBranch: r.GetDefaultBranch(),
+					cloneURL:      r.GetCloneURL(),
+					sshURL:        r.GetSSHURL(),
+					isPrivate:     r.GetPrivate(),
+					pushedAt:      r.PushedAt,
+					profile:       profile,
+					host:          host,
+				})
+			}
+			// If we can't fetch repo data, still add it but without the optimization data
+			// The sync will fall back to fetching it again
+			for _, repoFullName := range profile.SelectedRepos {
+				if _, failed := failedRepos[repoFullName]; !failed {
+					continue
+				}
+				if owner, repoName, found := strings.Cut(repoFullName, "/"); found {
+					allRepos = append(allRepos, repoToSync{
+						owner:   owner,
+						repo:    repoName,
+						profile: profile,
+						host:    host,
+					})
+				}
+			}
+			s.syncProgressChan <- profileSyncProgressUpdate{
+				status:  "collecting",
+				current: len(allRepos),
+				total:   0,
+			}
 		}
 	}
 
@@ -738,99 +824,166 @@ func (s *SyncProgressScreen) runSyncInBackground() {
 		}
 	}
 
-	// Parallel sync with worker pool (4 concurrent workers)
-	const numWorkers = 4
+	// Track completed count for progress
+	var completedCount int32
 
-	// Create job and result channels
-	jobs := make(chan int, len(allRepos))
+	// Lock each target directory so no other githubby process syncs it at the
+	// same time, and clean up leftovers from interrupted syncs once per target
+	// since the per-repo syncers below skip it
+	lockErrs := make(map[string]error)
+	var targetLocks []*lock.Lock
+	defer func() {
+		for _, l := range targetLocks {
+			_ = l.Release()
+		}
+	}()
+	for _, profile := range s.profiles {
+		if _, seen := lockErrs[profile.TargetDir]; seen {
+			continue
+		}
+		l, err := sync.LockTarget(ctx, profile.TargetDir, lock.PolicySkip, nil)
+		lockErrs[profile.TargetDir] = err
+		if err != nil {
+			continue
+		}
+		targetLocks = append(targetLocks, l)
+		_, _ = sync.Recover(ctx, gitOps, profile.TargetDir)
+	}
+
+	// Repos in a target locked by another process are reported as failed
+	runnable := make([]repoToSync, 0, len(allRepos))
+	for _, r := range allRepos {
+		lockErr := lockErrs[r.profile.TargetDir]
+		if lockErr == nil {
+			runnable = append(runnable, r)
+			continue
+		}
+		completedCount++
+		failed++
+		s.syncProgressChan <- profileSyncProgressUpdate{
+			repoName: fmt.Sprintf("%s/%s", r.owner, r.repo),
+			status:   "failed",
+			current:  int(completedCount),
+			total:    total,
+			err:      lockErr,
+		}
+	}
+	allRepos = runnable
+
+	// Parallel sync with a queue per profile, worked by as many workers as
+	// the profile's concurrency, so a profile with low concurrency never
+	// holds up the others. All workers share the syncer's overall limit.
+	queues := make(map[*state.SyncProfile]chan int, len(s.profiles))
+	for _, r := range allRepos {
+		if queues[r.profile] == nil {
+			queues[r.profile] = make(chan int, len(allRepos))
+		}
+	}
+	slots := make(chan struct{}, sync.MaxConcurrency)
+
+	// Create result channel
 	results := make(chan struct {
 		status string
 		idx    int
 		err    error
 	}, len(allRepos))
 
-	// Track completed count for progress
-	var completedCount int32
-
 	// Start workers
-	for w := 0; w < numWorkers; w++ {
-		go func() {
-			for idx := range jobs {
-				r := allRepos[idx]
-				repoName := fmt.Sprintf("%s/%s", r.owner, r.repo)
+	for profile, jobs := range queues {
+		for w := 0; w < profile.SyncConcurrency(); w++ {
+			go func() {
+				for idx := range jobs {
+					r := allRepos[idx]
+					repoName := fmt.Sprintf("%s/%s", r.owner, r.repo)
 
-				// Send progress update before starting this repo
-				s.syncProgressChan <- profileSyncProgressUpdate{
-					repoName: repoName,
-					status:   "syncing",
-					current:  int(completedCount) + 1,
-					total:    total,
-				}
+					slots <- struct{}{}
 
-				opts := &sync.Options{
-					Target:               r.profile.TargetDir,
-					IncludePrivate:       r.profile.IncludePrivate,
-					Include:              r.profile.IncludeFilter,
-					Exclude:              r.profile.ExcludeFilter,
-					SkipArchiveDetection: true, // TUI syncs per-repo; archive detection would walk entire dir per repo
-				}
+					// Send progress update before starting this repo
+					s.syncProgressChan <- profileSyncProgressUpdate{
+						repoName: repoName,
+						status:   "syncing",
+						current:  int(completedCount) + 1,
+						total:    total,
+					}
 
-				syncer := sync.New(client, gitOps, opts)
-
-				// Construct gh.Repository from our cached data to avoid redundant API call
-				// This enables fast sync optimization (comparing local/remote HEAD SHA)
-				repo := &gh.Repository{
-					Name:          gh.Ptr(r.repo),
-					DefaultBranch: gh.Ptr(r.defaultBranch),
-					Owner:         &gh.User{Login: gh.Ptr(r.owner)},
-					CloneURL:      gh.Ptr(r.cloneURL),
-					Private:       gh.Ptr(r.isPrivate),
-					PushedAt:      r.pushedAt,
-				}
-				result, err := syncer.SyncRepoWithData(s.ctx, repo)
-
-				status := "skipped"
-				var syncErr error
-				if err != nil {
-					status = "failed"
-					syncErr = err
-				} else if result != nil {
-					if len(result.Failed) > 0 {
+					opts := &sync.Options{
+						Target:               r.profile.TargetDir,
+						IncludePrivate:       r.profile.IncludePrivate,
+						Include:              r.profile.IncludeFilter,
+						Exclude:              r.profile.ExcludeFilter,
+						SkipArchiveDetection: true, // TUI syncs per-repo; archive detection would walk entire dir per repo
+						SkipRecovery:         true, // Recovery already ran once per target above
+						RepoTimeout:          r.profile.RepoTimeout,
+						SkipLFS:              r.profile.SkipLFS,
+						Mirror:               r.profile.Mirror(),
+						Depth:                r.profile.CloneDepth(),
+						SSH:                  r.profile.UseSSH(),
+					}
+
+					// Profiles sharing an account can use different SSH keys
+					profileGit := *r.host.git
+					profileGit.SSH = git.SSHOptions{IdentityFile: r.profile.SSHKey, KnownHostsFile: r.profile.SSHKnownHosts}
+					syncer := sync.New(r.host.client, &profileGit, opts)
+
+					// Construct gh.Repository from our cached data to avoid redundant API call
+					// This enables fast sync optimization (comparing local/remote HEAD SHA)
+					repo := &gh.Repository{
+						Name:          gh.Ptr(r.repo),
+						DefaultBranch: gh.Ptr(r.defaultBranch),
+						Owner:         &gh.User{Login: gh.Ptr(r.owner)},
+						CloneURL:      gh.Ptr(r.cloneURL),
+						SSHURL:        gh.Ptr(r.sshURL),
+						Private:       gh.Ptr(r.isPrivate),
+						PushedAt:      r.pushedAt,
+					}
+					result, err := syncer.SyncRepoWithData(ctx, repo)
+					<-slots
+
+					status := "skipped"
+					var syncErr error
+					if err != nil {
 						status = "failed"
-						// Get the error from the Failed map
-						for _, failErr := range result.Failed {
-							syncErr = failErr
-							break // Use the first error
+						syncErr = err
+					} else if result != nil {
+						if len(result.Failed) > 0 {
+							status = "failed"
+							// Get the error from the Failed map
+							for _, failErr := range result.Failed {
+								syncErr = failErr
+								break // Use the first error
+							}
+						} else if len(result.Cloned) > 0 {
+							status = "cloned"
+						} else if len(result.Updated) > 0 {
+							status = "updated"
+						} else if len(result.UpToDate) > 0 {
+							status = "up-to-date"
+						} else if len(result.Archived) > 0 {
+							status = "archived"
+						} else if len(result.Skipped) > 0 {
+							status = "skipped"
+						} else {
+							status = "skipped"
 						}
-					} else if len(result.Cloned) > 0 {
-						status = "cloned"
-					} else if len(result.Updated) > 0 {
-						status = "updated"
-					} else if len(result.UpToDate) > 0 {
-						status = "up-to-date"
-					} else if len(result.Archived) > 0 {
-						status = "archived"
-					} else if len(result.Skipped) > 0 {
-						status = "skipped"
-					} else {
-						status = "skipped"
 					}
-				}
 
-				results <- struct {
-					status string
-					idx    int
-					err    error
-				}{status: status, idx: idx, err: syncErr}
-			}
-		}()
+					results <- struct {
+						status string
+						idx    int
+						err    error
+					}{status: status, idx: idx, err: syncErr}
+				}
+			}()
+		}
 	}
 
 	// Send all jobs
-	for i := range allRepos {
-		jobs <- i
+	for i, r := range allRepos {
+		queues[r.profile] <- i
+	}
+	for _, jobs := range queues {
+		close(jobs)
 	}
-	close(jobs)
 
 	// Collect results
 	for i := 0; i < len(allRepos); i++ {
@@ -867,6 +1020,9 @@ func (s *SyncProgressScreen) runSyncInBackground() {
 
 	// Update profile last sync times
 	for _, profile := range s.profiles {
+		if lockErrs[profile.TargetDir] != nil {
+			continue
+		}
 		if s.app.Storage() != nil {
 			profile.LastSyncAt = time.Now()
 			s.app.Storage().UpdateProfile(profile)
@@ -895,6 +1051,28 @@ func (s *SyncProgressScreen) waitForSyncProgress() tea.Cmd {
 	}
 }
 
+// rateLimitWait returns the current wait for the rate limit, if the sync is
+// paused for it
+func (s *SyncProgressScreen) rateLimitWait() (github.RateLimitWait, bool) {
+	limiter := github.RateLimiterOf(s.app.GitHubClient())
+	if limiter == nil {
+		return github.RateLimitWait{}, false
+	}
+	return limiter.Waiting()
+}
+
+// formatRateLimitWait describes a pause for the rate limit with a countdown
+func formatRateLimitWait(wait github.RateLimitWait) string {
+	reason := "Rate limit reached"
+	switch {
+	case wait.Secondary:
+		reason = "Secondary rate limit reached"
+	case wait.Reserve:
+		reason = "Leaving the rest of the rate limit for browsing"
+	}
+	return fmt.Sprintf("⏸ %s - resuming in %s", reason, wait.Remaining())
+}
+
 // humanizeDuration converts a duration to a human-friendly string
 func humanizeDuration(d time.Duration) string {
 	if d < time.Second {
@@ -940,3 +1118,10 @@ type profileSyncProgressUpdate struct {
 type profileSyncProgressMsg struct {
 	update profileSyncProgressUpdate
 }
+
+// isAuthError reports whether err means the token was rejected or couldn't
+// be renewed, so syncing anything else would fail too
+func isAuthError(err error) bool {
+	var refreshErr *auth.RefreshError
+	return gherrors.IsUnauthorized(err) || gherrors.IsForbidden(err) || errors.As(err, &refreshErr)
+}
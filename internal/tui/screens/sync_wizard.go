@@ -21,8 +21,10 @@ import (
 	"github.com/charmbracelet/lipgloss"
 	gh "github.com/google/go-github/v68/github"
 
+	"github.com/Didstopia/githubby/internal/auth"
 	"github.com/Didstopia/githubby/internal/git"
 	"github.com/Didstopia/githubby/internal/github"
+	"github.com/Didstopia/githubby/internal/lock"
 	"github.com/Didstopia/githubby/internal/state"
 	"github.com/Didstopia/githubby/internal/sync"
 	"github.com/Didstopia/githubby/internal/tui"
@@ -118,6 +120,7 @@ type SyncWizard struct {
 	// Profile options
 	profileName   string
 	saveAsProfile bool
+	profileErr    error
 	confirmForm   *huh.Form
 
 	// Execution state
@@ -284,13 +287,25 @@ func (w *SyncWizard) initConfirmForm() {
 		huh.NewGroup(
 			huh.NewInput().
 				Title("Profile name").
-				Value(&w.profileName),
+				Value(&w.profileName).
+				Validate(w.validateProfileName),
 		).WithHideFunc(func() bool {
 			return !w.saveAsProfile
 		}),
 	).WithTheme(huh.ThemeCharm())
 }
 
+// validateProfileName checks the profile name entered in the confirm form
+func (w *SyncWizard) validateProfileName(name string) error {
+	if err := state.ValidateProfileName(name); err != nil {
+		return err
+	}
+	if w.app.Storage() == nil {
+		return nil
+	}
+	return w.app.Storage().State().CheckProfileName(name, "")
+}
+
 // initRepoList initializes the repository selection list
 func (w *SyncWizard) initRepoList() {
 	items := make([]list.Item, len(w.repoItems))
@@ -464,10 +479,7 @@ func (w *SyncWizard) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					return w, nil
 				}
 				// Expand ~ to home directory
-				if strings.HasPrefix(w.targetDir, "~") {
-					homeDir, _ := os.UserHomeDir()
-					w.targetDir = filepath.Join(homeDir, w.targetDir[1:])
-				}
+				w.targetDir = state.ExpandPath(w.targetDir)
 				w.step = WizardStepConfirm
 				w.initConfirmForm()
 				return w, w.confirmForm.Init()
@@ -572,6 +584,12 @@ func (w *SyncWizard) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					w.targetDir,
 					w.includePrivate,
 				)
+				// Sync with the account the repositories were listed with
+				account := w.app.Account()
+				profile.Account = account.Name
+				if auth.IsEnterprise(account.Hostname) {
+					profile.Host = account.Hostname
+				}
 				// Set sync mode based on user's choice
 				profile.SyncAllRepos = w.selectAllRepos
 				if !w.selectAllRepos {
@@ -585,7 +603,10 @@ func (w *SyncWizard) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				if !w.syncRecord.CompletedAt.IsZero() {
 					profile.LastSyncAt = w.syncRecord.CompletedAt
 				}
-				_ = w.app.Storage().AddProfile(profile)
+				w.profileErr = w.app.Storage().ValidateProfile(profile)
+				if w.profileErr == nil {
+					w.profileErr = w.app.Storage().AddProfile(profile)
+				}
 			}
 		}
 	}
@@ -847,10 +868,24 @@ func (w *SyncWizard) runSyncInBackground() {
 		w.syncDoneChan <- syncDoneUpdate{err: fmt.Errorf("git not available: %w", err)}
 		return
 	}
+	gitOps.Host = w.app.Account().Hostname
+	gitOps.TokenSource = w.app.TokenSource()
+
+	// Don't sync into a target another githubby process is already syncing
+	targetLock, err := sync.LockTarget(w.ctx, w.targetDir, lock.PolicyFail, nil)
+	if err != nil {
+		w.syncDoneChan <- syncDoneUpdate{err: err}
+		return
+	}
+	defer targetLock.Release()
+
+	// Clean up leftovers from interrupted syncs once, not once per repo
+	_, _ = sync.Recover(w.ctx, gitOps, w.targetDir)
 
 	opts := &sync.Options{
 		Target:         w.targetDir,
 		IncludePrivate: w.includePrivate,
+		SkipRecovery:   true,
 	}
 
 	syncer := sync.New(client, gitOps, opts)
@@ -1179,7 +1214,11 @@ func (w *SyncWizard) viewComplete() string {
 
 	if w.saveAsProfile {
 		content.WriteString("\n")
-		content.WriteString(w.styles.Success.Render(fmt.Sprintf("Profile '%s' saved!", w.profileName)))
+		if w.profileErr != nil {
+			content.WriteString(w.styles.Error.Render(fmt.Sprintf("Profile '%s' not saved: %v", w.profileName, w.profileErr)))
+		} else {
+			content.WriteString(w.styles.Success.Render(fmt.Sprintf("Profile '%s' saved!", w.profileName)))
+		}
 	}
 
 	content.WriteString("\n\n")
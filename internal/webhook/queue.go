@@ -0,0 +1,239 @@
+package webhook
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// DefaultDebounce is how long the queue waits for more events about a
+// repository before syncing it
+const DefaultDebounce = 10 * time.Second
+
+// Task is a queued sync of one repository for one profile
+type Task struct {
+	// ProfileID is the profile the repository is synced for
+	ProfileID string
+
+	// Repo is the repository's full name (owner/repo)
+	Repo string
+
+	// PreviousRepo is the name the local copy should be moved from first,
+	// after a rename or transfer
+	PreviousRepo string
+}
+
+// key identifies the tasks that are merged while debouncing
+func (t Task) key() string {
+	return t.ProfileID + "\x00" + t.Repo
+}
+
+// merge combines a task with one queued later for the same repository,
+// keeping a pending move
+func (t Task) merge(later Task) Task {
+	if later.PreviousRepo == "" {
+		later.PreviousRepo = t.PreviousRepo
+	}
+	return later
+}
+
+// taskState is where a queued task is
+type taskState int
+
+const (
+	// taskWaiting tasks wait for the debounce period to pass
+	taskWaiting taskState = iota
+	// taskReady tasks wait for a worker
+	taskReady
+	// taskRunning tasks are being synced
+	taskRunning
+)
+
+// entry is a task in the queue
+type entry struct {
+	task  Task
+	state taskState
+	timer *time.Timer
+	rerun *Task // arrived while running
+}
+
+// QueueOptions configures a Queue
+type QueueOptions struct {
+	// Debounce is how long to wait for more events about a repository
+	// before syncing it (0 = sync right away)
+	Debounce time.Duration
+
+	// Workers is the number of repositories synced at once (default 1)
+	Workers int
+}
+
+// Queue debounces tasks per repository and runs them on a pool of workers.
+// Events about a repository that arrive within the debounce period are
+// merged into one sync, and a repository is never synced twice at once;
+// events that arrive while it's syncing queue one more sync afterwards.
+type Queue struct {
+	opts QueueOptions
+	run  func(ctx context.Context, task Task)
+
+	mu      sync.Mutex
+	entries map[string]*entry
+	ready   []string
+	wake    chan struct{}
+}
+
+// NewQueue creates a queue that syncs tasks with run
+func NewQueue(opts QueueOptions, run func(ctx context.Context, task Task)) *Queue {
+	if opts.Workers <= 0 {
+		opts.Workers = 1
+	}
+	return &Queue{
+		opts:    opts,
+		run:     run,
+		entries: make(map[string]*entry),
+		wake:    make(chan struct{}, 1),
+	}
+}
+
+// Add queues a task, merging it with a queued task for the same repository
+func (q *Queue) Add(task Task) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	key := task.key()
+	e, ok := q.entries[key]
+	if !ok {
+		e = &entry{task: task}
+		q.entries[key] = e
+		q.schedule(key, e)
+		return
+	}
+
+	switch e.state {
+	case taskWaiting:
+		e.task = e.task.merge(task)
+		e.timer.Reset(q.opts.Debounce)
+	case taskReady:
+		e.task = e.task.merge(task)
+	case taskRunning:
+		merged := task
+		if e.rerun != nil {
+			merged = e.rerun.merge(task)
+		}
+		e.rerun = &merged
+	}
+}
+
+// Len returns the number of repositories waiting or syncing
+func (q *Queue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.entries)
+}
+
+// Run syncs queued tasks until ctx is cancelled, then waits for the running
+// syncs to finish
+func (q *Queue) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i := 0; i < q.opts.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			q.work(ctx)
+		}()
+	}
+	wg.Wait()
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, e := range q.entries {
+		if e.timer != nil {
+			e.timer.Stop()
+		}
+	}
+}
+
+// schedule starts the debounce timer of a waiting entry. Must be called with mu held.
+func (q *Queue) schedule(key string, e *entry) {
+	e.state = taskWaiting
+	e.timer = time.AfterFunc(q.opts.Debounce, func() { q.fire(key) })
+}
+
+// fire moves a waiting entry to the ready list once its debounce period passed
+func (q *Queue) fire(key string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	e, ok := q.entries[key]
+	if !ok || e.state != taskWaiting {
+		return
+	}
+	e.state = taskReady
+	q.ready = append(q.ready, key)
+	q.signal()
+}
+
+// signal wakes a worker. Must be called with mu held.
+func (q *Queue) signal() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// work runs ready tasks until ctx is cancelled
+func (q *Queue) work(ctx context.Context) {
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+
+		key, task, ok := q.next()
+		if !ok {
+			select {
+			case <-ctx.Done():
+				return
+			case <-q.wake:
+				continue
+			}
+		}
+
+		q.run(ctx, task)
+		q.finish(key)
+	}
+}
+
+// next takes the oldest ready task
+func (q *Queue) next() (string, Task, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.ready) == 0 {
+		return "", Task{}, false
+	}
+	key := q.ready[0]
+	q.ready = q.ready[1:]
+	// Let another worker pick up the rest
+	if len(q.ready) > 0 {
+		q.signal()
+	}
+
+	e := q.entries[key]
+	e.state = taskRunning
+	return key, e.task, true
+}
+
+// finish removes a finished task, or schedules the events that arrived while
+// it was running
+func (q *Queue) finish(key string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	e := q.entries[key]
+	if e.rerun == nil {
+		delete(q.entries, key)
+		return
+	}
+	e.task = *e.rerun
+	e.rerun = nil
+	q.schedule(key, e)
+}
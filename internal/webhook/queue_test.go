@@ -0,0 +1,109 @@
+package webhook
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recorder records the tasks a queue runs, optionally blocking each run
+// until release is closed
+type recorder struct {
+	mu      sync.Mutex
+	tasks   []Task
+	release chan struct{}
+}
+
+func (r *recorder) run(ctx context.Context, task Task) {
+	r.mu.Lock()
+	r.tasks = append(r.tasks, task)
+	r.mu.Unlock()
+	if r.release != nil {
+		select {
+		case <-r.release:
+		case <-ctx.Done():
+		}
+	}
+}
+
+func (r *recorder) ran() []Task {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Task(nil), r.tasks...)
+}
+
+// startQueue runs q in the background until the test ends
+func startQueue(t *testing.T, q *Queue) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		q.Run(ctx)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+}
+
+func TestQueue_Debounce(t *testing.T) {
+	r := &recorder{}
+	q := NewQueue(QueueOptions{Debounce: 50 * time.Millisecond}, r.run)
+	startQueue(t, q)
+
+	q.Add(Task{ProfileID: "p", Repo: "acme/api", PreviousRepo: "acme/old-api"})
+	q.Add(Task{ProfileID: "p", Repo: "acme/api"})
+	q.Add(Task{ProfileID: "p", Repo: "acme/web"})
+	q.Add(Task{ProfileID: "other", Repo: "acme/api"})
+	assert.Equal(t, 3, q.Len())
+
+	require.Eventually(t, func() bool { return len(r.ran()) == 3 }, time.Second, 10*time.Millisecond)
+	time.Sleep(100 * time.Millisecond)
+
+	ran := r.ran()
+	assert.Len(t, ran, 3, "events for the same repo should be merged")
+	assert.Contains(t, ran, Task{ProfileID: "p", Repo: "acme/api", PreviousRepo: "acme/old-api"}, "pending move should be kept")
+	assert.Contains(t, ran, Task{ProfileID: "p", Repo: "acme/web"})
+	assert.Contains(t, ran, Task{ProfileID: "other", Repo: "acme/api"})
+	assert.Eventually(t, func() bool { return q.Len() == 0 }, time.Second, 10*time.Millisecond)
+}
+
+func TestQueue_EventsWhileRunning(t *testing.T) {
+	r := &recorder{release: make(chan struct{})}
+	q := NewQueue(QueueOptions{Workers: 4}, r.run)
+	startQueue(t, q)
+
+	q.Add(Task{ProfileID: "p", Repo: "acme/api"})
+	require.Eventually(t, func() bool { return len(r.ran()) == 1 }, time.Second, 10*time.Millisecond)
+
+	// The repo isn't synced twice at once, even with free workers
+	q.Add(Task{ProfileID: "p", Repo: "acme/api"})
+	q.Add(Task{ProfileID: "p", Repo: "acme/api"})
+	time.Sleep(50 * time.Millisecond)
+	assert.Len(t, r.ran(), 1)
+
+	// Other repos sync in parallel
+	q.Add(Task{ProfileID: "p", Repo: "acme/web"})
+	require.Eventually(t, func() bool { return len(r.ran()) == 2 }, time.Second, 10*time.Millisecond)
+
+	// Once the first sync finishes, the events that arrived meanwhile sync once more
+	close(r.release)
+	require.Eventually(t, func() bool { return len(r.ran()) == 3 }, time.Second, 10*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	assert.Len(t, r.ran(), 3)
+	assert.Equal(t, "acme/api", r.ran()[2].Repo)
+}
+
+func TestQueue_AddBeforeRun(t *testing.T) {
+	r := &recorder{}
+	q := NewQueue(QueueOptions{}, r.run)
+	q.Add(Task{ProfileID: "p", Repo: "acme/api"})
+
+	startQueue(t, q)
+	assert.Eventually(t, func() bool { return len(r.ran()) == 1 }, time.Second, 10*time.Millisecond)
+}
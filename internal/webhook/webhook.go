@@ -0,0 +1,226 @@
+// Package webhook receives GitHub webhook deliveries and queues syncs of the
+// repositories they affect
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// GitHub webhook headers
+const (
+	// SignatureHeader holds the HMAC-SHA256 signature of the payload
+	SignatureHeader = "X-Hub-Signature-256"
+	// EventHeader holds the event type
+	EventHeader = "X-GitHub-Event"
+	// DeliveryHeader holds the unique ID of the delivery
+	DeliveryHeader = "X-GitHub-Delivery"
+	// EnterpriseHostHeader holds the hostname of the GitHub Enterprise Server
+	// that sent the delivery; github.com doesn't send it
+	EnterpriseHostHeader = "X-GitHub-Enterprise-Host"
+)
+
+// MaxPayloadSize is the largest payload accepted; GitHub caps payloads at 25 MB
+const MaxPayloadSize = 25 << 20
+
+// Signature errors
+var (
+	ErrMissingSignature = errors.New("missing " + SignatureHeader + " header")
+	ErrInvalidSignature = errors.New("signature doesn't match the payload")
+)
+
+// ErrUnsupportedEvent is returned by ParseEvent for event types that don't
+// affect synced repositories
+var ErrUnsupportedEvent = errors.New("unsupported event")
+
+// Sign returns the X-Hub-Signature-256 value of body for secret
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature checks that signature is the X-Hub-Signature-256 value of
+// body for secret
+func VerifySignature(secret, body []byte, signature string) error {
+	if signature == "" {
+		return ErrMissingSignature
+	}
+	if !hmac.Equal([]byte(signature), []byte(Sign(secret, body))) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// Event types handled by the receiver
+const (
+	EventPush       = "push"
+	EventCreate     = "create"
+	EventDelete     = "delete"
+	EventRepository = "repository"
+	EventRelease    = "release"
+	EventPing       = "ping"
+)
+
+// Repository actions that change where a repository lives
+const (
+	ActionRenamed     = "renamed"
+	ActionTransferred = "transferred"
+	ActionDeleted     = "deleted"
+)
+
+// Event is a webhook delivery that affects a repository
+type Event struct {
+	// Type is the X-GitHub-Event type
+	Type string
+
+	// Delivery is the X-GitHub-Delivery ID
+	Delivery string
+
+	// Host is the X-GitHub-Enterprise-Host hostname (empty for github.com)
+	Host string
+
+	// Action is the payload's action, e.g. "renamed" (empty for push, create and delete)
+	Action string
+
+	// Repo is the repository's full name (owner/repo) after the event
+	Repo string
+
+	// Private is true for private repositories
+	Private bool
+
+	// PreviousRepo is the repository's full name before it was renamed or
+	// transferred, if it was
+	PreviousRepo string
+}
+
+// payload holds the fields read from webhook payloads
+type payload struct {
+	Action     string `json:"action"`
+	Repository *struct {
+		FullName string `json:"full_name"`
+		Name     string `json:"name"`
+		Private  bool   `json:"private"`
+		Owner    struct {
+			Login string `json:"login"`
+		} `json:"owner"`
+	} `json:"repository"`
+	Changes struct {
+		Repository struct {
+			Name struct {
+				From string `json:"from"`
+			} `json:"name"`
+		} `json:"repository"`
+		Owner struct {
+			From struct {
+				User *struct {
+					Login string `json:"login"`
+				} `json:"user"`
+				Organization *struct {
+					Login string `json:"login"`
+				} `json:"organization"`
+			} `json:"from"`
+		} `json:"owner"`
+	} `json:"changes"`
+}
+
+// ParseEvent parses a webhook payload. Returns ErrUnsupportedEvent for event
+// types other than push, create, delete, repository and release.
+func ParseEvent(eventType, delivery string, body []byte) (*Event, error) {
+	switch eventType {
+	case EventPush, EventCreate, EventDelete, EventRepository, EventRelease:
+	default:
+		return nil, fmt.Errorf("%w %q", ErrUnsupportedEvent, eventType)
+	}
+
+	var p payload
+	if err := json.Unmarshal(body, &p); err != nil {
+		return nil, fmt.Errorf("invalid %s payload: %w", eventType, err)
+	}
+	if p.Repository == nil || !strings.Contains(p.Repository.FullName, "/") {
+		return nil, fmt.Errorf("%s payload has no repository", eventType)
+	}
+
+	event := &Event{
+		Type:     eventType,
+		Delivery: delivery,
+		Action:   p.Action,
+		Repo:     p.Repository.FullName,
+		Private:  p.Repository.Private,
+	}
+
+	if eventType == EventRepository {
+		switch p.Action {
+		case ActionRenamed:
+			if from := p.Changes.Repository.Name.From; from != "" {
+				event.PreviousRepo = p.Repository.Owner.Login + "/" + from
+			}
+		case ActionTransferred:
+			from := p.Changes.Owner.From
+			switch {
+			case from.Organization != nil && from.Organization.Login != "":
+				event.PreviousRepo = from.Organization.Login + "/" + p.Repository.Name
+			case from.User != nil && from.User.Login != "":
+				event.PreviousRepo = from.User.Login + "/" + p.Repository.Name
+			}
+		}
+	}
+
+	return event, nil
+}
+
+// Handler returns an http.Handler that verifies deliveries with secret and
+// passes the events they describe to dispatch. Deliveries are answered right
+// away; dispatch must not block.
+func Handler(secret []byte, dispatch func(*Event)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, MaxPayloadSize))
+		if err != nil {
+			http.Error(w, "payload too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		delivery := r.Header.Get(DeliveryHeader)
+		if err := VerifySignature(secret, body, r.Header.Get(SignatureHeader)); err != nil {
+			log.Printf("[webhook] Rejected delivery %s: %v", delivery, err)
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		eventType := r.Header.Get(EventHeader)
+		if eventType == EventPing {
+			fmt.Fprintln(w, "pong")
+			return
+		}
+
+		event, err := ParseEvent(eventType, delivery, body)
+		if errors.Is(err, ErrUnsupportedEvent) {
+			fmt.Fprintf(w, "ignored %s event\n", eventType)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		event.Host = strings.ToLower(strings.TrimSpace(r.Header.Get(EnterpriseHostHeader)))
+
+		dispatch(event)
+		w.WriteHeader(http.StatusAccepted)
+		fmt.Fprintln(w, "accepted")
+	})
+}
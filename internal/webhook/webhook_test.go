@@ -0,0 +1,143 @@
+package webhook
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testSecret = []byte("It's a Secret to Everybody")
+
+func TestVerifySignature(t *testing.T) {
+	body := []byte("Hello, World!")
+
+	// Example from GitHub's webhook documentation
+	signature := "sha256=757107ea0eb2509fc211221cce984b8a37570b6d7586c22c46f4379c8b043e17"
+	assert.Equal(t, signature, Sign(testSecret, body))
+	assert.NoError(t, VerifySignature(testSecret, body, signature))
+
+	assert.ErrorIs(t, VerifySignature(testSecret, body, ""), ErrMissingSignature)
+	assert.ErrorIs(t, VerifySignature(testSecret, []byte("Hello, World?"), signature), ErrInvalidSignature)
+	assert.ErrorIs(t, VerifySignature([]byte("wrong"), body, signature), ErrInvalidSignature)
+}
+
+func TestParseEvent(t *testing.T) {
+	tests := []struct {
+		name      string
+		eventType string
+		body      string
+		want      *Event
+		wantErr   string
+	}{
+		{
+			name:      "push",
+			eventType: EventPush,
+			body:      `{"ref":"refs/heads/main","repository":{"full_name":"acme/api","name":"api","private":true,"owner":{"login":"acme"}}}`,
+			want:      &Event{Type: EventPush, Delivery: "d1", Repo: "acme/api", Private: true},
+		},
+		{
+			name:      "release",
+			eventType: EventRelease,
+			body:      `{"action":"published","repository":{"full_name":"acme/api","name":"api","owner":{"login":"acme"}}}`,
+			want:      &Event{Type: EventRelease, Delivery: "d1", Action: "published", Repo: "acme/api"},
+		},
+		{
+			name:      "rename",
+			eventType: EventRepository,
+			body: `{"action":"renamed","changes":{"repository":{"name":{"from":"old-api"}}},
+				"repository":{"full_name":"acme/api","name":"api","owner":{"login":"acme"}}}`,
+			want: &Event{Type: EventRepository, Delivery: "d1", Action: ActionRenamed, Repo: "acme/api", PreviousRepo: "acme/old-api"},
+		},
+		{
+			name:      "transfer from an organization",
+			eventType: EventRepository,
+			body: `{"action":"transferred","changes":{"owner":{"from":{"organization":{"login":"old-org"}}}},
+				"repository":{"full_name":"acme/api","name":"api","owner":{"login":"acme"}}}`,
+			want: &Event{Type: EventRepository, Delivery: "d1", Action: ActionTransferred, Repo: "acme/api", PreviousRepo: "old-org/api"},
+		},
+		{
+			name:      "transfer from a user",
+			eventType: EventRepository,
+			body: `{"action":"transferred","changes":{"owner":{"from":{"user":{"login":"alice"}}}},
+				"repository":{"full_name":"acme/api","name":"api","owner":{"login":"acme"}}}`,
+			want: &Event{Type: EventRepository, Delivery: "d1", Action: ActionTransferred, Repo: "acme/api", PreviousRepo: "alice/api"},
+		},
+		{name: "unsupported", eventType: "issues", body: `{}`, wantErr: "unsupported event"},
+		{name: "invalid JSON", eventType: EventPush, body: `{`, wantErr: "invalid push payload"},
+		{name: "no repository", eventType: EventCreate, body: `{"ref":"v1"}`, wantErr: "has no repository"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event, err := ParseEvent(tt.eventType, "d1", []byte(tt.body))
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, event)
+		})
+	}
+}
+
+func TestHandler(t *testing.T) {
+	var events []*Event
+	handler := Handler(testSecret, func(event *Event) { events = append(events, event) })
+
+	deliver := func(method, eventType, body, signature string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/webhook", strings.NewReader(body))
+		req.Header.Set(EventHeader, eventType)
+		req.Header.Set(DeliveryHeader, "d1")
+		if signature != "" {
+			req.Header.Set(SignatureHeader, signature)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+	sign := func(body string) string { return Sign(testSecret, []byte(body)) }
+
+	push := `{"repository":{"full_name":"acme/api","name":"api","owner":{"login":"acme"}}}`
+
+	rec := deliver(http.MethodPost, EventPush, push, sign(push))
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	require.Len(t, events, 1)
+	assert.Equal(t, "acme/api", events[0].Repo)
+	assert.Empty(t, events[0].Host)
+
+	rec = deliver(http.MethodPost, EventPush, push, "")
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	rec = deliver(http.MethodPost, EventPush, push, sign("tampered"))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	rec = deliver(http.MethodGet, EventPush, push, sign(push))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+
+	rec = deliver(http.MethodPost, EventPing, `{"zen":"Keep it logically awesome."}`, sign(`{"zen":"Keep it logically awesome."}`))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "pong")
+
+	rec = deliver(http.MethodPost, "issues", push, sign(push))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "ignored issues event")
+
+	rec = deliver(http.MethodPost, EventPush, `{}`, sign(`{}`))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	assert.Len(t, events, 1, "only the valid push should be dispatched")
+
+	// GitHub Enterprise Server names itself
+	req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(push))
+	req.Header.Set(EventHeader, EventPush)
+	req.Header.Set(SignatureHeader, sign(push))
+	req.Header.Set(EnterpriseHostHeader, "GitHub.MyCompany.com")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	require.Len(t, events, 2)
+	assert.Equal(t, "github.mycompany.com", events[1].Host)
+}
@@ -0,0 +1,53 @@
+package util
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// WriteFileAtomic writes data to a temp file next to path, flushes it to
+// disk and renames it over path, so a crash leaves either the old or the new
+// file but never a partially written one
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tempFile := path + ".tmp"
+
+	f, err := os.OpenFile(tempFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		_ = os.Remove(tempFile)
+		return fmt.Errorf("failed to write temp file: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		_ = os.Remove(tempFile)
+		return fmt.Errorf("failed to flush temp file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		_ = os.Remove(tempFile)
+		return fmt.Errorf("failed to close temp file: %w", err)
+	}
+
+	if err := os.Rename(tempFile, path); err != nil {
+		// Clean up temp file on failure
+		_ = os.Remove(tempFile)
+		return fmt.Errorf("failed to rename temp file: %w", err)
+	}
+
+	syncDir(filepath.Dir(path))
+	return nil
+}
+
+// syncDir flushes a directory so a rename inside it survives a crash.
+// Best-effort: not every platform supports syncing directories (e.g., Windows).
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	_ = d.Sync()
+	d.Close()
+}
@@ -2,6 +2,8 @@
 package util
 
 import (
+	"net"
+	"net/url"
 	"strings"
 
 	gherrors "github.com/Didstopia/githubby/internal/errors"
@@ -55,27 +57,58 @@ func ValidateGitHubRepository(repository string) (string, string, error) {
 }
 
 // ParseRepositoryURL extracts owner and repo from a GitHub URL
-// Supports HTTPS and SSH URLs
-func ParseRepositoryURL(url string) (string, string, error) {
+// Supports HTTPS and SSH URLs (git@github.com:owner/repo and ssh://)
+func ParseRepositoryURL(rawURL string) (string, string, error) {
+	return ParseRepositoryURLForHost(rawURL, "github.com")
+}
+
+// ParseRepositoryURLForHost extracts owner and repo from a URL of the GitHub
+// instance at hostname, such as a GitHub Enterprise Server
+// Supports HTTPS and SSH URLs (git@host:owner/repo and ssh://)
+func ParseRepositoryURLForHost(rawURL, hostname string) (string, string, error) {
+	hostname = strings.ToLower(hostname)
+	hostname = strings.TrimPrefix(strings.TrimPrefix(hostname, "https://"), "http://")
+	if hostname == "" {
+		hostname = "github.com"
+	}
+	lower := strings.ToLower(rawURL)
+
+	// SSH is served on the host's default SSH port, whatever its web port
+	sshHost := hostname
+	if host, _, err := net.SplitHostPort(hostname); err == nil {
+		sshHost = host
+	}
+
 	// Handle SSH URL: git@github.com:owner/repo.git
-	if strings.HasPrefix(url, "git@github.com:") {
-		path := strings.TrimPrefix(url, "git@github.com:")
+	if prefix := "git@" + sshHost + ":"; strings.HasPrefix(lower, prefix) {
+		path := rawURL[len(prefix):]
 		path = strings.TrimSuffix(path, ".git")
 		return ValidateGitHubRepository(path)
 	}
 
-	// Handle HTTPS URL: https://github.com/owner/repo.git
-	if strings.Contains(url, "github.com/") {
-		// Find the path after github.com/
-		idx := strings.Index(url, "github.com/")
-		if idx == -1 {
-			return "", "", gherrors.NewValidationError("url", "not a GitHub URL")
+	// Handle SSH URL: ssh://git@github.com/owner/repo.git, with an optional port
+	if strings.HasPrefix(lower, "ssh://") {
+		parsed, err := url.Parse(rawURL)
+		if err != nil || !strings.EqualFold(parsed.Hostname(), sshHost) {
+			return "", "", gherrors.NewValidationError("url", "not a URL of "+hostname)
 		}
-		path := url[idx+len("github.com/"):]
+		path := strings.TrimPrefix(parsed.Path, "/")
+		path = strings.TrimSuffix(path, "/")
 		path = strings.TrimSuffix(path, ".git")
+		return ValidateGitHubRepository(path)
+	}
+
+	// Handle HTTPS URL: https://github.com/owner/repo.git
+	if idx := strings.Index(lower, hostname+"/"); idx != -1 {
+		// Don't match hosts that only end in the hostname
+		if idx > 0 && !strings.ContainsRune("/@.", rune(lower[idx-1])) {
+			return "", "", gherrors.NewValidationError("url", "not a URL of "+hostname)
+		}
+		path := rawURL[idx+len(hostname)+1:]
 		path = strings.TrimSuffix(path, "/")
+		path = strings.TrimSuffix(path, ".git")
 		return ValidateGitHubRepository(path)
 	}
 
-	return "", "", gherrors.NewValidationError("url", "not a GitHub URL")
+	return "", "", gherrors.NewValidationError("url", "not a URL of "+hostname)
 }
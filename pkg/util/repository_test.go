@@ -159,6 +159,25 @@ func TestParseRepositoryURL(t *testing.T) {
 			wantRepo:  "repo",
 			wantErr:   false,
 		},
+		{
+			name:      "ssh:// URL",
+			input:     "ssh://git@github.com/owner/repo.git",
+			wantOwner: "owner",
+			wantRepo:  "repo",
+			wantErr:   false,
+		},
+		{
+			name:      "ssh:// URL with port",
+			input:     "ssh://git@github.com:22/owner/repo",
+			wantOwner: "owner",
+			wantRepo:  "repo",
+			wantErr:   false,
+		},
+		{
+			name:    "ssh:// URL of another host",
+			input:   "ssh://git@gitlab.com/owner/repo.git",
+			wantErr: true,
+		},
 		{
 			name:    "non-GitHub URL",
 			input:   "https://gitlab.com/owner/repo",
@@ -189,3 +208,93 @@ func TestParseRepositoryURL(t *testing.T) {
 		})
 	}
 }
+
+func TestParseRepositoryURLForHost(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		hostname  string
+		wantOwner string
+		wantRepo  string
+		wantErr   bool
+	}{
+		{
+			name:      "Enterprise HTTPS URL",
+			input:     "https://ghes.example.com/owner/repo.git",
+			hostname:  "ghes.example.com",
+			wantOwner: "owner",
+			wantRepo:  "repo",
+		},
+		{
+			name:      "Enterprise SSH URL",
+			input:     "git@ghes.example.com:owner/repo.git",
+			hostname:  "ghes.example.com",
+			wantOwner: "owner",
+			wantRepo:  "repo",
+		},
+		{
+			name:      "Enterprise ssh:// URL",
+			input:     "ssh://git@GHES.example.com:2222/owner/repo.git",
+			hostname:  "ghes.example.com",
+			wantOwner: "owner",
+			wantRepo:  "repo",
+		},
+		{
+			name:      "SSH URL of an HTTP host with port",
+			input:     "git@127.0.0.1:owner/repo.git",
+			hostname:  "http://127.0.0.1:8080",
+			wantOwner: "owner",
+			wantRepo:  "repo",
+		},
+		{
+			name:      "HTTP host with port",
+			input:     "http://127.0.0.1:8080/owner/repo",
+			hostname:  "http://127.0.0.1:8080",
+			wantOwner: "owner",
+			wantRepo:  "repo",
+		},
+		{
+			name:      "hostname case is ignored",
+			input:     "https://GHES.example.com/Owner/Repo",
+			hostname:  "ghes.example.com",
+			wantOwner: "Owner",
+			wantRepo:  "Repo",
+		},
+		{
+			name:     "github.com URL for an enterprise host",
+			input:    "https://github.com/owner/repo",
+			hostname: "ghes.example.com",
+			wantErr:  true,
+		},
+		{
+			name:     "host that only ends in the hostname",
+			input:    "https://notghes.example.com/owner/repo",
+			hostname: "ghes.example.com",
+			wantErr:  true,
+		},
+		{
+			name:      "empty hostname means github.com",
+			input:     "https://github.com/owner/repo",
+			wantOwner: "owner",
+			wantRepo:  "repo",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			owner, repo, err := ParseRepositoryURLForHost(tt.input, tt.hostname)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseRepositoryURLForHost() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr {
+				if owner != tt.wantOwner {
+					t.Errorf("owner = %v, want %v", owner, tt.wantOwner)
+				}
+				if repo != tt.wantRepo {
+					t.Errorf("repo = %v, want %v", repo, tt.wantRepo)
+				}
+			}
+		})
+	}
+}